@@ -1,20 +1,31 @@
 package main
 
 import (
-	"fmt"
+	"context"
 	"log"
 	"net/http"
+	"net/url"
 
+	"github.com/CP-Payne/wonderpicai/internal/adapter/document/pdfreceipt"
 	"github.com/CP-Payne/wonderpicai/internal/adapter/externalauth/googleprovider"
 	"github.com/CP-Payne/wonderpicai/internal/adapter/generation/comfylite"
+	"github.com/CP-Payne/wonderpicai/internal/adapter/generation/genrouter"
+	"github.com/CP-Payne/wonderpicai/internal/adapter/generation/openaiimages"
+	"github.com/CP-Payne/wonderpicai/internal/adapter/mailer/logmailer"
+	"github.com/CP-Payne/wonderpicai/internal/adapter/mailer/smtpmailer"
+	"github.com/CP-Payne/wonderpicai/internal/adapter/metrics/prommetrics"
 	"github.com/CP-Payne/wonderpicai/internal/adapter/paymentprovider/stripe"
 	gormadapter "github.com/CP-Payne/wonderpicai/internal/adapter/persistence/gorm"
+	"github.com/CP-Payne/wonderpicai/internal/adapter/pricing/filerules"
 	"github.com/CP-Payne/wonderpicai/internal/adapter/tokenservice"
 	appconfig "github.com/CP-Payne/wonderpicai/internal/config"
+	"github.com/CP-Payne/wonderpicai/internal/domain"
 	allHandlers "github.com/CP-Payne/wonderpicai/internal/handler/http"
 	applogger "github.com/CP-Payne/wonderpicai/internal/logger"
+	"github.com/CP-Payne/wonderpicai/internal/port"
 	"github.com/CP-Payne/wonderpicai/internal/routes"
 	"github.com/CP-Payne/wonderpicai/internal/service"
+	"github.com/CP-Payne/wonderpicai/internal/tracing"
 	"go.uber.org/zap"
 )
 
@@ -30,14 +41,44 @@ func main() {
 	defer logger.Sync()
 	zap.ReplaceGlobals(logger)
 
+	shutdownTracing, err := tracing.Setup(context.Background(), logger, cfg.Tracing.OTLPEndpoint, cfg.Tracing.ServiceName, cfg.Tracing.SampleRatio)
+	if err != nil {
+		logger.Fatal("Failed to initialize tracing", zap.Error(err))
+	}
+	defer shutdownTracing(context.Background())
+
 	gormadapter.ConnectDatabase(cfg.Database.DSN, cfg.Server.AppEnv, cfg.Server.LogLevel, logger)
 	db := gormadapter.DB
 
+	appMetrics := prommetrics.NewMetrics(logger)
+
 	tokenService := tokenservice.NewTokenService(cfg.JWT.SecretKey, cfg.JWT.Issuer)
-	genClient := comfylite.NewClient(logger, fmt.Sprintf("http://%s:%s", cfg.ComfyLite.Host, cfg.ComfyLite.Port), fmt.Sprintf("http://localhost:%s/gen/update", cfg.Server.Port))
+	var genClient port.ImageGeneration
+
+	switch cfg.Generation.Provider {
+	case "openai":
+		// Images are generated synchronously by the generation workers
+		genClient = openaiimages.NewClient(logger, cfg.OpenAI.BaseURL, cfg.OpenAI.APIKey, cfg.OpenAI.Model, cfg.OpenAI.MaxPerRequest)
+	default:
+		webhookURL := cfg.Server.InternalWebhookBaseURL + "/gen/update"
 
-	baseURL := "http://localhost:" + cfg.Server.Port
-	successURL := baseURL + "/purchase/success"
+		genBackends := make([]genrouter.Backend, 0, len(cfg.ComfyLite.Nodes))
+		for _, node := range cfg.ComfyLite.Nodes {
+			genBackends = append(genBackends, genrouter.Backend{
+				Name:   node.Name,
+				Weight: node.Weight,
+				// The backend name lets the webhook handler check which node reported back
+				Client: comfylite.NewClient(logger.With(zap.String("backend", node.Name)), node.Name, node.URL, webhookURL+"?backend="+url.QueryEscape(node.Name), appMetrics),
+			})
+		}
+		genRouter := genrouter.NewRouter(logger, genrouter.Strategy(cfg.ComfyLite.Balancing), cfg.ComfyLite.HealthInterval, genBackends...)
+		go genRouter.Start(context.Background())
+		genClient = genRouter
+	}
+
+	baseURL := cfg.Server.PublicBaseURL
+	// Stripe replaces the placeholder with the ID of the completed session
+	successURL := baseURL + "/purchase/success?session_id={CHECKOUT_SESSION_ID}"
 	cancelURL := baseURL + "/purchase/cancel"
 
 	stripeProvider := stripe.NewProvider(logger, cfg.Stripe.Secret, cfg.Stripe.VerificationSecret, successURL, cancelURL)
@@ -47,18 +88,90 @@ func main() {
 	promptRepo := gormadapter.NewGormPromptRepository(db, logger)
 	imageRepo := gormadapter.NewGormImageRepository(db, logger)
 	walletRepo := gormadapter.NewGormWalletRepository(db, logger)
+	genJobRepo := gormadapter.NewGormGenerationJobRepository(db, logger)
+	creditPackageRepo := gormadapter.NewGormCreditPackageRepository(db, logger)
+	paymentRepo := gormadapter.NewGormPaymentRepository(db, logger)
+	promoRepo := gormadapter.NewGormPromoCodeRepository(db, logger)
+	subscriptionRepo := gormadapter.NewGormSubscriptionRepository(db, logger)
+	apiKeyRepo := gormadapter.NewGormAPIKeyRepository(db, logger)
+	webhookRepo := gormadapter.NewGormWebhookRepository(db, logger)
+	auditRepo := gormadapter.NewGormAuditRepository(db, logger)
+	appMetrics.WatchQueueDepth(genJobRepo)
 
 	walletSvc := service.NewWalletService(logger, walletRepo)
-	authSvc := service.NewAuthService(userRepo, tokenService, logger, googleAuthProvider)
-	genSvc := service.NewGenService(logger, genClient, promptRepo, imageRepo, walletSvc)
-	purchaseSvc := service.NewPurchaseService(logger, walletSvc, stripeProvider, userRepo)
+	auditSvc := service.NewAuditService(logger, auditRepo, userRepo)
+	webhookSvc := service.NewWebhookService(logger, webhookRepo, cfg.Webhook.MaxAttempts, cfg.Webhook.AllowPrivateTargets, auditSvc)
+	webhookDispatcher := service.NewWebhookDispatcher(logger, webhookRepo, cfg.Webhook.Workers, cfg.Webhook.PollInterval, cfg.Webhook.Timeout, cfg.Webhook.AllowPrivateTargets)
+	go webhookDispatcher.Start(context.Background())
+
+	var pricingSource port.PricingRulesSource
+	if cfg.Pricing.RulesFile != "" {
+		pricingSource = filerules.NewSource(logger, cfg.Pricing.RulesFile)
+	}
+	pricingSvc, err := service.NewPricingService(context.Background(), logger, pricingSource, cfg.Pricing.ReloadInterval)
+	if err != nil {
+		logger.Fatal("Failed to initialize pricing", zap.Error(err))
+	}
+	go pricingSvc.Start(context.Background())
 
-	apiHandlers := allHandlers.NewApiHandlers(authSvc, genSvc, purchaseSvc, logger)
+	authSvc := service.NewAuthService(userRepo, tokenService, logger, googleAuthProvider, auditSvc)
+	genSvc := service.NewGenService(logger, genClient, promptRepo, imageRepo, genJobRepo, walletSvc, pricingSvc, cfg.Generation.QueueMaxAttempts, domain.GenerationQuota{
+		MaxPendingPrompts: cfg.Generation.MaxPendingPromptsPerUser,
+		MaxQueuedImages:   cfg.Generation.MaxQueuedImagesPerUser,
+	}, webhookSvc, cfg.Webhook.LowCreditsThreshold, auditSvc, appMetrics)
+	genWorker := service.NewGenerationWorker(logger, genJobRepo, genClient, genSvc, webhookSvc, appMetrics, cfg.Generation.QueueWorkers, cfg.Generation.QueuePollInterval, cfg.Generation.QueueLease, cfg.Generation.ResultTimeout)
+	go genWorker.Start(context.Background())
+	var mailer port.Mailer
+	if cfg.Mail.Driver == "smtp" {
+		mailer = smtpmailer.NewMailer(logger, cfg.Mail.SMTPHost, cfg.Mail.SMTPPort, cfg.Mail.SMTPUsername, cfg.Mail.SMTPPassword, cfg.Mail.From)
+	} else {
+		mailer = logmailer.NewMailer(logger)
+	}
+	receiptSvc := service.NewReceiptService(logger, paymentRepo, userRepo, pdfreceipt.NewRenderer(logger), mailer, service.BusinessDetails{
+		Name:          cfg.Business.Name,
+		Address:       cfg.Business.Address,
+		TaxID:         cfg.Business.TaxID,
+		Email:         cfg.Business.Email,
+		TaxLabel:      cfg.Business.TaxLabel,
+		TaxRateBps:    cfg.Business.TaxRateBps,
+		InvoicePrefix: cfg.Business.InvoicePrefix,
+	})
+	subscriptionSvc := service.NewSubscriptionService(logger, stripeProvider, userRepo, subscriptionRepo, receiptSvc, baseURL+"/purchase")
+	purchaseSvc := service.NewPurchaseService(logger, walletSvc, stripeProvider, userRepo, creditPackageRepo, paymentRepo, promoRepo, subscriptionSvc, receiptSvc, webhookSvc, auditSvc, appMetrics)
+	creditPackageSvc := service.NewCreditPackageService(logger, creditPackageRepo, auditSvc)
+	promoSvc := service.NewPromoService(logger, promoRepo, userRepo, auditSvc)
+	apiKeySvc := service.NewAPIKeyService(logger, apiKeyRepo, auditSvc)
+	adminSvc := service.NewAdminService(logger, userRepo, walletRepo, genJobRepo, auditSvc, cfg.Admin.StuckPromptAfter)
+	if err := creditPackageSvc.SeedDefaults(context.Background()); err != nil {
+		logger.Fatal("Failed to seed credit packages", zap.Error(err))
+	}
+	if err := subscriptionSvc.SeedDefaultPlans(context.Background()); err != nil {
+		logger.Fatal("Failed to seed subscription plans", zap.Error(err))
+	}
+	if err := adminSvc.BootstrapAdmins(context.Background(), cfg.Admin.UserIDs); err != nil {
+		logger.Fatal("Failed to promote configured admins", zap.Error(err))
+	}
+
+	apiHandlers := allHandlers.NewApiHandlers(authSvc, genSvc, purchaseSvc, creditPackageSvc, promoSvc, subscriptionSvc, receiptSvc, walletSvc, apiKeySvc, webhookSvc, adminSvc, auditSvc, logger)
 
-	router := routes.NewRouter(apiHandlers, logger, tokenService, walletSvc)
+	router := routes.NewRouter(apiHandlers, logger, tokenService, walletSvc, apiKeySvc, userRepo, cfg.Server.TrustedProxies, appMetrics, appMetrics.Handler(), cfg.Metrics.Token)
+
+	if cfg.Metrics.Addr != "" {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("/metrics", appMetrics.Handler())
+		go func() {
+			logger.Info("Metrics server starting", zap.String("address", cfg.Metrics.Addr))
+			if err := http.ListenAndServe(cfg.Metrics.Addr, metricsMux); err != nil {
+				logger.Fatal("Failed to start metrics server", zap.Error(err))
+			}
+		}()
+	} else if cfg.Metrics.Token == "" {
+		logger.Warn("Metrics are not exposed, set METRICS_ADDR or METRICS_TOKEN to scrape them")
+	}
 
 	logger.Info("Server starting",
 		zap.String("address", "http://0.0.0.0:"+cfg.Server.Port),
+		zap.String("public_url", cfg.Server.PublicBaseURL),
 		zap.String("app_env", cfg.Server.AppEnv),
 	)
 
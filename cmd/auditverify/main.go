@@ -0,0 +1,48 @@
+// Command auditverify checks the hash chain of the audit log. It exits with status 1 when
+// the chain is broken and 2 when it could not be checked.
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	gormadapter "github.com/CP-Payne/wonderpicai/internal/adapter/persistence/gorm"
+	appconfig "github.com/CP-Payne/wonderpicai/internal/config"
+	applogger "github.com/CP-Payne/wonderpicai/internal/logger"
+	"github.com/CP-Payne/wonderpicai/internal/service"
+	"go.uber.org/zap"
+)
+
+func main() {
+	appconfig.LoadConfig()
+	cfg := appconfig.Cfg
+
+	logger, err := applogger.New(cfg.Server.LogLevel, cfg.Server.AppEnv)
+	if err != nil {
+		log.Fatalf("Failed to initialize application logger: %v", err)
+	}
+	defer logger.Sync()
+
+	gormadapter.ConnectDatabase(cfg.Database.DSN, cfg.Server.AppEnv, cfg.Server.LogLevel, logger)
+	db := gormadapter.DB
+
+	auditRepo := gormadapter.NewGormAuditRepository(db, logger)
+	userRepo := gormadapter.NewGormUserRepository(db, logger)
+	auditSvc := service.NewAuditService(logger, auditRepo, userRepo)
+
+	result, err := auditSvc.Verify(context.Background())
+	if err != nil {
+		logger.Error("Failed to verify audit chain", zap.Error(err))
+		fmt.Fprintf(os.Stderr, "audit chain could not be verified: %v\n", err)
+		os.Exit(2)
+	}
+
+	if !result.Intact() {
+		fmt.Printf("audit chain BROKEN at event #%d: %s (%d events before it verified)\n", result.BrokenAt, result.Problem, result.Checked)
+		os.Exit(1)
+	}
+
+	fmt.Printf("audit chain intact, %d events verified\n", result.Checked)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const (
+	defaultBaseURL = "http://localhost:3000"
+
+	envBaseURL = "WONDERPIC_URL"
+	envAPIKey  = "WONDERPIC_API_KEY"
+	envConfig  = "WONDERPIC_CONFIG"
+)
+
+// config is stored by login. The environment variables take precedence over the file.
+type config struct {
+	BaseURL string `json:"base_url"`
+	APIKey  string `json:"api_key"`
+}
+
+func configPath() (string, error) {
+	if path := os.Getenv(envConfig); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the config directory: %w", err)
+	}
+	return filepath.Join(dir, "wonderpic", "config.json"), nil
+}
+
+// loadConfig reads the config file, a missing file is an empty config
+func loadConfig() (config, error) {
+	var cfg config
+
+	path, err := configPath()
+	if err != nil {
+		return cfg, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return cfg, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return cfg, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	}
+
+	if baseURL := os.Getenv(envBaseURL); baseURL != "" {
+		cfg.BaseURL = baseURL
+	}
+	if apiKey := os.Getenv(envAPIKey); apiKey != "" {
+		cfg.APIKey = apiKey
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = defaultBaseURL
+	}
+	return cfg, nil
+}
+
+// saveConfig writes the config file readable by the current user only, it holds the API key
+func saveConfig(cfg config) (string, error) {
+	path, err := configPath()
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return "", fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return path, nil
+}
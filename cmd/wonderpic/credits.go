@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+func runCredits(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("credits", "credits [--json]")
+	asJSON := fs.Bool("json", false, "print JSON")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	wallet, err := a.client.GetWallet(ctx)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(a.stdout, wallet)
+	}
+
+	fmt.Fprintf(a.stdout, "%d credits\n", wallet.Credits)
+	return nil
+}
+
+// runBuy points to the purchase page, checkouts need the browser session of the web app
+func runBuy(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("buy", "buy [--open]")
+	open := fs.Bool("open", false, "open the page in the browser")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	purchaseURL := strings.TrimRight(a.cfg.BaseURL, "/") + "/purchase"
+	if !*open {
+		fmt.Fprintf(a.stdout, "Buy credits at %s\n", purchaseURL)
+		return nil
+	}
+
+	if err := openBrowser(purchaseURL); err != nil {
+		return fmt.Errorf("failed to open the browser, visit %s: %w", purchaseURL, err)
+	}
+	fmt.Fprintf(a.stderr, "Opened %s\n", purchaseURL)
+	return nil
+}
+
+// openBrowser starts the platform's URL handler without waiting for it
+func openBrowser(target string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", target)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", target)
+	default:
+		cmd = exec.Command("xdg-open", target)
+	}
+	return cmd.Start()
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CP-Payne/wonderpicai/pkg/client"
+)
+
+func runGen(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("gen", `gen "prompt" [-n COUNT] [--size SIZE] [--wait] [--json]`)
+	count := fs.Int("n", 1, "number of images")
+	size := fs.String("size", client.DefaultSize, "image `size`, a name such as landscape or WIDTHxHEIGHT")
+	model := fs.String("model", "", "generation `model`, the server default when empty")
+	steps := fs.Int("steps", 0, "sampling steps, the server default when 0")
+	priority := fs.Bool("priority", false, "use the priority queue, it costs extra credits")
+	wait := fs.Bool("wait", false, "follow progress until the prompt completes")
+	interval := fs.Duration("interval", 2*time.Second, "polling `interval` while waiting")
+	asJSON := fs.Bool("json", false, "print JSON")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(positional) == 0 {
+		fs.Usage()
+		return errors.New("a prompt is required")
+	}
+	sizeKey, err := imageSizeKey(*size)
+	if err != nil {
+		return err
+	}
+
+	prompt, err := a.client.CreateGeneration(ctx, client.GenerationRequest{
+		Prompt:     strings.Join(positional, " "),
+		ImageCount: *count,
+		Size:       sizeKey,
+		Model:      *model,
+		Steps:      *steps,
+		Priority:   *priority,
+	})
+	if err != nil {
+		return err
+	}
+
+	if *wait {
+		fmt.Fprintf(a.stderr, "Queued prompt %s for %d credits\n", prompt.ID, prompt.Cost)
+		prompt, err = followPrompt(ctx, a, prompt, *interval)
+		if err != nil {
+			return err
+		}
+	}
+
+	if *asJSON {
+		return printJSON(a.stdout, prompt)
+	}
+	printPrompt(a, prompt)
+	if prompt.Status == client.StatusFailed {
+		return errors.New("generation failed")
+	}
+	return nil
+}
+
+// followPrompt polls the prompt and reports progress on stderr until it is done
+func followPrompt(ctx context.Context, a *app, prompt *client.Prompt, interval time.Duration) (*client.Prompt, error) {
+	started := time.Now()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		done := 0
+		for _, image := range prompt.Images {
+			if image.Status.Done() {
+				done++
+			}
+		}
+		fmt.Fprintf(a.stderr, "\r%s: %d/%d images done (%s)   ", prompt.Status, done, prompt.ImageCount, time.Since(started).Round(time.Second))
+		if prompt.Status.Done() {
+			fmt.Fprintln(a.stderr)
+			return prompt, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			fmt.Fprintln(a.stderr)
+			return nil, fmt.Errorf("stopped waiting, follow up with \"wonderpic ls\": %w", ctx.Err())
+		case <-ticker.C:
+		}
+
+		next, err := a.client.GetPrompt(ctx, prompt.ID)
+		if err != nil {
+			fmt.Fprintln(a.stderr)
+			return nil, err
+		}
+		prompt = next
+	}
+}
+
+func printPrompt(a *app, prompt *client.Prompt) {
+	fmt.Fprintf(a.stdout, "Prompt %s: %s, %d credits\n", prompt.ID, prompt.Status, prompt.Cost)
+	if len(prompt.Images) == 0 {
+		return
+	}
+
+	t := newTable(a.stdout, "IMAGE", "STATUS")
+	for _, image := range prompt.Images {
+		t.row(image.ID, string(image.Status))
+	}
+	t.Flush()
+}
+
+// imageSizeKey accepts a size name or its dimensions, e.g. "landscape" or "768x512"
+func imageSizeKey(value string) (string, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	for _, size := range client.Sizes {
+		if size.Name == value {
+			return size.Name, nil
+		}
+	}
+
+	if w, h, ok := strings.Cut(value, "x"); ok {
+		width, errW := strconv.Atoi(w)
+		height, errH := strconv.Atoi(h)
+		if errW == nil && errH == nil {
+			for _, size := range client.Sizes {
+				if size.Width == width && size.Height == height {
+					return size.Name, nil
+				}
+			}
+		}
+	}
+
+	available := make([]string, len(client.Sizes))
+	for i, size := range client.Sizes {
+		available[i] = fmt.Sprintf("%s (%dx%d)", size.Name, size.Width, size.Height)
+	}
+	return "", fmt.Errorf("unknown size %q, available sizes are %s", value, strings.Join(available, ", "))
+}
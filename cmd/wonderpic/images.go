@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/CP-Payne/wonderpicai/pkg/client"
+)
+
+func runList(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("ls", "ls [--images] [--limit N] [--offset N] [--json]")
+	images := fs.Bool("images", false, "list images instead of prompts")
+	limit := fs.Int("limit", 20, "number of items")
+	offset := fs.Int("offset", 0, "number of items to skip")
+	asJSON := fs.Bool("json", false, "print JSON")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	opts := client.ListOptions{Limit: *limit, Offset: *offset}
+	if *images {
+		list, err := a.client.ListImages(ctx, opts)
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			return printJSON(a.stdout, list)
+		}
+
+		t := newTable(a.stdout, "ID", "PROMPT", "STATUS", "CREATED")
+		for _, image := range list.Data {
+			t.row(image.ID, image.PromptID, string(image.Status), formatTime(image.CreatedAt))
+		}
+		t.Flush()
+		printPagination(a, list.Pagination, len(list.Data))
+		return nil
+	}
+
+	list, err := a.client.ListPrompts(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(a.stdout, list)
+	}
+
+	t := newTable(a.stdout, "ID", "STATUS", "IMAGES", "SIZE", "COST", "CREATED", "PROMPT")
+	for _, prompt := range list.Data {
+		t.row(prompt.ID, string(prompt.Status), strconv.Itoa(prompt.ImageCount), prompt.Size, strconv.Itoa(prompt.Cost), formatTime(prompt.CreatedAt), truncate(prompt.Prompt, 48))
+	}
+	t.Flush()
+	printPagination(a, list.Pagination, len(list.Data))
+	return nil
+}
+
+// printPagination tells on stderr how to get the next page, so stdout stays a plain table
+func printPagination(a *app, page client.Pagination, count int) {
+	if count == 0 {
+		fmt.Fprintln(a.stderr, "Nothing found.")
+		return
+	}
+	if page.More() {
+		fmt.Fprintf(a.stderr, "Showing %d-%d of %d, use --offset %d for more.\n", page.Offset+1, page.Offset+count, page.Total, page.Offset+page.Limit)
+	}
+}
+
+func runGet(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("get", "get <image-id> [-o FILE]")
+	output := fs.String("o", "", "output `file`, - for stdout (default <image-id>.png)")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		fs.Usage()
+		return errors.New("one image ID is required")
+	}
+
+	id := positional[0]
+	if *output == "-" {
+		_, err := a.client.DownloadImage(ctx, id, a.stdout)
+		return err
+	}
+
+	path := *output
+	if path == "" {
+		path = id + ".png"
+	}
+
+	// Download next to the target and rename, so failed downloads leave no partial file
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".wonderpic-*")
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	n, err := a.client.DownloadImage(ctx, id, tmp)
+	if closeErr := tmp.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write %s: %w", path, closeErr)
+	}
+	if err != nil {
+		return err
+	}
+	// CreateTemp makes the file private, images get the usual permissions
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	fmt.Fprintf(a.stderr, "Saved %s (%s)\n", path, formatBytes(n))
+	return nil
+}
+
+func runRemove(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("rm", "rm <image-id>... | rm --failed")
+	failed := fs.Bool("failed", false, "delete all failed images")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if *failed {
+		if len(positional) > 0 {
+			return errors.New("--failed does not take image IDs")
+		}
+		if err := a.client.DeleteFailedImages(ctx); err != nil {
+			return err
+		}
+		fmt.Fprintln(a.stdout, "Deleted failed images")
+		return nil
+	}
+
+	if len(positional) == 0 {
+		fs.Usage()
+		return errors.New("at least one image ID is required")
+	}
+	for _, id := range positional {
+		if err := a.client.DeleteImage(ctx, id); err != nil {
+			return err
+		}
+		fmt.Fprintf(a.stdout, "Deleted %s\n", id)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/CP-Payne/wonderpicai/pkg/client"
+)
+
+func runLogin(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("login", "login [--url URL] [--key KEY]")
+	baseURL := fs.String("url", a.cfg.BaseURL, "server `URL`")
+	apiKey := fs.String("key", "", "API `key`, read from stdin when not given")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	key := strings.TrimSpace(*apiKey)
+	if key == "" {
+		fmt.Fprintf(a.stderr, "Create an API key at %s/account/api-keys\nAPI key: ", strings.TrimRight(*baseURL, "/"))
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return fmt.Errorf("failed to read the API key: %w", err)
+		}
+		key = strings.TrimSpace(line)
+	}
+	if key == "" {
+		return errors.New("an API key is required")
+	}
+
+	c, err := client.New(*baseURL, key, client.WithUserAgent("wonderpic-cli"))
+	if err != nil {
+		return err
+	}
+
+	// Any authenticated response proves the key, a key without the wallet:read scope gets a 403
+	_, err = c.GetWallet(ctx)
+	switch {
+	case err == nil:
+	case client.IsStatus(err, http.StatusForbidden):
+		fmt.Fprintln(a.stderr, "The key has no wallet:read scope, \"wonderpic credits\" will not work.")
+	case client.IsStatus(err, http.StatusUnauthorized):
+		return errors.New("the API key was rejected, check that it is active and not expired")
+	default:
+		return fmt.Errorf("failed to check the API key: %w", err)
+	}
+
+	path, err := saveConfig(config{BaseURL: strings.TrimRight(*baseURL, "/"), APIKey: key})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.stdout, "Logged in to %s, the key is stored in %s\n", *baseURL, path)
+	return nil
+}
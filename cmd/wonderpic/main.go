@@ -0,0 +1,144 @@
+// Command wonderpic generates, lists and downloads images with the WonderPicAI JSON API.
+//
+// Authenticate once with a personal API key created on the account page:
+//
+//	wonderpic login --url https://wonderpic.example.com
+//	wonderpic gen "a red fox in the snow" -n 4 --size landscape --wait
+//	wonderpic get <image-id> -o fox.png
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	"github.com/CP-Payne/wonderpicai/pkg/client"
+)
+
+const usage = `wonderpic is a command-line client for WonderPicAI.
+
+Usage:
+  wonderpic <command> [arguments]
+
+Commands:
+  login     store the server URL and an API key
+  gen       generate images from a prompt
+  ls        list prompts or images
+  get       download an image
+  rm        delete images
+  credits   show the credit balance
+  buy       show or open the page to buy credits
+
+Run "wonderpic <command> -h" for the arguments of a command.
+The WONDERPIC_URL and WONDERPIC_API_KEY environment variables override the stored login.
+`
+
+type command struct {
+	run func(ctx context.Context, app *app, args []string) error
+	// public commands run without an API key
+	public bool
+}
+
+var commands = map[string]command{
+	"login":   {run: runLogin, public: true},
+	"gen":     {run: runGen},
+	"ls":      {run: runList},
+	"get":     {run: runGet},
+	"rm":      {run: runRemove},
+	"credits": {run: runCredits},
+	"buy":     {run: runBuy, public: true},
+}
+
+// app is shared by the commands
+type app struct {
+	cfg    config
+	client *client.Client
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] == "-h" || os.Args[1] == "--help" || os.Args[1] == "help" {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "wonderpic: unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	err := execute(ctx, cmd, os.Args[2:])
+	switch {
+	case err == nil:
+	case errors.Is(err, flag.ErrHelp):
+		os.Exit(2)
+	default:
+		var apiErr *client.Error
+		if errors.As(err, &apiErr) {
+			// API errors already name the client
+			fmt.Fprintln(os.Stderr, err)
+		} else {
+			fmt.Fprintln(os.Stderr, "wonderpic:", err)
+		}
+		os.Exit(1)
+	}
+}
+
+func execute(ctx context.Context, cmd command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	a := &app{cfg: cfg, stdout: os.Stdout, stderr: os.Stderr}
+	if !cmd.public {
+		if cfg.APIKey == "" {
+			return errors.New(`not logged in, run "wonderpic login" or set ` + envAPIKey)
+		}
+		a.client, err = client.New(cfg.BaseURL, cfg.APIKey, client.WithUserAgent("wonderpic-cli"))
+		if err != nil {
+			return err
+		}
+	}
+
+	return cmd.run(ctx, a, args)
+}
+
+// parseFlags parses flags given before, between and after positional arguments, so
+// `gen "prompt" -n 4` works like `gen -n 4 "prompt"`. It returns the positional arguments.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		// Everything after "--" is positional, e.g. prompts starting with a dash
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+func newFlagSet(name, synopsis string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: wonderpic %s\n\n", synopsis)
+		fs.PrintDefaults()
+	}
+	return fs
+}
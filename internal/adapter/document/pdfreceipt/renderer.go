@@ -0,0 +1,145 @@
+package pdfreceipt
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/CP-Payne/wonderpicai/internal/money"
+	"github.com/CP-Payne/wonderpicai/internal/port"
+	"github.com/jung-kurt/gofpdf"
+	"go.uber.org/zap"
+)
+
+const (
+	margin     = 20.0
+	lineHeight = 6.0
+)
+
+// Column widths of the line item table, they add up to the printable width
+var columns = []float64{100, 20, 25, 25}
+
+type renderer struct {
+	logger *zap.Logger
+}
+
+// NewRenderer returns a renderer producing A4 receipts with the PDF core fonts
+func NewRenderer(logger *zap.Logger) port.ReceiptRenderer {
+	return &renderer{logger: logger.With(zap.String("component", "PDFReceiptRenderer"))}
+}
+
+func (r *renderer) Render(receipt *domain.Receipt) ([]byte, error) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(margin, margin, margin)
+	pdf.SetTitle("Receipt "+receipt.InvoiceNumber, true)
+	pdf.SetCreator(receipt.Seller.Name, true)
+	pdf.AddPage()
+
+	// The core fonts use cp1252, which covers the currency symbols we format
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	amount := func(minor int64) string {
+		return tr(money.Format(minor, receipt.Currency))
+	}
+
+	pdf.SetFont("Helvetica", "B", 20)
+	pdf.CellFormat(0, 10, tr(receipt.Seller.Name), "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	r.party(pdf, tr, receipt.Seller)
+
+	pdf.Ln(lineHeight)
+	pdf.SetFont("Helvetica", "B", 14)
+	pdf.CellFormat(0, 8, "Receipt", "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	r.field(pdf, "Invoice number", receipt.InvoiceNumber)
+	r.field(pdf, "Date of issue", receipt.IssuedAt.Format("2 January 2006"))
+	r.field(pdf, "Date paid", receipt.PaidAt.Format("2 January 2006"))
+
+	pdf.Ln(lineHeight)
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.CellFormat(0, lineHeight, "Billed to", "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	r.party(pdf, tr, receipt.Customer)
+
+	pdf.Ln(lineHeight)
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.SetFillColor(235, 235, 235)
+	for i, title := range []string{"Description", "Qty", "Unit price", "Amount"} {
+		align := "R"
+		if i == 0 {
+			align = "L"
+		}
+		pdf.CellFormat(columns[i], 8, title, "B", 0, align, true, 0, "")
+	}
+	pdf.Ln(-1)
+
+	pdf.SetFont("Helvetica", "", 10)
+	for _, line := range receipt.Lines {
+		pdf.CellFormat(columns[0], 8, tr(line.Description), "B", 0, "L", false, 0, "")
+		pdf.CellFormat(columns[1], 8, fmt.Sprintf("%d", line.Quantity), "B", 0, "R", false, 0, "")
+		pdf.CellFormat(columns[2], 8, amount(line.UnitMinor), "B", 0, "R", false, 0, "")
+		pdf.CellFormat(columns[3], 8, amount(line.TotalMinor), "B", 1, "R", false, 0, "")
+	}
+
+	pdf.Ln(2)
+	r.total(pdf, "Subtotal", amount(receipt.SubtotalMinor), false)
+	if receipt.TaxRateBps > 0 {
+		label := fmt.Sprintf("%s (%s%%)", receipt.TaxLabel, formatRate(receipt.TaxRateBps))
+		r.total(pdf, tr(label), amount(receipt.TaxMinor), false)
+	}
+	r.total(pdf, "Total", amount(receipt.TotalMinor), true)
+
+	if len(receipt.Notes) > 0 {
+		pdf.Ln(lineHeight)
+		pdf.SetFont("Helvetica", "", 9)
+		for _, note := range receipt.Notes {
+			pdf.MultiCell(0, 5, tr(note), "", "L", false)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		r.logger.Error("Failed to render receipt", zap.String("invoiceNumber", receipt.InvoiceNumber), zap.Error(err))
+		return nil, fmt.Errorf("failed to render receipt pdf: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (r *renderer) party(pdf *gofpdf.Fpdf, tr func(string) string, party domain.ReceiptParty) {
+	lines := []string{party.Name}
+	lines = append(lines, party.Address...)
+	if party.Email != "" {
+		lines = append(lines, party.Email)
+	}
+	if party.TaxID != "" {
+		lines = append(lines, "Tax ID: "+party.TaxID)
+	}
+
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		pdf.CellFormat(0, 5, tr(line), "", 1, "L", false, 0, "")
+	}
+}
+
+func (r *renderer) field(pdf *gofpdf.Fpdf, label, value string) {
+	pdf.CellFormat(40, lineHeight, label, "", 0, "L", false, 0, "")
+	pdf.CellFormat(0, lineHeight, value, "", 1, "L", false, 0, "")
+}
+
+func (r *renderer) total(pdf *gofpdf.Fpdf, label, value string, bold bool) {
+	if bold {
+		pdf.SetFont("Helvetica", "B", 11)
+		defer pdf.SetFont("Helvetica", "", 10)
+	}
+	labelWidth := columns[0] + columns[1] + columns[2]
+	pdf.CellFormat(labelWidth, 7, label, "", 0, "R", false, 0, "")
+	pdf.CellFormat(columns[3], 7, value, "", 1, "R", false, 0, "")
+}
+
+// formatRate formats basis points as a percentage without trailing zeros, e.g. 750 as "7.5"
+func formatRate(bps int) string {
+	rate := fmt.Sprintf("%d.%02d", bps/100, bps%100)
+	return strings.TrimSuffix(strings.TrimRight(rate, "0"), ".")
+}
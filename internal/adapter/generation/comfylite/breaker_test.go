@@ -0,0 +1,102 @@
+package comfylite
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	tests := []struct {
+		name  string
+		run   func(b *circuitBreaker)
+		want  breakerState
+		allow bool
+	}{
+		{
+			name:  "stays closed below the threshold",
+			run:   func(b *circuitBreaker) { b.RecordFailure(); b.RecordFailure() },
+			want:  breakerClosed,
+			allow: true,
+		},
+		{
+			name:  "opens at the threshold",
+			run:   func(b *circuitBreaker) { b.RecordFailure(); b.RecordFailure(); b.RecordFailure() },
+			want:  breakerOpen,
+			allow: false,
+		},
+		{
+			name: "success resets the failure count",
+			run: func(b *circuitBreaker) {
+				b.RecordFailure()
+				b.RecordFailure()
+				b.RecordSuccess()
+				b.RecordFailure()
+				b.RecordFailure()
+			},
+			want:  breakerClosed,
+			allow: true,
+		},
+		{
+			name: "release keeps the failure count",
+			run: func(b *circuitBreaker) {
+				b.RecordFailure()
+				b.RecordFailure()
+				b.Release()
+				b.RecordFailure()
+			},
+			want:  breakerOpen,
+			allow: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newCircuitBreaker(3, time.Hour)
+			tt.run(b)
+
+			if got := b.State(); got != tt.want {
+				t.Errorf("state = %s, want %s", got, tt.want)
+			}
+			if got := b.Allow(); got != tt.allow {
+				t.Errorf("Allow() = %t, want %t", got, tt.allow)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	tests := []struct {
+		name   string
+		finish func(b *circuitBreaker)
+		want   breakerState
+		allow  bool
+	}{
+		{name: "successful probe closes", finish: (*circuitBreaker).RecordSuccess, want: breakerClosed, allow: true},
+		{name: "failed probe opens again", finish: (*circuitBreaker).RecordFailure, want: breakerOpen, allow: false},
+		{name: "released probe lets the next one through", finish: (*circuitBreaker).Release, want: breakerHalfOpen, allow: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newCircuitBreaker(1, 50*time.Millisecond)
+			b.RecordFailure()
+			time.Sleep(60 * time.Millisecond)
+
+			if !b.Allow() {
+				t.Fatal("probe not allowed after the cooldown")
+			}
+			if b.Allow() {
+				t.Fatal("second request allowed while the probe is in flight")
+			}
+
+			tt.finish(b)
+			if got := b.State(); got != tt.want {
+				t.Errorf("state = %s, want %s", got, tt.want)
+			}
+			// A failed probe opened the breaker just now, so the cooldown has not passed
+			if got := b.Allow(); got != tt.allow {
+				t.Errorf("Allow() = %t, want %t", got, tt.allow)
+			}
+		})
+	}
+}
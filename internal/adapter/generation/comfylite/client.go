@@ -2,31 +2,53 @@ package comfylite
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"math/rand/v2"
+	"net"
 	"net/http"
 	"time"
 
+	"github.com/CP-Payne/wonderpicai/internal/domain"
 	"github.com/CP-Payne/wonderpicai/internal/port"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
+const (
+	maxAttempts      = 3
+	baseBackoff      = 250 * time.Millisecond
+	maxBackoff       = 2 * time.Second
+	breakerThreshold = 5
+	breakerCooldown  = 30 * time.Second
+)
+
 type ComfyLiteClient struct {
-	logger     *zap.Logger
+	logger *zap.Logger
+	// name identifies the backend in metrics
+	name       string
 	baseURL    string
 	HttpClient *http.Client
 	webhookURL string
+	breaker    *circuitBreaker
+	metrics    port.Metrics
 }
 
-func NewClient(logger *zap.Logger, baseUrl string, webhookURL string) port.ImageGeneration {
+func NewClient(logger *zap.Logger, name string, baseUrl string, webhookURL string, metrics port.Metrics) port.ImageGeneration {
 	return &ComfyLiteClient{
-		logger:     logger,
-		HttpClient: &http.Client{Timeout: 30 * time.Second},
+		logger:     logger.With(zap.String("component", "ComfyLiteClient")),
+		name:       name,
+		HttpClient: &http.Client{Timeout: 30 * time.Second, Transport: newTracingTransport()},
 		baseURL:    baseUrl,
 		webhookURL: webhookURL,
+		breaker:    newCircuitBreaker(breakerThreshold, breakerCooldown),
+		metrics:    metrics,
 	}
 }
 
@@ -40,11 +62,35 @@ type ComfyGenRequest struct {
 
 type ComfyGenResponse struct {
 	PromptID string `json:"prompt_id"`
+	Code     string `json:"code"`
 	Error    string `json:"error"`
 }
 
-func (c *ComfyLiteClient) GenerateImage(input *port.ImageGenerationInput) (uuid.UUID, error) {
-	url := c.baseURL + "/generate"
+// attemptError describes the outcome of a single failed request to ComfyLite.
+// retryable errors are attempted again after a backoff, and backend errors
+// count towards tripping the circuit breaker. POST /generate is not idempotent, so only
+// requests that ComfyLite provably did not queue are retryable.
+type attemptError struct {
+	err       error
+	retryable bool
+	backend   bool
+}
+
+func (e *attemptError) Error() string { return e.err.Error() }
+func (e *attemptError) Unwrap() error { return e.err }
+
+func (c *ComfyLiteClient) GenerateImage(ctx context.Context, input *port.ImageGenerationInput) (result *port.ImageGenerationResult, err error) {
+	ctx, span := tracer.Start(ctx, "ComfyLiteClient.GenerateImage", trace.WithAttributes(
+		attribute.String("comfylite.backend", c.name),
+		attribute.Int("prompt.image_count", input.ImageCount),
+	))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
 
 	data, err := json.Marshal(ComfyGenRequest{
 		Prompt:     input.Prompt,
@@ -55,21 +101,113 @@ func (c *ComfyLiteClient) GenerateImage(input *port.ImageGenerationInput) (uuid.
 	})
 	if err != nil {
 		c.logger.Error("Failed to marshal ComfyGenRequest", zap.Error(err))
-		return uuid.Nil, err
+		return nil, err
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if !c.breaker.Allow() {
+			c.logger.Warn("Circuit breaker open, failing fast", zap.String("state", c.breaker.State().String()))
+			c.metrics.GenerationBackendRequest(c.name, port.OutcomeCircuitOpen, 0)
+			return nil, fmt.Errorf("comfylite circuit breaker open: %w", domain.ErrGenerationUnavailable)
+		}
+
+		start := time.Now()
+		promptID, err := c.send(ctx, data)
+		if ctx.Err() != nil {
+			// Cancelled by the caller, which says nothing about the health of the backend
+			c.breaker.Release()
+			return nil, fmt.Errorf("generation request cancelled: %w", ctx.Err())
+		}
+		c.metrics.GenerationBackendRequest(c.name, attemptOutcome(err), time.Since(start))
+		if err == nil {
+			c.breaker.RecordSuccess()
+			return &port.ImageGenerationResult{PromptID: promptID}, nil
+		}
+
+		var attemptErr *attemptError
+		if !errors.As(err, &attemptErr) {
+			return nil, err
+		}
+
+		if attemptErr.backend {
+			c.breaker.RecordFailure()
+		} else {
+			// The backend answered, so it is reachable even though it refused the request
+			c.breaker.RecordSuccess()
+		}
+
+		lastErr = attemptErr.err
+		if !attemptErr.retryable || attempt == maxAttempts {
+			break
+		}
+
+		wait := backoff(attempt)
+		c.logger.Warn("Retrying ComfyLite generation request",
+			zap.Int("attempt", attempt),
+			zap.Duration("backoff", wait),
+			zap.Error(attemptErr.err),
+		)
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("generation request cancelled while waiting to retry: %w", ctx.Err())
+		case <-time.After(wait):
+		}
+	}
+
+	return nil, lastErr
+}
+
+// CheckHealth probes the ComfyLite health endpoint. It bypasses retries and the circuit breaker
+// so that it reflects the current state of the backend.
+func (c *ComfyLiteClient) CheckHealth(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/health", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create health request to ComfyLite: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(data))
+	resp, err := c.HttpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("comfylite health check failed: %w: %w", domain.ErrGenerationUnavailable, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("comfylite health check responded with status %d: %w", resp.StatusCode, domain.ErrGenerationUnavailable)
+	}
+
+	return nil
+}
+
+// send performs a single generation request. Every error it returns is an *attemptError
+func (c *ComfyLiteClient) send(ctx context.Context, data []byte) (uuid.UUID, error) {
+	url := c.baseURL + "/generate"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
 	if err != nil {
 		c.logger.Error("failed to create request", zap.Error(err))
-		return uuid.Nil, fmt.Errorf("failed to create request to ComfyLite: %w", err)
+		return uuid.Nil, &attemptError{err: fmt.Errorf("failed to create request to ComfyLite: %w", err)}
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := c.HttpClient.Do(req)
 	if err != nil {
-		c.logger.Error("Error sending request", zap.String("destination", url+"/gen"), zap.Error(err))
-		return uuid.Nil, fmt.Errorf("failed sending request to comfylite: %w", err)
+		c.logger.Error("Error sending request", zap.String("destination", url), zap.Error(err))
+		if notDelivered(err) {
+			return uuid.Nil, &attemptError{
+				err:       fmt.Errorf("failed connecting to comfylite: %w: %w", domain.ErrGenerationUnavailable, err),
+				retryable: true,
+				backend:   true,
+			}
+		}
+		// The request may have been queued before the connection failed or timed out
+		return uuid.Nil, &attemptError{
+			err:     fmt.Errorf("failed sending request to comfylite: %w: %w", domain.ErrGenerationOutcomeUnknown, err),
+			backend: true,
+		}
 	}
 
 	defer resp.Body.Close()
@@ -77,27 +215,115 @@ func (c *ComfyLiteClient) GenerateImage(input *port.ImageGenerationInput) (uuid.
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
 		c.logger.Error("Error reading response body", zap.Error(err))
-		return uuid.Nil, fmt.Errorf("error reading response body from comfylite: %w", err)
+		return uuid.Nil, &attemptError{
+			err:     fmt.Errorf("error reading response body from comfylite: %w: %w", domain.ErrGenerationOutcomeUnknown, err),
+			backend: true,
+		}
 	}
 
 	comfyResp := ComfyGenResponse{}
+	// The body is not guaranteed to be JSON on gateway errors, the status code is checked below
+	unmarshalErr := json.Unmarshal(respBody, &comfyResp)
 
-	err = json.Unmarshal(respBody, &comfyResp)
-	if err != nil {
-		c.logger.Error("Failed to read ComfyLite response", zap.Error(err))
-		return uuid.Nil, fmt.Errorf("failed to read comfyLite response: %w", err)
+	if resp.StatusCode >= http.StatusInternalServerError {
+		c.logger.Error("ComfyLite returned server error", zap.Int("status", resp.StatusCode), zap.String("error", comfyResp.Error))
+		// Only an error code from ComfyLite itself says the prompt was refused, a bare 5xx
+		// may come from a proxy that gave up after the request was forwarded
+		mapped := mapErrorCode(comfyResp.Code, domain.ErrGenerationOutcomeUnknown)
+		return uuid.Nil, &attemptError{
+			err:       fmt.Errorf("comfylite responded with status %d: %w", resp.StatusCode, mapped),
+			retryable: errors.Is(mapped, domain.ErrGenerationBusy),
+			backend:   true,
+		}
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		c.logger.Warn("ComfyLite is busy", zap.String("error", comfyResp.Error))
+		return uuid.Nil, &attemptError{
+			err:       fmt.Errorf("comfylite responded with status %d: %w", resp.StatusCode, domain.ErrGenerationBusy),
+			retryable: true,
+		}
 	}
 
-	if comfyResp.Error != "" {
-		c.logger.Error("ComfyLite failed generation request", zap.String("error", comfyResp.Error))
-		return uuid.Nil, fmt.Errorf("ComfyLite failed processing generation request: %w", errors.New(comfyResp.Error))
+	if unmarshalErr != nil {
+		c.logger.Error("Failed to read ComfyLite response", zap.Int("status", resp.StatusCode), zap.Error(unmarshalErr))
+		return uuid.Nil, &attemptError{err: fmt.Errorf("failed to read comfyLite response: %w", unmarshalErr)}
+	}
+
+	if comfyResp.Error != "" || resp.StatusCode >= http.StatusBadRequest {
+		c.logger.Error("ComfyLite failed generation request",
+			zap.Int("status", resp.StatusCode),
+			zap.String("code", comfyResp.Code),
+			zap.String("error", comfyResp.Error),
+		)
+		mapped := mapErrorCode(comfyResp.Code, domain.ErrGenerationRejected)
+		return uuid.Nil, &attemptError{
+			err:       fmt.Errorf("ComfyLite failed processing generation request: %w: %s", mapped, comfyResp.Error),
+			retryable: errors.Is(mapped, domain.ErrGenerationBusy),
+			backend:   errors.Is(mapped, domain.ErrGenerationUnavailable),
+		}
 	}
 
 	promptUUID, err := uuid.Parse(comfyResp.PromptID)
 	if err != nil {
 		c.logger.Error("failed to convert promptID string to uuid", zap.Error(err))
-		return uuid.Nil, fmt.Errorf("invalid prompt id received: %w", err)
+		return uuid.Nil, &attemptError{err: fmt.Errorf("invalid prompt id received: %w", err)}
 	}
 
 	return promptUUID, nil
 }
+
+// notDelivered reports whether err shows the request never reached ComfyLite, so that sending
+// it again cannot queue the prompt twice
+func notDelivered(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// mapErrorCode translates ComfyLite error codes into domain errors.
+// Unknown codes fall back to the provided error.
+func mapErrorCode(code string, fallback error) error {
+	switch code {
+	case "queue_full", "rate_limited":
+		return domain.ErrGenerationBusy
+	case "invalid_request", "invalid_dimensions", "invalid_image_count":
+		return domain.ErrInvalidGenerationInput
+	case "prompt_rejected", "content_policy":
+		return domain.ErrGenerationRejected
+	case "comfyui_unavailable", "workflow_error":
+		return domain.ErrGenerationUnavailable
+	default:
+		return fallback
+	}
+}
+
+// attemptOutcome classifies the result of a single request for the metrics
+func attemptOutcome(err error) string {
+	switch {
+	case err == nil:
+		return port.OutcomeSuccess
+	case errors.Is(err, domain.ErrGenerationBusy):
+		return port.OutcomeBusy
+	case errors.Is(err, domain.ErrGenerationUnavailable):
+		return port.OutcomeUnavailable
+	case errors.Is(err, domain.ErrGenerationOutcomeUnknown):
+		return port.OutcomeUnknown
+	case errors.Is(err, domain.ErrGenerationRejected), errors.Is(err, domain.ErrInvalidGenerationInput):
+		return port.OutcomeRejected
+	default:
+		return port.OutcomeError
+	}
+}
+
+// backoff returns an exponentially growing wait with full jitter
+func backoff(attempt int) time.Duration {
+	ceiling := baseBackoff << (attempt - 1)
+	if ceiling > maxBackoff {
+		ceiling = maxBackoff
+	}
+	return time.Duration(rand.Int64N(int64(ceiling)) + 1)
+}
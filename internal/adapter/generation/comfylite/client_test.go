@@ -0,0 +1,134 @@
+package comfylite
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/CP-Payne/wonderpicai/internal/adapter/metrics/prommetrics"
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/CP-Payne/wonderpicai/internal/port"
+	"go.uber.org/zap"
+)
+
+func newTestClient(baseURL string) *ComfyLiteClient {
+	logger := zap.NewNop()
+	return NewClient(logger, "test", baseURL, "http://app/gen/update", prommetrics.NewMetrics(logger)).(*ComfyLiteClient)
+}
+
+func TestSendClassification(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		body      string
+		wantErr   error
+		retryable bool
+		backend   bool
+	}{
+		{name: "accepted", status: http.StatusOK, body: `{"prompt_id":"6f1c1b0e-8d1a-4c55-9a57-0b8b7e1f0a11"}`},
+		{name: "too many requests", status: http.StatusTooManyRequests, body: `{}`, wantErr: domain.ErrGenerationBusy, retryable: true},
+		{name: "queue full", status: http.StatusServiceUnavailable, body: `{"code":"queue_full","error":"full"}`, wantErr: domain.ErrGenerationBusy, retryable: true, backend: true},
+		{name: "comfyui down", status: http.StatusServiceUnavailable, body: `{"code":"comfyui_unavailable","error":"down"}`, wantErr: domain.ErrGenerationUnavailable, backend: true},
+		{name: "bare gateway error", status: http.StatusBadGateway, body: `<html>bad gateway</html>`, wantErr: domain.ErrGenerationOutcomeUnknown, backend: true},
+		{name: "invalid request", status: http.StatusBadRequest, body: `{"code":"invalid_dimensions","error":"bad size"}`, wantErr: domain.ErrInvalidGenerationInput},
+		{name: "content policy", status: http.StatusUnprocessableEntity, body: `{"code":"content_policy","error":"no"}`, wantErr: domain.ErrGenerationRejected},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			_, err := newTestClient(server.URL).send(context.Background(), []byte(`{}`))
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("send() error = %v, want nil", err)
+				}
+				return
+			}
+
+			var attemptErr *attemptError
+			if !errors.As(err, &attemptErr) {
+				t.Fatalf("send() error = %v, want an *attemptError", err)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("send() error = %v, want %v", err, tt.wantErr)
+			}
+			if attemptErr.retryable != tt.retryable {
+				t.Errorf("retryable = %t, want %t", attemptErr.retryable, tt.retryable)
+			}
+			if attemptErr.backend != tt.backend {
+				t.Errorf("backend = %t, want %t", attemptErr.backend, tt.backend)
+			}
+		})
+	}
+}
+
+func TestSendConnectionRefusedIsRetryable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	_, err := newTestClient(url).send(context.Background(), []byte(`{}`))
+
+	var attemptErr *attemptError
+	if !errors.As(err, &attemptErr) || !attemptErr.retryable {
+		t.Fatalf("send() error = %v, want a retryable attempt error", err)
+	}
+	if !errors.Is(err, domain.ErrGenerationUnavailable) {
+		t.Errorf("send() error = %v, want %v", err, domain.ErrGenerationUnavailable)
+	}
+}
+
+func TestGenerateImageDoesNotResendAmbiguousFailures(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusGatewayTimeout)
+	}))
+	defer server.Close()
+
+	_, err := newTestClient(server.URL).GenerateImage(context.Background(), &port.ImageGenerationInput{Prompt: "cat", ImageCount: 1})
+	if !errors.Is(err, domain.ErrGenerationOutcomeUnknown) {
+		t.Fatalf("GenerateImage() error = %v, want %v", err, domain.ErrGenerationOutcomeUnknown)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("server received %d requests, want 1", got)
+	}
+}
+
+func TestGenerateImageCancelledLeavesBreakerAlone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cancel()
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := newTestClient(server.URL)
+	for range breakerThreshold - 1 {
+		client.breaker.RecordFailure()
+	}
+
+	_, err := client.GenerateImage(ctx, &port.ImageGenerationInput{Prompt: "cat", ImageCount: 1})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("GenerateImage() error = %v, want %v", err, context.Canceled)
+	}
+
+	// The cancelled attempt neither counted as a failure nor reset the earlier ones
+	if got := client.breaker.State(); got != breakerClosed {
+		t.Fatalf("state = %s, want %s", got, breakerClosed)
+	}
+	client.breaker.RecordFailure()
+	if got := client.breaker.State(); got != breakerOpen {
+		t.Errorf("state after one more failure = %s, want %s", got, breakerOpen)
+	}
+}
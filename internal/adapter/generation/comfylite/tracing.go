@@ -0,0 +1,25 @@
+package comfylite
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/CP-Payne/wonderpicai/internal/adapter/generation/comfylite")
+
+// newTracingTransport records a client span for every request to ComfyLite and sends the trace
+// context along in the traceparent header, so that ComfyLite can continue the trace. Requests
+// outside of a trace, such as health checks, are sent as they are.
+func newTracingTransport() http.RoundTripper {
+	return otelhttp.NewTransport(http.DefaultTransport,
+		otelhttp.WithFilter(func(r *http.Request) bool {
+			return trace.SpanContextFromContext(r.Context()).IsValid()
+		}),
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return "ComfyLite " + r.Method + " " + r.URL.Path
+		}),
+	)
+}
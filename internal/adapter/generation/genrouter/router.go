@@ -0,0 +1,270 @@
+package genrouter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/CP-Payne/wonderpicai/internal/port"
+	"go.uber.org/zap"
+)
+
+type Strategy string
+
+const (
+	WeightedRoundRobin Strategy = "weighted"
+	LeastOutstanding   Strategy = "least-outstanding"
+)
+
+const (
+	healthCheckTimeout = 5 * time.Second
+	// recoveryCooldown is how long an unhealthy node is skipped before requests may try it
+	// again, so nodes recover even when no health probes run
+	recoveryCooldown = 30 * time.Second
+)
+
+// Backend is a single named generation node.
+type Backend struct {
+	Name   string
+	Weight int
+	Client port.ImageGeneration
+}
+
+type node struct {
+	Backend
+	healthy bool
+	// downSince is when the node was last marked unhealthy
+	downSince time.Time
+	// currentWeight is used by the smooth weighted round-robin selection
+	currentWeight int
+	// outstanding counts prompts accepted by the node that have not finished yet.
+	// It is kept in memory and starts at zero after a restart.
+	outstanding int
+}
+
+// Router spreads generation requests over several backends. Unhealthy backends are skipped
+// and requests fail over to the next backend when a node is unreachable or busy. An unhealthy
+// node is tried again once its cooldown passed and is healthy again after its first success.
+// The last healthy node is never marked down, its own circuit breaker fails fast instead.
+type Router struct {
+	logger         *zap.Logger
+	strategy       Strategy
+	healthInterval time.Duration
+
+	mu    sync.Mutex
+	nodes []*node
+}
+
+func NewRouter(logger *zap.Logger, strategy Strategy, healthInterval time.Duration, backends ...Backend) *Router {
+	nodes := make([]*node, 0, len(backends))
+	for _, b := range backends {
+		if b.Weight < 1 {
+			b.Weight = 1
+		}
+		// Nodes are assumed healthy until a probe or request says otherwise
+		nodes = append(nodes, &node{Backend: b, healthy: true})
+	}
+
+	if strategy != LeastOutstanding {
+		strategy = WeightedRoundRobin
+	}
+
+	return &Router{
+		logger:         logger.With(zap.String("component", "GenRouter")),
+		strategy:       strategy,
+		healthInterval: healthInterval,
+		nodes:          nodes,
+	}
+}
+
+// Start runs the periodic health probes until ctx is cancelled.
+func (r *Router) Start(ctx context.Context) {
+	if r.healthInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(r.healthInterval)
+	defer ticker.Stop()
+
+	r.probeAll(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.probeAll(ctx)
+		}
+	}
+}
+
+func (r *Router) GenerateImage(ctx context.Context, input *port.ImageGenerationInput) (*port.ImageGenerationResult, error) {
+	tried := make(map[*node]bool, len(r.nodes))
+	lastErr := fmt.Errorf("no healthy generation backend: %w", domain.ErrGenerationUnavailable)
+
+	for {
+		n := r.pick(tried)
+		if n == nil {
+			return nil, lastErr
+		}
+		tried[n] = true
+
+		result, err := n.Client.GenerateImage(ctx, input)
+		if err == nil {
+			r.mu.Lock()
+			n.outstanding++
+			r.mu.Unlock()
+			r.setHealthy(n, true)
+
+			result.Backend = n.Name
+			return result, nil
+		}
+
+		if ctx.Err() != nil {
+			return nil, err
+		}
+
+		switch {
+		case errors.Is(err, domain.ErrGenerationUnavailable):
+			r.logger.Warn("Generation backend unavailable, failing over", zap.String("backend", n.Name), zap.Error(err))
+			r.setHealthy(n, false)
+		case errors.Is(err, domain.ErrGenerationBusy):
+			r.logger.Warn("Generation backend busy, failing over", zap.String("backend", n.Name), zap.Error(err))
+		default:
+			// The request itself was refused, another node would refuse it as well
+			return nil, err
+		}
+
+		lastErr = err
+	}
+}
+
+// PromptFinished releases the outstanding slot held by a prompt on the given backend.
+func (r *Router) PromptFinished(backend string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, n := range r.nodes {
+		if n.Name == backend && n.outstanding > 0 {
+			n.outstanding--
+			return
+		}
+	}
+}
+
+// pick selects the next healthy node that has not been tried yet, or nil when none is left.
+func (r *Router) pick(tried map[*node]bool) *node {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var candidates []*node
+	for _, n := range r.nodes {
+		if !tried[n] && (n.healthy || time.Since(n.downSince) >= recoveryCooldown) {
+			candidates = append(candidates, n)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	if r.strategy == LeastOutstanding {
+		return leastOutstanding(candidates)
+	}
+	return smoothWeighted(candidates)
+}
+
+// smoothWeighted implements nginx style smooth weighted round-robin.
+// Must be called with the router mutex held.
+func smoothWeighted(candidates []*node) *node {
+	var best *node
+	total := 0
+	for _, n := range candidates {
+		n.currentWeight += n.Weight
+		total += n.Weight
+		if best == nil || n.currentWeight > best.currentWeight {
+			best = n
+		}
+	}
+	best.currentWeight -= total
+	return best
+}
+
+// leastOutstanding picks the node with the fewest outstanding prompts relative to its weight.
+// Must be called with the router mutex held.
+func leastOutstanding(candidates []*node) *node {
+	best := candidates[0]
+	for _, n := range candidates[1:] {
+		// Compare outstanding/weight without floating point
+		if n.outstanding*best.Weight < best.outstanding*n.Weight {
+			best = n
+		}
+	}
+	return best
+}
+
+func (r *Router) probeAll(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, n := range r.nodes {
+		checker, ok := n.Client.(port.ImageGenerationHealthChecker)
+		if !ok {
+			continue
+		}
+
+		wg.Add(1)
+		go func(n *node, checker port.ImageGenerationHealthChecker) {
+			defer wg.Done()
+
+			probeCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+			defer cancel()
+
+			err := checker.CheckHealth(probeCtx)
+			if err != nil && ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				r.logger.Debug("Generation backend health check failed", zap.String("backend", n.Name), zap.Error(err))
+			}
+			r.setHealthy(n, err == nil)
+		}(n, checker)
+	}
+	wg.Wait()
+}
+
+func (r *Router) setHealthy(n *node, healthy bool) {
+	r.mu.Lock()
+	if !healthy {
+		if n.healthy && r.healthyCount() == 1 {
+			r.mu.Unlock()
+			r.logger.Debug("Keeping the last healthy generation backend in rotation", zap.String("backend", n.Name))
+			return
+		}
+		// Restarts the cooldown of a node that failed again after it was retried
+		n.downSince = time.Now()
+	}
+	changed := n.healthy != healthy
+	n.healthy = healthy
+	r.mu.Unlock()
+
+	if !changed {
+		return
+	}
+	if healthy {
+		r.logger.Info("Generation backend is healthy again", zap.String("backend", n.Name))
+	} else {
+		r.logger.Warn("Generation backend marked unhealthy", zap.String("backend", n.Name))
+	}
+}
+
+// healthyCount must be called with the router mutex held.
+func (r *Router) healthyCount() int {
+	count := 0
+	for _, n := range r.nodes {
+		if n.healthy {
+			count++
+		}
+	}
+	return count
+}
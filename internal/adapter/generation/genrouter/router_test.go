@@ -0,0 +1,131 @@
+package genrouter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/CP-Payne/wonderpicai/internal/port"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// fakeBackend fails with err until it is cleared and counts the requests it received
+type fakeBackend struct {
+	err      error
+	requests int
+}
+
+func (f *fakeBackend) GenerateImage(ctx context.Context, input *port.ImageGenerationInput) (*port.ImageGenerationResult, error) {
+	f.requests++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &port.ImageGenerationResult{PromptID: uuid.New()}, nil
+}
+
+var errUnavailable = fmt.Errorf("connection refused: %w", domain.ErrGenerationUnavailable)
+
+func newTestRouter(backends ...*fakeBackend) *Router {
+	named := make([]Backend, len(backends))
+	for i, b := range backends {
+		named[i] = Backend{Name: fmt.Sprintf("node-%d", i), Weight: 1, Client: b}
+	}
+	return NewRouter(zap.NewNop(), WeightedRoundRobin, 0, named...)
+}
+
+func generate(r *Router) (*port.ImageGenerationResult, error) {
+	return r.GenerateImage(context.Background(), &port.ImageGenerationInput{Prompt: "cat", ImageCount: 1})
+}
+
+func TestRouterFailsOverToHealthyNode(t *testing.T) {
+	down, up := &fakeBackend{err: errUnavailable}, &fakeBackend{}
+	r := newTestRouter(down, up)
+
+	for range 3 {
+		result, err := generate(r)
+		if err != nil {
+			t.Fatalf("GenerateImage() error = %v", err)
+		}
+		if result.Backend != "node-1" {
+			t.Errorf("Backend = %q, want node-1", result.Backend)
+		}
+	}
+	if down.requests != 1 {
+		t.Errorf("unhealthy node received %d requests, want 1", down.requests)
+	}
+}
+
+func TestRouterKeepsLastHealthyNode(t *testing.T) {
+	only := &fakeBackend{err: errUnavailable}
+	r := newTestRouter(only)
+
+	if _, err := generate(r); !errors.Is(err, domain.ErrGenerationUnavailable) {
+		t.Fatalf("GenerateImage() error = %v, want %v", err, domain.ErrGenerationUnavailable)
+	}
+
+	// A transient error must not take the only node out of rotation
+	only.err = nil
+	if _, err := generate(r); err != nil {
+		t.Fatalf("GenerateImage() after recovery error = %v", err)
+	}
+}
+
+func TestRouterRetriesUnhealthyNodeAfterCooldown(t *testing.T) {
+	a, b := &fakeBackend{err: errUnavailable}, &fakeBackend{err: errUnavailable}
+	r := newTestRouter(a, b)
+
+	// node-0 is marked down, node-1 is kept as the last healthy node
+	if _, err := generate(r); err == nil {
+		t.Fatal("GenerateImage() succeeded with every node down")
+	}
+	if r.nodes[0].healthy {
+		t.Fatal("node-0 still healthy after failing")
+	}
+
+	a.err = nil
+	b.err = errUnavailable
+	if _, err := generate(r); err == nil {
+		t.Fatal("GenerateImage() reached node-0 before its cooldown passed")
+	}
+
+	r.nodes[0].downSince = time.Now().Add(-recoveryCooldown)
+	result, err := generate(r)
+	if err != nil {
+		t.Fatalf("GenerateImage() after the cooldown error = %v", err)
+	}
+	if result.Backend != "node-0" {
+		t.Errorf("Backend = %q, want node-0", result.Backend)
+	}
+	if !r.nodes[0].healthy {
+		t.Error("node-0 not healthy again after a success")
+	}
+}
+
+func TestRouterDoesNotFailOverRefusedRequests(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{name: "rejected", err: domain.ErrGenerationRejected},
+		{name: "invalid input", err: domain.ErrInvalidGenerationInput},
+		{name: "outcome unknown", err: domain.ErrGenerationOutcomeUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := &fakeBackend{err: tt.err}, &fakeBackend{err: tt.err}
+			r := newTestRouter(a, b)
+
+			if _, err := generate(r); !errors.Is(err, tt.err) {
+				t.Fatalf("GenerateImage() error = %v, want %v", err, tt.err)
+			}
+			if a.requests+b.requests != 1 {
+				t.Errorf("backends received %d requests, want 1", a.requests+b.requests)
+			}
+		})
+	}
+}
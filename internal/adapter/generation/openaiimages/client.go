@@ -0,0 +1,254 @@
+package openaiimages
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/CP-Payne/wonderpicai/internal/port"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	maxAttempts = 3
+	baseBackoff = 500 * time.Millisecond
+	maxBackoff  = 5 * time.Second
+)
+
+// Client is a port.ImageGeneration adapter for synchronous image APIs that follow the
+// OpenAI `/v1/images/generations` shape. GenerateImage blocks until the images are generated
+// and returns them in the result, so the generation job stays claimed by the queue worker
+// until then and is picked up again if the app stops halfway.
+type Client struct {
+	logger        *zap.Logger
+	baseURL       string
+	apiKey        string
+	model         string
+	maxPerRequest int
+	HttpClient    *http.Client
+}
+
+func NewClient(logger *zap.Logger, baseURL, apiKey, model string, maxPerRequest int) *Client {
+	if maxPerRequest < 1 {
+		maxPerRequest = 1
+	}
+
+	return &Client{
+		logger:        logger.With(zap.String("component", "OpenAIImagesClient")),
+		baseURL:       baseURL,
+		apiKey:        apiKey,
+		model:         model,
+		maxPerRequest: maxPerRequest,
+		HttpClient:    &http.Client{Timeout: 2 * time.Minute},
+	}
+}
+
+type imagesRequest struct {
+	Model          string `json:"model,omitempty"`
+	Prompt         string `json:"prompt"`
+	N              int    `json:"n"`
+	Size           string `json:"size"`
+	ResponseFormat string `json:"response_format"`
+}
+
+type imagesResponse struct {
+	Data []struct {
+		B64JSON string `json:"b64_json"`
+	} `json:"data"`
+	Error *apiError `json:"error"`
+}
+
+type apiError struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+	Code    string `json:"code"`
+}
+
+// attemptError describes a failed call to the images API and whether it is worth retrying
+type attemptError struct {
+	err       error
+	retryable bool
+}
+
+func (e *attemptError) Error() string { return e.err.Error() }
+func (e *attemptError) Unwrap() error { return e.err }
+
+func (c *Client) GenerateImage(ctx context.Context, input *port.ImageGenerationInput) (*port.ImageGenerationResult, error) {
+	if input.ImageCount < 1 {
+		return nil, fmt.Errorf("image count must be at least 1: %w", domain.ErrInvalidGenerationInput)
+	}
+
+	images := make([][]byte, 0, input.ImageCount)
+	for remaining := input.ImageCount; remaining > 0; {
+		n := min(remaining, c.maxPerRequest)
+
+		batch, err := c.generateWithRetry(ctx, input, n)
+		if err != nil {
+			c.logger.Error("Image generation failed", zap.Int("generated", len(images)), zap.Error(err))
+			if len(images) > 0 {
+				// The earlier batches are already paid for, the caller keeps them and only asks for the rest
+				return &port.ImageGenerationResult{Images: images}, err
+			}
+			return nil, err
+		}
+
+		images = append(images, batch...)
+		remaining -= len(batch)
+	}
+
+	// The images API has no IDs of its own, the prompt is tracked under a generated one
+	return &port.ImageGenerationResult{PromptID: uuid.New(), Images: images}, nil
+}
+
+func (c *Client) generateWithRetry(ctx context.Context, input *port.ImageGenerationInput, n int) ([][]byte, error) {
+	var lastErr error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		images, err := c.generate(ctx, input, n)
+		if err == nil {
+			return images, nil
+		}
+
+		var attemptErr *attemptError
+		if !errors.As(err, &attemptErr) || !attemptErr.retryable {
+			return nil, err
+		}
+		lastErr = err
+
+		if attempt == maxAttempts {
+			break
+		}
+
+		wait := backoff(attempt)
+		c.logger.Warn("Retrying images API request", zap.Int("attempt", attempt), zap.Duration("backoff", wait), zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+	return nil, lastErr
+}
+
+func (c *Client) generate(ctx context.Context, input *port.ImageGenerationInput, n int) ([][]byte, error) {
+	data, err := json.Marshal(imagesRequest{
+		Model:          c.model,
+		Prompt:         input.Prompt,
+		N:              n,
+		Size:           fmt.Sprintf("%dx%d", input.Width, input.Height),
+		ResponseFormat: "b64_json",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal images request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/v1/images/generations", bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create images request: %w", err)
+	}
+	c.setHeaders(req)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.HttpClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("images request cancelled: %w", ctx.Err())
+		}
+		if notDelivered(err) {
+			return nil, &attemptError{
+				err:       fmt.Errorf("failed connecting to images api: %w: %w", domain.ErrGenerationUnavailable, err),
+				retryable: true,
+			}
+		}
+		// The images may be generated, and paid for, even though the response never arrived
+		return nil, fmt.Errorf("failed sending request to images api: %w: %w", domain.ErrGenerationOutcomeUnknown, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading images api response: %w: %w", domain.ErrGenerationOutcomeUnknown, err)
+	}
+
+	imagesResp := imagesResponse{}
+	unmarshalErr := json.Unmarshal(respBody, &imagesResp)
+
+	if resp.StatusCode != http.StatusOK {
+		var message, code string
+		if imagesResp.Error != nil {
+			message, code = imagesResp.Error.Message, imagesResp.Error.Code
+		}
+		return nil, statusError(resp.StatusCode, code, message)
+	}
+
+	if unmarshalErr != nil {
+		return nil, fmt.Errorf("failed to read images api response: %w", unmarshalErr)
+	}
+
+	if len(imagesResp.Data) != n {
+		return nil, fmt.Errorf("images api returned %d images, expected %d", len(imagesResp.Data), n)
+	}
+
+	images := make([][]byte, 0, n)
+	for i, d := range imagesResp.Data {
+		decoded, err := base64.StdEncoding.DecodeString(d.B64JSON)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 data for image at index %d: %w", i, err)
+		}
+		images = append(images, decoded)
+	}
+
+	return images, nil
+}
+
+func (c *Client) setHeaders(req *http.Request) {
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+}
+
+// notDelivered reports whether err shows the request never reached the images API, so that
+// sending it again cannot generate the images twice
+func notDelivered(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// statusError maps a non 200 response from the images API to a domain error
+func statusError(status int, code, message string) error {
+	switch {
+	case status == http.StatusTooManyRequests:
+		return &attemptError{err: fmt.Errorf("images api responded with status %d: %w: %s", status, domain.ErrGenerationBusy, message), retryable: true}
+	case status >= http.StatusInternalServerError:
+		return &attemptError{err: fmt.Errorf("images api responded with status %d: %w: %s", status, domain.ErrGenerationUnavailable, message), retryable: true}
+	case code == "content_policy_violation":
+		return fmt.Errorf("images api rejected the prompt: %w: %s", domain.ErrGenerationRejected, message)
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return fmt.Errorf("images api refused the credentials (status %d): %w: %s", status, domain.ErrGenerationUnavailable, message)
+	default:
+		return fmt.Errorf("images api responded with status %d: %w: %s", status, domain.ErrInvalidGenerationInput, message)
+	}
+}
+
+// backoff returns an exponentially growing wait with full jitter
+func backoff(attempt int) time.Duration {
+	ceiling := baseBackoff << (attempt - 1)
+	if ceiling > maxBackoff {
+		ceiling = maxBackoff
+	}
+	return time.Duration(rand.Int64N(int64(ceiling)) + 1)
+}
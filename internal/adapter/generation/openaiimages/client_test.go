@@ -0,0 +1,164 @@
+package openaiimages
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/CP-Payne/wonderpicai/internal/port"
+	"go.uber.org/zap"
+)
+
+// stubImagesAPI answers /v1/images/generations with one image per requested image, after
+// failing the first `failures` requests with the given status and error code
+func stubImagesAPI(t *testing.T, failures int, status int, code string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := requests.Add(1)
+		if r.URL.Path != "/v1/images/generations" || r.Method != http.MethodPost {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer test-key" {
+			t.Errorf("Authorization = %q, want %q", got, "Bearer test-key")
+		}
+
+		var req imagesRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if int(n) <= failures {
+			w.WriteHeader(status)
+			fmt.Fprintf(w, `{"error":{"message":"failed","code":%q}}`, code)
+			return
+		}
+
+		resp := imagesResponse{}
+		for i := range req.N {
+			resp.Data = append(resp.Data, struct {
+				B64JSON string `json:"b64_json"`
+			}{B64JSON: base64.StdEncoding.EncodeToString(fmt.Appendf(nil, "%s-%d-%d", req.Size, n, i))})
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &requests
+}
+
+func TestGenerateImage(t *testing.T) {
+	tests := []struct {
+		name          string
+		imageCount    int
+		maxPerRequest int
+		failures      int
+		status        int
+		code          string
+		wantErr       error
+		wantRequests  int32
+	}{
+		{name: "single request", imageCount: 2, maxPerRequest: 4, wantRequests: 1},
+		{name: "split into batches", imageCount: 5, maxPerRequest: 2, wantRequests: 3},
+		{name: "busy is retried", imageCount: 1, maxPerRequest: 1, failures: 1, status: http.StatusTooManyRequests, wantRequests: 2},
+		{name: "server error is retried", imageCount: 1, maxPerRequest: 1, failures: 2, status: http.StatusInternalServerError, wantRequests: 3},
+		{name: "gives up after max attempts", imageCount: 1, maxPerRequest: 1, failures: maxAttempts, status: http.StatusServiceUnavailable, wantErr: domain.ErrGenerationUnavailable, wantRequests: maxAttempts},
+		{name: "content policy is not retried", imageCount: 1, maxPerRequest: 1, failures: 1, status: http.StatusBadRequest, code: "content_policy_violation", wantErr: domain.ErrGenerationRejected, wantRequests: 1},
+		{name: "bad request is not retried", imageCount: 1, maxPerRequest: 1, failures: 1, status: http.StatusBadRequest, wantErr: domain.ErrInvalidGenerationInput, wantRequests: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := stubImagesAPI(t, tt.failures, tt.status, tt.code)
+			client := NewClient(zap.NewNop(), server.URL, "test-key", "dall-e-2", tt.maxPerRequest)
+
+			result, err := client.GenerateImage(context.Background(), &port.ImageGenerationInput{
+				Prompt:     "a cat",
+				ImageCount: tt.imageCount,
+				Width:      512,
+				Height:     512,
+			})
+
+			if got := requests.Load(); got != tt.wantRequests {
+				t.Errorf("images API received %d requests, want %d", got, tt.wantRequests)
+			}
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("GenerateImage() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GenerateImage() error = %v", err)
+			}
+			if len(result.Images) != tt.imageCount {
+				t.Errorf("got %d images, want %d", len(result.Images), tt.imageCount)
+			}
+			if string(result.Images[0][:7]) != "512x512" {
+				t.Errorf("image was generated with size %q, want 512x512", result.Images[0][:7])
+			}
+		})
+	}
+}
+
+func TestGenerateImageDoesNotResendLostResponses(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		// Drop the connection after the request arrived, as a proxy timing out would
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("failed to hijack connection: %v", err)
+			return
+		}
+		conn.Close()
+	}))
+	defer server.Close()
+
+	client := NewClient(zap.NewNop(), server.URL, "test-key", "dall-e-2", 1)
+	_, err := client.GenerateImage(context.Background(), &port.ImageGenerationInput{Prompt: "a cat", ImageCount: 1, Width: 512, Height: 512})
+
+	if !errors.Is(err, domain.ErrGenerationOutcomeUnknown) {
+		t.Fatalf("GenerateImage() error = %v, want %v", err, domain.ErrGenerationOutcomeUnknown)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("images API received %d requests, want 1", got)
+	}
+}
+
+func TestGenerateImageReturnsEarlierBatchesOnFailure(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		// The first batch succeeds, every later one finds the backend unavailable
+		if requests.Add(1) > 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprint(w, `{"error":{"message":"failed"}}`)
+			return
+		}
+		fmt.Fprintf(w, `{"data":[{"b64_json":%q}]}`, base64.StdEncoding.EncodeToString([]byte("first")))
+	}))
+	defer server.Close()
+
+	client := NewClient(zap.NewNop(), server.URL, "test-key", "dall-e-2", 1)
+	result, err := client.GenerateImage(context.Background(), &port.ImageGenerationInput{Prompt: "a cat", ImageCount: 3, Width: 512, Height: 512})
+
+	if !errors.Is(err, domain.ErrGenerationUnavailable) {
+		t.Fatalf("GenerateImage() error = %v, want %v", err, domain.ErrGenerationUnavailable)
+	}
+	if result == nil || len(result.Images) != 1 || string(result.Images[0]) != "first" {
+		t.Fatalf("GenerateImage() result = %+v, want the image of the first batch", result)
+	}
+	if got := requests.Load(); got != 1+maxAttempts {
+		t.Errorf("images API received %d requests, want %d", got, 1+maxAttempts)
+	}
+}
@@ -0,0 +1,32 @@
+package logmailer
+
+import (
+	"context"
+
+	"github.com/CP-Payne/wonderpicai/internal/port"
+	"go.uber.org/zap"
+)
+
+type mailer struct {
+	logger *zap.Logger
+}
+
+// NewMailer returns a mailer that only logs messages, for development without an SMTP server
+func NewMailer(logger *zap.Logger) port.Mailer {
+	return &mailer{logger: logger.With(zap.String("component", "LogMailer"))}
+}
+
+func (m *mailer) Send(ctx context.Context, msg port.MailMessage) error {
+	attachments := make([]string, len(msg.Attachments))
+	for i, attachment := range msg.Attachments {
+		attachments[i] = attachment.Filename
+	}
+
+	m.logger.Info("Mail not sent, logging only",
+		zap.String("to", msg.To),
+		zap.String("subject", msg.Subject),
+		zap.String("body", msg.Body),
+		zap.Strings("attachments", attachments),
+	)
+	return nil
+}
@@ -0,0 +1,121 @@
+package smtpmailer
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"time"
+
+	"github.com/CP-Payne/wonderpicai/internal/port"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type mailer struct {
+	logger *zap.Logger
+	addr   string
+	host   string
+	auth   smtp.Auth
+	from   string
+}
+
+// NewMailer returns a mailer sending through an SMTP server. STARTTLS is used when the server
+// offers it and credentials are only sent over TLS.
+func NewMailer(logger *zap.Logger, host, port, username, password, from string) port.Mailer {
+	m := &mailer{
+		logger: logger.With(zap.String("component", "SMTPMailer")),
+		addr:   net.JoinHostPort(host, port),
+		host:   host,
+		from:   from,
+	}
+	if username != "" {
+		m.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return m
+}
+
+func (m *mailer) Send(ctx context.Context, msg port.MailMessage) error {
+	data, err := m.build(msg)
+	if err != nil {
+		return fmt.Errorf("failed to build mail: %w", err)
+	}
+
+	// net/smtp does not take a context, the send runs until the server answers
+	if err := smtp.SendMail(m.addr, m.auth, m.from, []string{msg.To}, data); err != nil {
+		m.logger.Error("Failed to send mail", zap.String("to", msg.To), zap.String("subject", msg.Subject), zap.Error(err))
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+
+	m.logger.Info("Mail sent", zap.String("to", msg.To), zap.String("subject", msg.Subject))
+	return nil
+}
+
+// build encodes the message as multipart/mixed with a plain text body and base64 attachments
+func (m *mailer) build(msg port.MailMessage) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	headers := []struct{ key, value string }{
+		{"From", m.from},
+		{"To", msg.To},
+		{"Subject", mime.QEncoding.Encode("utf-8", msg.Subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"Message-ID", fmt.Sprintf("<%s@%s>", uuid.NewString(), m.host)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "multipart/mixed; boundary=" + writer.Boundary()},
+	}
+	for _, header := range headers {
+		fmt.Fprintf(&buf, "%s: %s\r\n", header.key, header.value)
+	}
+	buf.WriteString("\r\n")
+
+	body, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := writeBase64(body, []byte(msg.Body)); err != nil {
+		return nil, err
+	}
+
+	for _, attachment := range msg.Attachments {
+		part, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {attachment.ContentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename})},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeBase64(part, attachment.Data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeBase64 writes data base64 encoded in lines of 76 characters
+func writeBase64(w io.Writer, data []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 0 {
+		n := min(76, len(encoded))
+		if _, err := fmt.Fprintf(w, "%s\r\n", encoded[:n]); err != nil {
+			return err
+		}
+		encoded = encoded[n:]
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package prommetrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/CP-Payne/wonderpicai/internal/port"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+)
+
+const namespace = "wonderpicai"
+
+// queueDepthTimeout bounds the query counting queued jobs while a scrape waits for it
+const queueDepthTimeout = 5 * time.Second
+
+// Metrics records the application metrics in its own Prometheus registry, which is served
+// by Handler together with the Go runtime and process metrics.
+type Metrics struct {
+	logger   *zap.Logger
+	registry *prometheus.Registry
+
+	httpRequests          *prometheus.HistogramVec
+	generationSubmissions *prometheus.CounterVec
+	generationDuration    *prometheus.HistogramVec
+	backendRequests       *prometheus.HistogramVec
+	credits               *prometheus.CounterVec
+	paymentWebhooks       *prometheus.CounterVec
+}
+
+func NewMetrics(logger *zap.Logger) *Metrics {
+	m := &Metrics{
+		logger:   logger.With(zap.String("component", "PrometheusMetrics")),
+		registry: prometheus.NewRegistry(),
+
+		httpRequests: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Duration of handled HTTP requests by route pattern.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		generationSubmissions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "generation_submissions_total",
+			Help:      "Generation requests made by users, by outcome.",
+		}, []string{"outcome"}),
+		generationDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "generation_duration_seconds",
+			Help:      "Time from the creation of a prompt until it completed or failed.",
+			Buckets:   []float64{1, 2, 5, 10, 20, 30, 60, 120, 300, 600, 1800},
+		}, []string{"status"}),
+		backendRequests: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "generation_backend_request_duration_seconds",
+			Help:      "Duration of single requests to generation backends, by outcome.",
+			Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+		}, []string{"backend", "outcome"}),
+		credits: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "credits_total",
+			Help:      "Credits debited for generations, refunded and purchased, by ledger kind.",
+		}, []string{"kind"}),
+		paymentWebhooks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "payment_webhook_events_total",
+			Help:      "Events received from the payment provider, by event type and outcome.",
+		}, []string{"event", "outcome"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.generationSubmissions,
+		m.generationDuration,
+		m.backendRequests,
+		m.credits,
+		m.paymentWebhooks,
+	)
+
+	return m
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{
+		ErrorLog: zap.NewStdLog(m.logger),
+	})
+}
+
+// WatchQueueDepth exposes the number of jobs of pending prompts by job status. The jobs are
+// counted when the metrics are scraped.
+func (m *Metrics) WatchQueueDepth(jobRepo port.GenerationJobRepository) {
+	m.registry.MustRegister(&queueDepthCollector{
+		logger:  m.logger,
+		jobRepo: jobRepo,
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "generation", "queue_depth"),
+			"Jobs of pending prompts by job status: queued, running, or submitted and awaiting the result.",
+			[]string{"status"}, nil,
+		),
+	})
+}
+
+func (m *Metrics) ObserveHTTPRequest(method, route string, status int, duration time.Duration) {
+	m.httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Observe(duration.Seconds())
+}
+
+func (m *Metrics) GenerationSubmitted(outcome string) {
+	m.generationSubmissions.WithLabelValues(outcome).Inc()
+}
+
+func (m *Metrics) GenerationFinished(status domain.Status, took time.Duration) {
+	m.generationDuration.WithLabelValues(strings.ToLower(status.String())).Observe(took.Seconds())
+}
+
+func (m *Metrics) GenerationBackendRequest(backend, outcome string, duration time.Duration) {
+	m.backendRequests.WithLabelValues(backend, outcome).Observe(duration.Seconds())
+}
+
+func (m *Metrics) CreditsMoved(kind domain.CreditTransactionKind, amount int) {
+	if amount < 0 {
+		amount = -amount
+	}
+	m.credits.WithLabelValues(string(kind)).Add(float64(amount))
+}
+
+func (m *Metrics) PaymentWebhook(eventType, outcome string) {
+	m.paymentWebhooks.WithLabelValues(eventType, outcome).Inc()
+}
+
+type queueDepthCollector struct {
+	logger  *zap.Logger
+	jobRepo port.GenerationJobRepository
+	desc    *prometheus.Desc
+}
+
+func (c *queueDepthCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *queueDepthCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), queueDepthTimeout)
+	defer cancel()
+
+	depth, err := c.jobRepo.QueueDepth(ctx)
+	if err != nil {
+		// The gauge is left out of this scrape rather than reported as an empty queue
+		c.logger.Warn("Failed to count queued generation jobs for metrics", zap.Error(err))
+		return
+	}
+
+	for _, status := range []domain.JobStatus{domain.JobQueued, domain.JobRunning, domain.JobSubmitted} {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(depth[status]), string(status))
+	}
+}
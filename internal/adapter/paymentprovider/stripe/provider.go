@@ -4,17 +4,29 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"os"
+	"time"
 
 	"github.com/CP-Payne/wonderpicai/internal/domain"
 	"github.com/CP-Payne/wonderpicai/internal/port"
 	"github.com/stripe/stripe-go/v82"
+	portalsession "github.com/stripe/stripe-go/v82/billingportal/session"
 	"github.com/stripe/stripe-go/v82/checkout/session"
+	"github.com/stripe/stripe-go/v82/coupon"
+	"github.com/stripe/stripe-go/v82/invoicepayment"
 	"github.com/stripe/stripe-go/v82/product"
 	"github.com/stripe/stripe-go/v82/webhook"
 	"go.uber.org/zap"
 )
 
+// subscriptionReferenceKey is the metadata key of our subscription ID on Stripe subscriptions
+const subscriptionReferenceKey = "subscription_ref"
+
+var checkoutEventTypes = map[stripe.EventType]port.ProviderEventType{
+	"checkout.session.completed":               port.EventCheckoutCompleted,
+	"checkout.session.async_payment_succeeded": port.EventCheckoutAsyncSucceeded,
+	"checkout.session.async_payment_failed":    port.EventCheckoutAsyncFailed,
+}
+
 type StripeProvider struct {
 	logger         *zap.Logger
 	endpointSecret string
@@ -32,21 +44,22 @@ func NewProvider(logger *zap.Logger, privateKey, endpointSecret string, successU
 	}
 }
 
-func (p *StripeProvider) CreateCheckoutSession(user port.UserData, product port.ProductData) (string, error) {
+func (p *StripeProvider) CreateCheckoutSession(user port.UserData, product port.ProductData) (*port.CheckoutSession, error) {
 	params := &stripe.CheckoutSessionParams{
-		CustomerEmail: stripe.String(user.Email),
-		Mode:          stripe.String(string(stripe.CheckoutSessionModePayment)),
+		CustomerEmail:     stripe.String(user.Email),
+		ClientReferenceID: stripe.String(product.Reference),
+		Mode:              stripe.String(string(stripe.CheckoutSessionModePayment)),
 		LineItems: []*stripe.CheckoutSessionLineItemParams{
 			{
 				PriceData: &stripe.CheckoutSessionLineItemPriceDataParams{
-					Currency: stripe.String("usd"),
+					Currency: stripe.String(product.Currency),
 					ProductData: &stripe.CheckoutSessionLineItemPriceDataProductDataParams{
 						Name: stripe.String(product.Name),
 						Metadata: map[string]string{
 							"option": product.Option,
 						},
 					},
-					UnitAmount: stripe.Int64(int64(product.Price) * 100),
+					UnitAmount: stripe.Int64(product.PriceMinor),
 				},
 				Quantity: stripe.Int64(int64(product.Quantity)),
 			},
@@ -58,15 +71,91 @@ func (p *StripeProvider) CreateCheckoutSession(user port.UserData, product port.
 		CancelURL:  stripe.String(p.cancelURL),
 	}
 	params.AddExpand("line_items")
+
+	if !product.ExpiresAt.IsZero() {
+		params.ExpiresAt = stripe.Int64(product.ExpiresAt.Unix())
+	}
+
+	if product.DiscountPercent > 0 {
+		// A single use coupon per checkout, the redemption limits are enforced on our side
+		c, err := coupon.New(&stripe.CouponParams{
+			Name:           stripe.String(product.DiscountName),
+			PercentOff:     stripe.Float64(float64(product.DiscountPercent)),
+			Duration:       stripe.String(string(stripe.CouponDurationOnce)),
+			MaxRedemptions: stripe.Int64(1),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create stripe coupon: %w", err)
+		}
+		params.Discounts = []*stripe.CheckoutSessionDiscountParams{
+			{Coupon: stripe.String(c.ID)},
+		}
+	}
+
 	s, err := session.New(params)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
+	return &port.CheckoutSession{ID: s.ID, URL: s.URL}, nil
+}
+
+func (p *StripeProvider) CreateSubscriptionCheckout(user port.UserData, sub port.SubscriptionData) (*port.CheckoutSession, error) {
+	params := &stripe.CheckoutSessionParams{
+		ClientReferenceID: stripe.String(sub.Reference),
+		Mode:              stripe.String(string(stripe.CheckoutSessionModeSubscription)),
+		LineItems: []*stripe.CheckoutSessionLineItemParams{
+			{
+				PriceData: &stripe.CheckoutSessionLineItemPriceDataParams{
+					Currency: stripe.String(sub.Currency),
+					ProductData: &stripe.CheckoutSessionLineItemPriceDataProductDataParams{
+						Name: stripe.String(sub.Name),
+					},
+					UnitAmount: stripe.Int64(sub.PriceMinor),
+					Recurring: &stripe.CheckoutSessionLineItemPriceDataRecurringParams{
+						Interval: stripe.String(sub.Interval),
+					},
+				},
+				Quantity: stripe.Int64(1),
+			},
+		},
+		// The reference is copied to the subscription so that its invoices can be matched
+		// even when they arrive before the completed checkout
+		SubscriptionData: &stripe.CheckoutSessionSubscriptionDataParams{
+			Metadata: map[string]string{
+				subscriptionReferenceKey: sub.Reference,
+			},
+		},
+		SuccessURL: stripe.String(p.successURL),
+		CancelURL:  stripe.String(p.cancelURL),
+	}
+
+	if user.CustomerID != "" {
+		params.Customer = stripe.String(user.CustomerID)
+	} else {
+		params.CustomerEmail = stripe.String(user.Email)
+	}
+
+	s, err := session.New(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return &port.CheckoutSession{ID: s.ID, URL: s.URL}, nil
+}
+
+func (p *StripeProvider) CreatePortalSession(customerID string, returnURL string) (string, error) {
+	s, err := portalsession.New(&stripe.BillingPortalSessionParams{
+		Customer:  stripe.String(customerID),
+		ReturnURL: stripe.String(returnURL),
+	})
+	if err != nil {
+		return "", err
+	}
 	return s.URL, nil
 }
 
-func (p *StripeProvider) HandleEvent(r *http.Request, data []byte) (*port.SessionSuccess, error) {
+func (p *StripeProvider) HandleEvent(r *http.Request, data []byte) (*port.ProviderEvent, error) {
 
 	event := stripe.Event{}
 
@@ -84,56 +173,236 @@ func (p *StripeProvider) HandleEvent(r *http.Request, data []byte) (*port.Sessio
 	}
 	// Unmarshal the event data into an appropriate struct depending on its Type
 	switch event.Type {
-	case "checkout.session.completed":
-		var sessionEvent stripe.CheckoutSession
-
-		err := json.Unmarshal(event.Data.Raw, &sessionEvent)
+	case "checkout.session.completed", "checkout.session.async_payment_succeeded", "checkout.session.async_payment_failed":
+		sessionSuccess, err := p.checkoutCompleted(event)
 		if err != nil {
-			p.logger.Error("Failed parsing stripe webhook JSON", zap.Error(err))
-			return nil, fmt.Errorf("failed parsing stripe webhook json into checkout session: %w", err)
+			return nil, err
 		}
+		return &port.ProviderEvent{ID: event.ID, Type: checkoutEventTypes[event.Type], Checkout: sessionSuccess}, nil
 
-		// Get Line items
-		params := &stripe.CheckoutSessionListLineItemsParams{
-			Session: stripe.String(sessionEvent.ID),
+	case "charge.refunded":
+		var charge stripe.Charge
+		if err := json.Unmarshal(event.Data.Raw, &charge); err != nil {
+			p.logger.Error("Failed parsing stripe webhook JSON", zap.Error(err))
+			return nil, fmt.Errorf("failed parsing stripe webhook json into charge: %w", err)
+		}
+		if charge.PaymentIntent == nil {
+			return nil, domain.ErrUnhandledEvent
 		}
 
-		var option string
-
-		result := session.ListLineItems(params)
+		return &port.ProviderEvent{ID: event.ID, Type: port.EventChargeRefunded, Reversal: &port.PaymentReversal{
+			PaymentID:   charge.PaymentIntent.ID,
+			ChargeID:    charge.ID,
+			AmountMinor: charge.AmountRefunded,
+			Currency:    string(charge.Currency),
+		}}, nil
 
-		for result.Next() {
-			item := result.LineItem()
+	case "charge.dispute.created":
+		var dispute stripe.Dispute
+		if err := json.Unmarshal(event.Data.Raw, &dispute); err != nil {
+			p.logger.Error("Failed parsing stripe webhook JSON", zap.Error(err))
+			return nil, fmt.Errorf("failed parsing stripe webhook json into dispute: %w", err)
+		}
+		if dispute.PaymentIntent == nil {
+			return nil, domain.ErrUnhandledEvent
+		}
 
-			if item.Price == nil || item.Price.Product == nil {
-				p.logger.Error("Price or Product field is nil in line item")
-				return nil, fmt.Errorf("failed accessing price or product field in line item")
-			}
+		reversal := &port.PaymentReversal{
+			PaymentID:   dispute.PaymentIntent.ID,
+			DisputeID:   dispute.ID,
+			AmountMinor: dispute.Amount,
+			Currency:    string(dispute.Currency),
+			Reason:      string(dispute.Reason),
+		}
+		if dispute.Charge != nil {
+			reversal.ChargeID = dispute.Charge.ID
+		}
+		return &port.ProviderEvent{ID: event.ID, Type: port.EventChargeDisputed, Reversal: reversal}, nil
 
-			productID := item.Price.Product.ID
+	case "invoice.paid":
+		var invoice stripe.Invoice
+		if err := json.Unmarshal(event.Data.Raw, &invoice); err != nil {
+			p.logger.Error("Failed parsing stripe webhook JSON", zap.Error(err))
+			return nil, fmt.Errorf("failed parsing stripe webhook json into invoice: %w", err)
+		}
 
-			stripeProduct, err := product.Get(productID, nil)
+		paid := invoicePaid(&invoice)
+		if paid.SubscriptionID == "" {
+			// One-off payments are handled through their checkout session
+			return nil, domain.ErrUnhandledEvent
+		}
+		if paid.PaymentID == "" && paid.AmountPaid > 0 {
+			// The invoice's payments are not part of the event unless expanded
+			paymentID, err := p.invoicePaymentID(invoice.ID)
 			if err != nil {
-				p.logger.Error("Failed to retrieve product from Stripe", zap.Error(err))
-				return nil, fmt.Errorf("failed to retrieve product from stripe: %w", err)
+				p.logger.Error("Failed loading invoice payment", zap.String("invoiceID", invoice.ID), zap.Error(err))
+				return nil, fmt.Errorf("failed loading payment of invoice: %w", err)
 			}
-
-			option = stripeProduct.Metadata["option"]
+			paid.PaymentID = paymentID
 		}
+		return &port.ProviderEvent{ID: event.ID, Type: port.EventInvoicePaid, Invoice: paid}, nil
 
-		sessionSuccess := port.SessionSuccess{
-			UserEmail: sessionEvent.CustomerDetails.Email,
-			Option:    option,
+	case "customer.subscription.updated", "customer.subscription.deleted":
+		var subscription stripe.Subscription
+		if err := json.Unmarshal(event.Data.Raw, &subscription); err != nil {
+			p.logger.Error("Failed parsing stripe webhook JSON", zap.Error(err))
+			return nil, fmt.Errorf("failed parsing stripe webhook json into subscription: %w", err)
 		}
 
-		// p.logger.Debug("Session data after successfull purchase", zap.Any("session", sessionEvent))
-		return &sessionSuccess, nil
+		eventType := port.EventSubscriptionUpdated
+		if event.Type == "customer.subscription.deleted" {
+			eventType = port.EventSubscriptionCanceled
+		}
+		return &port.ProviderEvent{ID: event.ID, Type: eventType, Subscription: subscriptionChange(&subscription)}, nil
 
 	default:
-		fmt.Fprintf(os.Stderr, "Unhandled event type: %s\n", event.Type)
 		p.logger.Warn("Unhandled event type", zap.Any("type", event.Type))
 	}
 
 	return nil, domain.ErrUnhandledEvent
 
 }
+
+func (p *StripeProvider) checkoutCompleted(event stripe.Event) (*port.SessionSuccess, error) {
+	var sessionEvent stripe.CheckoutSession
+
+	err := json.Unmarshal(event.Data.Raw, &sessionEvent)
+	if err != nil {
+		p.logger.Error("Failed parsing stripe webhook JSON", zap.Error(err))
+		return nil, fmt.Errorf("failed parsing stripe webhook json into checkout session: %w", err)
+	}
+
+	sessionSuccess := port.SessionSuccess{
+		SessionID:   sessionEvent.ID,
+		Reference:   sessionEvent.ClientReferenceID,
+		AmountTotal: sessionEvent.AmountTotal,
+		Currency:    string(sessionEvent.Currency),
+		Paid:        sessionEvent.PaymentStatus != stripe.CheckoutSessionPaymentStatusUnpaid,
+	}
+	if sessionEvent.PaymentIntent != nil {
+		sessionSuccess.PaymentID = sessionEvent.PaymentIntent.ID
+	}
+	if sessionEvent.CustomerDetails != nil {
+		sessionSuccess.UserEmail = sessionEvent.CustomerDetails.Email
+	}
+	if sessionEvent.Customer != nil {
+		sessionSuccess.CustomerID = sessionEvent.Customer.ID
+	}
+
+	if sessionEvent.Mode == stripe.CheckoutSessionModeSubscription {
+		if sessionEvent.Subscription != nil {
+			sessionSuccess.SubscriptionID = sessionEvent.Subscription.ID
+		}
+		return &sessionSuccess, nil
+	}
+
+	// Get Line items
+	params := &stripe.CheckoutSessionListLineItemsParams{
+		Session: stripe.String(sessionEvent.ID),
+	}
+
+	result := session.ListLineItems(params)
+
+	for result.Next() {
+		item := result.LineItem()
+
+		if item.Price == nil || item.Price.Product == nil {
+			p.logger.Error("Price or Product field is nil in line item")
+			return nil, fmt.Errorf("failed accessing price or product field in line item")
+		}
+
+		productID := item.Price.Product.ID
+
+		stripeProduct, err := product.Get(productID, nil)
+		if err != nil {
+			p.logger.Error("Failed to retrieve product from Stripe", zap.Error(err))
+			return nil, fmt.Errorf("failed to retrieve product from stripe: %w", err)
+		}
+
+		sessionSuccess.Option = stripeProduct.Metadata["option"]
+	}
+
+	return &sessionSuccess, nil
+}
+
+// invoicePaymentID returns the payment intent that paid the invoice, refunds and disputes of
+// the invoice refer to it
+func (p *StripeProvider) invoicePaymentID(invoiceID string) (string, error) {
+	params := &stripe.InvoicePaymentListParams{
+		Invoice: stripe.String(invoiceID),
+		Status:  stripe.String("paid"),
+	}
+	iter := invoicepayment.List(params)
+	for iter.Next() {
+		if id := paymentIntentID(iter.InvoicePayment()); id != "" {
+			return id, nil
+		}
+	}
+	return "", iter.Err()
+}
+
+func paymentIntentID(payment *stripe.InvoicePayment) string {
+	if payment == nil || payment.Status != "paid" || payment.Payment == nil || payment.Payment.PaymentIntent == nil {
+		return ""
+	}
+	return payment.Payment.PaymentIntent.ID
+}
+
+func invoicePaid(invoice *stripe.Invoice) *port.InvoicePaid {
+	paid := &port.InvoicePaid{
+		InvoiceID:  invoice.ID,
+		AmountPaid: invoice.AmountPaid,
+		Currency:   string(invoice.Currency),
+	}
+	if invoice.Payments != nil {
+		for _, payment := range invoice.Payments.Data {
+			if id := paymentIntentID(payment); id != "" {
+				paid.PaymentID = id
+				break
+			}
+		}
+	}
+	if invoice.Customer != nil {
+		paid.CustomerID = invoice.Customer.ID
+	}
+	if invoice.Parent != nil && invoice.Parent.SubscriptionDetails != nil {
+		details := invoice.Parent.SubscriptionDetails
+		if details.Subscription != nil {
+			paid.SubscriptionID = details.Subscription.ID
+		}
+		paid.Reference = details.Metadata[subscriptionReferenceKey]
+	}
+
+	// The invoice's own period is the previous one for renewals, the line item covers the
+	// period that was paid for
+	if invoice.Lines != nil {
+		for _, line := range invoice.Lines.Data {
+			if line.Period != nil {
+				paid.PeriodStart = time.Unix(line.Period.Start, 0)
+				paid.PeriodEnd = time.Unix(line.Period.End, 0)
+				break
+			}
+		}
+	}
+
+	return paid
+}
+
+func subscriptionChange(subscription *stripe.Subscription) *port.SubscriptionChange {
+	change := &port.SubscriptionChange{
+		SubscriptionID:    subscription.ID,
+		Reference:         subscription.Metadata[subscriptionReferenceKey],
+		Status:            string(subscription.Status),
+		CancelAtPeriodEnd: subscription.CancelAtPeriodEnd,
+	}
+	if subscription.CanceledAt > 0 {
+		change.CanceledAt = time.Unix(subscription.CanceledAt, 0)
+	}
+	if subscription.Items != nil {
+		for _, item := range subscription.Items.Data {
+			change.CurrentPeriodEnd = time.Unix(item.CurrentPeriodEnd, 0)
+			break
+		}
+	}
+	return change
+}
@@ -0,0 +1,63 @@
+package stripe
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stripe/stripe-go/v82"
+)
+
+func TestInvoicePaid(t *testing.T) {
+	tests := []struct {
+		name          string
+		payments      string
+		wantPaymentID string
+	}{
+		{
+			name:          "paid by payment intent",
+			payments:      `{"object":"list","data":[{"id":"inpay_1","status":"paid","payment":{"type":"payment_intent","payment_intent":"pi_123"}}]}`,
+			wantPaymentID: "pi_123",
+		},
+		{
+			name: "canceled payment is skipped",
+			payments: `{"object":"list","data":[
+				{"id":"inpay_1","status":"canceled","payment":{"type":"payment_intent","payment_intent":"pi_old"}},
+				{"id":"inpay_2","status":"paid","payment":{"type":"payment_intent","payment_intent":"pi_new"}}]}`,
+			wantPaymentID: "pi_new",
+		},
+		{name: "payments not included", payments: `null`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := `{
+				"id": "in_123",
+				"amount_paid": 900,
+				"currency": "usd",
+				"customer": "cus_123",
+				"parent": {"subscription_details": {"subscription": "sub_123", "metadata": {"subscription_ref": "ref_123"}}},
+				"lines": {"object": "list", "data": [{"period": {"start": 1767225600, "end": 1769904000}}]},
+				"payments": ` + tt.payments + `
+			}`
+			var invoice stripe.Invoice
+			if err := json.Unmarshal([]byte(raw), &invoice); err != nil {
+				t.Fatalf("failed to parse invoice: %v", err)
+			}
+
+			paid := invoicePaid(&invoice)
+			if paid.PaymentID != tt.wantPaymentID {
+				t.Errorf("PaymentID = %q, want %q", paid.PaymentID, tt.wantPaymentID)
+			}
+			if paid.InvoiceID != "in_123" || paid.SubscriptionID != "sub_123" || paid.CustomerID != "cus_123" || paid.Reference != "ref_123" {
+				t.Errorf("invoicePaid() = %+v, want the invoice, subscription, customer and reference", paid)
+			}
+			if paid.AmountPaid != 900 || paid.Currency != "usd" {
+				t.Errorf("paid %d %s, want 900 usd", paid.AmountPaid, paid.Currency)
+			}
+			if !paid.PeriodStart.Equal(time.Unix(1767225600, 0)) || !paid.PeriodEnd.Equal(time.Unix(1769904000, 0)) {
+				t.Errorf("period = %v - %v, want the line item's period", paid.PeriodStart, paid.PeriodEnd)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/CP-Payne/wonderpicai/internal/port"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type gormAPIKeyRepository struct {
+	db     *gorm.DB
+	logger *zap.Logger
+}
+
+func NewGormAPIKeyRepository(db *gorm.DB, logger *zap.Logger) port.APIKeyRepository {
+	return &gormAPIKeyRepository{db: db, logger: logger.With(zap.String("component", "APIKeyRepoGORM"))}
+}
+
+func (r *gormAPIKeyRepository) Create(ctx context.Context, key *domain.APIKey) error {
+	if err := r.db.WithContext(ctx).Create(key).Error; err != nil {
+		r.logger.Error("Failed to create API key", zap.String("userID", key.UserID.String()), zap.Error(err))
+		return fmt.Errorf("database error creating API key: %w", err)
+	}
+	return nil
+}
+
+func (r *gormAPIKeyRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]domain.APIKey, error) {
+	var keys []domain.APIKey
+
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Find(&keys).Error
+	if err != nil {
+		r.logger.Error("Failed to list API keys", zap.String("userID", userID.String()), zap.Error(err))
+		return nil, fmt.Errorf("database error listing API keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+func (r *gormAPIKeyRepository) GetByPrefix(ctx context.Context, prefix string) (*domain.APIKey, error) {
+	var key domain.APIKey
+
+	err := r.db.WithContext(ctx).Where("prefix = ?", prefix).First(&key).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrRecordNotFound
+		}
+		r.logger.Error("Failed to get API key", zap.String("prefix", prefix), zap.Error(err))
+		return nil, fmt.Errorf("database error fetching API key: %w", err)
+	}
+
+	return &key, nil
+}
+
+func (r *gormAPIKeyRepository) Revoke(ctx context.Context, userID uuid.UUID, id uuid.UUID, at time.Time) (*domain.APIKey, error) {
+	var key domain.APIKey
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND user_id = ?", id, userID).
+			First(&key).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return domain.ErrRecordNotFound
+			}
+			return err
+		}
+
+		if key.RevokedAt != nil {
+			return nil
+		}
+
+		key.RevokedAt = &at
+		return tx.Model(&key).Update("revoked_at", at).Error
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrRecordNotFound) {
+			return nil, err
+		}
+		r.logger.Error("Failed to revoke API key", zap.String("apiKeyID", id.String()), zap.Error(err))
+		return nil, fmt.Errorf("database error revoking API key: %w", err)
+	}
+
+	return &key, nil
+}
+
+func (r *gormAPIKeyRepository) TouchLastUsed(ctx context.Context, id uuid.UUID, at time.Time) error {
+	err := r.db.WithContext(ctx).Model(&domain.APIKey{}).Where("id = ?", id).UpdateColumn("last_used_at", at).Error
+	if err != nil {
+		return fmt.Errorf("database error updating API key last use: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/CP-Payne/wonderpicai/internal/port"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// auditChainLock is the advisory lock key that serializes appends to the audit chain
+const auditChainLock = 0x61756469
+
+type gormAuditRepository struct {
+	db     *gorm.DB
+	logger *zap.Logger
+}
+
+func NewGormAuditRepository(db *gorm.DB, logger *zap.Logger) port.AuditRepository {
+	return &gormAuditRepository{db: db, logger: logger.With(zap.String("component", "AuditRepoGORM"))}
+}
+
+func (r *gormAuditRepository) Append(ctx context.Context, event *domain.AuditEvent) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Appends must not interleave, each event needs the hash of the one before it
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", auditChainLock).Error; err != nil {
+			return fmt.Errorf("failed to lock audit chain: %w", err)
+		}
+
+		var last domain.AuditEvent
+		err := tx.Order("sequence DESC").Take(&last).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to read end of audit chain: %w", err)
+		}
+
+		if event.ID == uuid.Nil {
+			event.ID = uuid.New()
+		}
+		// Postgres keeps microseconds, the hash must cover the stored value
+		event.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+		event.Sequence = last.Sequence + 1
+		event.PrevHash = last.Hash
+		event.Hash = event.ComputeHash()
+
+		return tx.Create(event).Error
+	})
+	if err != nil {
+		r.logger.Error("Failed to append audit event", zap.String("action", string(event.Action)), zap.Error(err))
+		return fmt.Errorf("database error appending audit event: %w", err)
+	}
+	return nil
+}
+
+func (r *gormAuditRepository) List(ctx context.Context, filter port.AuditFilter, page domain.Page) ([]domain.AuditEvent, int64, error) {
+	db := r.db.WithContext(ctx).Model(&domain.AuditEvent{})
+	if filter.Action != "" {
+		db = db.Where("action = ?", filter.Action)
+	}
+	if actor := strings.TrimSpace(filter.ActorEmail); actor != "" {
+		db = db.Where("actor_email ILIKE ?", "%"+escapeLike(actor)+"%")
+	}
+	if filter.TargetType != "" {
+		db = db.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		db = db.Where("target_id = ?", filter.TargetID)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		r.logger.Error("Failed to count audit events", zap.Error(err))
+		return nil, 0, fmt.Errorf("database error counting audit events: %w", err)
+	}
+
+	var events []domain.AuditEvent
+	if err := db.Order("sequence DESC").Limit(page.Limit).Offset(page.Offset).Find(&events).Error; err != nil {
+		r.logger.Error("Failed to list audit events", zap.Error(err))
+		return nil, 0, fmt.Errorf("database error listing audit events: %w", err)
+	}
+
+	return events, total, nil
+}
+
+func (r *gormAuditRepository) ListAfter(ctx context.Context, sequence int64, limit int) ([]domain.AuditEvent, error) {
+	var events []domain.AuditEvent
+	err := r.db.WithContext(ctx).
+		Where("sequence > ?", sequence).
+		Order("sequence ASC").
+		Limit(limit).
+		Find(&events).Error
+	if err != nil {
+		r.logger.Error("Failed to read audit chain", zap.Int64("after", sequence), zap.Error(err))
+		return nil, fmt.Errorf("database error reading audit chain: %w", err)
+	}
+	return events, nil
+}
+
+// protectAuditEvents rejects updates and deletes of audit events in the database itself,
+// so that only a privileged database user can break the chain
+func protectAuditEvents(db *gorm.DB) error {
+	statements := []string{
+		`CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS audit_events_append_only ON audit_events`,
+		`CREATE TRIGGER audit_events_append_only BEFORE UPDATE OR DELETE ON audit_events
+	FOR EACH ROW EXECUTE FUNCTION audit_events_append_only()`,
+		`DROP TRIGGER IF EXISTS audit_events_no_truncate ON audit_events`,
+		`CREATE TRIGGER audit_events_no_truncate BEFORE TRUNCATE ON audit_events
+	FOR EACH STATEMENT EXECUTE FUNCTION audit_events_append_only()`,
+	}
+
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			return fmt.Errorf("failed to protect audit events: %w", err)
+		}
+	}
+	return nil
+}
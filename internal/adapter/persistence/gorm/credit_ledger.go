@@ -0,0 +1,36 @@
+package gorm
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/CP-Payne/wonderpicai/internal/port"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// recordCreditChange appends the ledger entry of a balance change made in tx, together with
+// the balance after it. It must run in the transaction that changed the wallet.
+func recordCreditChange(tx *gorm.DB, userID uuid.UUID, amount int, change port.CreditChange) error {
+	var balance uint
+	if err := tx.Model(&domain.Wallet{}).Where("user_id = ?", userID).Select("credits").Scan(&balance).Error; err != nil {
+		return fmt.Errorf("failed to read balance for credit ledger: %w", err)
+	}
+
+	now := time.Now()
+	entry := domain.CreditTransaction{
+		BaseModel: domain.BaseModel{ID: uuid.New(), CreatedAt: now, UpdatedAt: now},
+		UserID:    userID,
+		Amount:    amount,
+		Balance:   balance,
+		Kind:      change.Kind,
+		Reference: change.Reference,
+		Reason:    change.Reason,
+		ActorID:   change.ActorID,
+	}
+	if err := tx.Create(&entry).Error; err != nil {
+		return fmt.Errorf("failed to write credit ledger: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/CP-Payne/wonderpicai/internal/port"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type gormCreditPackageRepository struct {
+	db     *gorm.DB
+	logger *zap.Logger
+}
+
+func NewGormCreditPackageRepository(db *gorm.DB, logger *zap.Logger) port.CreditPackageRepository {
+	return &gormCreditPackageRepository{db: db, logger: logger.With(zap.String("component", "CreditPackageRepoGORM"))}
+}
+
+func (r *gormCreditPackageRepository) ListActive(ctx context.Context) ([]domain.CreditPackage, error) {
+	var pkgs []domain.CreditPackage
+
+	err := r.db.WithContext(ctx).Preload("Prices").Where("active = ?", true).Order("sort_order, price_minor").Find(&pkgs).Error
+	if err != nil {
+		r.logger.Error("Failed to list active credit packages", zap.Error(err))
+		return nil, fmt.Errorf("database error listing active credit packages: %w", err)
+	}
+
+	return pkgs, nil
+}
+
+func (r *gormCreditPackageRepository) ListAll(ctx context.Context) ([]domain.CreditPackage, error) {
+	var pkgs []domain.CreditPackage
+
+	err := r.db.WithContext(ctx).Preload("Prices").Order("sort_order, price_minor").Find(&pkgs).Error
+	if err != nil {
+		r.logger.Error("Failed to list credit packages", zap.Error(err))
+		return nil, fmt.Errorf("database error listing credit packages: %w", err)
+	}
+
+	return pkgs, nil
+}
+
+func (r *gormCreditPackageRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.CreditPackage, error) {
+	var pkg domain.CreditPackage
+
+	err := r.db.WithContext(ctx).Unscoped().Preload("Prices").Where("id = ?", id).First(&pkg).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrRecordNotFound
+		}
+		r.logger.Error("Failed to get credit package", zap.String("packageID", id.String()), zap.Error(err))
+		return nil, fmt.Errorf("database error fetching credit package: %w", err)
+	}
+
+	return &pkg, nil
+}
+
+func (r *gormCreditPackageRepository) Create(ctx context.Context, pkg *domain.CreditPackage) error {
+	if err := r.db.WithContext(ctx).Create(pkg).Error; err != nil {
+		r.logger.Error("Failed to create credit package", zap.Error(err))
+		return fmt.Errorf("database error creating credit package: %w", err)
+	}
+	return nil
+}
+
+func (r *gormCreditPackageRepository) Update(ctx context.Context, pkg *domain.CreditPackage) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Select("*") so that false and zero values (e.g. deactivating a package) are written too
+		result := tx.Model(pkg).Select("*").Omit("id", "created_at", "deleted_at", "Prices").Updates(pkg)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return domain.ErrRecordNotFound
+		}
+
+		// The prices are replaced as a whole, removed currencies are deleted for good so that
+		// they can be added again later without violating the unique index
+		if err := tx.Unscoped().Where("package_id = ?", pkg.ID).Delete(&domain.CreditPackagePrice{}).Error; err != nil {
+			return err
+		}
+		if len(pkg.Prices) > 0 {
+			if err := tx.Create(&pkg.Prices).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrRecordNotFound) {
+			return err
+		}
+		r.logger.Error("Failed to update credit package", zap.String("packageID", pkg.ID.String()), zap.Error(err))
+		return fmt.Errorf("database error updating credit package: %w", err)
+	}
+	return nil
+}
+
+func (r *gormCreditPackageRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result := r.db.WithContext(ctx).Delete(&domain.CreditPackage{}, "id = ?", id)
+	if result.Error != nil {
+		r.logger.Error("Failed to delete credit package", zap.String("packageID", id.String()), zap.Error(result.Error))
+		return fmt.Errorf("database error deleting credit package: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *gormCreditPackageRepository) CreateIfEmpty(ctx context.Context, pkgs []domain.CreditPackage) (bool, error) {
+	created := false
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		// Deleted packages count as well, an admin removing every package should not bring the defaults back
+		if err := tx.Unscoped().Model(&domain.CreditPackage{}).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+
+		if err := tx.Create(&pkgs).Error; err != nil {
+			return err
+		}
+		created = true
+		return nil
+	})
+	if err != nil {
+		r.logger.Error("Failed to create default credit packages", zap.Error(err))
+		return false, fmt.Errorf("database error creating default credit packages: %w", err)
+	}
+
+	return created, nil
+}
@@ -0,0 +1,516 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/CP-Payne/wonderpicai/internal/port"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type gormGenerationJobRepository struct {
+	db     *gorm.DB
+	logger *zap.Logger
+}
+
+func NewGormGenerationJobRepository(db *gorm.DB, logger *zap.Logger) port.GenerationJobRepository {
+	return &gormGenerationJobRepository{db: db, logger: logger.With(zap.String("component", "GenerationJobRepoGORM"))}
+}
+
+func (r *gormGenerationJobRepository) EnqueuePrompt(ctx context.Context, prompt *domain.Prompt, maxAttempts int, quota domain.GenerationQuota) (*domain.Prompt, error) {
+
+	var images []domain.Image
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+
+		// Locking the wallet serializes concurrent requests of the same user, so the quota
+		// check below cannot be raced past.
+		var wallet domain.Wallet
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", prompt.UserID).First(&wallet).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return domain.ErrInsufficientFunds
+			}
+			return fmt.Errorf("db error locking wallet: %w", err)
+		}
+
+		if err := checkQuota(tx, prompt, quota); err != nil {
+			return err
+		}
+
+		result := tx.Model(&domain.Wallet{}).
+			Where("user_id = ? AND credits >= ?", prompt.UserID, prompt.Cost).
+			Update("credits", gorm.Expr("credits - ?", prompt.Cost))
+		if result.Error != nil {
+			return fmt.Errorf("db error subtracting credits: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return domain.ErrInsufficientFunds
+		}
+
+		if err := tx.Create(prompt).Error; err != nil {
+			return err
+		}
+		if err := recordCreditChange(tx, prompt.UserID, -prompt.Cost, port.CreditChange{
+			Kind:      domain.CreditGeneration,
+			Reference: prompt.ID.String(),
+		}); err != nil {
+			return err
+		}
+
+		images = make([]domain.Image, prompt.ImageCount)
+		for i := 0; i < prompt.ImageCount; i++ {
+			images[i] = domain.Image{
+				BaseModel: domain.BaseModel{
+					ID:        uuid.New(),
+					CreatedAt: time.Now(),
+					UpdatedAt: time.Now(),
+				},
+				PromptID:  prompt.ID,
+				ImageData: nil,
+				Status:    domain.Pending,
+			}
+		}
+		if err := tx.Create(&images).Error; err != nil {
+			return err
+		}
+
+		job := domain.GenerationJob{
+			BaseModel: domain.BaseModel{
+				ID:        uuid.New(),
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+			},
+			PromptID:    prompt.ID,
+			UserID:      prompt.UserID,
+			Status:      domain.JobQueued,
+			MaxAttempts: maxAttempts,
+			RunAt:       time.Now(),
+		}
+		if err := tx.Create(&job).Error; err != nil {
+			return err
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		if errors.Is(err, domain.ErrInsufficientFunds) {
+			r.logger.Warn("Failed to enqueue prompt: insufficient funds or user not found",
+				zap.String("userID", prompt.UserID.String()),
+				zap.Int("cost", prompt.Cost))
+			return nil, err
+		}
+		if errors.Is(err, domain.ErrGenerationLimitReached) {
+			r.logger.Info("Failed to enqueue prompt: generation limit reached",
+				zap.String("userID", prompt.UserID.String()),
+				zap.Error(err))
+			return nil, err
+		}
+		r.logger.Error("Failed to enqueue prompt", zap.String("userID", prompt.UserID.String()), zap.Error(err))
+		return nil, fmt.Errorf("failed to enqueue prompt in database: %w", err)
+	}
+
+	prompt.Images = images
+
+	return prompt, nil
+}
+
+// checkQuota fails with domain.ErrGenerationLimitReached when adding the prompt would exceed
+// the user's limits. Prompts count as in progress until the backend reports back, or until the
+// generation worker fails them once they waited longer than the result timeout.
+func checkQuota(tx *gorm.DB, prompt *domain.Prompt, quota domain.GenerationQuota) error {
+	if quota.MaxPendingPrompts <= 0 && quota.MaxQueuedImages <= 0 {
+		return nil
+	}
+
+	var usage struct {
+		Prompts int
+		Images  int
+	}
+	err := tx.Model(&domain.Prompt{}).
+		Select("COUNT(*) AS prompts, COALESCE(SUM(image_count), 0) AS images").
+		Where("user_id = ? AND status = ?", prompt.UserID, domain.Pending).
+		Scan(&usage).Error
+	if err != nil {
+		return fmt.Errorf("db error counting pending prompts: %w", err)
+	}
+
+	if quota.MaxPendingPrompts > 0 && usage.Prompts+1 > quota.MaxPendingPrompts {
+		return fmt.Errorf("%d prompts already in progress (limit %d): %w", usage.Prompts, quota.MaxPendingPrompts, domain.ErrGenerationLimitReached)
+	}
+	if quota.MaxQueuedImages > 0 && usage.Images+prompt.ImageCount > quota.MaxQueuedImages {
+		return fmt.Errorf("%d images already in progress (limit %d): %w", usage.Images, quota.MaxQueuedImages, domain.ErrGenerationLimitReached)
+	}
+
+	return nil
+}
+
+func (r *gormGenerationJobRepository) ClaimNext(ctx context.Context, limit int, lease time.Duration) ([]domain.GenerationJob, error) {
+	var claimed []domain.GenerationJob
+	now := time.Now()
+
+	// Running jobs whose lease expired belong to a worker that died, they are claimed again.
+	// Jobs are ordered by their turn within the user's own queue first, which hands out work
+	// round-robin across users. SKIP LOCKED lets several workers (and app instances) claim
+	// concurrently without blocking.
+	//
+	// The claimable condition is repeated on j: after locking a row Postgres re-checks only the
+	// conditions on the locked table, and the ranked table may still show a job that another
+	// worker claimed and committed in the meantime.
+	err := r.db.WithContext(ctx).Raw(`
+		UPDATE generation_jobs
+		SET status = ?, attempts = attempts + 1, locked_until = ?, updated_at = ?
+		WHERE id IN (
+			SELECT j.id FROM generation_jobs j
+			JOIN (
+				SELECT id, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY run_at, created_at) AS turn
+				FROM generation_jobs
+				WHERE deleted_at IS NULL
+				  AND ((status = ? AND run_at <= ?) OR (status = ? AND locked_until < ?))
+			) ranked ON ranked.id = j.id
+			WHERE j.deleted_at IS NULL
+			  AND ((j.status = ? AND j.run_at <= ?) OR (j.status = ? AND j.locked_until < ?))
+			ORDER BY ranked.turn, j.run_at
+			LIMIT ?
+			FOR UPDATE OF j SKIP LOCKED
+		)
+		RETURNING id`,
+		domain.JobRunning, now.Add(lease), now,
+		domain.JobQueued, now, domain.JobRunning, now,
+		domain.JobQueued, now, domain.JobRunning, now,
+		limit,
+	).Scan(&claimed).Error
+	if err != nil {
+		r.logger.Error("Failed to claim generation jobs", zap.Error(err))
+		return nil, fmt.Errorf("database error claiming generation jobs: %w", err)
+	}
+
+	if len(claimed) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uuid.UUID, len(claimed))
+	for i, job := range claimed {
+		ids[i] = job.ID
+	}
+
+	var jobs []domain.GenerationJob
+	if err := r.db.WithContext(ctx).Preload("Prompt").Where("id IN ?", ids).Order("run_at").Find(&jobs).Error; err != nil {
+		r.logger.Error("Failed to load claimed generation jobs", zap.Error(err))
+		return nil, fmt.Errorf("database error loading claimed generation jobs: %w", err)
+	}
+
+	return jobs, nil
+}
+
+func (r *gormGenerationJobRepository) QueuePosition(ctx context.Context, userID uuid.UUID) (int, error) {
+	var position int
+
+	// Uses the same round-robin order as ClaimNext
+	err := r.db.WithContext(ctx).Raw(`
+		SELECT COALESCE(MIN(position), 0) FROM (
+			SELECT user_id, ROW_NUMBER() OVER (ORDER BY turn, run_at) AS position
+			FROM (
+				SELECT user_id, run_at, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY run_at, created_at) AS turn
+				FROM generation_jobs
+				WHERE deleted_at IS NULL AND status = ?
+			) turns
+		) queue
+		WHERE user_id = ?`,
+		domain.JobQueued, userID,
+	).Scan(&position).Error
+	if err != nil {
+		r.logger.Error("Failed to determine queue position", zap.String("userID", userID.String()), zap.Error(err))
+		return 0, fmt.Errorf("database error determining queue position: %w", err)
+	}
+
+	return position, nil
+}
+
+func (r *gormGenerationJobRepository) QueueDepth(ctx context.Context) (map[domain.JobStatus]int64, error) {
+	var rows []struct {
+		Status domain.JobStatus
+		Count  int64
+	}
+
+	err := r.db.WithContext(ctx).
+		Model(&domain.GenerationJob{}).
+		Select("generation_jobs.status, COUNT(*) AS count").
+		Joins("JOIN prompts ON prompts.id = generation_jobs.prompt_id AND prompts.deleted_at IS NULL").
+		Where("prompts.status = ? AND generation_jobs.status <> ?", domain.Pending, domain.JobDead).
+		Group("generation_jobs.status").
+		Scan(&rows).Error
+	if err != nil {
+		r.logger.Error("Failed to count queued generation jobs", zap.Error(err))
+		return nil, fmt.Errorf("database error counting queued generation jobs: %w", err)
+	}
+
+	depth := make(map[domain.JobStatus]int64, len(rows))
+	for _, row := range rows {
+		depth[row.Status] = row.Count
+	}
+	return depth, nil
+}
+
+func (r *gormGenerationJobRepository) MarkSubmitted(ctx context.Context, jobID uuid.UUID, externalPromptID uuid.UUID, backend string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var job domain.GenerationJob
+		if err := tx.First(&job, "id = ?", jobID).Error; err != nil {
+			return fmt.Errorf("failed to find generation job: %w", err)
+		}
+
+		if err := tx.Model(&domain.Prompt{}).Where("id = ?", job.PromptID).Updates(map[string]any{
+			"external_prompt_id": externalPromptID,
+			"backend":            backend,
+			"updated_at":         time.Now(),
+		}).Error; err != nil {
+			r.logger.Error("Failed to store external prompt ID", zap.String("jobID", jobID.String()), zap.Error(err))
+			return fmt.Errorf("failed to store external prompt id: %w", err)
+		}
+
+		if err := tx.Model(&job).Updates(map[string]any{
+			"status":       domain.JobSubmitted,
+			"locked_until": nil,
+			"last_error":   "",
+		}).Error; err != nil {
+			r.logger.Error("Failed to mark generation job as submitted", zap.String("jobID", jobID.String()), zap.Error(err))
+			return fmt.Errorf("failed to mark generation job as submitted: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func (r *gormGenerationJobRepository) ExtendLease(ctx context.Context, jobID uuid.UUID, lease time.Duration) error {
+	err := r.db.WithContext(ctx).Model(&domain.GenerationJob{}).
+		Where("id = ? AND status = ?", jobID, domain.JobRunning).
+		Update("locked_until", time.Now().Add(lease)).Error
+	if err != nil {
+		r.logger.Error("Failed to extend generation job lease", zap.String("jobID", jobID.String()), zap.Error(err))
+		return fmt.Errorf("failed to extend generation job lease: %w", err)
+	}
+	return nil
+}
+
+func (r *gormGenerationJobRepository) Reschedule(ctx context.Context, jobID uuid.UUID, runAt time.Time, lastErr string) error {
+	err := r.db.WithContext(ctx).Model(&domain.GenerationJob{}).Where("id = ?", jobID).Updates(map[string]any{
+		"status":       domain.JobQueued,
+		"run_at":       runAt,
+		"locked_until": nil,
+		"last_error":   lastErr,
+	}).Error
+	if err != nil {
+		r.logger.Error("Failed to reschedule generation job", zap.String("jobID", jobID.String()), zap.Error(err))
+		return fmt.Errorf("failed to reschedule generation job: %w", err)
+	}
+	return nil
+}
+
+func (r *gormGenerationJobRepository) StorePartialImages(ctx context.Context, jobID uuid.UUID, images [][]byte) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var job domain.GenerationJob
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&job, "id = ?", jobID).Error; err != nil {
+			return fmt.Errorf("failed to find generation job: %w", err)
+		}
+
+		// Locked against the prompt being failed at the same time
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&job.Prompt, "id = ?", job.PromptID).Error; err != nil {
+			return fmt.Errorf("failed to find prompt: %w", err)
+		}
+		if job.Status == domain.JobDead || job.Prompt.Status != domain.Pending {
+			return domain.ErrPromptAlreadyFinished
+		}
+
+		var placeholderImages []domain.Image
+		if err := tx.Where("prompt_id = ? AND status = ?", job.PromptID, domain.Pending).
+			Order("created_at, id").
+			Limit(len(images)).
+			Find(&placeholderImages).Error; err != nil {
+			return fmt.Errorf("failed to find placeholder images: %w", err)
+		}
+		if len(placeholderImages) != len(images) {
+			return fmt.Errorf("mismatch: %d placeholders left, but %d images were provided", len(placeholderImages), len(images))
+		}
+
+		for i, imageData := range images {
+			placeholderImages[i].ImageData = imageData
+			placeholderImages[i].Status = domain.Completed
+			placeholderImages[i].UpdatedAt = time.Now()
+		}
+		if err := tx.Save(&placeholderImages).Error; err != nil {
+			return fmt.Errorf("failed to save images: %w", err)
+		}
+
+		if err := tx.Model(&job).Update("generated", gorm.Expr("generated + ?", len(images))).Error; err != nil {
+			return fmt.Errorf("failed to count generated images: %w", err)
+		}
+
+		return nil
+	})
+
+	if errors.Is(err, domain.ErrPromptAlreadyFinished) {
+		return err
+	}
+	if err != nil {
+		r.logger.Error("Failed to store partial generation result", zap.String("jobID", jobID.String()), zap.Error(err))
+		return fmt.Errorf("database transaction failed: %w", err)
+	}
+
+	return nil
+}
+
+func (r *gormGenerationJobRepository) MarkDead(ctx context.Context, jobID uuid.UUID, lastErr string) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var job domain.GenerationJob
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&job, "id = ?", jobID).Error; err != nil {
+			return fmt.Errorf("failed to find generation job: %w", err)
+		}
+
+		// The prompt is locked as well, so a result arriving at the same time is either stored
+		// before the prompt is failed or refused afterwards
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&job.Prompt, "id = ?", job.PromptID).Error; err != nil {
+			return fmt.Errorf("failed to find prompt: %w", err)
+		}
+
+		if job.Status == domain.JobDead || job.Prompt.Status != domain.Pending {
+			// Already refunded, or the result arrived in the meantime
+			return domain.ErrPromptAlreadyFinished
+		}
+
+		if err := tx.Model(&job).Updates(map[string]any{
+			"status":       domain.JobDead,
+			"locked_until": nil,
+			"last_error":   lastErr,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to mark generation job as dead: %w", err)
+		}
+
+		if err := tx.Model(&domain.Prompt{}).Where("id = ?", job.PromptID).Update("status", domain.Failed).Error; err != nil {
+			return fmt.Errorf("failed to mark prompt as failed: %w", err)
+		}
+
+		if err := tx.Model(&domain.Image{}).Where("prompt_id = ?", job.PromptID).Update("status", domain.Failed).Error; err != nil {
+			return fmt.Errorf("failed to mark images as failed: %w", err)
+		}
+
+		result := tx.Model(&domain.Wallet{}).
+			Where("user_id = ?", job.UserID).
+			Update("credits", gorm.Expr("credits + ?", job.Prompt.Cost))
+		if result.Error != nil {
+			return fmt.Errorf("failed to refund credits: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("failed to refund credits: %w", domain.ErrRecordNotFound)
+		}
+		if err := recordCreditChange(tx, job.UserID, job.Prompt.Cost, port.CreditChange{
+			Kind:      domain.CreditRefund,
+			Reference: job.PromptID.String(),
+			Reason:    "generation failed",
+		}); err != nil {
+			return err
+		}
+
+		return nil
+	})
+
+	if errors.Is(err, domain.ErrPromptAlreadyFinished) {
+		return err
+	}
+	if err != nil {
+		r.logger.Error("CRITICAL: Failed to dead-letter generation job", zap.String("jobID", jobID.String()), zap.Error(err))
+		return fmt.Errorf("database transaction failed: %w", err)
+	}
+
+	return nil
+}
+
+func (r *gormGenerationJobRepository) ListExpiredSubmissions(ctx context.Context, submittedBefore time.Time, limit int) ([]domain.GenerationJob, error) {
+	var jobs []domain.GenerationJob
+
+	// Submitting is the last change to a job, so updated_at is the submission time
+	err := r.db.WithContext(ctx).
+		Preload("Prompt").
+		Joins("JOIN prompts ON prompts.id = generation_jobs.prompt_id AND prompts.deleted_at IS NULL").
+		Where("prompts.status = ? AND generation_jobs.status = ? AND generation_jobs.updated_at < ?", domain.Pending, domain.JobSubmitted, submittedBefore).
+		Order("generation_jobs.updated_at").
+		Limit(limit).
+		Find(&jobs).Error
+	if err != nil {
+		r.logger.Error("Failed to list expired generation submissions", zap.Error(err))
+		return nil, fmt.Errorf("database error listing expired generation submissions: %w", err)
+	}
+
+	return jobs, nil
+}
+
+func (r *gormGenerationJobRepository) ListStuck(ctx context.Context, before time.Time, limit int) ([]domain.GenerationJob, error) {
+	var jobs []domain.GenerationJob
+
+	err := r.db.WithContext(ctx).
+		Preload("Prompt").
+		Joins("JOIN prompts ON prompts.id = generation_jobs.prompt_id AND prompts.deleted_at IS NULL").
+		Where("prompts.status = ? AND generation_jobs.status <> ? AND generation_jobs.updated_at < ?", domain.Pending, domain.JobDead, before).
+		Order("generation_jobs.updated_at").
+		Limit(limit).
+		Find(&jobs).Error
+	if err != nil {
+		r.logger.Error("Failed to list stuck generation jobs", zap.Error(err))
+		return nil, fmt.Errorf("database error listing stuck generation jobs: %w", err)
+	}
+
+	return jobs, nil
+}
+
+func (r *gormGenerationJobRepository) Requeue(ctx context.Context, promptID uuid.UUID, reason string) (*domain.GenerationJob, error) {
+	var job domain.GenerationJob
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Prompt").Where("prompt_id = ?", promptID).First(&job).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return domain.ErrPromptNotFound
+			}
+			return fmt.Errorf("failed to load generation job: %w", err)
+		}
+
+		// Failed prompts have been refunded and completed ones delivered
+		if job.Status == domain.JobDead || job.Prompt.Status != domain.Pending {
+			return domain.ErrPromptNotRetryable
+		}
+
+		// Clearing the external ID drops a late result of the earlier submission
+		if err := tx.Model(&domain.Prompt{}).Where("id = ?", promptID).Updates(map[string]any{
+			"external_prompt_id": nil,
+			"backend":            "",
+			"updated_at":         time.Now(),
+		}).Error; err != nil {
+			return fmt.Errorf("failed to reset prompt: %w", err)
+		}
+
+		if err := tx.Model(&job).Updates(map[string]any{
+			"status":       domain.JobQueued,
+			"attempts":     0,
+			"run_at":       time.Now(),
+			"locked_until": nil,
+			"last_error":   reason,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to requeue generation job: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrPromptNotFound) || errors.Is(err, domain.ErrPromptNotRetryable) {
+			return nil, err
+		}
+		r.logger.Error("Failed to requeue generation job", zap.String("promptID", promptID.String()), zap.Error(err))
+		return nil, fmt.Errorf("database transaction failed: %w", err)
+	}
+
+	return &job, nil
+}
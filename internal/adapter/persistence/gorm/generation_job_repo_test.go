@@ -0,0 +1,171 @@
+package gorm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// createQueuedJobs adds a user with count queued jobs and removes them when the test ends
+func createQueuedJobs(t *testing.T, db *gorm.DB, count int) []uuid.UUID {
+	t.Helper()
+
+	user := domain.User{
+		BaseModel: domain.BaseModel{ID: uuid.New()},
+		Username:  "claim-test",
+		Email:     uuid.NewString() + "@example.com",
+		Password:  "x",
+	}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Unscoped().Where("user_id = ?", user.ID).Delete(&domain.GenerationJob{})
+		db.Unscoped().Where("user_id = ?", user.ID).Delete(&domain.Prompt{})
+		db.Unscoped().Delete(&user)
+	})
+
+	ids := make([]uuid.UUID, count)
+	for i := range ids {
+		prompt := domain.Prompt{
+			BaseModel:  domain.BaseModel{ID: uuid.New()},
+			UserID:     user.ID,
+			Text:       fmt.Sprintf("prompt %d", i),
+			Cost:       1,
+			ImageCount: 1,
+			Status:     domain.Pending,
+		}
+		job := domain.GenerationJob{
+			BaseModel:   domain.BaseModel{ID: uuid.New()},
+			PromptID:    prompt.ID,
+			UserID:      user.ID,
+			Status:      domain.JobQueued,
+			MaxAttempts: 3,
+			RunAt:       time.Now().Add(-time.Minute),
+		}
+		if err := db.Create(&prompt).Error; err != nil {
+			t.Fatalf("failed to create prompt: %v", err)
+		}
+		if err := db.Create(&job).Error; err != nil {
+			t.Fatalf("failed to create generation job: %v", err)
+		}
+		ids[i] = job.ID
+	}
+	return ids
+}
+
+// TestClaimNextConcurrently runs several workers against the same queue. Every job has to be
+// handed to exactly one of them, a job claimed twice would be generated and paid for twice.
+func TestClaimNextConcurrently(t *testing.T) {
+	db := testDB(t)
+	repo := NewGormGenerationJobRepository(db, zap.NewNop())
+
+	const users, jobsPerUser, workers = 4, 50, 8
+	var ids []uuid.UUID
+	for range users {
+		ids = append(ids, createQueuedJobs(t, db, jobsPerUser)...)
+	}
+
+	var mu sync.Mutex
+	claims := make(map[uuid.UUID]int)
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				jobs, err := repo.ClaimNext(context.Background(), 2, time.Minute)
+				if err != nil {
+					t.Errorf("ClaimNext() error = %v", err)
+					return
+				}
+				if len(jobs) == 0 {
+					return
+				}
+				mu.Lock()
+				for _, job := range jobs {
+					claims[job.ID]++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, id := range ids {
+		if claims[id] != 1 {
+			t.Errorf("job %s was claimed %d times, want once", id, claims[id])
+		}
+	}
+
+	var repeated int64
+	if err := db.Model(&domain.GenerationJob{}).Where("id IN ? AND attempts <> 1", ids).Count(&repeated).Error; err != nil {
+		t.Fatalf("failed to count attempts: %v", err)
+	}
+	if repeated > 0 {
+		t.Errorf("%d jobs were claimed more than once", repeated)
+	}
+}
+
+// TestStorePartialImages keeps the images of a partly failed attempt, so that the retry only
+// delivers the rest and the prompt completes with all of them
+func TestStorePartialImages(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	jobRepo := NewGormGenerationJobRepository(db, zap.NewNop())
+	promptRepo := NewGormPromptRepository(db, zap.NewNop())
+
+	jobID := createQueuedJobs(t, db, 1)[0]
+	var job domain.GenerationJob
+	if err := db.Preload("Prompt").First(&job, "id = ?", jobID).Error; err != nil {
+		t.Fatalf("failed to load generation job: %v", err)
+	}
+	if err := db.Model(&job.Prompt).Update("image_count", 3).Error; err != nil {
+		t.Fatalf("failed to update prompt: %v", err)
+	}
+	t.Cleanup(func() { db.Unscoped().Where("prompt_id = ?", job.PromptID).Delete(&domain.Image{}) })
+	for range 3 {
+		image := domain.Image{BaseModel: domain.BaseModel{ID: uuid.New()}, PromptID: job.PromptID, Status: domain.Pending}
+		if err := db.Create(&image).Error; err != nil {
+			t.Fatalf("failed to create placeholder image: %v", err)
+		}
+	}
+
+	if err := jobRepo.StorePartialImages(ctx, jobID, [][]byte{[]byte("first")}); err != nil {
+		t.Fatalf("StorePartialImages() error = %v", err)
+	}
+	if err := db.First(&job, "id = ?", jobID).Error; err != nil {
+		t.Fatalf("failed to load generation job: %v", err)
+	}
+	if job.Generated != 1 {
+		t.Errorf("job generated = %d, want 1", job.Generated)
+	}
+
+	externalID := uuid.New()
+	if err := jobRepo.MarkSubmitted(ctx, jobID, externalID, ""); err != nil {
+		t.Fatalf("MarkSubmitted() error = %v", err)
+	}
+	prompt, err := promptRepo.UpdatePlaceholderImages(ctx, externalID, "", [][]byte{[]byte("second"), []byte("third")}, domain.Completed)
+	if err != nil {
+		t.Fatalf("UpdatePlaceholderImages() error = %v", err)
+	}
+	if prompt.Status != domain.Completed {
+		t.Errorf("prompt status = %s, want %s", prompt.Status, domain.Completed)
+	}
+	if len(prompt.Images) != 3 {
+		t.Fatalf("prompt has %d images, want 3", len(prompt.Images))
+	}
+	for _, image := range prompt.Images {
+		if image.Status != domain.Completed || len(image.ImageData) == 0 {
+			t.Errorf("image %s status = %s with %d bytes, want completed with data", image.ID, image.Status, len(image.ImageData))
+		}
+	}
+}
@@ -35,6 +35,10 @@ func ConnectDatabase(dsn, appEnv, logLevel string, appLogger *zap.Logger) {
 
 	appLogger.Info("Database connection established.")
 
+	if err := registerTracing(DB); err != nil {
+		appLogger.Fatal("Failed to register database tracing", zap.Error(err))
+	}
+
 	if appEnv == "production<TODO:REMOVE>" {
 
 		err = DB.Migrator().DropTable(&domain.User{})
@@ -76,6 +80,66 @@ func ConnectDatabase(dsn, appEnv, logLevel string, appLogger *zap.Logger) {
 		appLogger.Fatal("Failed to auto-migrate database schema", zap.Error(err))
 	}
 
+	err = DB.AutoMigrate(&domain.GenerationJob{})
+	if err != nil {
+		appLogger.Fatal("Failed to auto-migrate database schema", zap.Error(err))
+	}
+
+	err = DB.AutoMigrate(&domain.CreditPackage{})
+	if err != nil {
+		appLogger.Fatal("Failed to auto-migrate database schema", zap.Error(err))
+	}
+
+	err = DB.AutoMigrate(&domain.CreditPackagePrice{})
+	if err != nil {
+		appLogger.Fatal("Failed to auto-migrate database schema", zap.Error(err))
+	}
+
+	err = DB.AutoMigrate(&domain.Payment{})
+	if err != nil {
+		appLogger.Fatal("Failed to auto-migrate database schema", zap.Error(err))
+	}
+
+	err = DB.AutoMigrate(&domain.PromoCode{})
+	if err != nil {
+		appLogger.Fatal("Failed to auto-migrate database schema", zap.Error(err))
+	}
+
+	err = DB.AutoMigrate(&domain.PromoRedemption{})
+	if err != nil {
+		appLogger.Fatal("Failed to auto-migrate database schema", zap.Error(err))
+	}
+
+	err = DB.AutoMigrate(&domain.SubscriptionPlan{}, &domain.Subscription{}, &domain.SubscriptionGrant{})
+	if err != nil {
+		appLogger.Fatal("Failed to auto-migrate database schema", zap.Error(err))
+	}
+
+	err = DB.AutoMigrate(&domain.Counter{})
+	if err != nil {
+		appLogger.Fatal("Failed to auto-migrate database schema", zap.Error(err))
+	}
+
+	err = DB.AutoMigrate(&domain.APIKey{})
+	if err != nil {
+		appLogger.Fatal("Failed to auto-migrate database schema", zap.Error(err))
+	}
+
+	err = DB.AutoMigrate(&domain.WebhookEndpoint{}, &domain.WebhookDelivery{})
+	if err != nil {
+		appLogger.Fatal("Failed to auto-migrate database schema", zap.Error(err))
+	}
+
+	err = DB.AutoMigrate(&domain.CreditTransaction{}, &domain.AuditEvent{})
+	if err != nil {
+		appLogger.Fatal("Failed to auto-migrate database schema", zap.Error(err))
+	}
+
+	err = protectAuditEvents(DB)
+	if err != nil {
+		appLogger.Fatal("Failed to auto-migrate database schema", zap.Error(err))
+	}
+
 	appLogger.Info("Database schema migrated")
 }
 
@@ -0,0 +1,32 @@
+package gorm
+
+import (
+	"os"
+	"testing"
+
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// testDB connects to the database in TEST_DATABASE_DSN. The tests change rows they did not
+// create, so it has to be a database used for nothing else.
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: gormlogger.Default.LogMode(gormlogger.Silent)})
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&domain.User{}, &domain.Wallet{}, &domain.Prompt{}, &domain.Image{}, &domain.GenerationJob{}, &domain.CreditTransaction{},
+		&domain.Payment{}, &domain.SubscriptionPlan{}, &domain.Subscription{}, &domain.SubscriptionGrant{}); err != nil {
+		t.Fatalf("failed to migrate test database: %v", err)
+	}
+	return db
+}
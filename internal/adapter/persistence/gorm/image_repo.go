@@ -42,6 +42,31 @@ func (r *gormImageRepository) GetByID(ctx context.Context, userID uuid.UUID, ima
 	return &image, nil
 }
 
+func (r *gormImageRepository) ListByUser(ctx context.Context, userID uuid.UUID, page domain.Page) ([]domain.Image, int64, error) {
+	subQuery := r.db.Model(&domain.Prompt{}).Select("id").Where("user_id = ?", userID)
+
+	var total int64
+	if err := r.db.WithContext(ctx).Model(&domain.Image{}).Where("prompt_id IN (?)", subQuery).Count(&total).Error; err != nil {
+		r.logger.Error("Failed to count images for user", zap.String("userID", userID.String()), zap.Error(err))
+		return nil, 0, fmt.Errorf("database error counting images: %w", err)
+	}
+
+	var images []domain.Image
+	err := r.db.WithContext(ctx).
+		Omit("image_data").
+		Where("prompt_id IN (?)", subQuery).
+		Order("created_at desc, id").
+		Limit(page.Limit).
+		Offset(page.Offset).
+		Find(&images).Error
+	if err != nil {
+		r.logger.Error("Failed to list images for user", zap.String("userID", userID.String()), zap.Error(err))
+		return nil, 0, fmt.Errorf("database error listing images: %w", err)
+	}
+
+	return images, total, nil
+}
+
 func (r *gormImageRepository) Delete(ctx context.Context, userID uuid.UUID, imageID uuid.UUID) error {
 
 	subQuery := r.db.Model(&domain.Prompt{}).Select("id").Where("user_id = ?", userID)
@@ -0,0 +1,365 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/CP-Payne/wonderpicai/internal/port"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type gormPaymentRepository struct {
+	db     *gorm.DB
+	logger *zap.Logger
+}
+
+func NewGormPaymentRepository(db *gorm.DB, logger *zap.Logger) port.PaymentRepository {
+	return &gormPaymentRepository{db: db, logger: logger.With(zap.String("component", "PaymentRepoGORM"))}
+}
+
+func (r *gormPaymentRepository) Create(ctx context.Context, payment *domain.Payment) error {
+	if err := r.db.WithContext(ctx).Create(payment).Error; err != nil {
+		r.logger.Error("Failed to create payment", zap.String("userID", payment.UserID.String()), zap.Error(err))
+		return fmt.Errorf("database error creating payment: %w", err)
+	}
+	return nil
+}
+
+func (r *gormPaymentRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Payment, error) {
+	var payment domain.Payment
+
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&payment).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrRecordNotFound
+		}
+		r.logger.Error("Failed to get payment", zap.String("paymentID", id.String()), zap.Error(err))
+		return nil, fmt.Errorf("database error fetching payment: %w", err)
+	}
+
+	return &payment, nil
+}
+
+func (r *gormPaymentRepository) GetBySessionID(ctx context.Context, sessionID string) (*domain.Payment, error) {
+	var payment domain.Payment
+
+	err := r.db.WithContext(ctx).Where("provider_session_id = ?", sessionID).First(&payment).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrRecordNotFound
+		}
+		r.logger.Error("Failed to get payment by session", zap.String("sessionID", sessionID), zap.Error(err))
+		return nil, fmt.Errorf("database error fetching payment: %w", err)
+	}
+
+	return &payment, nil
+}
+
+func (r *gormPaymentRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]domain.Payment, error) {
+	var payments []domain.Payment
+
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND status NOT IN ?", userID, []domain.PaymentStatus{domain.PaymentPending, domain.PaymentFailed}).
+		Order("created_at desc").
+		Find(&payments).Error
+	if err != nil {
+		r.logger.Error("Failed to list payments", zap.String("userID", userID.String()), zap.Error(err))
+		return nil, fmt.Errorf("database error listing payments: %w", err)
+	}
+
+	return payments, nil
+}
+
+func (r *gormPaymentRepository) AttachSession(ctx context.Context, id uuid.UUID, sessionID string) error {
+	err := r.db.WithContext(ctx).Model(&domain.Payment{}).Where("id = ?", id).Update("provider_session_id", sessionID).Error
+	if err != nil {
+		r.logger.Error("Failed to attach checkout session to payment", zap.String("paymentID", id.String()), zap.Error(err))
+		return fmt.Errorf("database error attaching checkout session: %w", err)
+	}
+	return nil
+}
+
+func (r *gormPaymentRepository) MarkFailed(ctx context.Context, id uuid.UUID) error {
+	err := r.db.WithContext(ctx).Model(&domain.Payment{}).
+		Where("id = ? AND status IN ?", id, []domain.PaymentStatus{domain.PaymentPending, domain.PaymentProcessing}).
+		Update("status", domain.PaymentFailed).Error
+	if err != nil {
+		r.logger.Error("Failed to mark payment as failed", zap.String("paymentID", id.String()), zap.Error(err))
+		return fmt.Errorf("database error marking payment as failed: %w", err)
+	}
+	return nil
+}
+
+func (r *gormPaymentRepository) MarkProcessing(ctx context.Context, id uuid.UUID, providerPaymentID string) error {
+	err := r.db.WithContext(ctx).Model(&domain.Payment{}).
+		Where("id = ? AND status = ?", id, domain.PaymentPending).
+		Updates(map[string]any{
+			"status":              domain.PaymentProcessing,
+			"provider_payment_id": providerPaymentID,
+		}).Error
+	if err != nil {
+		r.logger.Error("Failed to mark payment as processing", zap.String("paymentID", id.String()), zap.Error(err))
+		return fmt.Errorf("database error marking payment as processing: %w", err)
+	}
+	return nil
+}
+
+func (r *gormPaymentRepository) Complete(ctx context.Context, id uuid.UUID, amountMinor int64, currency string, providerPaymentID string) (*domain.Payment, bool, error) {
+	var payment domain.Payment
+	completed := false
+	mismatch := false
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&payment).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return domain.ErrRecordNotFound
+			}
+			return fmt.Errorf("failed to load payment: %w", err)
+		}
+
+		// Repeated webhook deliveries are a no-op
+		switch payment.Status {
+		case domain.PaymentPending, domain.PaymentProcessing, domain.PaymentFailed:
+		default:
+			return nil
+		}
+
+		// The stored amount is what the checkout was created with, a different charge is held
+		// for review instead of adding credits that were not paid for
+		mismatch = amountMinor != payment.AmountMinor || !strings.EqualFold(currency, payment.Currency)
+		payment.Status = domain.PaymentCompleted
+		payment.CompletedAt = &now
+		if mismatch {
+			payment.Status = domain.PaymentAmountMismatch
+			payment.CompletedAt = nil
+		}
+		payment.ProviderPaymentID = providerPaymentID
+
+		err := tx.Model(&domain.Payment{}).Where("id = ?", payment.ID).Updates(map[string]any{
+			"status":              payment.Status,
+			"provider_payment_id": payment.ProviderPaymentID,
+			"completed_at":        payment.CompletedAt,
+			"updated_at":          now,
+		}).Error
+		if err != nil {
+			return fmt.Errorf("failed to mark payment as completed: %w", err)
+		}
+		if mismatch {
+			return nil
+		}
+
+		credit := tx.Model(&domain.Wallet{}).
+			Where("user_id = ?", payment.UserID).
+			Update("credits", gorm.Expr("credits + ?", payment.Credits))
+		if credit.Error != nil {
+			return fmt.Errorf("failed to add credits: %w", credit.Error)
+		}
+		if credit.RowsAffected == 0 {
+			return fmt.Errorf("failed to add credits: %w", domain.ErrRecordNotFound)
+		}
+		if err := recordCreditChange(tx, payment.UserID, payment.Credits, port.CreditChange{
+			Kind:      domain.CreditPurchase,
+			Reference: payment.ID.String(),
+			Reason:    payment.Description,
+		}); err != nil {
+			return err
+		}
+
+		// A discount reserved for the checkout now counts as used for good
+		err = tx.Model(&domain.PromoRedemption{}).
+			Where("payment_id = ? AND status = ?", id, domain.RedemptionReserved).
+			Updates(map[string]any{
+				"status":         domain.RedemptionRedeemed,
+				"reserved_until": nil,
+				"updated_at":     now,
+			}).Error
+		if err != nil {
+			return fmt.Errorf("failed to redeem promo code reservation: %w", err)
+		}
+
+		completed = true
+		return nil
+	})
+
+	if err != nil {
+		// Only a missing payment is reported as not found, a missing wallet is a real failure
+		if errors.Is(err, domain.ErrRecordNotFound) && payment.ID == uuid.Nil {
+			return nil, false, err
+		}
+		r.logger.Error("CRITICAL: Failed to complete payment", zap.String("paymentID", id.String()), zap.Error(err))
+		return nil, false, fmt.Errorf("database transaction failed: %w", err)
+	}
+	if mismatch {
+		return &payment, false, domain.ErrPaymentAmountMismatch
+	}
+
+	return &payment, completed, nil
+}
+
+func (r *gormPaymentRepository) Reverse(ctx context.Context, req port.PaymentReversalRequest) (*port.PaymentReversalResult, error) {
+	result := &port.PaymentReversalResult{}
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		// Locking the payment makes concurrent refund and dispute events take back credits once
+		var payment domain.Payment
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("provider_payment_id = ?", req.ProviderPaymentID).First(&payment).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return domain.ErrRecordNotFound
+			}
+			return fmt.Errorf("failed to load payment: %w", err)
+		}
+		result.Payment = &payment
+
+		owed := 0
+		// Payments that never completed have not added any credits
+		if payment.CompletedAt != nil {
+			owed = payment.CreditsToReverse(req.RefundedMinor, req.Disputed)
+		}
+
+		if owed > 0 {
+			var wallet domain.Wallet
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", payment.UserID).First(&wallet).Error; err != nil {
+				return fmt.Errorf("failed to load wallet: %w", err)
+			}
+
+			result.Reversed = min(owed, int(wallet.Credits))
+			result.Shortfall = owed - result.Reversed
+
+			err := tx.Model(&domain.Wallet{}).Where("id = ?", wallet.ID).
+				Update("credits", gorm.Expr("credits - ?", result.Reversed)).Error
+			if err != nil {
+				return fmt.Errorf("failed to take back credits: %w", err)
+			}
+
+			reason := "refund"
+			if req.Disputed {
+				reason = "dispute"
+			}
+			if err := recordCreditChange(tx, payment.UserID, -result.Reversed, port.CreditChange{
+				Kind:      domain.CreditPurchaseReversal,
+				Reference: payment.ID.String(),
+				Reason:    reason,
+			}); err != nil {
+				return err
+			}
+		}
+
+		if result.Shortfall > 0 {
+			err := tx.Model(&domain.User{}).Where("id = ?", payment.UserID).
+				Updates(map[string]any{
+					"flagged_at":  now,
+					"flag_reason": req.FlagReason,
+				}).Error
+			if err != nil {
+				return fmt.Errorf("failed to flag user: %w", err)
+			}
+		}
+
+		payment.ReversedCredits += owed
+		payment.RefundedMinor = max(payment.RefundedMinor, req.RefundedMinor)
+		switch {
+		case req.Disputed:
+			payment.Status = domain.PaymentDisputed
+		case payment.Status != domain.PaymentDisputed && payment.RefundedMinor >= payment.AmountMinor:
+			payment.Status = domain.PaymentRefunded
+		}
+
+		err = tx.Model(&domain.Payment{}).Where("id = ?", payment.ID).
+			Updates(map[string]any{
+				"status":           payment.Status,
+				"refunded_minor":   payment.RefundedMinor,
+				"reversed_credits": payment.ReversedCredits,
+				"updated_at":       now,
+			}).Error
+		if err != nil {
+			return fmt.Errorf("failed to update payment: %w", err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		if errors.Is(err, domain.ErrRecordNotFound) {
+			return nil, err
+		}
+		r.logger.Error("CRITICAL: Failed to reverse payment", zap.String("providerPaymentID", req.ProviderPaymentID), zap.Error(err))
+		return nil, fmt.Errorf("database transaction failed: %w", err)
+	}
+
+	return result, nil
+}
+
+func (r *gormPaymentRepository) AssignInvoice(ctx context.Context, id uuid.UUID, taxRateBps int) (*domain.Payment, error) {
+	var payment domain.Payment
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&payment).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return domain.ErrRecordNotFound
+			}
+			return fmt.Errorf("failed to load payment: %w", err)
+		}
+
+		if payment.InvoiceNumber != nil {
+			return nil
+		}
+		if payment.CompletedAt == nil {
+			return domain.ErrReceiptUnavailable
+		}
+
+		// The counter row lock is held until commit, so committed numbers are gapless and unique
+		err = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&domain.Counter{Name: domain.InvoiceCounter}).Error
+		if err != nil {
+			return fmt.Errorf("failed to create invoice counter: %w", err)
+		}
+		var counter domain.Counter
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("name = ?", domain.InvoiceCounter).First(&counter).Error
+		if err != nil {
+			return fmt.Errorf("failed to load invoice counter: %w", err)
+		}
+		counter.Value++
+		if err := tx.Model(&counter).Update("value", counter.Value).Error; err != nil {
+			return fmt.Errorf("failed to update invoice counter: %w", err)
+		}
+
+		now := time.Now()
+		payment.InvoiceNumber = &counter.Value
+		payment.TaxRateBps = taxRateBps
+		payment.InvoicedAt = &now
+
+		err = tx.Model(&domain.Payment{}).Where("id = ?", payment.ID).
+			Updates(map[string]any{
+				"invoice_number": counter.Value,
+				"tax_rate_bps":   taxRateBps,
+				"invoiced_at":    now,
+			}).Error
+		if err != nil {
+			return fmt.Errorf("failed to assign invoice number: %w", err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		if errors.Is(err, domain.ErrRecordNotFound) || errors.Is(err, domain.ErrReceiptUnavailable) {
+			return nil, err
+		}
+		r.logger.Error("Failed to assign invoice number", zap.String("paymentID", id.String()), zap.Error(err))
+		return nil, fmt.Errorf("database transaction failed: %w", err)
+	}
+
+	return &payment, nil
+}
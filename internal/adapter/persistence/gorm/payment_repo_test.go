@@ -0,0 +1,49 @@
+package gorm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/CP-Payne/wonderpicai/internal/port"
+	"go.uber.org/zap"
+)
+
+// TestReverseInvoicePayment refunds a subscription invoice, which has to take back the
+// credits it granted like a refunded credit pack
+func TestReverseInvoicePayment(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	paymentRepo := NewGormPaymentRepository(db, zap.NewNop())
+	walletRepo := NewGormWalletRepository(db, zap.NewNop())
+	f := newSubscriptionFixture(t, db, 0)
+
+	paymentIntentID := "pi_" + f.sub.ID.String()
+	f.grantPeriod("in_"+f.sub.ID.String(), paymentIntentID, time.Now())
+
+	result, err := paymentRepo.Reverse(ctx, port.PaymentReversalRequest{
+		ProviderPaymentID: paymentIntentID,
+		RefundedMinor:     f.plan.PriceMinor,
+	})
+	if err != nil {
+		t.Fatalf("Reverse() error = %v", err)
+	}
+	if result.Reversed != f.plan.CreditsPerPeriod || result.Shortfall != 0 {
+		t.Errorf("Reverse() reversed %d credits with a shortfall of %d, want %d and none", result.Reversed, result.Shortfall, f.plan.CreditsPerPeriod)
+	}
+	if result.Payment.SubscriptionID == nil || *result.Payment.SubscriptionID != f.sub.ID {
+		t.Errorf("Reverse() found payment of subscription %v, want %s", result.Payment.SubscriptionID, f.sub.ID)
+	}
+	if result.Payment.Status != domain.PaymentRefunded {
+		t.Errorf("payment status = %s, want %s", result.Payment.Status, domain.PaymentRefunded)
+	}
+
+	wallet, err := walletRepo.GetByUserID(ctx, f.user.ID)
+	if err != nil {
+		t.Fatalf("GetByUserID() error = %v", err)
+	}
+	if wallet.Credits != 0 {
+		t.Errorf("wallet has %d credits after the refund, want 0", wallet.Credits)
+	}
+}
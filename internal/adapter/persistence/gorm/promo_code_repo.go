@@ -0,0 +1,197 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/CP-Payne/wonderpicai/internal/port"
+	"github.com/google/uuid"
+	"github.com/jackc/pgconn"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type gormPromoCodeRepository struct {
+	db     *gorm.DB
+	logger *zap.Logger
+}
+
+func NewGormPromoCodeRepository(db *gorm.DB, logger *zap.Logger) port.PromoCodeRepository {
+	return &gormPromoCodeRepository{db: db, logger: logger.With(zap.String("component", "PromoCodeRepoGORM"))}
+}
+
+func (r *gormPromoCodeRepository) GetByCode(ctx context.Context, code string) (*domain.PromoCode, error) {
+	var promo domain.PromoCode
+
+	err := r.db.WithContext(ctx).Where("code = ?", strings.ToUpper(code)).First(&promo).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrPromoCodeInvalid
+		}
+		r.logger.Error("Failed to get promo code", zap.Error(err))
+		return nil, fmt.Errorf("database error fetching promo code: %w", err)
+	}
+
+	return &promo, nil
+}
+
+func (r *gormPromoCodeRepository) ListAll(ctx context.Context) ([]domain.PromoCode, error) {
+	var promos []domain.PromoCode
+
+	err := withRedeemed(r.db.WithContext(ctx)).Order("created_at DESC").Find(&promos).Error
+	if err != nil {
+		r.logger.Error("Failed to list promo codes", zap.Error(err))
+		return nil, fmt.Errorf("database error listing promo codes: %w", err)
+	}
+
+	return promos, nil
+}
+
+func (r *gormPromoCodeRepository) Create(ctx context.Context, promo *domain.PromoCode) error {
+	if err := r.db.WithContext(ctx).Create(promo).Error; err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return fmt.Errorf("%w: promo code %s", domain.ErrDuplicateEntry, promo.Code)
+		}
+		r.logger.Error("Failed to create promo code", zap.Error(err))
+		return fmt.Errorf("database error creating promo code: %w", err)
+	}
+	return nil
+}
+
+func (r *gormPromoCodeRepository) SetActive(ctx context.Context, id uuid.UUID, active bool) (*domain.PromoCode, error) {
+	result := r.db.WithContext(ctx).Model(&domain.PromoCode{}).Where("id = ?", id).Update("active", active)
+	if result.Error != nil {
+		r.logger.Error("Failed to update promo code", zap.String("promoCodeID", id.String()), zap.Error(result.Error))
+		return nil, fmt.Errorf("database error updating promo code: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, domain.ErrRecordNotFound
+	}
+
+	var promo domain.PromoCode
+	if err := withRedeemed(r.db.WithContext(ctx)).Where("id = ?", id).First(&promo).Error; err != nil {
+		return nil, fmt.Errorf("database error fetching promo code: %w", err)
+	}
+	return &promo, nil
+}
+
+func (r *gormPromoCodeRepository) Redeem(ctx context.Context, req port.PromoRedemptionRequest) (*domain.PromoCode, *domain.PromoRedemption, error) {
+	var promo domain.PromoCode
+	var redemption domain.PromoRedemption
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Locking the code serializes its redemptions, so the counts below cannot be raced past
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("code = ?", strings.ToUpper(req.Code)).First(&promo).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return domain.ErrPromoCodeInvalid
+			}
+			return fmt.Errorf("db error locking promo code: %w", err)
+		}
+
+		now := time.Now()
+		if err := promo.CheckRedeemable(now, req.PackageID); err != nil {
+			return err
+		}
+
+		// Reservations of checkouts that were never paid stop counting once they run out
+		var used struct {
+			Total  int
+			ByUser int
+		}
+		err = tx.Model(&domain.PromoRedemption{}).
+			Select("COUNT(*) AS total, COUNT(*) FILTER (WHERE user_id = ?) AS by_user", req.UserID).
+			Where("promo_code_id = ? AND (status = ? OR reserved_until > ?)", promo.ID, domain.RedemptionRedeemed, now).
+			Scan(&used).Error
+		if err != nil {
+			return fmt.Errorf("db error counting redemptions: %w", err)
+		}
+
+		if promo.MaxRedemptions > 0 && used.Total >= promo.MaxRedemptions {
+			return domain.ErrPromoCodeExhausted
+		}
+		if promo.PerUserLimit > 0 && used.ByUser >= promo.PerUserLimit {
+			return domain.ErrPromoCodeAlreadyUsed
+		}
+
+		redemption = domain.PromoRedemption{
+			BaseModel: domain.BaseModel{
+				ID:        uuid.New(),
+				CreatedAt: now,
+				UpdatedAt: now,
+			},
+			PromoCodeID:   promo.ID,
+			UserID:        req.UserID,
+			PaymentID:     req.PaymentID,
+			Status:        domain.RedemptionRedeemed,
+			ReservedUntil: req.ReservedUntil,
+		}
+		if req.ReservedUntil != nil {
+			redemption.Status = domain.RedemptionReserved
+		}
+
+		if err := tx.Create(&redemption).Error; err != nil {
+			return fmt.Errorf("db error recording redemption: %w", err)
+		}
+
+		if promo.Kind != domain.PromoCredits || redemption.Status != domain.RedemptionRedeemed {
+			return nil
+		}
+
+		// Credits are added in the same transaction, so a code is never used up without them
+		result := tx.Model(&domain.Wallet{}).
+			Where("user_id = ?", req.UserID).
+			Update("credits", gorm.Expr("credits + ?", promo.Credits))
+		if result.Error != nil {
+			return fmt.Errorf("failed to add promo credits: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("failed to add promo credits: %w", domain.ErrRecordNotFound)
+		}
+		return recordCreditChange(tx, req.UserID, promo.Credits, port.CreditChange{
+			Kind:      domain.CreditPromo,
+			Reference: redemption.ID.String(),
+			Reason:    promo.Code,
+		})
+	})
+
+	if err != nil {
+		if isPromoError(err) {
+			return nil, nil, err
+		}
+		r.logger.Error("Failed to redeem promo code", zap.String("userID", req.UserID.String()), zap.Error(err))
+		return nil, nil, fmt.Errorf("failed to redeem promo code: %w", err)
+	}
+
+	return &promo, &redemption, nil
+}
+
+func (r *gormPromoCodeRepository) Release(ctx context.Context, redemptionID uuid.UUID) error {
+	if err := r.db.WithContext(ctx).Delete(&domain.PromoRedemption{}, "id = ?", redemptionID).Error; err != nil {
+		r.logger.Error("Failed to release promo redemption", zap.String("redemptionID", redemptionID.String()), zap.Error(err))
+		return fmt.Errorf("database error releasing promo redemption: %w", err)
+	}
+	return nil
+}
+
+// withRedeemed selects promo codes together with their number of completed redemptions
+func withRedeemed(db *gorm.DB) *gorm.DB {
+	return db.Model(&domain.PromoCode{}).Select(`promo_codes.*, (
+		SELECT COUNT(*) FROM promo_redemptions pr
+		WHERE pr.promo_code_id = promo_codes.id AND pr.deleted_at IS NULL AND pr.status = ?
+	) AS redeemed`, domain.RedemptionRedeemed)
+}
+
+func isPromoError(err error) bool {
+	return errors.Is(err, domain.ErrPromoCodeInvalid) ||
+		errors.Is(err, domain.ErrPromoCodeExpired) ||
+		errors.Is(err, domain.ErrPromoCodeExhausted) ||
+		errors.Is(err, domain.ErrPromoCodeAlreadyUsed) ||
+		errors.Is(err, domain.ErrPromoCodeNotApplicable)
+}
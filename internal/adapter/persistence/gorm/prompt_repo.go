@@ -2,6 +2,7 @@ package gorm
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -10,6 +11,7 @@ import (
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type gormPromptRepository struct {
@@ -73,93 +75,89 @@ func (r *gormPromptRepository) FindAllByUser(ctx context.Context, userID uuid.UU
 	return prompts, nil
 }
 
-// func (r *gormPromptRepository) UpdatePlaceholderImages(ctx context.Context, externalPromptID uuid.UUID, images [][]byte, status domain.Status) (*domain.Prompt, error) {
-// 	var prompt domain.Prompt
-// 	var finalErr error
-
-// 	txErr := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-// 		if err := tx.Where("external_prompt_id = ?", externalPromptID).First(&prompt).Error; err != nil {
-// 			r.logger.Warn("External prompt ID does not exist in database", zap.String("externalPromptID", externalPromptID.String()))
-// 			return err
-// 		}
-
-// 		var placeholderImages []domain.Image
-// 		if err := tx.Where("prompt_id = ? AND status = ?", prompt.ID, domain.Pending).Find(&placeholderImages).Error; err != nil {
-// 			finalErr = fmt.Errorf("failed to find placeholder images: %w", err)
-// 			prompt.Status = domain.Failed
-// 		}
-
-// 		if finalErr == nil {
-// 			if len(placeholderImages) != len(images) {
-// 				finalErr = fmt.Errorf("mismatch: expected %d images, but %d was provided", len(placeholderImages), len(images))
-// 				prompt.Status = domain.Failed
-// 			} else {
-// 				for i, imageData := range images {
-// 					imageToUpdate := &placeholderImages[i]
-// 					imageToUpdate.ImageData = imageData
-// 					imageToUpdate.UpdatedAt = time.Now()
-// 					imageToUpdate.Status = status
-
-// 					if err := tx.Save(imageToUpdate).Error; err != nil {
-// 						finalErr = fmt.Errorf("failed to save image index %d: %w", i, err)
-// 						prompt.Status = domain.Failed
-// 						break
-// 					}
-// 				}
-// 			}
-// 		}
-
-// 		if finalErr == nil {
-// 			prompt.Status = status
-// 			prompt.Images = placeholderImages
-// 		}
-
-// 		if prompt.Status == domain.Failed {
-// 			if err := tx.Model(&domain.Image{}).Where("prompt_id = ?", prompt.ID).Update("status", domain.Failed).Error; err != nil {
-// 				r.logger.Error("CRITICAL: Failed to update images to FAILED status", zap.Error(err))
-// 				return err
-// 			}
-// 		}
-
-// 		if err := tx.Save(&prompt).Error; err != nil {
-// 			r.logger.Error("CRITICAL: Failed to save final prompt status", zap.Error(err))
-// 			return err // Abort transaction!
-// 		}
-
-// 		return nil
-// 	})
-
-// 	if txErr != nil {
-// 		r.logger.Error("Transaction rolled back due to fatal error", zap.Error(txErr))
-// 		return nil, fmt.Errorf("database transaction failed: %w", txErr)
-// 	}
-
-// 	if finalErr != nil {
-// 		r.logger.Error("Image update operation failed", zap.Error(finalErr))
-// 		return nil, finalErr
-// 	}
-
-// 	// Success!
-// 	return &prompt, nil
-// }
-
-func (r *gormPromptRepository) UpdatePlaceholderImages(ctx context.Context, externalPromptID uuid.UUID, images [][]byte, desiredStatus domain.Status) (*domain.Prompt, error) {
+func (r *gormPromptRepository) FindByID(ctx context.Context, userID uuid.UUID, promptID uuid.UUID) (*domain.Prompt, error) {
+	var prompt domain.Prompt
+
+	err := r.db.WithContext(ctx).
+		Preload("Images", withoutImageData).
+		Where("id = ? AND user_id = ?", promptID, userID).
+		First(&prompt).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrPromptNotFound
+		}
+		return nil, fmt.Errorf("failed retrieving prompt from repo: %w", err)
+	}
+
+	return &prompt, nil
+}
+
+func (r *gormPromptRepository) ListByUser(ctx context.Context, userID uuid.UUID, page domain.Page) ([]domain.Prompt, int64, error) {
+	var total int64
+	if err := r.db.WithContext(ctx).Model(&domain.Prompt{}).Where("user_id = ?", userID).Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed counting prompts in repo: %w", err)
+	}
+
+	var prompts []domain.Prompt
+	err := r.db.WithContext(ctx).
+		Preload("Images", withoutImageData).
+		Where("user_id = ?", userID).
+		Order("created_at desc, id").
+		Limit(page.Limit).
+		Offset(page.Offset).
+		Find(&prompts).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed listing prompts from repo: %w", err)
+	}
+
+	return prompts, total, nil
+}
+
+// withoutImageData loads image rows without their (large) image data
+func withoutImageData(db *gorm.DB) *gorm.DB {
+	return db.Omit("image_data").Order("created_at, id")
+}
+
+func (r *gormPromptRepository) UpdatePlaceholderImages(ctx context.Context, externalPromptID uuid.UUID, backend string, images [][]byte, desiredStatus domain.Status) (*domain.Prompt, error) {
 	var prompt domain.Prompt
 	var finalErr error
 
 	txErr := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 
-		if err := tx.Where("external_prompt_id = ?", externalPromptID).First(&prompt).Error; err != nil {
-			r.logger.Warn("External prompt ID does not exist in database", zap.String("externalPromptID", externalPromptID.String()))
+		// Locked against the generation worker failing the prompt at the same time
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("external_prompt_id = ?", externalPromptID).First(&prompt).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				r.logger.Warn("External prompt ID does not exist in database", zap.String("externalPromptID", externalPromptID.String()))
+				return domain.ErrRecordNotFound
+			}
 			return err
 		}
 
+		if !prompt.AcceptsResultFrom(backend) {
+			r.logger.Warn("Generation result reported by a backend that does not own the prompt",
+				zap.String("promptID", prompt.ID.String()),
+				zap.String("backend", backend),
+				zap.String("promptBackend", prompt.Backend),
+			)
+			return domain.ErrPromptBackendMismatch
+		}
+
+		// A result arriving after the prompt was failed and refunded is dropped
+		if prompt.Status != domain.Pending {
+			r.logger.Warn("Generation result reported for a finished prompt",
+				zap.String("promptID", prompt.ID.String()),
+				zap.String("status", prompt.Status.String()),
+			)
+			return domain.ErrPromptAlreadyFinished
+		}
+
 		var placeholderImages []domain.Image
 		if err := tx.Where("prompt_id = ? AND status = ?", prompt.ID, domain.Pending).Find(&placeholderImages).Error; err != nil {
 			finalErr = fmt.Errorf("failed to find placeholder images: %w", err)
 		}
 
-		if finalErr == nil {
+		// A failed generation carries no images, every placeholder is marked as failed below
+		if finalErr == nil && desiredStatus != domain.Failed {
 			if len(placeholderImages) != len(images) {
 				finalErr = fmt.Errorf("mismatch: expected %d images, but %d was provided", len(placeholderImages), len(images))
 			} else {
@@ -187,12 +185,23 @@ func (r *gormPromptRepository) UpdatePlaceholderImages(ctx context.Context, exte
 			return err
 		}
 
+		// Images stored by an earlier, partly failed attempt share the prompt's fate
+		if finalStatus == domain.Failed {
+			if err := tx.Model(&domain.Image{}).Where("prompt_id = ?", prompt.ID).Update("status", domain.Failed).Error; err != nil {
+				r.logger.Error("CRITICAL: Failed to update images to FAILED status", zap.Error(err), zap.String("promptID", prompt.ID.String()))
+				return err
+			}
+		}
+
 		if err := tx.Save(&prompt).Error; err != nil {
 			r.logger.Error("CRITICAL: Failed to save final prompt status", zap.Error(err), zap.String("promptID", prompt.ID.String()))
 			return err
 		}
 
-		prompt.Images = placeholderImages
+		if err := tx.Where("prompt_id = ?", prompt.ID).Order("created_at, id").Find(&prompt.Images).Error; err != nil {
+			r.logger.Error("Failed to load prompt images", zap.Error(err), zap.String("promptID", prompt.ID.String()))
+			return err
+		}
 		return nil
 	})
 
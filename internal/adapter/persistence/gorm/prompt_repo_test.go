@@ -0,0 +1,81 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func TestUpdatePlaceholderImagesChecksBackend(t *testing.T) {
+	db := testDB(t)
+	repo := NewGormPromptRepository(db, zap.NewNop())
+
+	user := domain.User{
+		BaseModel: domain.BaseModel{ID: uuid.New()},
+		Username:  "result-test",
+		Email:     uuid.NewString() + "@example.com",
+		Password:  "x",
+	}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Unscoped().Where("prompt_id IN (?)", db.Model(&domain.Prompt{}).Select("id").Where("user_id = ?", user.ID)).Delete(&domain.Image{})
+		db.Unscoped().Where("user_id = ?", user.ID).Delete(&domain.Prompt{})
+		db.Unscoped().Delete(&user)
+	})
+
+	tests := []struct {
+		name    string
+		backend string
+		wantErr error
+	}{
+		{name: "missing backend", backend: "", wantErr: domain.ErrPromptBackendMismatch},
+		{name: "other backend", backend: "node-b", wantErr: domain.ErrPromptBackendMismatch},
+		{name: "owning backend", backend: "node-a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			externalID := uuid.New()
+			prompt := domain.Prompt{
+				BaseModel:        domain.BaseModel{ID: uuid.New()},
+				ExternalPromptID: &externalID,
+				Backend:          "node-a",
+				UserID:           user.ID,
+				Cost:             1,
+				ImageCount:       1,
+				Status:           domain.Pending,
+				Images:           []domain.Image{{BaseModel: domain.BaseModel{ID: uuid.New()}, Status: domain.Pending}},
+			}
+			if err := db.Create(&prompt).Error; err != nil {
+				t.Fatalf("failed to create prompt: %v", err)
+			}
+
+			_, err := repo.UpdatePlaceholderImages(context.Background(), externalID, tt.backend, [][]byte{[]byte("image")}, domain.Completed)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("UpdatePlaceholderImages() error = %v, want %v", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("UpdatePlaceholderImages() unexpected error: %v", err)
+			}
+
+			var stored domain.Prompt
+			if err := db.First(&stored, "id = ?", prompt.ID).Error; err != nil {
+				t.Fatalf("failed to load prompt: %v", err)
+			}
+			wantStatus := domain.Completed
+			if tt.wantErr != nil {
+				wantStatus = domain.Pending
+			}
+			if stored.Status != wantStatus {
+				t.Errorf("prompt status = %s, want %s", stored.Status, wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,259 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/CP-Payne/wonderpicai/internal/port"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type gormSubscriptionRepository struct {
+	db     *gorm.DB
+	logger *zap.Logger
+}
+
+func NewGormSubscriptionRepository(db *gorm.DB, logger *zap.Logger) port.SubscriptionRepository {
+	return &gormSubscriptionRepository{db: db, logger: logger.With(zap.String("component", "SubscriptionRepoGORM"))}
+}
+
+func (r *gormSubscriptionRepository) ListActivePlans(ctx context.Context) ([]domain.SubscriptionPlan, error) {
+	var plans []domain.SubscriptionPlan
+
+	err := r.db.WithContext(ctx).Where("active = ?", true).Order("sort_order, price_minor").Find(&plans).Error
+	if err != nil {
+		r.logger.Error("Failed to list subscription plans", zap.Error(err))
+		return nil, fmt.Errorf("database error listing subscription plans: %w", err)
+	}
+
+	return plans, nil
+}
+
+func (r *gormSubscriptionRepository) GetPlanByID(ctx context.Context, id uuid.UUID) (*domain.SubscriptionPlan, error) {
+	var plan domain.SubscriptionPlan
+
+	err := r.db.WithContext(ctx).Unscoped().Where("id = ?", id).First(&plan).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrRecordNotFound
+		}
+		r.logger.Error("Failed to get subscription plan", zap.String("planID", id.String()), zap.Error(err))
+		return nil, fmt.Errorf("database error fetching subscription plan: %w", err)
+	}
+
+	return &plan, nil
+}
+
+func (r *gormSubscriptionRepository) CreatePlansIfEmpty(ctx context.Context, plans []domain.SubscriptionPlan) (bool, error) {
+	created := false
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Unscoped().Model(&domain.SubscriptionPlan{}).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+
+		if err := tx.Create(&plans).Error; err != nil {
+			return err
+		}
+		created = true
+		return nil
+	})
+	if err != nil {
+		r.logger.Error("Failed to create default subscription plans", zap.Error(err))
+		return false, fmt.Errorf("database error creating default subscription plans: %w", err)
+	}
+
+	return created, nil
+}
+
+func (r *gormSubscriptionRepository) Create(ctx context.Context, sub *domain.Subscription) error {
+	if err := r.db.WithContext(ctx).Omit("Plan").Create(sub).Error; err != nil {
+		r.logger.Error("Failed to create subscription", zap.String("userID", sub.UserID.String()), zap.Error(err))
+		return fmt.Errorf("database error creating subscription: %w", err)
+	}
+	return nil
+}
+
+func (r *gormSubscriptionRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Subscription, error) {
+	var sub domain.Subscription
+
+	err := r.db.WithContext(ctx).Preload("Plan", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Where("id = ?", id).First(&sub).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrRecordNotFound
+		}
+		r.logger.Error("Failed to get subscription", zap.String("subscriptionID", id.String()), zap.Error(err))
+		return nil, fmt.Errorf("database error fetching subscription: %w", err)
+	}
+
+	return &sub, nil
+}
+
+func (r *gormSubscriptionRepository) GetCurrentByUserID(ctx context.Context, userID uuid.UUID) (*domain.Subscription, error) {
+	var sub domain.Subscription
+
+	err := r.db.WithContext(ctx).Preload("Plan", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Where("user_id = ? AND status IN ?", userID, []domain.SubscriptionStatus{domain.SubscriptionActive, domain.SubscriptionPastDue}).
+		Order("created_at DESC").
+		First(&sub).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrNoSubscription
+		}
+		r.logger.Error("Failed to get current subscription", zap.String("userID", userID.String()), zap.Error(err))
+		return nil, fmt.Errorf("database error fetching current subscription: %w", err)
+	}
+
+	return &sub, nil
+}
+
+func (r *gormSubscriptionRepository) GetByProviderID(ctx context.Context, providerSubscriptionID string) (*domain.Subscription, error) {
+	var sub domain.Subscription
+
+	err := r.db.WithContext(ctx).Preload("Plan", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Where("provider_subscription_id = ?", providerSubscriptionID).First(&sub).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrRecordNotFound
+		}
+		r.logger.Error("Failed to get subscription by provider ID", zap.String("providerSubscriptionID", providerSubscriptionID), zap.Error(err))
+		return nil, fmt.Errorf("database error fetching subscription: %w", err)
+	}
+
+	return &sub, nil
+}
+
+func (r *gormSubscriptionRepository) GetByCheckoutSessionID(ctx context.Context, sessionID string) (*domain.Subscription, error) {
+	var sub domain.Subscription
+
+	err := r.db.WithContext(ctx).Preload("Plan", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Where("checkout_session_id = ?", sessionID).First(&sub).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrRecordNotFound
+		}
+		r.logger.Error("Failed to get subscription by checkout session", zap.String("sessionID", sessionID), zap.Error(err))
+		return nil, fmt.Errorf("database error fetching subscription: %w", err)
+	}
+
+	return &sub, nil
+}
+
+func (r *gormSubscriptionRepository) Update(ctx context.Context, sub *domain.Subscription) error {
+	result := r.db.WithContext(ctx).Model(sub).Select("*").Omit("id", "created_at", "deleted_at", "Plan").Updates(sub)
+	if result.Error != nil {
+		r.logger.Error("Failed to update subscription", zap.String("subscriptionID", sub.ID.String()), zap.Error(result.Error))
+		return fmt.Errorf("database error updating subscription: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *gormSubscriptionRepository) GrantPeriod(ctx context.Context, grant *domain.SubscriptionGrant, payment *domain.Payment) (bool, error) {
+	granted := false
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var sub domain.Subscription
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", grant.SubscriptionID).First(&sub).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return domain.ErrRecordNotFound
+			}
+			return fmt.Errorf("db error locking subscription: %w", err)
+		}
+
+		var plan domain.SubscriptionPlan
+		if err := tx.Unscoped().Where("id = ?", sub.PlanID).First(&plan).Error; err != nil {
+			return fmt.Errorf("db error loading subscription plan: %w", err)
+		}
+
+		var existing int64
+		if err := tx.Model(&domain.SubscriptionGrant{}).Where("provider_invoice_id = ?", grant.ProviderInvoiceID).Count(&existing).Error; err != nil {
+			return fmt.Errorf("db error checking previous grants: %w", err)
+		}
+		if existing > 0 {
+			return nil
+		}
+
+		var wallet domain.Wallet
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", sub.UserID).First(&wallet).Error; err != nil {
+			return fmt.Errorf("db error locking wallet: %w", err)
+		}
+
+		var previous domain.SubscriptionGrant
+		err := tx.Where("subscription_id = ?", sub.ID).Order("period_end DESC").First(&previous).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("db error loading previous grant: %w", err)
+		}
+
+		// Credits spent on generations since the previous grant, less refunds of failed ones
+		spent := 0
+		if previous.ID != uuid.Nil {
+			var net int
+			if err := tx.Model(&domain.CreditTransaction{}).
+				Select("COALESCE(SUM(amount), 0)").
+				Where("user_id = ? AND kind IN ? AND created_at >= ?", sub.UserID, []domain.CreditTransactionKind{domain.CreditGeneration, domain.CreditRefund}, previous.CreatedAt).
+				Scan(&net).Error; err != nil {
+				return fmt.Errorf("db error summing credits spent: %w", err)
+			}
+			spent = max(0, -net)
+		}
+
+		if err := tx.Create(payment).Error; err != nil {
+			return fmt.Errorf("db error recording invoice payment: %w", err)
+		}
+
+		grant.PaymentID = &payment.ID
+		grant.ExpiredCredits, grant.RolloverCredits = plan.ExpiredAllowance(previous.Allowance(), spent, int(wallet.Credits))
+		if err := tx.Create(grant).Error; err != nil {
+			return fmt.Errorf("db error recording grant: %w", err)
+		}
+
+		delta := grant.Credits - grant.ExpiredCredits
+		if err := tx.Model(&wallet).Update("credits", gorm.Expr("credits + ?", delta)).Error; err != nil {
+			return fmt.Errorf("db error updating wallet: %w", err)
+		}
+		if err := recordCreditChange(tx, sub.UserID, delta, port.CreditChange{
+			Kind:      domain.CreditSubscription,
+			Reference: grant.ProviderInvoiceID,
+			Reason:    fmt.Sprintf("%d credits granted, %d unused credits expired", grant.Credits, grant.ExpiredCredits),
+		}); err != nil {
+			return err
+		}
+
+		if err := tx.Model(&sub).Updates(map[string]any{
+			"status":               domain.SubscriptionActive,
+			"current_period_start": grant.PeriodStart,
+			"current_period_end":   grant.PeriodEnd,
+		}).Error; err != nil {
+			return fmt.Errorf("db error updating subscription period: %w", err)
+		}
+
+		granted = true
+		return nil
+	})
+
+	if err != nil {
+		if errors.Is(err, domain.ErrRecordNotFound) {
+			return false, err
+		}
+		r.logger.Error("CRITICAL: Failed to grant subscription credits",
+			zap.String("subscriptionID", grant.SubscriptionID.String()),
+			zap.String("invoiceID", grant.ProviderInvoiceID),
+			zap.Error(err))
+		return false, fmt.Errorf("database transaction failed: %w", err)
+	}
+
+	return granted, nil
+}
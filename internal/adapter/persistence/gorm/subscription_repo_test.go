@@ -0,0 +1,133 @@
+package gorm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/CP-Payne/wonderpicai/internal/port"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// subscriptionFixture is a subscriber with an empty wallet, removed when the test ends
+type subscriptionFixture struct {
+	t    *testing.T
+	repo port.SubscriptionRepository
+	user domain.User
+	plan domain.SubscriptionPlan
+	sub  domain.Subscription
+}
+
+func newSubscriptionFixture(t *testing.T, db *gorm.DB, rolloverCap int) *subscriptionFixture {
+	t.Helper()
+
+	f := &subscriptionFixture{t: t, repo: NewGormSubscriptionRepository(db, zap.NewNop())}
+	f.plan = domain.SubscriptionPlan{
+		BaseModel:        domain.BaseModel{ID: uuid.New()},
+		Name:             "Monthly",
+		CreditsPerPeriod: 100,
+		PriceMinor:       900,
+		Currency:         "usd",
+		Interval:         "month",
+		RolloverCap:      &rolloverCap,
+		Active:           true,
+	}
+	f.user = domain.User{
+		BaseModel: domain.BaseModel{ID: uuid.New()},
+		Username:  "subscription-test",
+		Email:     uuid.NewString() + "@example.com",
+		Password:  "x",
+		Wallet:    domain.Wallet{BaseModel: domain.BaseModel{ID: uuid.New()}},
+	}
+	f.sub = domain.Subscription{
+		BaseModel: domain.BaseModel{ID: uuid.New()},
+		UserID:    f.user.ID,
+		PlanID:    f.plan.ID,
+		Status:    domain.SubscriptionIncomplete,
+	}
+	for _, record := range []any{&f.plan, &f.user, &f.sub} {
+		if err := db.Create(record).Error; err != nil {
+			t.Fatalf("failed to create %T: %v", record, err)
+		}
+	}
+	// The wallet default would add sign up credits
+	if err := db.Model(&domain.Wallet{}).Where("user_id = ?", f.user.ID).Update("credits", 0).Error; err != nil {
+		t.Fatalf("failed to empty wallet: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Unscoped().Where("subscription_id = ?", f.sub.ID).Delete(&domain.SubscriptionGrant{})
+		db.Unscoped().Where("user_id = ?", f.user.ID).Delete(&domain.Payment{})
+		db.Unscoped().Where("user_id = ?", f.user.ID).Delete(&domain.CreditTransaction{})
+		db.Unscoped().Delete(&f.sub)
+		db.Unscoped().Delete(&f.plan)
+		db.Unscoped().Where("user_id = ?", f.user.ID).Delete(&domain.Wallet{})
+		db.Unscoped().Delete(&f.user)
+	})
+	return f
+}
+
+// grantPeriod grants the plan's credits for a paid invoice
+func (f *subscriptionFixture) grantPeriod(invoiceID, providerPaymentID string, start time.Time) *domain.SubscriptionGrant {
+	f.t.Helper()
+
+	grant := &domain.SubscriptionGrant{
+		BaseModel:         domain.BaseModel{ID: uuid.New()},
+		SubscriptionID:    f.sub.ID,
+		ProviderInvoiceID: invoiceID,
+		Credits:           f.plan.CreditsPerPeriod,
+		PeriodStart:       start,
+		PeriodEnd:         start.AddDate(0, 1, 0),
+	}
+	now := time.Now()
+	payment := &domain.Payment{
+		BaseModel:         domain.BaseModel{ID: uuid.New()},
+		UserID:            f.user.ID,
+		SubscriptionID:    &f.sub.ID,
+		Credits:           f.plan.CreditsPerPeriod,
+		AmountMinor:       f.plan.PriceMinor,
+		Currency:          f.plan.Currency,
+		Status:            domain.PaymentCompleted,
+		CompletedAt:       &now,
+		ProviderPaymentID: providerPaymentID,
+	}
+	granted, err := f.repo.GrantPeriod(context.Background(), grant, payment)
+	if err != nil || !granted {
+		f.t.Fatalf("GrantPeriod() = %v, %v, want granted", granted, err)
+	}
+	return grant
+}
+
+// TestGrantPeriodExpiresUnusedAllowance renews a plan without rollover after the user spent
+// the whole allowance and bought more credits. The purchased credits have to be kept.
+func TestGrantPeriodExpiresUnusedAllowance(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	walletRepo := NewGormWalletRepository(db, zap.NewNop())
+	f := newSubscriptionFixture(t, db, 0)
+
+	start := time.Now().Add(-time.Hour)
+	f.grantPeriod("in_first_"+f.sub.ID.String(), "", start)
+
+	if err := walletRepo.SubtractCredits(ctx, f.user.ID, 100, port.CreditChange{Kind: domain.CreditGeneration}); err != nil {
+		t.Fatalf("SubtractCredits() error = %v", err)
+	}
+	if err := walletRepo.AddCredits(ctx, f.user.ID, 250, port.CreditChange{Kind: domain.CreditPurchase}); err != nil {
+		t.Fatalf("AddCredits() error = %v", err)
+	}
+
+	renewal := f.grantPeriod("in_second_"+f.sub.ID.String(), "", start.AddDate(0, 1, 0))
+	if renewal.ExpiredCredits != 0 {
+		t.Errorf("renewal expired %d credits, want none", renewal.ExpiredCredits)
+	}
+
+	wallet, err := walletRepo.GetByUserID(ctx, f.user.ID)
+	if err != nil {
+		t.Fatalf("GetByUserID() error = %v", err)
+	}
+	if wallet.Credits != 350 {
+		t.Errorf("wallet has %d credits, want 350", wallet.Credits)
+	}
+}
@@ -0,0 +1,82 @@
+package gorm
+
+import (
+	"errors"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const tracingSpanKey = "tracing:span"
+
+var tracer = otel.Tracer("github.com/CP-Payne/wonderpicai/internal/adapter/persistence/gorm")
+
+// registerTracing records a client span for every statement run with a context that is already
+// part of a trace. Statements without one, such as the polling of background workers, are not
+// traced so they do not each start a trace of their own.
+func registerTracing(db *gorm.DB) error {
+	callbacks := db.Callback()
+	err := errors.Join(
+		callbacks.Create().Before("gorm:create").Register("tracing:before_create", startStatementSpan("create")),
+		callbacks.Create().After("gorm:create").Register("tracing:after_create", endStatementSpan),
+		callbacks.Query().Before("gorm:query").Register("tracing:before_query", startStatementSpan("query")),
+		callbacks.Query().After("gorm:query").Register("tracing:after_query", endStatementSpan),
+		callbacks.Update().Before("gorm:update").Register("tracing:before_update", startStatementSpan("update")),
+		callbacks.Update().After("gorm:update").Register("tracing:after_update", endStatementSpan),
+		callbacks.Delete().Before("gorm:delete").Register("tracing:before_delete", startStatementSpan("delete")),
+		callbacks.Delete().After("gorm:delete").Register("tracing:after_delete", endStatementSpan),
+		callbacks.Row().Before("gorm:row").Register("tracing:before_row", startStatementSpan("row")),
+		callbacks.Row().After("gorm:row").Register("tracing:after_row", endStatementSpan),
+		callbacks.Raw().Before("gorm:raw").Register("tracing:before_raw", startStatementSpan("raw")),
+		callbacks.Raw().After("gorm:raw").Register("tracing:after_raw", endStatementSpan),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to register tracing callbacks: %w", err)
+	}
+	return nil
+}
+
+func startStatementSpan(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx := db.Statement.Context
+		if !trace.SpanContextFromContext(ctx).IsValid() {
+			return
+		}
+
+		// The model is parsed before the callbacks run, so the table is known unless the
+		// statement is raw SQL
+		name := "gorm." + operation
+		attributes := []attribute.KeyValue{semconv.DBSystemPostgreSQL, semconv.DBOperationName(operation)}
+		if table := db.Statement.Table; table != "" {
+			name += " " + table
+			attributes = append(attributes, semconv.DBCollectionName(table))
+		}
+
+		_, span := tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attributes...))
+		db.InstanceSet(tracingSpanKey, span)
+	}
+}
+
+func endStatementSpan(db *gorm.DB) {
+	value, ok := db.InstanceGet(tracingSpanKey)
+	if !ok {
+		return
+	}
+	span := value.(trace.Span)
+	defer span.End()
+
+	span.SetAttributes(
+		semconv.DBQueryText(db.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+	)
+
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(db.Error)
+		span.SetStatus(codes.Error, db.Error.Error())
+	}
+}
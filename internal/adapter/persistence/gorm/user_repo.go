@@ -1,9 +1,11 @@
 package gorm
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/CP-Payne/wonderpicai/internal/domain"
 	"github.com/CP-Payne/wonderpicai/internal/port"
@@ -112,3 +114,89 @@ func (r *gormUserRepository) GetByID(userID uuid.UUID) (*domain.User, error) {
 	}
 	return &user, nil
 }
+
+func (r *gormUserRepository) UpdateCurrency(userID uuid.UUID, currency string) error {
+	result := r.db.Model(&domain.User{}).Where("id = ?", userID).Update("currency", currency)
+	if result.Error != nil {
+		r.logger.Error("Failed to update user currency", zap.String("userID", userID.String()), zap.Error(result.Error))
+		return fmt.Errorf("database error updating user currency: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *gormUserRepository) Search(ctx context.Context, query string, page domain.Page) ([]domain.User, int64, error) {
+	db := r.db.WithContext(ctx).Model(&domain.User{})
+	if query = strings.TrimSpace(query); query != "" {
+		pattern := "%" + escapeLike(query) + "%"
+		db = db.Where("email ILIKE ? OR username ILIKE ?", pattern, pattern)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		r.logger.Error("Failed to count users", zap.String("query", query), zap.Error(err))
+		return nil, 0, fmt.Errorf("database error counting users: %w", err)
+	}
+
+	var users []domain.User
+	err := db.Preload("Wallet").
+		Order("created_at DESC").
+		Limit(page.Limit).
+		Offset(page.Offset).
+		Find(&users).Error
+	if err != nil {
+		r.logger.Error("Failed to search users", zap.String("query", query), zap.Error(err))
+		return nil, 0, fmt.Errorf("database error searching users: %w", err)
+	}
+
+	return users, total, nil
+}
+
+func (r *gormUserRepository) SetRole(ctx context.Context, userID uuid.UUID, role domain.Role) error {
+	result := r.db.WithContext(ctx).Model(&domain.User{}).Where("id = ?", userID).Update("role", role)
+	if result.Error != nil {
+		r.logger.Error("Failed to update user role", zap.String("userID", userID.String()), zap.Error(result.Error))
+		return fmt.Errorf("database error updating user role: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *gormUserRepository) SetDisabled(ctx context.Context, userID uuid.UUID, disabledAt *time.Time, reason string) error {
+	result := r.db.WithContext(ctx).Model(&domain.User{}).Where("id = ?", userID).Updates(map[string]any{
+		"disabled_at":     disabledAt,
+		"disabled_reason": reason,
+	})
+	if result.Error != nil {
+		r.logger.Error("Failed to update user disabled state", zap.String("userID", userID.String()), zap.Error(result.Error))
+		return fmt.Errorf("database error updating user disabled state: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *gormUserRepository) PromoteToAdmin(ctx context.Context, userIDs []uuid.UUID) (int64, error) {
+	if len(userIDs) == 0 {
+		return 0, nil
+	}
+
+	result := r.db.WithContext(ctx).Model(&domain.User{}).
+		Where("id IN ? AND role <> ?", userIDs, domain.RoleAdmin).
+		Update("role", domain.RoleAdmin)
+	if result.Error != nil {
+		r.logger.Error("Failed to promote admins", zap.Error(result.Error))
+		return 0, fmt.Errorf("database error promoting admins: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
@@ -2,6 +2,7 @@ package gorm
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/CP-Payne/wonderpicai/internal/domain"
@@ -9,6 +10,7 @@ import (
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type gormWalletRepository struct {
@@ -39,73 +41,116 @@ func (r *gormWalletRepository) GetByUserID(ctx context.Context, userID uuid.UUID
 
 }
 
-func (r *gormWalletRepository) SubtractCredits(ctx context.Context, userID uuid.UUID, amount int) error {
+func (r *gormWalletRepository) SubtractCredits(ctx context.Context, userID uuid.UUID, amount int, change port.CreditChange) error {
 
-	expression := gorm.Expr("credits - ?", amount)
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&domain.Wallet{}).
+			Where("user_id = ? AND credits >= ?", userID, amount).
+			Update("credits", gorm.Expr("credits - ?", amount))
+		if result.Error != nil {
+			return fmt.Errorf("db error subtracting credits: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return domain.ErrInsufficientFunds
+		}
 
-	result := r.db.Model(&domain.Wallet{}).
-		Where("user_id = ? AND credits >= ?", userID, amount).
-		Update("credits", expression)
+		return recordCreditChange(tx, userID, -amount, change)
+	})
 
-	if result.Error != nil {
-		r.logger.Error("Database error during credit subtraction",
-			zap.String("userID", userID.String()),
-			zap.Int("amount", amount),
-			zap.Error(result.Error))
-		return fmt.Errorf("db error subtracting credits: %w", result.Error)
-	}
-	if result.RowsAffected == 0 {
+	if errors.Is(err, domain.ErrInsufficientFunds) {
 		r.logger.Warn("Failed to subtract credits: insufficient funds or user not found",
 			zap.String("userID", userID.String()),
 			zap.Int("amount", amount))
-		return domain.ErrInsufficientFunds
+		return err
+	}
+	if err != nil {
+		r.logger.Error("Database error during credit subtraction",
+			zap.String("userID", userID.String()),
+			zap.Int("amount", amount),
+			zap.Error(err))
+		return err
 	}
 
 	return nil
 
 }
 
-func (r *gormWalletRepository) AddCredits(ctx context.Context, userID uuid.UUID, amount int) error {
-	expression := gorm.Expr("credits + ?", amount)
+func (r *gormWalletRepository) AddCredits(ctx context.Context, userID uuid.UUID, amount int, change port.CreditChange) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&domain.Wallet{}).
+			Where("user_id = ?", userID).
+			Update("credits", gorm.Expr("credits + ?", amount))
 
-	result := r.db.Model(&domain.Wallet{}).
-		Where("user_id = ?", userID).
-		Update("credits", expression)
+		if result.Error != nil {
+			return result.Error
+		}
 
-	if result.Error != nil {
-		return result.Error
-	}
-
-	if result.RowsAffected == 0 {
-		// This would mean the user_id doesn't exist
-		return domain.ErrRecordNotFound
-	}
+		if result.RowsAffected == 0 {
+			// This would mean the user_id doesn't exist
+			return domain.ErrRecordNotFound
+		}
 
-	return nil
+		return recordCreditChange(tx, userID, amount, change)
+	})
 }
 
-func (r *gormWalletRepository) AddCreditsToEmail(ctx context.Context, email string, amount int) error {
+func (r *gormWalletRepository) AddCreditsToEmail(ctx context.Context, email string, amount int, change port.CreditChange) (bool, error) {
+	var user domain.User
+	if err := r.db.WithContext(ctx).Select("id").Where("email = ?", email).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, domain.ErrRecordNotFound
+		}
+		return false, err
+	}
 
-	expression := gorm.Expr("credits + ?", amount)
-	subQuery := r.db.Model(&domain.User{}).Select("id").Where("email = ?", email)
+	added := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Locking the wallet serializes concurrent deliveries of the same event
+		var wallet domain.Wallet
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", user.ID).First(&wallet).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return domain.ErrRecordNotFound
+			}
+			return fmt.Errorf("db error locking wallet: %w", err)
+		}
+
+		var existing int64
+		if err := tx.Model(&domain.CreditTransaction{}).
+			Where("user_id = ? AND kind = ? AND reference = ?", user.ID, change.Kind, change.Reference).
+			Count(&existing).Error; err != nil {
+			return fmt.Errorf("db error checking credit ledger: %w", err)
+		}
+		if existing > 0 {
+			return nil
+		}
+
+		if err := tx.Model(&wallet).Update("credits", gorm.Expr("credits + ?", amount)).Error; err != nil {
+			return fmt.Errorf("db error adding credits: %w", err)
+		}
+		added = true
+		return recordCreditChange(tx, user.ID, amount, change)
+	})
+	if err != nil {
+		return false, err
+	}
 
-	result := r.db.WithContext(ctx).
-		Model(&domain.Wallet{}).
-		Where("user_id IN (?)", subQuery).
-		Update("credits", expression)
+	return added, nil
+}
 
-	// result := r.db.Model(&domain.Wallet{}).
-	// 	Where("user_id = ?", userID).
-	// 	Update("credits", expression)
+func (r *gormWalletRepository) ListTransactions(ctx context.Context, userID uuid.UUID, page domain.Page) ([]domain.CreditTransaction, int64, error) {
+	db := r.db.WithContext(ctx).Model(&domain.CreditTransaction{}).Where("user_id = ?", userID)
 
-	if result.Error != nil {
-		return result.Error
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		r.logger.Error("Failed to count credit transactions", zap.String("userID", userID.String()), zap.Error(err))
+		return nil, 0, fmt.Errorf("database error counting credit transactions: %w", err)
 	}
 
-	if result.RowsAffected == 0 {
-		// This would mean the user_id doesn't exist
-		return domain.ErrRecordNotFound
+	var transactions []domain.CreditTransaction
+	if err := db.Order("created_at DESC").Limit(page.Limit).Offset(page.Offset).Find(&transactions).Error; err != nil {
+		r.logger.Error("Failed to list credit transactions", zap.String("userID", userID.String()), zap.Error(err))
+		return nil, 0, fmt.Errorf("database error listing credit transactions: %w", err)
 	}
 
-	return nil
+	return transactions, total, nil
 }
@@ -0,0 +1,64 @@
+package gorm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/CP-Payne/wonderpicai/internal/port"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func TestAddCreditsToEmailOnce(t *testing.T) {
+	db := testDB(t)
+	repo := NewGormWalletRepository(db, zap.NewNop())
+
+	user := domain.User{
+		BaseModel: domain.BaseModel{ID: uuid.New()},
+		Username:  "wallet-test",
+		Email:     uuid.NewString() + "@example.com",
+		Password:  "x",
+		Wallet:    domain.Wallet{BaseModel: domain.BaseModel{ID: uuid.New()}, Credits: 10},
+	}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Unscoped().Where("user_id = ?", user.ID).Delete(&domain.CreditTransaction{})
+		db.Unscoped().Where("user_id = ?", user.ID).Delete(&domain.Wallet{})
+		db.Unscoped().Delete(&user)
+	})
+
+	change := port.CreditChange{Kind: domain.CreditPurchase, Reference: "cs_test_" + user.ID.String()}
+	tests := []struct {
+		name      string
+		change    port.CreditChange
+		wantAdded bool
+		wantTotal uint
+	}{
+		{name: "first delivery", change: change, wantAdded: true, wantTotal: 110},
+		{name: "redelivery", change: change, wantAdded: false, wantTotal: 110},
+		{name: "other checkout", change: port.CreditChange{Kind: domain.CreditPurchase, Reference: "cs_other_" + user.ID.String()}, wantAdded: true, wantTotal: 210},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			added, err := repo.AddCreditsToEmail(context.Background(), user.Email, 100, tt.change)
+			if err != nil {
+				t.Fatalf("AddCreditsToEmail() error = %v", err)
+			}
+			if added != tt.wantAdded {
+				t.Errorf("AddCreditsToEmail() added = %v, want %v", added, tt.wantAdded)
+			}
+
+			wallet, err := repo.GetByUserID(context.Background(), user.ID)
+			if err != nil {
+				t.Fatalf("GetByUserID() error = %v", err)
+			}
+			if wallet.Credits != tt.wantTotal {
+				t.Errorf("wallet has %d credits, want %d", wallet.Credits, tt.wantTotal)
+			}
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/CP-Payne/wonderpicai/internal/port"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type gormWebhookRepository struct {
+	db     *gorm.DB
+	logger *zap.Logger
+}
+
+func NewGormWebhookRepository(db *gorm.DB, logger *zap.Logger) port.WebhookRepository {
+	return &gormWebhookRepository{db: db, logger: logger.With(zap.String("component", "WebhookRepoGORM"))}
+}
+
+func (r *gormWebhookRepository) CreateEndpoint(ctx context.Context, endpoint *domain.WebhookEndpoint) error {
+	if err := r.db.WithContext(ctx).Create(endpoint).Error; err != nil {
+		r.logger.Error("Failed to create webhook endpoint", zap.String("userID", endpoint.UserID.String()), zap.Error(err))
+		return fmt.Errorf("database error creating webhook endpoint: %w", err)
+	}
+	return nil
+}
+
+func (r *gormWebhookRepository) ListEndpoints(ctx context.Context, userID uuid.UUID) ([]domain.WebhookEndpoint, error) {
+	var endpoints []domain.WebhookEndpoint
+
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Find(&endpoints).Error
+	if err != nil {
+		r.logger.Error("Failed to list webhook endpoints", zap.String("userID", userID.String()), zap.Error(err))
+		return nil, fmt.Errorf("database error listing webhook endpoints: %w", err)
+	}
+
+	return endpoints, nil
+}
+
+func (r *gormWebhookRepository) GetEndpoint(ctx context.Context, userID uuid.UUID, id uuid.UUID) (*domain.WebhookEndpoint, error) {
+	var endpoint domain.WebhookEndpoint
+
+	err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&endpoint).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrRecordNotFound
+		}
+		r.logger.Error("Failed to get webhook endpoint", zap.String("endpointID", id.String()), zap.Error(err))
+		return nil, fmt.Errorf("database error fetching webhook endpoint: %w", err)
+	}
+
+	return &endpoint, nil
+}
+
+func (r *gormWebhookRepository) SetEndpointActive(ctx context.Context, userID uuid.UUID, id uuid.UUID, active bool) (*domain.WebhookEndpoint, error) {
+	result := r.db.WithContext(ctx).Model(&domain.WebhookEndpoint{}).
+		Where("id = ? AND user_id = ?", id, userID).
+		Updates(map[string]any{"active": active, "updated_at": time.Now()})
+	if result.Error != nil {
+		r.logger.Error("Failed to update webhook endpoint", zap.String("endpointID", id.String()), zap.Error(result.Error))
+		return nil, fmt.Errorf("database error updating webhook endpoint: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, domain.ErrRecordNotFound
+	}
+
+	return r.GetEndpoint(ctx, userID, id)
+}
+
+func (r *gormWebhookRepository) DeleteEndpoint(ctx context.Context, userID uuid.UUID, id uuid.UUID) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND user_id = ?", id, userID).Delete(&domain.WebhookEndpoint{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return domain.ErrRecordNotFound
+		}
+
+		// Deliveries still waiting for the endpoint are given up
+		return tx.Model(&domain.WebhookDelivery{}).
+			Where("endpoint_id = ? AND status IN ?", id, []domain.DeliveryStatus{domain.DeliveryPending, domain.DeliverySending}).
+			Updates(map[string]any{"status": domain.DeliveryFailed, "last_error": "endpoint deleted", "updated_at": time.Now()}).Error
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrRecordNotFound) {
+			return err
+		}
+		r.logger.Error("Failed to delete webhook endpoint", zap.String("endpointID", id.String()), zap.Error(err))
+		return fmt.Errorf("database error deleting webhook endpoint: %w", err)
+	}
+	return nil
+}
+
+func (r *gormWebhookRepository) EnqueueEvent(ctx context.Context, userID uuid.UUID, event domain.WebhookEventType, eventID uuid.UUID, payload string, maxAttempts int) (int, error) {
+	var endpoints []domain.WebhookEndpoint
+	err := r.db.WithContext(ctx).Where("user_id = ? AND active = ?", userID, true).Find(&endpoints).Error
+	if err != nil {
+		return 0, fmt.Errorf("database error finding webhook endpoints: %w", err)
+	}
+
+	now := time.Now()
+	var deliveries []domain.WebhookDelivery
+	for _, endpoint := range endpoints {
+		if !endpoint.Subscribed(event) {
+			continue
+		}
+		deliveries = append(deliveries, domain.WebhookDelivery{
+			BaseModel:     domain.BaseModel{ID: uuid.New(), CreatedAt: now, UpdatedAt: now},
+			EndpointID:    endpoint.ID,
+			UserID:        userID,
+			EventID:       eventID,
+			EventType:     event,
+			Payload:       payload,
+			Status:        domain.DeliveryPending,
+			MaxAttempts:   maxAttempts,
+			NextAttemptAt: now,
+		})
+	}
+
+	if len(deliveries) == 0 {
+		return 0, nil
+	}
+
+	if err := r.db.WithContext(ctx).Omit("Endpoint").Create(&deliveries).Error; err != nil {
+		r.logger.Error("Failed to enqueue webhook deliveries", zap.String("event", string(event)), zap.Error(err))
+		return 0, fmt.Errorf("database error enqueueing webhook deliveries: %w", err)
+	}
+
+	return len(deliveries), nil
+}
+
+func (r *gormWebhookRepository) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]domain.WebhookDelivery, error) {
+	var claimed []domain.WebhookDelivery
+	now := time.Now()
+
+	// Sending deliveries whose lease expired belong to a dispatcher that died, they are claimed again
+	err := r.db.WithContext(ctx).Raw(`
+		UPDATE webhook_deliveries
+		SET status = ?, attempts = attempts + 1, locked_until = ?, updated_at = ?
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE deleted_at IS NULL
+			  AND ((status = ? AND next_attempt_at <= ?) OR (status = ? AND locked_until < ?))
+			ORDER BY next_attempt_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id`,
+		domain.DeliverySending, now.Add(lease), now,
+		domain.DeliveryPending, now, domain.DeliverySending, now,
+		limit,
+	).Scan(&claimed).Error
+	if err != nil {
+		r.logger.Error("Failed to claim webhook deliveries", zap.Error(err))
+		return nil, fmt.Errorf("database error claiming webhook deliveries: %w", err)
+	}
+
+	if len(claimed) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uuid.UUID, len(claimed))
+	for i, delivery := range claimed {
+		ids[i] = delivery.ID
+	}
+
+	var deliveries []domain.WebhookDelivery
+	err = r.db.WithContext(ctx).
+		Preload("Endpoint", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Where("id IN ?", ids).
+		Order("next_attempt_at").
+		Find(&deliveries).Error
+	if err != nil {
+		r.logger.Error("Failed to load claimed webhook deliveries", zap.Error(err))
+		return nil, fmt.Errorf("database error loading claimed webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+func (r *gormWebhookRepository) MarkDelivered(ctx context.Context, id uuid.UUID, responseStatus int, responseBody string) error {
+	now := time.Now()
+	err := r.db.WithContext(ctx).Model(&domain.WebhookDelivery{}).Where("id = ?", id).Updates(map[string]any{
+		"status":          domain.DeliverySucceeded,
+		"response_status": responseStatus,
+		"response_body":   responseBody,
+		"last_error":      "",
+		"locked_until":    nil,
+		"delivered_at":    now,
+		"updated_at":      now,
+	}).Error
+	if err != nil {
+		return fmt.Errorf("database error marking webhook delivery as delivered: %w", err)
+	}
+	return nil
+}
+
+func (r *gormWebhookRepository) MarkAttemptFailed(ctx context.Context, id uuid.UUID, attempt port.WebhookAttempt) error {
+	updates := map[string]any{
+		"status":          domain.DeliveryFailed,
+		"response_status": attempt.ResponseStatus,
+		"response_body":   attempt.ResponseBody,
+		"last_error":      attempt.Error,
+		"locked_until":    nil,
+		"updated_at":      time.Now(),
+	}
+	if attempt.NextAttemptAt != nil {
+		updates["status"] = domain.DeliveryPending
+		updates["next_attempt_at"] = *attempt.NextAttemptAt
+	}
+
+	if err := r.db.WithContext(ctx).Model(&domain.WebhookDelivery{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+		return fmt.Errorf("database error recording failed webhook attempt: %w", err)
+	}
+	return nil
+}
+
+func (r *gormWebhookRepository) ListDeliveries(ctx context.Context, userID uuid.UUID, endpointID uuid.UUID, limit int) ([]domain.WebhookDelivery, error) {
+	var deliveries []domain.WebhookDelivery
+
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND endpoint_id = ?", userID, endpointID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&deliveries).Error
+	if err != nil {
+		r.logger.Error("Failed to list webhook deliveries", zap.String("endpointID", endpointID.String()), zap.Error(err))
+		return nil, fmt.Errorf("database error listing webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+func (r *gormWebhookRepository) Redeliver(ctx context.Context, userID uuid.UUID, deliveryID uuid.UUID, maxAttempts int) (*domain.WebhookDelivery, error) {
+	var original domain.WebhookDelivery
+	err := r.db.WithContext(ctx).
+		Joins("JOIN webhook_endpoints ON webhook_endpoints.id = webhook_deliveries.endpoint_id AND webhook_endpoints.deleted_at IS NULL").
+		Where("webhook_deliveries.id = ? AND webhook_deliveries.user_id = ?", deliveryID, userID).
+		First(&original).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrRecordNotFound
+		}
+		return nil, fmt.Errorf("database error fetching webhook delivery: %w", err)
+	}
+
+	now := time.Now()
+	delivery := domain.WebhookDelivery{
+		BaseModel:     domain.BaseModel{ID: uuid.New(), CreatedAt: now, UpdatedAt: now},
+		EndpointID:    original.EndpointID,
+		UserID:        original.UserID,
+		EventID:       original.EventID,
+		EventType:     original.EventType,
+		Payload:       original.Payload,
+		Status:        domain.DeliveryPending,
+		MaxAttempts:   maxAttempts,
+		NextAttemptAt: now,
+	}
+	if err := r.db.WithContext(ctx).Omit("Endpoint").Create(&delivery).Error; err != nil {
+		r.logger.Error("Failed to create redelivery", zap.String("deliveryID", deliveryID.String()), zap.Error(err))
+		return nil, fmt.Errorf("database error creating redelivery: %w", err)
+	}
+
+	return &delivery, nil
+}
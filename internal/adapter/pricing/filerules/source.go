@@ -0,0 +1,65 @@
+package filerules
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/CP-Payne/wonderpicai/internal/port"
+	"go.uber.org/zap"
+)
+
+// Source reads pricing rules from a JSON file. The file is only parsed again when its
+// modification time changes, so it is cheap to poll.
+type Source struct {
+	logger *zap.Logger
+	path   string
+
+	mu      sync.Mutex
+	modTime time.Time
+	rules   *domain.PricingRules
+}
+
+func NewSource(logger *zap.Logger, path string) port.PricingRulesSource {
+	return &Source{
+		logger: logger.With(zap.String("component", "PricingFileRules")),
+		path:   path,
+	}
+}
+
+func (s *Source) Load(ctx context.Context) (*domain.PricingRules, error) {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat pricing rules file: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.rules != nil && info.ModTime().Equal(s.modTime) {
+		return s.rules, nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pricing rules file: %w", err)
+	}
+
+	rules := &domain.PricingRules{}
+	if err := json.Unmarshal(data, rules); err != nil {
+		return nil, fmt.Errorf("failed to parse pricing rules file: %w: %w", domain.ErrInvalidPricingRules, err)
+	}
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.logger.Debug("Pricing rules file parsed", zap.String("path", s.path), zap.String("version", rules.Version))
+
+	s.modTime = info.ModTime()
+	s.rules = rules
+	return rules, nil
+}
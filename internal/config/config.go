@@ -3,10 +3,15 @@ package config
 import (
 	"fmt"
 	"log"
+	"math"
+	"net/netip"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/joho/godotenv"
 )
 
@@ -16,13 +21,30 @@ type AppConfig struct {
 	JWT        JWTConfig
 	GoogleAuth GoogleAuth
 	ComfyLite  ComfyLiteConfig
+	OpenAI     OpenAIImagesConfig
+	Generation GenerationConfig
+	Pricing    PricingConfig
 	Stripe     StripeConfig
+	Admin      AdminConfig
+	Business   BusinessConfig
+	Mail       MailConfig
+	Webhook    WebhookConfig
+	Metrics    MetricsConfig
+	Tracing    TracingConfig
 }
 
 type ServerConfig struct {
 	AppEnv   string
 	Port     string
 	LogLevel string
+	// PublicBaseURL is the URL users reach the app at, used for redirects back from external
+	// services, e.g. "https://wonderpic.example.com"
+	PublicBaseURL string
+	// InternalWebhookBaseURL is the URL backends on the internal network call back to,
+	// defaults to PublicBaseURL
+	InternalWebhookBaseURL string
+	// TrustedProxies are the reverse proxies whose X-Forwarded-* headers are honoured
+	TrustedProxies []netip.Prefix
 }
 
 type DatabaseConfig struct {
@@ -50,6 +72,112 @@ type GoogleAuth struct {
 type ComfyLiteConfig struct {
 	Host string
 	Port string
+	// Nodes lists the ComfyLite backends requests are balanced between.
+	// When COMFYLITE_NODES is not set it contains a single node built from Host and Port.
+	Nodes          []ComfyLiteNode
+	Balancing      string
+	HealthInterval time.Duration
+}
+
+type ComfyLiteNode struct {
+	Name   string
+	URL    string
+	Weight int
+}
+
+// Synchronous OpenAI compatible images API config
+type OpenAIImagesConfig struct {
+	BaseURL       string
+	APIKey        string
+	Model         string
+	MaxPerRequest int
+}
+
+type GenerationConfig struct {
+	// Provider selects the generation backend: "comfylite" or "openai"
+	Provider string
+	// QueueWorkers is the number of workers submitting queued prompts to the backend
+	QueueWorkers      int
+	QueuePollInterval time.Duration
+	// QueueMaxAttempts is how often a job is submitted before it is dead-lettered and refunded
+	QueueMaxAttempts int
+	// QueueLease is how long a claimed job stays locked before another worker may take it over
+	QueueLease time.Duration
+	// ResultTimeout is how long a submitted prompt may wait for its result before it is failed
+	// and refunded, e.g. when the completion webhook got lost. Zero disables the timeout.
+	ResultTimeout time.Duration
+	// Per user limits on work in progress, 0 disables the limit
+	MaxPendingPromptsPerUser int
+	MaxQueuedImagesPerUser   int
+}
+
+type PricingConfig struct {
+	// RulesFile is a JSON file with pricing rules. The built-in rules are used when empty.
+	RulesFile      string
+	ReloadInterval time.Duration
+}
+
+type AdminConfig struct {
+	// UserIDs of users that are given the admin role at startup. IDs rather than emails, as
+	// emails are not verified and anyone could sign up with a configured address.
+	UserIDs []uuid.UUID
+	// StuckPromptAfter is how long a pending prompt may go without progress before it is
+	// listed as stuck in the admin area
+	StuckPromptAfter time.Duration
+}
+
+// BusinessConfig holds the seller details printed on receipts
+type BusinessConfig struct {
+	Name    string
+	Address []string
+	TaxID   string
+	Email   string
+	// TaxLabel names the tax included in prices, e.g. "VAT"
+	TaxLabel string
+	// TaxRateBps is the tax rate included in prices in basis points, 2000 is 20%
+	TaxRateBps    int
+	InvoicePrefix string
+}
+
+type MailConfig struct {
+	// Driver selects how mail is sent: "smtp", or "log" to only log messages
+	Driver       string
+	From         string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+}
+
+// WebhookConfig controls delivery of user webhooks
+type WebhookConfig struct {
+	Workers      int
+	PollInterval time.Duration
+	// MaxAttempts is how often a delivery is attempted before it is marked as failed
+	MaxAttempts int
+	// Timeout limits a single delivery request
+	Timeout time.Duration
+	// LowCreditsThreshold emits credits.low when a balance drops below it
+	LowCreditsThreshold int
+	// AllowPrivateTargets permits plain http URLs and private or loopback addresses, for development
+	AllowPrivateTargets bool
+}
+
+// MetricsConfig controls where the Prometheus metrics are exposed
+type MetricsConfig struct {
+	// Addr serves /metrics on a separate listener, e.g. ":9091", meant for an internal network
+	Addr string
+	// Token exposes /metrics on the main server to requests carrying it as a bearer token
+	Token string
+}
+
+// TracingConfig controls OpenTelemetry tracing, which is disabled without an endpoint
+type TracingConfig struct {
+	// OTLPEndpoint is the URL of an OTLP/HTTP collector, e.g. "http://localhost:4318"
+	OTLPEndpoint string
+	ServiceName  string
+	// SampleRatio is the share of new traces that are recorded, from 0 to 1
+	SampleRatio float64
 }
 
 type StripeConfig struct {
@@ -78,6 +206,13 @@ func LoadConfig() {
 	Cfg.Server.AppEnv = getEnv("APP_ENV", "development")
 	Cfg.Server.Port = getEnv("PORT", "8080")
 	Cfg.Server.LogLevel = getEnv("LOG_LEVEL", "info")
+	Cfg.Server.PublicBaseURL = mustParseBaseURL("PUBLIC_BASE_URL", getEnv("PUBLIC_BASE_URL", "http://localhost:"+Cfg.Server.Port))
+	Cfg.Server.InternalWebhookBaseURL = mustParseBaseURL("INTERNAL_WEBHOOK_BASE_URL", getEnv("INTERNAL_WEBHOOK_BASE_URL", Cfg.Server.PublicBaseURL))
+	trustedProxies, err := parseTrustedProxies(getEnv("TRUSTED_PROXIES", ""))
+	if err != nil {
+		log.Fatalf("FATAL: Invalid TRUSTED_PROXIES value: %v", err)
+	}
+	Cfg.Server.TrustedProxies = trustedProxies
 
 	// --- Database Config ---
 	Cfg.Database.Host = getEnv("DB_HOST", "localhost")
@@ -119,11 +254,113 @@ func LoadConfig() {
 	// --- ComfyLite ---
 	Cfg.ComfyLite.Host = getEnv("COMFYLITE_HOST", "127.0.0.1")
 	Cfg.ComfyLite.Port = getEnv("COMFYLITE_PORT", "8081")
+	Cfg.ComfyLite.Balancing = getEnv("COMFYLITE_BALANCING", "weighted")
+	Cfg.ComfyLite.HealthInterval = getEnvDuration("COMFYLITE_HEALTH_INTERVAL", 15*time.Second)
+
+	nodesStr := getEnv("COMFYLITE_NODES", "")
+	if nodesStr == "" {
+		Cfg.ComfyLite.Nodes = []ComfyLiteNode{{
+			Name:   "default",
+			URL:    fmt.Sprintf("http://%s:%s", Cfg.ComfyLite.Host, Cfg.ComfyLite.Port),
+			Weight: 1,
+		}}
+	} else {
+		nodes, err := parseComfyLiteNodes(nodesStr)
+		if err != nil {
+			log.Fatalf("FATAL: Invalid COMFYLITE_NODES value: %v", err)
+		}
+		Cfg.ComfyLite.Nodes = nodes
+	}
+
+	// --- OpenAI compatible images API ---
+	Cfg.OpenAI.BaseURL = strings.TrimRight(getEnv("OPENAI_IMAGES_BASE_URL", "https://api.openai.com"), "/")
+	Cfg.OpenAI.APIKey = getEnv("OPENAI_IMAGES_API_KEY", "")
+	Cfg.OpenAI.Model = getEnv("OPENAI_IMAGES_MODEL", "dall-e-2")
+	Cfg.OpenAI.MaxPerRequest = getEnvInt("OPENAI_IMAGES_MAX_PER_REQUEST", 1)
+
+	// --- Generation ---
+	Cfg.Generation.Provider = getEnv("GEN_PROVIDER", "comfylite")
+	if Cfg.Generation.Provider != "comfylite" && Cfg.Generation.Provider != "openai" {
+		log.Fatalf("FATAL: Invalid GEN_PROVIDER value '%s', expected 'comfylite' or 'openai'", Cfg.Generation.Provider)
+	}
+	Cfg.Generation.QueueWorkers = getEnvInt("GEN_QUEUE_WORKERS", 4)
+	Cfg.Generation.QueuePollInterval = getEnvDuration("GEN_QUEUE_POLL_INTERVAL", time.Second)
+	Cfg.Generation.QueueMaxAttempts = getEnvInt("GEN_QUEUE_MAX_ATTEMPTS", 5)
+	Cfg.Generation.QueueLease = getEnvDuration("GEN_QUEUE_LEASE", 2*time.Minute)
+	Cfg.Generation.ResultTimeout = getEnvDuration("GEN_RESULT_TIMEOUT", 30*time.Minute)
+	Cfg.Generation.MaxPendingPromptsPerUser = getEnvInt("GEN_MAX_PENDING_PROMPTS_PER_USER", 3)
+	Cfg.Generation.MaxQueuedImagesPerUser = getEnvInt("GEN_MAX_QUEUED_IMAGES_PER_USER", 20)
+
+	// --- Pricing ---
+	Cfg.Pricing.RulesFile = getEnv("PRICING_RULES_FILE", "")
+	Cfg.Pricing.ReloadInterval = getEnvDuration("PRICING_RELOAD_INTERVAL", 30*time.Second)
 
 	// --- Stripe ---
 	Cfg.Stripe.Secret = getEnv("STRIPE_SECRET", "")
 	Cfg.Stripe.VerificationSecret = getEnv("STRIPE_WEBHOOK_VERIFICATION_SECRET", "")
 
+	// --- Admin ---
+	for _, value := range strings.Split(getEnv("ADMIN_USER_IDS", ""), ",") {
+		if value = strings.TrimSpace(value); value == "" {
+			continue
+		}
+		id, err := uuid.Parse(value)
+		if err != nil {
+			log.Fatalf("FATAL: Invalid ADMIN_USER_IDS value '%s', expected user IDs", value)
+		}
+		Cfg.Admin.UserIDs = append(Cfg.Admin.UserIDs, id)
+	}
+	Cfg.Admin.StuckPromptAfter = getEnvDuration("ADMIN_STUCK_PROMPT_AFTER", 15*time.Minute)
+
+	// --- Business ---
+	Cfg.Business.Name = getEnv("BUSINESS_NAME", "WonderPicAI")
+	for _, line := range strings.Split(getEnv("BUSINESS_ADDRESS", ""), ";") {
+		if line = strings.TrimSpace(line); line != "" {
+			Cfg.Business.Address = append(Cfg.Business.Address, line)
+		}
+	}
+	Cfg.Business.TaxID = getEnv("BUSINESS_TAX_ID", "")
+	Cfg.Business.Email = getEnv("BUSINESS_EMAIL", "wonderpicai@example.com")
+	Cfg.Business.TaxLabel = getEnv("TAX_LABEL", "VAT")
+	Cfg.Business.InvoicePrefix = getEnv("INVOICE_PREFIX", "WP-")
+	taxRate, err := strconv.ParseFloat(getEnv("TAX_RATE_PERCENT", "0"), 64)
+	if err != nil || taxRate < 0 || taxRate >= 100 {
+		log.Fatalf("FATAL: Invalid TAX_RATE_PERCENT value, expected a percentage such as 20 or 7.5")
+	}
+	Cfg.Business.TaxRateBps = int(math.Round(taxRate * 100))
+
+	// --- Mail ---
+	Cfg.Mail.Driver = getEnv("MAIL_DRIVER", "log")
+	if Cfg.Mail.Driver != "log" && Cfg.Mail.Driver != "smtp" {
+		log.Fatalf("FATAL: Invalid MAIL_DRIVER value '%s', expected 'log' or 'smtp'", Cfg.Mail.Driver)
+	}
+	Cfg.Mail.From = getEnv("MAIL_FROM", Cfg.Business.Email)
+	Cfg.Mail.SMTPHost = getEnv("SMTP_HOST", "localhost")
+	Cfg.Mail.SMTPPort = getEnv("SMTP_PORT", "587")
+	Cfg.Mail.SMTPUsername = getEnv("SMTP_USERNAME", "")
+	Cfg.Mail.SMTPPassword = getEnv("SMTP_PASSWORD", "")
+
+	// --- Webhooks ---
+	Cfg.Webhook.Workers = getEnvInt("WEBHOOK_WORKERS", 2)
+	Cfg.Webhook.PollInterval = getEnvDuration("WEBHOOK_POLL_INTERVAL", 2*time.Second)
+	Cfg.Webhook.MaxAttempts = getEnvInt("WEBHOOK_MAX_ATTEMPTS", 8)
+	Cfg.Webhook.Timeout = getEnvDuration("WEBHOOK_TIMEOUT", 10*time.Second)
+	Cfg.Webhook.LowCreditsThreshold = getEnvInt("WEBHOOK_LOW_CREDITS_THRESHOLD", 10)
+	Cfg.Webhook.AllowPrivateTargets = getEnv("WEBHOOK_ALLOW_PRIVATE_TARGETS", strconv.FormatBool(appEnv != "production")) == "true"
+
+	// --- Metrics ---
+	Cfg.Metrics.Addr = getEnv("METRICS_ADDR", "")
+	Cfg.Metrics.Token = getEnv("METRICS_TOKEN", "")
+
+	// --- Tracing ---
+	Cfg.Tracing.OTLPEndpoint = getEnv("TRACING_OTLP_ENDPOINT", "")
+	Cfg.Tracing.ServiceName = getEnv("TRACING_SERVICE_NAME", "wonderpicai")
+	sampleRatio, err := strconv.ParseFloat(getEnv("TRACING_SAMPLE_RATIO", "1"), 64)
+	if err != nil || sampleRatio < 0 || sampleRatio > 1 {
+		log.Fatalf("FATAL: Invalid TRACING_SAMPLE_RATIO value, expected a number from 0 to 1")
+	}
+	Cfg.Tracing.SampleRatio = sampleRatio
+
 	// -- Google Auth ---
 	Cfg.GoogleAuth.ClientSecret = getEnv("GOOGLE_CLIENT_SECRET", "")
 
@@ -131,6 +368,125 @@ func LoadConfig() {
 
 }
 
+// mustParseBaseURL validates an absolute http(s) URL and strips its trailing slash
+func mustParseBaseURL(key, value string) string {
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		log.Fatalf("FATAL: Invalid %s value '%s', expected an absolute http or https URL", key, value)
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		log.Fatalf("FATAL: Invalid %s value '%s', the URL must not have a query or fragment", key, value)
+	}
+	return strings.TrimRight(value, "/")
+}
+
+// parseTrustedProxies parses a comma separated list of IP addresses and CIDR ranges
+func parseTrustedProxies(value string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("entry %q is not a valid CIDR range", entry)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("entry %q is not a valid IP address", entry)
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+
+	return prefixes, nil
+}
+
+// parseComfyLiteNodes parses a comma separated list of `name|url|weight` entries.
+// The weight is optional and defaults to 1.
+func parseComfyLiteNodes(value string) ([]ComfyLiteNode, error) {
+	var nodes []ComfyLiteNode
+	seen := make(map[string]bool)
+
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.Split(entry, "|")
+		if len(parts) < 2 || len(parts) > 3 {
+			return nil, fmt.Errorf("entry %q must have the form name|url|weight", entry)
+		}
+
+		node := ComfyLiteNode{
+			Name:   strings.TrimSpace(parts[0]),
+			URL:    strings.TrimRight(strings.TrimSpace(parts[1]), "/"),
+			Weight: 1,
+		}
+		if node.Name == "" || node.URL == "" {
+			return nil, fmt.Errorf("entry %q has an empty name or url", entry)
+		}
+		if seen[node.Name] {
+			return nil, fmt.Errorf("duplicate node name %q", node.Name)
+		}
+		seen[node.Name] = true
+
+		if len(parts) == 3 {
+			weight, err := strconv.Atoi(strings.TrimSpace(parts[2]))
+			if err != nil || weight < 1 {
+				return nil, fmt.Errorf("entry %q has an invalid weight", entry)
+			}
+			node.Weight = weight
+		}
+
+		nodes = append(nodes, node)
+	}
+
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("no nodes configured")
+	}
+
+	return nodes, nil
+}
+
+// getEnvInt retrieves an integer environment variable or returns the default value
+func getEnvInt(key string, defaultValue int) int {
+	value, exists := os.LookupEnv(key)
+	if !exists || value == "" {
+		return defaultValue
+	}
+
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Warning: Invalid %s value '%s', using default %d: %v", key, value, defaultValue, err)
+		return defaultValue
+	}
+	return i
+}
+
+// getEnvDuration retrieves a duration environment variable (e.g. "15s") or returns the default value
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value, exists := os.LookupEnv(key)
+	if !exists || value == "" {
+		return defaultValue
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Warning: Invalid %s value '%s', using default %s: %v", key, value, defaultValue, err)
+		return defaultValue
+	}
+	return d
+}
+
 // getEnv retrieves the environment variable or returns a default value
 func getEnv(key, defaultValue string) string {
 	if value, exists := os.LookupEnv(key); exists {
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func TestParseComfyLiteNodes(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    []ComfyLiteNode
+		wantErr bool
+	}{
+		{
+			name:  "single node with default weight",
+			value: "gpu1|http://gpu1:8000/",
+			want:  []ComfyLiteNode{{Name: "gpu1", URL: "http://gpu1:8000", Weight: 1}},
+		},
+		{
+			name:  "several nodes with weights and spaces",
+			value: " gpu1 | http://gpu1:8000 | 3 , gpu2|http://gpu2:8000,",
+			want: []ComfyLiteNode{
+				{Name: "gpu1", URL: "http://gpu1:8000", Weight: 3},
+				{Name: "gpu2", URL: "http://gpu2:8000", Weight: 1},
+			},
+		},
+		{name: "empty", value: " , ", wantErr: true},
+		{name: "missing url", value: "gpu1", wantErr: true},
+		{name: "too many parts", value: "gpu1|http://gpu1:8000|1|x", wantErr: true},
+		{name: "empty name", value: "|http://gpu1:8000", wantErr: true},
+		{name: "duplicate name", value: "gpu1|http://a:8000,gpu1|http://b:8000", wantErr: true},
+		{name: "zero weight", value: "gpu1|http://gpu1:8000|0", wantErr: true},
+		{name: "non numeric weight", value: "gpu1|http://gpu1:8000|heavy", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseComfyLiteNodes(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseComfyLiteNodes(%q) error = %v, wantErr %t", tt.value, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseComfyLiteNodes(%q) = %+v, want %+v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    []netip.Prefix
+		wantErr bool
+	}{
+		{name: "empty", value: "", want: nil},
+		{
+			name:  "single addresses",
+			value: "10.0.0.1, ::1",
+			want:  []netip.Prefix{netip.MustParsePrefix("10.0.0.1/32"), netip.MustParsePrefix("::1/128")},
+		},
+		{
+			name:  "ranges are masked",
+			value: "10.1.2.3/8,fd00::1/64,",
+			want:  []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("fd00::/64")},
+		},
+		{name: "invalid address", value: "10.0.0.256", wantErr: true},
+		{name: "invalid range", value: "10.0.0.0/33", wantErr: true},
+		{name: "host name", value: "proxy.internal", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTrustedProxies(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseTrustedProxies(%q) error = %v, wantErr %t", tt.value, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseTrustedProxies(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
@@ -4,12 +4,17 @@ import (
 	"context"
 	"errors"
 
+	"github.com/CP-Payne/wonderpicai/internal/domain"
 	"github.com/google/uuid"
 )
 
 type contextKey string
 
-const userIDKey = contextKey("userID")
+const (
+	userIDKey = contextKey("userID")
+	apiKeyKey = contextKey("apiKey")
+	roleKey   = contextKey("role")
+)
 
 var ErrUserNotAuthenticated = errors.New("no user ID found in context")
 
@@ -43,3 +48,37 @@ func IsAuthenticated(ctx context.Context) bool {
 	_, ok := userIDFromContext(ctx)
 	return ok
 }
+
+// NewContextWithAPIKey records the API key a request was authenticated with
+func NewContextWithAPIKey(ctx context.Context, key *domain.APIKey) context.Context {
+	return context.WithValue(ctx, apiKeyKey, key)
+}
+
+// APIKey returns the API key of the request, requests authenticated with a session have none
+func APIKey(ctx context.Context) (*domain.APIKey, bool) {
+	key, ok := ctx.Value(apiKeyKey).(*domain.APIKey)
+	return key, ok && key != nil
+}
+
+// NewContextWithRole records the role of the signed in user, it is set by the middleware
+// that loads the user
+func NewContextWithRole(ctx context.Context, role domain.Role) context.Context {
+	return context.WithValue(ctx, roleKey, role)
+}
+
+// Role returns the role of the signed in user, if a middleware loaded it
+func Role(ctx context.Context) (domain.Role, bool) {
+	role, ok := ctx.Value(roleKey).(domain.Role)
+	return role, ok
+}
+
+// HasRole reports whether the signed in user has at least the given role
+func HasRole(ctx context.Context, required domain.Role) bool {
+	role, ok := Role(ctx)
+	return ok && role.Allows(required)
+}
+
+// IsStaff reports whether the signed in user has access to the admin area
+func IsStaff(ctx context.Context) bool {
+	return HasRole(ctx, domain.RoleSupport)
+}
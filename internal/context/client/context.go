@@ -0,0 +1,23 @@
+package client
+
+import "context"
+
+type contextKey string
+
+const infoKey = contextKey("clientInfo")
+
+// Info describes the client a request came from
+type Info struct {
+	IP        string
+	UserAgent string
+}
+
+func NewContext(ctx context.Context, info Info) context.Context {
+	return context.WithValue(ctx, infoKey, info)
+}
+
+// FromContext returns the client of the current request, empty outside of requests
+func FromContext(ctx context.Context) Info {
+	info, _ := ctx.Value(infoKey).(Info)
+	return info
+}
@@ -0,0 +1,32 @@
+package forwarded
+
+import (
+	"context"
+	"net/http"
+)
+
+type contextKey string
+
+const schemeKey = contextKey("forwardedScheme")
+
+// NewContextWithScheme stores the scheme a trusted proxy received the request with
+func NewContextWithScheme(ctx context.Context, scheme string) context.Context {
+	return context.WithValue(ctx, schemeKey, scheme)
+}
+
+func schemeFromContext(ctx context.Context) (string, bool) {
+	val, ok := ctx.Value(schemeKey).(string)
+	if !ok {
+		return "", false
+	}
+
+	return val, true
+}
+
+// IsSecure reports whether the client connected over TLS, either to us or to a trusted proxy
+func IsSecure(r *http.Request) bool {
+	if scheme, ok := schemeFromContext(r.Context()); ok {
+		return scheme == "https"
+	}
+	return r.TLS != nil
+}
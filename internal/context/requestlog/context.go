@@ -0,0 +1,89 @@
+package requestlog
+
+import (
+	"context"
+	"sync"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+type contextKey string
+
+const requestKey = contextKey("requestLog")
+
+// request holds what is known about the current request. The user only becomes known once
+// the auth middleware ran deeper in the chain, so it is set in place for the access log to see.
+type request struct {
+	id     string
+	mu     sync.RWMutex
+	userID string
+}
+
+// NewContext returns ctx carrying the ID of the request it belongs to
+func NewContext(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestKey, &request{id: requestID})
+}
+
+func fromContext(ctx context.Context) (*request, bool) {
+	req, ok := ctx.Value(requestKey).(*request)
+	return req, ok && req != nil
+}
+
+// ID returns the ID of the current request, empty outside of requests
+func ID(ctx context.Context) string {
+	if req, ok := fromContext(ctx); ok {
+		return req.id
+	}
+	return ""
+}
+
+// SetUserID records the user the current request was authenticated as
+func SetUserID(ctx context.Context, userID string) {
+	if req, ok := fromContext(ctx); ok {
+		req.mu.Lock()
+		req.userID = userID
+		req.mu.Unlock()
+	}
+}
+
+// UserID returns the user the current request was authenticated as, empty if it was not
+func UserID(ctx context.Context) string {
+	if req, ok := fromContext(ctx); ok {
+		req.mu.RLock()
+		defer req.mu.RUnlock()
+		return req.userID
+	}
+	return ""
+}
+
+// Fields returns the request ID, the user, the chi route pattern and the trace ID of the
+// current request, leaving out those that are not known
+func Fields(ctx context.Context) []zap.Field {
+	var fields []zap.Field
+	if id := ID(ctx); id != "" {
+		fields = append(fields, zap.String("requestID", id))
+	}
+	if userID := UserID(ctx); userID != "" {
+		fields = append(fields, zap.String("userID", userID))
+	}
+	if rctx := chi.RouteContext(ctx); rctx != nil && rctx.RoutePattern() != "" {
+		fields = append(fields, zap.String("route", rctx.RoutePattern()))
+	}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		fields = append(fields, zap.String("traceID", spanContext.TraceID().String()))
+	}
+	return fields
+}
+
+// Logger returns base enriched with the fields of the current request, so that log lines can
+// be correlated with the access log and the error ID shown to the user. Outside of requests
+// base is returned as is.
+func Logger(ctx context.Context, base *zap.Logger) *zap.Logger {
+	fields := Fields(ctx)
+	if len(fields) == 0 {
+		return base
+	}
+	return base.With(fields...)
+}
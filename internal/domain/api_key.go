@@ -0,0 +1,76 @@
+package domain
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type APIScope string
+
+const (
+	ScopeGenerate     APIScope = "generate"
+	ScopeGalleryRead  APIScope = "gallery:read"
+	ScopeGalleryWrite APIScope = "gallery:write"
+	ScopeWalletRead   APIScope = "wallet:read"
+)
+
+// APIScopes lists every scope an API key can be granted
+var APIScopes = []APIScope{ScopeGenerate, ScopeGalleryRead, ScopeGalleryWrite, ScopeWalletRead}
+
+func (s APIScope) Valid() bool {
+	return slices.Contains(APIScopes, s)
+}
+
+// APIKeyPrefix starts every API key so keys are recognisable in the Authorization header and in leaked secrets
+const APIKeyPrefix = "wp_"
+
+// APIKey is a personal key authenticating JSON API requests for a user.
+// The secret is shown once on creation, only its SHA-256 hash is stored.
+type APIKey struct {
+	BaseModel
+	UserID uuid.UUID `gorm:"type:uuid;not null;index"`
+	Name   string    `gorm:"not null"`
+	// Prefix is the public part of the key used to look it up, e.g. "wp_3f9a1c2e"
+	Prefix string `gorm:"uniqueIndex;not null"`
+	// SecretHash is the hex encoded SHA-256 of the full key
+	SecretHash string `gorm:"not null"`
+	// Scopes are stored space separated
+	Scopes     string `gorm:"not null"`
+	LastUsedAt *time.Time
+	// ExpiresAt is nil for keys that do not expire
+	ExpiresAt *time.Time
+	RevokedAt *time.Time
+}
+
+func (k *APIKey) ScopeList() []APIScope {
+	fields := strings.Fields(k.Scopes)
+	scopes := make([]APIScope, len(fields))
+	for i, f := range fields {
+		scopes[i] = APIScope(f)
+	}
+	return scopes
+}
+
+func (k *APIKey) HasScope(scope APIScope) bool {
+	return slices.Contains(k.ScopeList(), scope)
+}
+
+// Usable reports whether the key is neither revoked nor expired at now
+func (k *APIKey) Usable(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
+
+// JoinScopes returns scopes in the stored format
+func JoinScopes(scopes []APIScope) string {
+	parts := make([]string, len(scopes))
+	for i, s := range scopes {
+		parts[i] = string(s)
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,113 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AuditAction string
+
+const (
+	AuditSignup        AuditAction = "auth.signup"
+	AuditLogin         AuditAction = "auth.login"
+	AuditLoginFailed   AuditAction = "auth.login_failed"
+	AuditGoogleLogin   AuditAction = "auth.google_login"
+	AuditAPIKeyCreated AuditAction = "api_key.created"
+	AuditAPIKeyRevoked AuditAction = "api_key.revoked"
+
+	AuditCreditsPurchased AuditAction = "credits.purchased"
+	AuditPaymentReversed  AuditAction = "payment.reversed"
+	AuditPaymentMismatch  AuditAction = "payment.amount_mismatch"
+	AuditPromoRedeemed    AuditAction = "promo.redeemed"
+
+	AuditImageDeleted        AuditAction = "image.deleted"
+	AuditFailedImagesDeleted AuditAction = "image.failed_deleted"
+	AuditWebhookCreated      AuditAction = "webhook.created"
+	AuditWebhookDeleted      AuditAction = "webhook.deleted"
+
+	AuditUserRoleChanged AuditAction = "user.role_changed"
+	AuditUserDisabled    AuditAction = "user.disabled"
+	AuditUserEnabled     AuditAction = "user.enabled"
+	AuditCreditsGranted  AuditAction = "credits.granted"
+	AuditCreditsRevoked  AuditAction = "credits.revoked"
+	AuditPromptRetried   AuditAction = "prompt.retried"
+	AuditPackageCreated  AuditAction = "package.created"
+	AuditPackageUpdated  AuditAction = "package.updated"
+	AuditPackageDeleted  AuditAction = "package.deleted"
+	AuditPromoCreated    AuditAction = "promo.created"
+	AuditPromoUpdated    AuditAction = "promo.updated"
+)
+
+// AuditActions lists every audited action, for filtering the audit log
+var AuditActions = []AuditAction{
+	AuditSignup, AuditLogin, AuditLoginFailed, AuditGoogleLogin, AuditAPIKeyCreated, AuditAPIKeyRevoked,
+	AuditCreditsPurchased, AuditPaymentReversed, AuditPaymentMismatch, AuditPromoRedeemed,
+	AuditImageDeleted, AuditFailedImagesDeleted, AuditWebhookCreated, AuditWebhookDeleted,
+	AuditUserRoleChanged, AuditUserDisabled, AuditUserEnabled, AuditCreditsGranted, AuditCreditsRevoked,
+	AuditPromptRetried, AuditPackageCreated, AuditPackageUpdated, AuditPackageDeleted, AuditPromoCreated, AuditPromoUpdated,
+}
+
+// AuditEvent records a security or money relevant action. Events are append-only and
+// hash-chained: every event includes the hash of the event before it, so changing or
+// removing an event breaks the chain from that event on.
+type AuditEvent struct {
+	ID uuid.UUID `gorm:"type:uuid;primarykey;not null"`
+	// Sequence numbers the chain without gaps, starting at 1
+	Sequence  int64     `gorm:"uniqueIndex;not null"`
+	CreatedAt time.Time `gorm:"index;not null"`
+	// ActorID is empty for anonymous visitors and the system, e.g. a failed login or a
+	// payment provider event
+	ActorID    *uuid.UUID  `gorm:"type:uuid;index"`
+	ActorEmail string      `gorm:"not null;default:''"`
+	Action     AuditAction `gorm:"size:64;not null;index"`
+	// TargetType and TargetID name the record the action changed, e.g. "user" and its ID
+	TargetType string `gorm:"size:32;not null"`
+	TargetID   string `gorm:"index"`
+	// Metadata is a JSON object with the parameters of the action. It is kept as text so
+	// the stored bytes are the hashed bytes.
+	Metadata  string `gorm:"type:text"`
+	IP        string
+	UserAgent string
+	PrevHash  string `gorm:"size:64;not null"`
+	Hash      string `gorm:"size:64;not null"`
+}
+
+// ComputeHash returns the hex SHA-256 of the event's content and PrevHash
+func (e *AuditEvent) ComputeHash() string {
+	content := struct {
+		Sequence   int64      `json:"sequence"`
+		PrevHash   string     `json:"prevHash"`
+		ID         uuid.UUID  `json:"id"`
+		CreatedAt  string     `json:"createdAt"`
+		ActorID    *uuid.UUID `json:"actorId"`
+		ActorEmail string     `json:"actorEmail"`
+		Action     string     `json:"action"`
+		TargetType string     `json:"targetType"`
+		TargetID   string     `json:"targetId"`
+		Metadata   string     `json:"metadata"`
+		IP         string     `json:"ip"`
+		UserAgent  string     `json:"userAgent"`
+	}{
+		Sequence:   e.Sequence,
+		PrevHash:   e.PrevHash,
+		ID:         e.ID,
+		CreatedAt:  e.CreatedAt.UTC().Format(time.RFC3339Nano),
+		ActorID:    e.ActorID,
+		ActorEmail: e.ActorEmail,
+		Action:     string(e.Action),
+		TargetType: e.TargetType,
+		TargetID:   e.TargetID,
+		Metadata:   e.Metadata,
+		IP:         e.IP,
+		UserAgent:  e.UserAgent,
+	}
+
+	// Marshalling a struct of strings and numbers cannot fail
+	raw, _ := json.Marshal(content)
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
+}
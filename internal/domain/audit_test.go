@@ -0,0 +1,77 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestAuditEventComputeHash(t *testing.T) {
+	actorID := uuid.MustParse("6f1c2a52-3b5e-4d8a-9c47-1e2f3a4b5c6d")
+	base := AuditEvent{
+		ID:         uuid.MustParse("0b8e6d1a-7c2f-4e3b-a5d9-8f7e6c5b4a39"),
+		Sequence:   7,
+		CreatedAt:  time.Date(2026, 3, 14, 9, 26, 53, 589793000, time.UTC),
+		ActorID:    &actorID,
+		ActorEmail: "admin@example.com",
+		Action:     AuditCreditsGranted,
+		TargetType: "user",
+		TargetID:   "42",
+		Metadata:   `{"credits":100}`,
+		IP:         "203.0.113.7",
+		UserAgent:  "curl/8.0",
+		PrevHash:   "4f53cda18c2baa0c0354bb5f9a3ecbe5ed12ab4d8e11ba873c2f11161202b945",
+	}
+	want := base.ComputeHash()
+
+	if len(want) != 64 {
+		t.Fatalf("ComputeHash() = %q, want 64 hex characters", want)
+	}
+
+	t.Run("time zone", func(t *testing.T) {
+		event := base
+		event.CreatedAt = base.CreatedAt.In(time.FixedZone("UTC+2", 2*60*60))
+		if got := event.ComputeHash(); got != want {
+			t.Errorf("ComputeHash() = %q, want %q for the same instant", got, want)
+		}
+	})
+
+	t.Run("stored hash is not hashed", func(t *testing.T) {
+		event := base
+		event.Hash = want
+		if got := event.ComputeHash(); got != want {
+			t.Errorf("ComputeHash() = %q, want %q", got, want)
+		}
+	})
+
+	otherActor := uuid.MustParse("9a8b7c6d-5e4f-4a3b-8c2d-1e0f9a8b7c6d")
+	tests := []struct {
+		name   string
+		modify func(e *AuditEvent)
+	}{
+		{name: "id", modify: func(e *AuditEvent) { e.ID = uuid.MustParse("1c9f7e2b-8d3a-4f4c-b6e0-9a8f7d6c5b4a") }},
+		{name: "sequence", modify: func(e *AuditEvent) { e.Sequence++ }},
+		{name: "created at", modify: func(e *AuditEvent) { e.CreatedAt = e.CreatedAt.Add(time.Nanosecond) }},
+		{name: "actor", modify: func(e *AuditEvent) { e.ActorID = &otherActor }},
+		{name: "no actor", modify: func(e *AuditEvent) { e.ActorID = nil }},
+		{name: "actor email", modify: func(e *AuditEvent) { e.ActorEmail = "other@example.com" }},
+		{name: "action", modify: func(e *AuditEvent) { e.Action = AuditCreditsRevoked }},
+		{name: "target type", modify: func(e *AuditEvent) { e.TargetType = "payment" }},
+		{name: "target id", modify: func(e *AuditEvent) { e.TargetID = "43" }},
+		{name: "metadata", modify: func(e *AuditEvent) { e.Metadata = `{"credits":1000}` }},
+		{name: "ip", modify: func(e *AuditEvent) { e.IP = "203.0.113.8" }},
+		{name: "user agent", modify: func(e *AuditEvent) { e.UserAgent = "curl/8.1" }},
+		{name: "previous hash", modify: func(e *AuditEvent) { e.PrevHash = "" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := base
+			tt.modify(&event)
+			if got := event.ComputeHash(); got == want {
+				t.Errorf("ComputeHash() did not change when the %s changed", tt.name)
+			}
+		})
+	}
+}
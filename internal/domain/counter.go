@@ -0,0 +1,12 @@
+package domain
+
+// Counter is a named gapless sequence, e.g. for invoice numbers. Rows are locked until the
+// transaction taking a number commits, so a number is only issued once it is committed and
+// a rolled back transaction leaves no gap.
+type Counter struct {
+	Name  string `gorm:"primaryKey"`
+	Value int64  `gorm:"not null;default:0"`
+}
+
+// InvoiceCounter is the Counter invoice numbers are taken from
+const InvoiceCounter = "invoice"
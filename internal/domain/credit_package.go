@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// CreditPackage is a pack of credits offered on the purchase page
+type CreditPackage struct {
+	BaseModel
+	Name    string `gorm:"not null"`
+	Credits int    `gorm:"not null;check:credits > 0"`
+	// PriceMinor is the default price in the currency's minor unit, e.g. cents
+	PriceMinor int64  `gorm:"not null;check:price_minor >= 0"`
+	Currency   string `gorm:"size:3;not null"`
+	Active     bool   `gorm:"not null;default:true;index"`
+	SortOrder  int    `gorm:"not null;default:0"`
+	// Badge is an optional label such as "Best value" shown on the package
+	Badge string
+	// Prices are the prices in currencies other than the default one
+	Prices []CreditPackagePrice `gorm:"foreignKey:PackageID;references:ID"`
+}
+
+// CreditPackagePrice is the price of a package in a specific currency
+type CreditPackagePrice struct {
+	BaseModel
+	PackageID  uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_package_price_currency"`
+	Currency   string    `gorm:"size:3;not null;uniqueIndex:idx_package_price_currency"`
+	PriceMinor int64     `gorm:"not null;check:price_minor >= 0"`
+}
+
+// PriceIn returns the price of the package in the requested currency. Packages without a
+// price in that currency are sold in their default currency.
+func (p *CreditPackage) PriceIn(currency string) (priceMinor int64, priceCurrency string) {
+	currency = strings.ToLower(currency)
+	for _, price := range p.Prices {
+		if price.Currency == currency {
+			return price.PriceMinor, price.Currency
+		}
+	}
+	return p.PriceMinor, p.Currency
+}
@@ -0,0 +1,33 @@
+package domain
+
+import "github.com/google/uuid"
+
+type CreditTransactionKind string
+
+const (
+	CreditGeneration       CreditTransactionKind = "generation"
+	CreditRefund           CreditTransactionKind = "refund"
+	CreditPurchase         CreditTransactionKind = "purchase"
+	CreditPurchaseReversal CreditTransactionKind = "purchase_reversal"
+	CreditSubscription     CreditTransactionKind = "subscription"
+	CreditPromo            CreditTransactionKind = "promo"
+	CreditAdminGrant       CreditTransactionKind = "admin_grant"
+	CreditAdminRevoke      CreditTransactionKind = "admin_revoke"
+)
+
+// CreditTransaction is an entry of the credit ledger, written in the same transaction as
+// the wallet change it records.
+type CreditTransaction struct {
+	BaseModel
+	UserID uuid.UUID `gorm:"type:uuid;not null;index"`
+	// Amount is positive for credits added and negative for credits taken
+	Amount int `gorm:"not null"`
+	// Balance is the wallet balance after the change
+	Balance uint                  `gorm:"not null"`
+	Kind    CreditTransactionKind `gorm:"size:32;not null"`
+	// Reference is the ID of what caused the change, e.g. the prompt or payment
+	Reference string
+	Reason    string
+	// ActorID is the admin who made a manual change
+	ActorID *uuid.UUID `gorm:"type:uuid"`
+}
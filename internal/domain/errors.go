@@ -6,12 +6,55 @@ var (
 	ErrUserNotFound            = errors.New("user not found")
 	ErrEmailAlreadyExists      = errors.New("email already exists")
 	ErrInvalidCredentials      = errors.New("invalid credentials")
+	ErrAccountDisabled         = errors.New("account disabled")
+	ErrInvalidRole             = errors.New("invalid role")
 	ErrInsufficientPermissions = errors.New("insufficient permissions")
+	ErrOwnAccount              = errors.New("cannot change your own account")
+	ErrReasonRequired          = errors.New("a reason is required")
+	ErrInvalidCreditAmount     = errors.New("invalid credit amount")
 	ErrDuplicateEntry          = errors.New("entry with unique field already exists")
 	ErrImageNotFound           = errors.New("image not found")
+	ErrPromptNotFound          = errors.New("prompt not found")
+	ErrPromptNotRetryable      = errors.New("prompt cannot be retried")
+	ErrPromptBackendMismatch   = errors.New("prompt belongs to another generation backend")
+	ErrPromptAlreadyFinished   = errors.New("prompt already finished")
 	ErrRecordNotFound          = errors.New("record not found")
 	ErrInsufficientFunds       = errors.New("insufficient funds")
 	ErrInvalidPurchaseOption   = errors.New("invalid purchase option")
+	ErrUnsupportedCurrency     = errors.New("unsupported currency")
+	ErrPaymentAmountMismatch   = errors.New("paid amount does not match the payment")
+
+	// Promo code errors
+	ErrPromoCodeInvalid       = errors.New("invalid promo code")
+	ErrPromoCodeExpired       = errors.New("promo code expired")
+	ErrPromoCodeExhausted     = errors.New("promo code fully redeemed")
+	ErrPromoCodeAlreadyUsed   = errors.New("promo code already used")
+	ErrPromoCodeNotApplicable = errors.New("promo code not applicable")
+
+	ErrNoSubscription      = errors.New("no active subscription")
+	ErrAlreadySubscribed   = errors.New("already subscribed")
+	ErrInvalidSubscription = errors.New("invalid subscription plan")
+
+	ErrReceiptUnavailable = errors.New("receipt not available for an unpaid payment")
+
+	ErrInvalidAPIKey   = errors.New("invalid API key")
+	ErrInvalidAPIScope = errors.New("invalid API key scope")
+
+	ErrInvalidWebhookURL   = errors.New("invalid webhook URL")
+	ErrInvalidWebhookEvent = errors.New("invalid webhook event")
 
 	ErrUnhandledEvent = errors.New("unhandled event")
+
+	// Image generation backend errors
+	ErrGenerationUnavailable  = errors.New("image generation backend unavailable")
+	ErrGenerationBusy         = errors.New("image generation backend busy")
+	ErrGenerationRejected     = errors.New("image generation request rejected")
+	ErrInvalidGenerationInput = errors.New("invalid image generation input")
+	// ErrGenerationOutcomeUnknown means the request may have reached the backend before it
+	// failed, so sending it again could queue the prompt twice
+	ErrGenerationOutcomeUnknown = errors.New("image generation request outcome unknown")
+
+	ErrGenerationLimitReached = errors.New("generation limit reached")
+
+	ErrInvalidPricingRules = errors.New("invalid pricing rules")
 )
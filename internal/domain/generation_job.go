@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type JobStatus string
+
+const (
+	// JobQueued jobs are waiting to be submitted to the generation backend
+	JobQueued JobStatus = "queued"
+	// JobRunning jobs are claimed by a worker. The claim expires at LockedUntil so that
+	// jobs held by a crashed worker are picked up again.
+	JobRunning JobStatus = "running"
+	// JobSubmitted jobs were accepted by the backend, the result arrives through the prompt
+	JobSubmitted JobStatus = "submitted"
+	// JobDead jobs ran out of attempts or were rejected. Their credits are refunded.
+	JobDead JobStatus = "dead"
+)
+
+type GenerationJob struct {
+	BaseModel
+	PromptID    uuid.UUID `gorm:"type:uuid;uniqueIndex;not null"`
+	Prompt      Prompt    `gorm:"foreignKey:PromptID;references:ID"`
+	UserID      uuid.UUID `gorm:"type:uuid;index;not null"`
+	Status      JobStatus `gorm:"index;not null"`
+	Attempts    int       `gorm:"not null;default:0"`
+	MaxAttempts int       `gorm:"not null"`
+	RunAt       time.Time `gorm:"index;not null"`
+	LockedUntil *time.Time
+	LastError   string
+	// Generated counts the images stored by earlier attempts, which are not generated again
+	Generated int `gorm:"not null;default:0"`
+}
+
+// RemainingImages returns the number of images the job still has to generate
+func (j *GenerationJob) RemainingImages() int {
+	return j.Prompt.ImageCount - j.Generated
+}
+
+// GenerationQuota limits how much work a single user may have in progress at once.
+// Zero values disable the respective limit.
+type GenerationQuota struct {
+	MaxPendingPrompts int
+	MaxQueuedImages   int
+}
@@ -30,17 +30,48 @@ func (s Status) String() string {
 	}
 }
 
+// APIName returns the name of the status in the JSON API and in webhook events
+func (s Status) APIName() string {
+	switch s {
+	case Failed:
+		return "failed"
+	case Pending:
+		return "pending"
+	case PartiallyCompleted:
+		return "partially_completed"
+	case Completed:
+		return "completed"
+	default:
+		return "unknown"
+	}
+}
+
 type Prompt struct {
 	BaseModel
-	ExternalPromptID uuid.UUID `gorm:"type:uuid;uniqueIndex;not null"`
+	// ExternalPromptID is assigned once the generation backend accepts the prompt
+	ExternalPromptID *uuid.UUID `gorm:"type:uuid;uniqueIndex"`
+	Backend          string     `gorm:"index"`
 	UserID           uuid.UUID
-	Cost             int `gorm:"not null"`
+	Text             string `gorm:"type:text"`
+	Cost             int    `gorm:"not null"`
+	PriceVersion     string
 	ImageCount       int
+	Size             string
 	Width            int
 	Height           int
 	Images           []Image `gorm:"foreignKey:PromptID;references:ID"`
 	Status           Status
 	LastChecked      time.Time
+	// TraceParent is the W3C trace context of the request that created the prompt, so that
+	// its submission and completion can be followed in the same trace
+	TraceParent string `gorm:"size:55"`
+}
+
+// AcceptsResultFrom reports whether the named backend may report the prompt's result. A prompt
+// submitted to a named backend only accepts results reported under that name, an unnamed
+// report included.
+func (p *Prompt) AcceptsResultFrom(backend string) bool {
+	return p.Backend == "" || p.Backend == backend
 }
 
 type Image struct {
@@ -0,0 +1,31 @@
+package domain
+
+// ImageSize is an output size users can pick for a generation. The credit cost of
+// each size is defined by the active PricingRules.
+type ImageSize struct {
+	Key         string
+	Label       string
+	AspectRatio string
+	Width       int
+	Height      int
+}
+
+const DefaultImageSize = "square"
+
+// ImageSizes is the catalog of supported sizes, from smallest to largest
+var ImageSizes = []ImageSize{
+	{Key: "square", Label: "Square", AspectRatio: "1:1", Width: 512, Height: 512},
+	{Key: "portrait", Label: "Portrait", AspectRatio: "2:3", Width: 512, Height: 768},
+	{Key: "landscape", Label: "Landscape", AspectRatio: "3:2", Width: 768, Height: 512},
+	{Key: "hd", Label: "HD", AspectRatio: "1:1", Width: 1024, Height: 1024},
+}
+
+// ImageSizeByKey looks up a size in the catalog
+func ImageSizeByKey(key string) (ImageSize, bool) {
+	for _, size := range ImageSizes {
+		if size.Key == key {
+			return size, true
+		}
+	}
+	return ImageSize{}, false
+}
@@ -0,0 +1,48 @@
+package domain
+
+import "testing"
+
+func TestStatusAPIName(t *testing.T) {
+	tests := []struct {
+		status Status
+		want   string
+	}{
+		{status: Failed, want: "failed"},
+		{status: Pending, want: "pending"},
+		{status: PartiallyCompleted, want: "partially_completed"},
+		{status: Completed, want: "completed"},
+		{status: Status(42), want: "unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := tt.status.APIName(); got != tt.want {
+				t.Errorf("Status(%d).APIName() = %q, want %q", tt.status, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPromptAcceptsResultFrom(t *testing.T) {
+	tests := []struct {
+		name          string
+		promptBackend string
+		backend       string
+		want          bool
+	}{
+		{name: "owning backend", promptBackend: "node-a", backend: "node-a", want: true},
+		{name: "other backend", promptBackend: "node-a", backend: "node-b", want: false},
+		{name: "missing backend", promptBackend: "node-a", backend: "", want: false},
+		{name: "unnamed prompt backend", promptBackend: "", backend: "", want: true},
+		{name: "unnamed prompt backend reported by a node", promptBackend: "", backend: "node-a", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prompt := &Prompt{Backend: tt.promptBackend}
+			if got := prompt.AcceptsResultFrom(tt.backend); got != tt.want {
+				t.Errorf("AcceptsResultFrom(%q) with prompt backend %q = %v, want %v", tt.backend, tt.promptBackend, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,12 @@
+package domain
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// Page selects a window of a listing ordered newest first
+type Page struct {
+	Limit  int
+	Offset int
+}
@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type PaymentStatus string
+
+const (
+	// PaymentPending is a checkout that was started but not paid yet
+	PaymentPending PaymentStatus = "pending"
+	// PaymentProcessing checkouts were paid with a delayed method, e.g. a bank debit, whose
+	// funds have not arrived yet
+	PaymentProcessing PaymentStatus = "processing"
+	// PaymentCompleted payments have been paid and their credits added to the wallet
+	PaymentCompleted PaymentStatus = "completed"
+	// PaymentFailed checkouts could not be created with the provider or their delayed
+	// payment failed
+	PaymentFailed PaymentStatus = "failed"
+	// PaymentRefunded payments were refunded in full, partial refunds stay completed
+	PaymentRefunded PaymentStatus = "refunded"
+	// PaymentDisputed payments were charged back by the customer's bank
+	PaymentDisputed PaymentStatus = "disputed"
+	// PaymentAmountMismatch payments were reported paid with another amount or currency than
+	// the checkout was created with. Their credits are held back until the payment is reviewed.
+	PaymentAmountMismatch PaymentStatus = "amount_mismatch"
+)
+
+// Payment records a credit package purchase or a paid subscription invoice. Amount and
+// currency are what the customer was charged, which is not necessarily the package's
+// default price.
+type Payment struct {
+	BaseModel
+	UserID uuid.UUID `gorm:"type:uuid;not null;index"`
+	// PackageID is uuid.Nil for subscription invoices, which set SubscriptionID instead
+	PackageID      uuid.UUID  `gorm:"type:uuid;not null"`
+	SubscriptionID *uuid.UUID `gorm:"type:uuid;index"`
+	// Description is the package or plan name at the time of purchase
+	Description string
+	// ProviderSessionID is the checkout session at the payment provider
+	ProviderSessionID string        `gorm:"index"`
+	Credits           int           `gorm:"not null"`
+	AmountMinor       int64         `gorm:"not null"`
+	Currency          string        `gorm:"size:3;not null"`
+	Status            PaymentStatus `gorm:"not null;index"`
+	CompletedAt       *time.Time
+	// PromoCodeID is the discount code used for the purchase
+	PromoCodeID *uuid.UUID `gorm:"type:uuid"`
+	// ProviderPaymentID is the payment at the provider that refunds and disputes refer to
+	ProviderPaymentID string `gorm:"index"`
+	// RefundedMinor is the amount refunded so far and ReversedCredits the credits taken back
+	RefundedMinor   int64
+	ReversedCredits int
+	// InvoiceNumber is assigned from a gapless sequence when the first receipt is issued,
+	// together with the tax rate included in the amount
+	InvoiceNumber *int64 `gorm:"uniqueIndex"`
+	TaxRateBps    int
+	InvoicedAt    *time.Time
+}
+
+// CreditsToReverse returns the credits still to take back after a refund of refundedMinor in
+// total, or a dispute. Partial refunds take back the same share of the credits.
+func (p *Payment) CreditsToReverse(refundedMinor int64, disputed bool) int {
+	target := p.Credits
+	if !disputed && p.AmountMinor > 0 && refundedMinor < p.AmountMinor {
+		target = int(int64(p.Credits) * refundedMinor / p.AmountMinor)
+	}
+	return max(0, target-p.ReversedCredits)
+}
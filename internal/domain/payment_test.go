@@ -0,0 +1,34 @@
+package domain
+
+import "testing"
+
+func TestCreditsToReverse(t *testing.T) {
+	tests := []struct {
+		name            string
+		amountMinor     int64
+		reversedCredits int
+		refundedMinor   int64
+		disputed        bool
+		want            int
+	}{
+		{name: "no refund", amountMinor: 1000, refundedMinor: 0, want: 0},
+		{name: "full refund", amountMinor: 1000, refundedMinor: 1000, want: 100},
+		{name: "refund above the amount", amountMinor: 1000, refundedMinor: 1200, want: 100},
+		{name: "partial refund", amountMinor: 1000, refundedMinor: 500, want: 50},
+		{name: "partial refund rounds down", amountMinor: 1000, refundedMinor: 333, want: 33},
+		{name: "second partial refund", amountMinor: 1000, reversedCredits: 30, refundedMinor: 500, want: 20},
+		{name: "already reversed", amountMinor: 1000, reversedCredits: 60, refundedMinor: 500, want: 0},
+		{name: "dispute", amountMinor: 1000, disputed: true, want: 100},
+		{name: "dispute after partial refund", amountMinor: 1000, reversedCredits: 40, refundedMinor: 400, disputed: true, want: 60},
+		{name: "free payment", amountMinor: 0, refundedMinor: 0, want: 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payment := &Payment{Credits: 100, AmountMinor: tt.amountMinor, ReversedCredits: tt.reversedCredits}
+			if got := payment.CreditsToReverse(tt.refundedMinor, tt.disputed); got != tt.want {
+				t.Errorf("CreditsToReverse(%d, %t) = %d, want %d", tt.refundedMinor, tt.disputed, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+)
+
+// PricingRules describe how the credit cost of a generation is calculated.
+// Percentages are whole numbers so that prices stay exact.
+type PricingRules struct {
+	// Version is recorded on every prompt priced with these rules
+	Version string `json:"version"`
+	// SizeCosts is the base cost per image for each key of ImageSizes
+	SizeCosts map[string]int `json:"size_costs"`
+	// ModelPercents scales the cost for a model or workflow, 100 being the base price.
+	// Models that are not listed are charged the base price.
+	ModelPercents map[string]int `json:"model_percents,omitempty"`
+	// IncludedSteps are sampling steps covered by the base price. Every started block of
+	// StepBlock steps above that costs ExtraStepBlockCost per image.
+	IncludedSteps      int `json:"included_steps,omitempty"`
+	StepBlock          int `json:"step_block,omitempty"`
+	ExtraStepBlockCost int `json:"extra_step_block_cost,omitempty"`
+	// PrioritySurchargePercent is added to the cost of prompts that skip ahead in the queue
+	PrioritySurchargePercent int `json:"priority_surcharge_percent,omitempty"`
+	// BulkDiscounts apply the discount of the highest MinImages tier reached
+	BulkDiscounts []BulkDiscount `json:"bulk_discounts,omitempty"`
+}
+
+type BulkDiscount struct {
+	MinImages int `json:"min_images"`
+	Percent   int `json:"percent"`
+}
+
+// PriceQuoteInput is everything the price of a generation can depend on
+type PriceQuoteInput struct {
+	Size       string
+	ImageCount int
+	Model      string
+	Steps      int
+	Priority   bool
+}
+
+type PriceQuote struct {
+	Total int
+	// Version of the rules the quote was calculated with
+	Version string
+}
+
+// DefaultPricingRules are used when no rules file is configured
+func DefaultPricingRules() *PricingRules {
+	return &PricingRules{
+		Version: "builtin-1",
+		SizeCosts: map[string]int{
+			"square":    2,
+			"portrait":  3,
+			"landscape": 3,
+			"hd":        6,
+		},
+	}
+}
+
+// Validate checks that the rules price every catalog size and that all values are sensible.
+func (p *PricingRules) Validate() error {
+	if p.Version == "" {
+		return fmt.Errorf("%w: version is required", ErrInvalidPricingRules)
+	}
+	for _, size := range ImageSizes {
+		cost, ok := p.SizeCosts[size.Key]
+		if !ok {
+			return fmt.Errorf("%w: no cost for size %q", ErrInvalidPricingRules, size.Key)
+		}
+		if cost < 1 {
+			return fmt.Errorf("%w: cost for size %q must be at least 1", ErrInvalidPricingRules, size.Key)
+		}
+	}
+	for model, percent := range p.ModelPercents {
+		if percent < 1 {
+			return fmt.Errorf("%w: percent for model %q must be positive", ErrInvalidPricingRules, model)
+		}
+	}
+	if p.IncludedSteps < 0 || p.StepBlock < 0 || p.ExtraStepBlockCost < 0 {
+		return fmt.Errorf("%w: step pricing must not be negative", ErrInvalidPricingRules)
+	}
+	if p.ExtraStepBlockCost > 0 && p.StepBlock == 0 {
+		return fmt.Errorf("%w: step_block is required when extra steps are charged", ErrInvalidPricingRules)
+	}
+	if p.PrioritySurchargePercent < 0 {
+		return fmt.Errorf("%w: priority surcharge must not be negative", ErrInvalidPricingRules)
+	}
+	for _, d := range p.BulkDiscounts {
+		if d.MinImages < 1 || d.Percent < 0 || d.Percent >= 100 {
+			return fmt.Errorf("%w: bulk discounts need min_images >= 1 and a percent between 0 and 99", ErrInvalidPricingRules)
+		}
+	}
+	return nil
+}
+
+// Quote calculates the total cost of a generation. Surcharges are rounded up and
+// discounts rounded down, and every image costs at least one credit.
+func (p *PricingRules) Quote(in PriceQuoteInput) (PriceQuote, error) {
+	perImage, ok := p.SizeCosts[in.Size]
+	if !ok {
+		return PriceQuote{}, fmt.Errorf("no price for size %q: %w", in.Size, ErrInvalidGenerationInput)
+	}
+	if in.ImageCount < 1 {
+		return PriceQuote{}, fmt.Errorf("image count must be at least 1: %w", ErrInvalidGenerationInput)
+	}
+
+	if p.ExtraStepBlockCost > 0 && in.Steps > p.IncludedSteps {
+		blocks := (in.Steps - p.IncludedSteps + p.StepBlock - 1) / p.StepBlock
+		perImage += blocks * p.ExtraStepBlockCost
+	}
+
+	total := perImage * in.ImageCount
+
+	if percent, ok := p.ModelPercents[in.Model]; ok {
+		total = ceilPercent(total, percent)
+	}
+
+	if in.Priority && p.PrioritySurchargePercent > 0 {
+		total = ceilPercent(total, 100+p.PrioritySurchargePercent)
+	}
+
+	if discount := p.bulkDiscount(in.ImageCount); discount > 0 {
+		total = total * (100 - discount) / 100
+	}
+
+	total = max(total, in.ImageCount)
+
+	return PriceQuote{Total: total, Version: p.Version}, nil
+}
+
+func (p *PricingRules) bulkDiscount(imageCount int) int {
+	discounts := append([]BulkDiscount(nil), p.BulkDiscounts...)
+	sort.Slice(discounts, func(i, j int) bool { return discounts[i].MinImages < discounts[j].MinImages })
+
+	percent := 0
+	for _, d := range discounts {
+		if imageCount >= d.MinImages {
+			percent = d.Percent
+		}
+	}
+	return percent
+}
+
+func ceilPercent(amount, percent int) int {
+	return (amount*percent + 99) / 100
+}
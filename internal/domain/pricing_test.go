@@ -0,0 +1,94 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPricingRulesQuote(t *testing.T) {
+	rules := &PricingRules{
+		Version:                  "test-1",
+		SizeCosts:                map[string]int{"square": 2, "portrait": 3, "landscape": 3, "hd": 6},
+		ModelPercents:            map[string]int{"sdxl": 150, "lite": 50},
+		IncludedSteps:            20,
+		StepBlock:                10,
+		ExtraStepBlockCost:       1,
+		PrioritySurchargePercent: 25,
+		BulkDiscounts:            []BulkDiscount{{MinImages: 8, Percent: 20}, {MinImages: 4, Percent: 10}},
+	}
+
+	tests := []struct {
+		name    string
+		input   PriceQuoteInput
+		want    int
+		wantErr error
+	}{
+		{name: "base price", input: PriceQuoteInput{Size: "square", ImageCount: 1}, want: 2},
+		{name: "per image", input: PriceQuoteInput{Size: "hd", ImageCount: 2}, want: 12},
+		{name: "included steps", input: PriceQuoteInput{Size: "square", ImageCount: 1, Steps: 20}, want: 2},
+		{name: "started step block", input: PriceQuoteInput{Size: "square", ImageCount: 1, Steps: 21}, want: 3},
+		{name: "several step blocks", input: PriceQuoteInput{Size: "square", ImageCount: 2, Steps: 40}, want: 8},
+		{name: "model surcharge rounds up", input: PriceQuoteInput{Size: "square", ImageCount: 1, Model: "sdxl"}, want: 3},
+		{name: "cheaper model", input: PriceQuoteInput{Size: "square", ImageCount: 3, Model: "lite"}, want: 3},
+		{name: "unknown model", input: PriceQuoteInput{Size: "square", ImageCount: 1, Model: "other"}, want: 2},
+		{name: "priority surcharge rounds up", input: PriceQuoteInput{Size: "square", ImageCount: 1, Priority: true}, want: 3},
+		{name: "bulk discount rounds down", input: PriceQuoteInput{Size: "square", ImageCount: 4}, want: 7},
+		{name: "highest bulk tier", input: PriceQuoteInput{Size: "square", ImageCount: 8}, want: 12},
+		{name: "at least one credit per image", input: PriceQuoteInput{Size: "square", ImageCount: 8, Model: "lite"}, want: 8},
+		{name: "unknown size", input: PriceQuoteInput{Size: "huge", ImageCount: 1}, wantErr: ErrInvalidGenerationInput},
+		{name: "no images", input: PriceQuoteInput{Size: "square", ImageCount: 0}, wantErr: ErrInvalidGenerationInput},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quote, err := rules.Quote(tt.input)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Quote() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Quote() unexpected error: %v", err)
+			}
+			if quote.Total != tt.want {
+				t.Errorf("Quote() total = %d, want %d", quote.Total, tt.want)
+			}
+			if quote.Version != rules.Version {
+				t.Errorf("Quote() version = %q, want %q", quote.Version, rules.Version)
+			}
+		})
+	}
+}
+
+func TestPricingRulesValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(p *PricingRules)
+		wantErr bool
+	}{
+		{name: "default rules", modify: func(p *PricingRules) {}},
+		{name: "missing version", modify: func(p *PricingRules) { p.Version = "" }, wantErr: true},
+		{name: "missing size", modify: func(p *PricingRules) { delete(p.SizeCosts, "hd") }, wantErr: true},
+		{name: "free size", modify: func(p *PricingRules) { p.SizeCosts["square"] = 0 }, wantErr: true},
+		{name: "free model", modify: func(p *PricingRules) { p.ModelPercents = map[string]int{"lite": 0} }, wantErr: true},
+		{name: "extra steps without block", modify: func(p *PricingRules) { p.ExtraStepBlockCost = 1 }, wantErr: true},
+		{name: "full bulk discount", modify: func(p *PricingRules) {
+			p.BulkDiscounts = []BulkDiscount{{MinImages: 2, Percent: 100}}
+		}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := DefaultPricingRules()
+			tt.modify(rules)
+			err := rules.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidPricingRules) {
+				t.Errorf("Validate() error = %v, want ErrInvalidPricingRules", err)
+			}
+		})
+	}
+}
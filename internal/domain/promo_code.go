@@ -0,0 +1,111 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type PromoKind string
+
+const (
+	// PromoCredits codes add credits to the wallet when redeemed
+	PromoCredits PromoKind = "credits"
+	// PromoDiscount codes take a percentage off the price of a package at checkout
+	PromoDiscount PromoKind = "discount"
+)
+
+// PromoCode is a marketing code users can redeem on the purchase page
+type PromoCode struct {
+	BaseModel
+	// Code is stored in upper case and matched case insensitively
+	Code string    `gorm:"uniqueIndex;not null"`
+	Kind PromoKind `gorm:"not null"`
+	// Credits granted by PromoCredits codes
+	Credits int `gorm:"not null;default:0"`
+	// PercentOff is the discount of PromoDiscount codes
+	PercentOff int `gorm:"not null;default:0"`
+	// PackageID limits a discount to one package, nil applies to all packages
+	PackageID *uuid.UUID `gorm:"type:uuid"`
+	// MaxRedemptions across all users and PerUserLimit per user, 0 is unlimited
+	MaxRedemptions int `gorm:"not null;default:0"`
+	PerUserLimit   int `gorm:"not null;default:1"`
+	// ValidFrom and ValidUntil bound the validity window, nil is open ended
+	ValidFrom  *time.Time
+	ValidUntil *time.Time
+	Active     bool `gorm:"not null;default:true"`
+	// Redeemed is the number of redemptions, filled when listing codes
+	Redeemed int `gorm:"->;-:migration"`
+}
+
+type RedemptionStatus string
+
+const (
+	// RedemptionReserved holds a discount for an open checkout until ReservedUntil
+	RedemptionReserved RedemptionStatus = "reserved"
+	RedemptionRedeemed RedemptionStatus = "redeemed"
+)
+
+// PromoRedemption is a use of a promo code. Reservations whose checkout was neither paid
+// nor renewed before ReservedUntil no longer count against the limits.
+type PromoRedemption struct {
+	BaseModel
+	PromoCodeID   uuid.UUID        `gorm:"type:uuid;not null;index"`
+	UserID        uuid.UUID        `gorm:"type:uuid;not null;index"`
+	PaymentID     *uuid.UUID       `gorm:"type:uuid;index"`
+	Status        RedemptionStatus `gorm:"not null"`
+	ReservedUntil *time.Time
+}
+
+// CheckValid checks that the code is active and within its validity window
+func (p *PromoCode) CheckValid(now time.Time) error {
+	if !p.Active {
+		return ErrPromoCodeInvalid
+	}
+	if p.ValidFrom != nil && now.Before(*p.ValidFrom) {
+		return ErrPromoCodeInvalid
+	}
+	if p.ValidUntil != nil && !now.Before(*p.ValidUntil) {
+		return ErrPromoCodeExpired
+	}
+	return nil
+}
+
+// CheckRedeemable validates everything about a redemption except the redemption limits.
+// packageID is the package bought with a discount code and nil for credit grants.
+func (p *PromoCode) CheckRedeemable(now time.Time, packageID *uuid.UUID) error {
+	if err := p.CheckValid(now); err != nil {
+		return err
+	}
+
+	switch p.Kind {
+	case PromoCredits:
+		if packageID != nil {
+			return fmt.Errorf("credit codes cannot be used at checkout: %w", ErrPromoCodeNotApplicable)
+		}
+	case PromoDiscount:
+		if packageID == nil {
+			return fmt.Errorf("discount codes are applied at checkout: %w", ErrPromoCodeNotApplicable)
+		}
+		if p.PackageID != nil && *p.PackageID != *packageID {
+			return ErrPromoCodeNotApplicable
+		}
+	default:
+		return ErrPromoCodeInvalid
+	}
+
+	return nil
+}
+
+// AppliesTo reports whether a discount code can be used for the package
+func (p *PromoCode) AppliesTo(packageID uuid.UUID) bool {
+	return p.Kind == PromoDiscount && (p.PackageID == nil || *p.PackageID == packageID)
+}
+
+// Discounted applies the code's discount to a price in minor units, rounding the discount
+// to the nearest minor unit like the payment provider does.
+func (p *PromoCode) Discounted(priceMinor int64) int64 {
+	discount := (priceMinor*int64(p.PercentOff) + 50) / 100
+	return priceMinor - discount
+}
@@ -0,0 +1,47 @@
+package domain
+
+import "time"
+
+// Receipt is the document issued for a completed payment. Amounts are in minor units of
+// Currency and include tax.
+type Receipt struct {
+	InvoiceNumber string
+	IssuedAt      time.Time
+	PaidAt        time.Time
+	Seller        ReceiptParty
+	Customer      ReceiptParty
+	Lines         []ReceiptLine
+	Currency      string
+	// SubtotalMinor is the total without tax
+	SubtotalMinor int64
+	TaxLabel      string
+	TaxRateBps    int
+	TaxMinor      int64
+	TotalMinor    int64
+	// Notes are printed below the totals, e.g. for refunds
+	Notes []string
+}
+
+type ReceiptParty struct {
+	Name    string
+	Address []string
+	TaxID   string
+	Email   string
+}
+
+type ReceiptLine struct {
+	Description string
+	Quantity    int
+	UnitMinor   int64
+	TotalMinor  int64
+}
+
+// InclusiveTax returns the tax contained in a tax inclusive amount
+func InclusiveTax(amountMinor int64, taxRateBps int) int64 {
+	if taxRateBps <= 0 {
+		return 0
+	}
+	rate := int64(taxRateBps)
+	// Rounded to the nearest minor unit
+	return (amountMinor*rate*2 + 10000 + rate) / (2 * (10000 + rate))
+}
@@ -0,0 +1,32 @@
+package domain
+
+import "slices"
+
+// Role grants access to the admin area. Roles are ordered, every role includes the
+// permissions of the roles before it.
+type Role string
+
+const (
+	RoleUser Role = "user"
+	// RoleSupport can look up users, their wallets and prompts and retry stuck prompts
+	RoleSupport Role = "support"
+	// RoleAdmin can additionally change credits, roles and accounts and manage the catalog
+	RoleAdmin Role = "admin"
+)
+
+// Roles lists every role from least to most privileged
+var Roles = []Role{RoleUser, RoleSupport, RoleAdmin}
+
+func (r Role) Valid() bool {
+	return slices.Contains(Roles, r)
+}
+
+// Allows reports whether r has at least the permissions of required
+func (r Role) Allows(required Role) bool {
+	return r.Valid() && slices.Index(Roles, r) >= slices.Index(Roles, required)
+}
+
+// Staff reports whether r has access to the admin area
+func (r Role) Staff() bool {
+	return r.Allows(RoleSupport)
+}
@@ -0,0 +1,95 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SubscriptionPlan is a recurring plan that grants credits every billing period
+type SubscriptionPlan struct {
+	BaseModel
+	Name             string `gorm:"not null"`
+	CreditsPerPeriod int    `gorm:"not null;check:credits_per_period > 0"`
+	// PriceMinor is the price per period in the currency's minor unit
+	PriceMinor int64  `gorm:"not null;check:price_minor >= 0"`
+	Currency   string `gorm:"size:3;not null"`
+	// Interval is the billing period, "month" or "year"
+	Interval string `gorm:"not null;default:'month'"`
+	// RolloverCap is the most unused allowance carried into the next period. Nil carries
+	// everything over, 0 lets unused allowance expire.
+	RolloverCap *int
+	Active      bool `gorm:"not null;default:true"`
+	SortOrder   int  `gorm:"not null;default:0"`
+}
+
+type SubscriptionStatus string
+
+const (
+	// SubscriptionIncomplete subscriptions have a checkout that was not completed yet
+	SubscriptionIncomplete SubscriptionStatus = "incomplete"
+	SubscriptionActive     SubscriptionStatus = "active"
+	// SubscriptionPastDue subscriptions failed to renew, the provider keeps retrying
+	SubscriptionPastDue  SubscriptionStatus = "past_due"
+	SubscriptionCanceled SubscriptionStatus = "canceled"
+)
+
+// Subscription is a user's subscription to a plan at the payment provider
+type Subscription struct {
+	BaseModel
+	UserID uuid.UUID          `gorm:"type:uuid;not null;index"`
+	PlanID uuid.UUID          `gorm:"type:uuid;not null"`
+	Plan   SubscriptionPlan   `gorm:"foreignKey:PlanID"`
+	Status SubscriptionStatus `gorm:"not null;index"`
+	// Provider identifiers are known once the checkout completed
+	ProviderSubscriptionID *string `gorm:"uniqueIndex"`
+	ProviderCustomerID     string
+	// CheckoutSessionID is the provider checkout the subscription was started with
+	CheckoutSessionID  string `gorm:"index"`
+	CurrentPeriodStart *time.Time
+	CurrentPeriodEnd   *time.Time
+	// CancelAtPeriodEnd is set when the user canceled but the paid period has not ended yet
+	CancelAtPeriodEnd bool `gorm:"not null;default:false"`
+	CanceledAt        *time.Time
+}
+
+// IsCurrent reports whether the subscription is still running and managed by the user
+func (s *Subscription) IsCurrent() bool {
+	return s.Status == SubscriptionActive || s.Status == SubscriptionPastDue
+}
+
+// SubscriptionGrant records the credits granted for one paid invoice of a subscription
+type SubscriptionGrant struct {
+	BaseModel
+	SubscriptionID uuid.UUID `gorm:"type:uuid;not null;index"`
+	// ProviderInvoiceID makes repeated deliveries of the same invoice grant only once
+	ProviderInvoiceID string `gorm:"uniqueIndex;not null"`
+	Credits           int    `gorm:"not null"`
+	// ExpiredCredits is the unused allowance of the previous period above the rollover cap
+	ExpiredCredits int `gorm:"not null;default:0"`
+	// RolloverCredits is the unused allowance of the previous period carried into this one,
+	// it is part of this period's allowance
+	RolloverCredits int `gorm:"not null;default:0"`
+	PeriodStart     time.Time
+	PeriodEnd       time.Time
+	// PaymentID is the payment the invoice is listed and receipted as
+	PaymentID *uuid.UUID `gorm:"type:uuid"`
+}
+
+// Allowance is the credits available from the subscription in the grant's period
+func (g *SubscriptionGrant) Allowance() int {
+	return g.Credits + g.RolloverCredits
+}
+
+// ExpiredAllowance calculates how much of the previous period's allowance expires at renewal
+// and how much is carried over. Allowance credits are considered spent before purchased ones,
+// so the unused allowance is the allowance minus the credits spent during the period, and no
+// more than the wallet balance.
+func (p *SubscriptionPlan) ExpiredAllowance(allowance, spent, walletCredits int) (expired, carried int) {
+	unused := min(max(0, allowance-spent), max(0, walletCredits))
+	if p.RolloverCap == nil {
+		return 0, unused
+	}
+	carried = min(unused, *p.RolloverCap)
+	return unused - carried, carried
+}
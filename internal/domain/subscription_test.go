@@ -0,0 +1,40 @@
+package domain
+
+import "testing"
+
+func TestExpiredAllowance(t *testing.T) {
+	intPtr := func(v int) *int { return &v }
+
+	tests := []struct {
+		name          string
+		rolloverCap   *int
+		allowance     int
+		spent         int
+		walletCredits int
+		wantExpired   int
+		wantCarried   int
+	}{
+		{name: "unlimited rollover", rolloverCap: nil, allowance: 100, walletCredits: 100, wantCarried: 100},
+		{name: "no rollover", rolloverCap: intPtr(0), allowance: 100, walletCredits: 100, wantExpired: 100},
+		{name: "partly spent", rolloverCap: intPtr(0), allowance: 100, spent: 60, walletCredits: 40, wantExpired: 40},
+		{name: "allowance spent before purchased credits", rolloverCap: intPtr(0), allowance: 100, spent: 100, walletCredits: 250, wantExpired: 0},
+		{name: "unspent with purchased credits", rolloverCap: intPtr(0), allowance: 100, spent: 20, walletCredits: 330, wantExpired: 80},
+		{name: "more spent than the allowance", rolloverCap: intPtr(0), allowance: 100, spent: 150, walletCredits: 200, wantExpired: 0},
+		{name: "above the cap", rolloverCap: intPtr(30), allowance: 100, spent: 20, walletCredits: 80, wantExpired: 50, wantCarried: 30},
+		{name: "within the cap", rolloverCap: intPtr(30), allowance: 100, spent: 80, walletCredits: 20, wantCarried: 20},
+		{name: "wallet below the unused allowance", rolloverCap: intPtr(0), allowance: 100, walletCredits: 30, wantExpired: 30},
+		{name: "empty wallet", rolloverCap: intPtr(0), allowance: 100, walletCredits: 0},
+		{name: "no previous grant", rolloverCap: intPtr(0), allowance: 0, walletCredits: 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := &SubscriptionPlan{RolloverCap: tt.rolloverCap}
+			expired, carried := plan.ExpiredAllowance(tt.allowance, tt.spent, tt.walletCredits)
+			if expired != tt.wantExpired || carried != tt.wantCarried {
+				t.Errorf("ExpiredAllowance(%d, %d, %d) = %d expired, %d carried, want %d expired, %d carried",
+					tt.allowance, tt.spent, tt.walletCredits, expired, carried, tt.wantExpired, tt.wantCarried)
+			}
+		})
+	}
+}
@@ -1,10 +1,27 @@
 package domain
 
+import "time"
+
 type User struct {
 	BaseModel
 	Username string `gorm:"not null"`
 	Email    string `gorm:"uniqueIndex;not null"`
 	Password string `gorm:"not null"`
+	// Currency is the preferred currency for purchases, empty to pick one from the browser locale
+	Currency string `gorm:"size:3"`
 	Wallet   Wallet
 	Prompts  []Prompt `gorm:"foreignKey:UserID;references:ID"`
+
+	// FlaggedAt is set when a refund or dispute took back more credits than the user had left
+	FlaggedAt  *time.Time
+	FlagReason string
+
+	Role Role `gorm:"size:16;not null;default:user"`
+	// DisabledAt is set when an admin disabled the account, disabled users cannot sign in
+	DisabledAt     *time.Time
+	DisabledReason string
+}
+
+func (u *User) Disabled() bool {
+	return u.DisabledAt != nil
 }
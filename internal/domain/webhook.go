@@ -0,0 +1,101 @@
+package domain
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type WebhookEventType string
+
+const (
+	EventPromptCompleted  WebhookEventType = "prompt.completed"
+	EventPromptFailed     WebhookEventType = "prompt.failed"
+	EventCreditsPurchased WebhookEventType = "credits.purchased"
+	EventCreditsLow       WebhookEventType = "credits.low"
+)
+
+// WebhookEventTypes lists every event an endpoint can subscribe to
+var WebhookEventTypes = []WebhookEventType{EventPromptCompleted, EventPromptFailed, EventCreditsPurchased, EventCreditsLow}
+
+func (t WebhookEventType) Valid() bool {
+	return slices.Contains(WebhookEventTypes, t)
+}
+
+// WebhookEndpoint is a user's URL that receives events. Deliveries are signed with Secret.
+type WebhookEndpoint struct {
+	BaseModel
+	UserID uuid.UUID `gorm:"type:uuid;not null;index"`
+	URL    string    `gorm:"not null"`
+	// Secret signs deliveries with HMAC-SHA256, it is kept in plain text because signing needs it
+	Secret string `gorm:"not null"`
+	// Events are stored space separated
+	Events string `gorm:"not null"`
+	Active bool   `gorm:"not null;default:true"`
+}
+
+func (e *WebhookEndpoint) EventList() []WebhookEventType {
+	fields := strings.Fields(e.Events)
+	events := make([]WebhookEventType, len(fields))
+	for i, f := range fields {
+		events[i] = WebhookEventType(f)
+	}
+	return events
+}
+
+func (e *WebhookEndpoint) Subscribed(event WebhookEventType) bool {
+	return slices.Contains(e.EventList(), event)
+}
+
+// JoinEvents returns events in the stored format
+func JoinEvents(events []WebhookEventType) string {
+	parts := make([]string, len(events))
+	for i, e := range events {
+		parts[i] = string(e)
+	}
+	return strings.Join(parts, " ")
+}
+
+type DeliveryStatus string
+
+const (
+	// DeliveryPending deliveries wait for their next attempt at NextAttemptAt
+	DeliveryPending DeliveryStatus = "pending"
+	// DeliverySending deliveries are claimed by a dispatcher until LockedUntil
+	DeliverySending   DeliveryStatus = "sending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	// DeliveryFailed deliveries ran out of attempts, they can be redelivered by hand
+	DeliveryFailed DeliveryStatus = "failed"
+)
+
+// WebhookDelivery is an event sent to an endpoint and the log of its attempts.
+// Redeliveries are new deliveries of the same event ID, so receivers can deduplicate.
+type WebhookDelivery struct {
+	BaseModel
+	EndpointID    uuid.UUID        `gorm:"type:uuid;not null;index"`
+	Endpoint      WebhookEndpoint  `gorm:"foreignKey:EndpointID;references:ID"`
+	UserID        uuid.UUID        `gorm:"type:uuid;not null;index"`
+	EventID       uuid.UUID        `gorm:"type:uuid;not null;index"`
+	EventType     WebhookEventType `gorm:"not null"`
+	Payload       string           `gorm:"type:text;not null"`
+	Status        DeliveryStatus   `gorm:"index;not null"`
+	Attempts      int              `gorm:"not null;default:0"`
+	MaxAttempts   int              `gorm:"not null"`
+	NextAttemptAt time.Time        `gorm:"index;not null"`
+	LockedUntil   *time.Time
+	// ResponseStatus and ResponseBody are from the last attempt, the body is truncated
+	ResponseStatus int
+	ResponseBody   string `gorm:"type:text"`
+	LastError      string
+	DeliveredAt    *time.Time
+}
+
+// WebhookEvent is the JSON body of a delivery
+type WebhookEvent struct {
+	ID        uuid.UUID        `json:"id"`
+	Type      WebhookEventType `json:"type"`
+	CreatedAt time.Time        `json:"created_at"`
+	Data      any              `json:"data"`
+}
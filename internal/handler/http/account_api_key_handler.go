@@ -0,0 +1,247 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/CP-Payne/wonderpicai/internal/context/auth"
+	"github.com/CP-Payne/wonderpicai/internal/context/requestlog"
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/CP-Payne/wonderpicai/internal/handler/http/response"
+	"github.com/CP-Payne/wonderpicai/internal/service"
+	"github.com/CP-Payne/wonderpicai/internal/validation"
+	accountPages "github.com/CP-Payne/wonderpicai/web/template/pages/account"
+	"github.com/CP-Payne/wonderpicai/web/template/viewmodel"
+)
+
+// defaultAPIKeyExpiry is the preselected expiry of new keys in days
+const defaultAPIKeyExpiry = "90"
+
+var apiScopeLabels = map[domain.APIScope]string{
+	domain.ScopeGenerate:     "create generations",
+	domain.ScopeGalleryRead:  "list prompts and download images",
+	domain.ScopeGalleryWrite: "delete images",
+	domain.ScopeWalletRead:   "read the credit balance",
+}
+
+var apiKeyExpiryOptions = []viewmodel.AccountOption{
+	{Value: "30", Label: "In 30 days"},
+	{Value: "90", Label: "In 90 days"},
+	{Value: "365", Label: "In 1 year"},
+	{Value: "never", Label: "Never"},
+}
+
+type APIKeyRequest struct {
+	Name string `validate:"required,max=64"`
+	// Scopes are checked against domain.APIScopes
+	Scopes []string
+	// ExpiryDays is 0 for keys that do not expire
+	ExpiryDays int `validate:"gte=0,lte=365"`
+}
+
+func (h *AccountHandler) ShowAPIKeysPage(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	userID, err := auth.UserID(r.Context())
+	if err != nil {
+		logger.Error("Failed to get userID from context", zap.Error(err))
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		return
+	}
+
+	keys, err := h.apiKeyService.List(r.Context(), userID)
+	if err != nil {
+		logger.Error("Failed to list API keys", zap.String("userID", userID.String()), zap.Error(err))
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		return
+	}
+
+	now := time.Now()
+	rows := make([]viewmodel.AccountAPIKey, len(keys))
+	for i := range keys {
+		rows[i] = accountAPIKey(&keys[i], now)
+	}
+
+	form := newAPIKeyForm()
+	form.Expiry = defaultAPIKeyExpiry
+
+	err = accountPages.APIKeysPage(viewmodel.AccountAPIKeysViewData{Keys: rows, Form: form}).Render(r.Context(), w)
+	if err != nil {
+		logger.Error("Failed to render API keys page", zap.Error(err))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *AccountHandler) HandleAPIKeyCreate(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	userID, err := auth.UserID(r.Context())
+	if err != nil {
+		logger.Error("Failed to get userID from context", zap.Error(err))
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		return
+	}
+
+	req, vm, ok := h.parseAPIKeyForm(r)
+	if !ok {
+		if loadErr := response.LoadAccountNewAPIKeyForm(w, r, logger, vm); loadErr != nil {
+			response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		}
+		return
+	}
+
+	input := service.APIKeyInput{Name: req.Name}
+	for _, scope := range req.Scopes {
+		input.Scopes = append(input.Scopes, domain.APIScope(scope))
+	}
+	if req.ExpiryDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, req.ExpiryDays)
+		input.ExpiresAt = &expiresAt
+	}
+
+	key, secret, err := h.apiKeyService.Create(r.Context(), userID, input)
+	if err != nil {
+		logger.Error("Failed to create API key", zap.String("userID", userID.String()), zap.Error(err))
+		vm.Error = "Failed to create the API key, please try again."
+		if loadErr := response.LoadAccountNewAPIKeyForm(w, r, logger, vm); loadErr != nil {
+			response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		}
+		return
+	}
+
+	// The full key must not be cached by the browser or proxies
+	w.Header().Set("Cache-Control", "no-store")
+	created := viewmodel.AccountAPIKeyCreated{Key: accountAPIKey(key, time.Now()), Secret: secret}
+	if loadErr := response.LoadAccountCreatedAPIKey(w, r, logger, created); loadErr != nil {
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+	}
+}
+
+func (h *AccountHandler) HandleAPIKeyRevoke(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	userID, err := auth.UserID(r.Context())
+	if err != nil {
+		logger.Error("Failed to get userID from context", zap.Error(err))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "API key not found", http.StatusNotFound)
+		return
+	}
+
+	key, err := h.apiKeyService.Revoke(r.Context(), userID, id)
+	if err != nil {
+		if errors.Is(err, domain.ErrRecordNotFound) {
+			http.Error(w, "API key not found", http.StatusNotFound)
+			return
+		}
+		logger.Error("Failed to revoke API key", zap.String("apiKeyID", id.String()), zap.Error(err))
+		http.Error(w, "failed to revoke API key", http.StatusInternalServerError)
+		return
+	}
+
+	if loadErr := response.LoadAccountAPIKeyRow(w, r, logger, accountAPIKey(key, time.Now())); loadErr != nil {
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+	}
+}
+
+func (h *AccountHandler) parseAPIKeyForm(r *http.Request) (APIKeyRequest, viewmodel.AccountAPIKeyForm, bool) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	vm := newAPIKeyForm()
+
+	if err := r.ParseForm(); err != nil {
+		logger.Error("Failed to parse form", zap.Error(err))
+		vm.Error = "Invalid form submission."
+		return APIKeyRequest{}, vm, false
+	}
+
+	req := APIKeyRequest{
+		Name:   strings.TrimSpace(r.FormValue("name")),
+		Scopes: r.Form["scopes"],
+	}
+	vm.Name = req.Name
+	vm.Scopes = req.Scopes
+	vm.Expiry = r.FormValue("expiry")
+
+	if vm.Expiry != "never" {
+		days, err := strconv.Atoi(vm.Expiry)
+		if err != nil || days < 1 {
+			vm.Errors["expiry"] = "choose when the key expires"
+		}
+		req.ExpiryDays = days
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		fieldErrors, generalErr := validation.TranslateValidationErrors(err)
+		for field, msg := range fieldErrors {
+			vm.Errors[field] = msg
+		}
+		vm.Error = generalErr
+	}
+	if len(req.Scopes) == 0 {
+		vm.Errors["scopes"] = "select at least one scope"
+	}
+	for _, scope := range req.Scopes {
+		if !domain.APIScope(scope).Valid() {
+			vm.Errors["scopes"] = "unknown scope " + scope
+		}
+	}
+
+	return req, vm, len(vm.Errors) == 0 && vm.Error == ""
+}
+
+func newAPIKeyForm() viewmodel.AccountAPIKeyForm {
+	form := viewmodel.AccountAPIKeyForm{
+		ExpiryOptions: apiKeyExpiryOptions,
+		Errors:        map[string]string{},
+	}
+	for _, scope := range domain.APIScopes {
+		form.ScopeOptions = append(form.ScopeOptions, viewmodel.AccountOption{Value: string(scope), Label: apiScopeLabels[scope]})
+	}
+	return form
+}
+
+func accountAPIKey(key *domain.APIKey, now time.Time) viewmodel.AccountAPIKey {
+	vm := viewmodel.AccountAPIKey{
+		ID:       key.ID.String(),
+		Name:     key.Name,
+		Prefix:   key.Prefix,
+		Created:  key.CreatedAt.Format("2 Jan 2006"),
+		LastUsed: "Never",
+		Expires:  "Never",
+	}
+	for _, scope := range key.ScopeList() {
+		vm.Scopes = append(vm.Scopes, string(scope))
+	}
+	if key.LastUsedAt != nil {
+		vm.LastUsed = key.LastUsedAt.Format("2 Jan 2006 15:04")
+	}
+	if key.ExpiresAt != nil {
+		vm.Expires = key.ExpiresAt.Format("2 Jan 2006")
+	}
+
+	switch {
+	case key.RevokedAt != nil:
+		vm.Status, vm.StatusClass = "Revoked", "badge-ghost"
+	case !key.Usable(now):
+		vm.Status, vm.StatusClass = "Expired", "badge-warning"
+	default:
+		vm.Status, vm.StatusClass = "Active", "badge-success"
+		vm.Revocable = true
+	}
+
+	return vm
+}
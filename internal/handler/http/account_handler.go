@@ -0,0 +1,140 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/CP-Payne/wonderpicai/internal/context/auth"
+	"github.com/CP-Payne/wonderpicai/internal/context/requestlog"
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/CP-Payne/wonderpicai/internal/handler/http/response"
+	"github.com/CP-Payne/wonderpicai/internal/money"
+	"github.com/CP-Payne/wonderpicai/internal/service"
+	accountPages "github.com/CP-Payne/wonderpicai/web/template/pages/account"
+	"github.com/CP-Payne/wonderpicai/web/template/viewmodel"
+)
+
+type AccountHandler struct {
+	logger         *zap.Logger
+	validate       *validator.Validate
+	receiptService service.ReceiptService
+	apiKeyService  service.APIKeyService
+	webhookService service.WebhookService
+}
+
+func NewAccountHandler(logger *zap.Logger, validate *validator.Validate, receiptService service.ReceiptService, apiKeyService service.APIKeyService, webhookService service.WebhookService) *AccountHandler {
+	return &AccountHandler{
+		logger:         logger.With(zap.String("component", "AccountHandler")),
+		validate:       validate,
+		receiptService: receiptService,
+		apiKeyService:  apiKeyService,
+		webhookService: webhookService,
+	}
+}
+
+func (h *AccountHandler) ShowPurchasesPage(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	userID, err := auth.UserID(r.Context())
+	if err != nil {
+		logger.Error("Failed to get userID from context", zap.Error(err))
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		return
+	}
+
+	payments, err := h.receiptService.ListPurchases(r.Context(), userID)
+	if err != nil {
+		logger.Error("Failed to list purchases", zap.String("userID", userID.String()), zap.Error(err))
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		return
+	}
+
+	formatter := money.NewFormatter(r.Header.Get("Accept-Language"))
+	purchases := make([]viewmodel.AccountPurchase, len(payments))
+	for i, payment := range payments {
+		purchases[i] = accountPurchase(&payment, formatter)
+	}
+
+	err = accountPages.PurchasesPage(viewmodel.AccountPurchasesViewData{Purchases: purchases}).Render(r.Context(), w)
+	if err != nil {
+		logger.Error("Failed to render purchases page", zap.Error(err))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *AccountHandler) HandleReceiptDownload(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	userID, err := auth.UserID(r.Context())
+	if err != nil {
+		logger.Error("Failed to get userID from context", zap.Error(err))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	paymentID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "receipt not found", http.StatusNotFound)
+		return
+	}
+
+	filename, pdf, err := h.receiptService.ReceiptPDF(r.Context(), userID, paymentID)
+	if err != nil {
+		if errors.Is(err, domain.ErrRecordNotFound) || errors.Is(err, domain.ErrReceiptUnavailable) {
+			http.Error(w, "receipt not found", http.StatusNotFound)
+			return
+		}
+		logger.Error("Failed to create receipt", zap.String("paymentID", paymentID.String()), zap.Error(err))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Header().Set("Cache-Control", "private, no-store")
+	if _, err := w.Write(pdf); err != nil {
+		logger.Warn("Failed to write receipt", zap.Error(err))
+	}
+}
+
+func accountPurchase(payment *domain.Payment, formatter money.Formatter) viewmodel.AccountPurchase {
+	purchase := viewmodel.AccountPurchase{
+		Date:        payment.CreatedAt.Format("2 Jan 2006"),
+		Description: payment.Description,
+		Credits:     payment.Credits,
+		Amount:      formatter.Format(payment.AmountMinor, payment.Currency),
+	}
+	if purchase.Description == "" {
+		purchase.Description = "Credit pack"
+	}
+	if payment.CompletedAt != nil {
+		purchase.ReceiptURL = fmt.Sprintf("/account/purchases/%s/receipt", payment.ID)
+	}
+
+	switch payment.Status {
+	case domain.PaymentCompleted:
+		purchase.Status, purchase.StatusClass = "Paid", "badge-success"
+		if payment.RefundedMinor > 0 {
+			purchase.Status, purchase.StatusClass = "Partially refunded", "badge-warning"
+		}
+	case domain.PaymentProcessing:
+		purchase.Status, purchase.StatusClass = "Processing", "badge-info"
+	case domain.PaymentRefunded:
+		purchase.Status, purchase.StatusClass = "Refunded", "badge-ghost"
+	case domain.PaymentDisputed:
+		purchase.Status, purchase.StatusClass = "Disputed", "badge-error"
+	case domain.PaymentAmountMismatch:
+		purchase.Status, purchase.StatusClass = "Under review", "badge-warning"
+	default:
+		purchase.Status, purchase.StatusClass = string(payment.Status), "badge-ghost"
+	}
+
+	return purchase
+}
@@ -0,0 +1,309 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/CP-Payne/wonderpicai/internal/context/auth"
+	"github.com/CP-Payne/wonderpicai/internal/context/requestlog"
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/CP-Payne/wonderpicai/internal/handler/http/response"
+	"github.com/CP-Payne/wonderpicai/internal/service"
+	accountPages "github.com/CP-Payne/wonderpicai/web/template/pages/account"
+	"github.com/CP-Payne/wonderpicai/web/template/viewmodel"
+)
+
+var webhookEventLabels = map[domain.WebhookEventType]string{
+	domain.EventPromptCompleted:  "a prompt finished generating",
+	domain.EventPromptFailed:     "a prompt failed",
+	domain.EventCreditsPurchased: "a credit purchase completed",
+	domain.EventCreditsLow:       "a generation took the balance below the low credits threshold",
+}
+
+func (h *AccountHandler) ShowWebhooksPage(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	userID, err := auth.UserID(r.Context())
+	if err != nil {
+		logger.Error("Failed to get userID from context", zap.Error(err))
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		return
+	}
+
+	endpoints, err := h.webhookService.ListEndpoints(r.Context(), userID)
+	if err != nil {
+		logger.Error("Failed to list webhook endpoints", zap.String("userID", userID.String()), zap.Error(err))
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		return
+	}
+
+	rows := make([]viewmodel.AccountWebhook, len(endpoints))
+	for i := range endpoints {
+		rows[i] = accountWebhook(&endpoints[i])
+	}
+
+	err = accountPages.WebhooksPage(viewmodel.AccountWebhooksViewData{Webhooks: rows, Form: newWebhookForm()}).Render(r.Context(), w)
+	if err != nil {
+		logger.Error("Failed to render webhooks page", zap.Error(err))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *AccountHandler) ShowWebhookPage(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	userID, err := auth.UserID(r.Context())
+	if err != nil {
+		logger.Error("Failed to get userID from context", zap.Error(err))
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.HxRedirectErrorPage(w, r, http.StatusNotFound, "")
+		return
+	}
+
+	endpoint, err := h.webhookService.Endpoint(r.Context(), userID, id)
+	if err != nil {
+		if errors.Is(err, domain.ErrRecordNotFound) {
+			response.HxRedirectErrorPage(w, r, http.StatusNotFound, "")
+			return
+		}
+		logger.Error("Failed to get webhook endpoint", zap.String("endpointID", id.String()), zap.Error(err))
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		return
+	}
+
+	deliveries, err := h.webhookService.ListDeliveries(r.Context(), userID, id)
+	if err != nil {
+		logger.Error("Failed to list webhook deliveries", zap.String("endpointID", id.String()), zap.Error(err))
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		return
+	}
+
+	data := viewmodel.AccountWebhookViewData{
+		Webhook:    accountWebhook(endpoint),
+		Secret:     endpoint.Secret,
+		Deliveries: make([]viewmodel.AccountWebhookDelivery, len(deliveries)),
+	}
+	for i := range deliveries {
+		data.Deliveries[i] = accountWebhookDelivery(&deliveries[i])
+	}
+
+	// The page shows the signing secret
+	w.Header().Set("Cache-Control", "no-store")
+	if err := accountPages.WebhookPage(data).Render(r.Context(), w); err != nil {
+		logger.Error("Failed to render webhook page", zap.Error(err))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *AccountHandler) HandleWebhookCreate(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	userID, err := auth.UserID(r.Context())
+	if err != nil {
+		logger.Error("Failed to get userID from context", zap.Error(err))
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		return
+	}
+
+	vm := newWebhookForm()
+	if err := r.ParseForm(); err != nil {
+		logger.Error("Failed to parse form", zap.Error(err))
+		vm.Error = "Invalid form submission."
+		if loadErr := response.LoadAccountNewWebhookForm(w, r, logger, vm); loadErr != nil {
+			response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		}
+		return
+	}
+	vm.URL = strings.TrimSpace(r.FormValue("url"))
+	vm.Events = r.Form["events"]
+
+	input := service.WebhookEndpointInput{URL: vm.URL}
+	for _, event := range vm.Events {
+		input.Events = append(input.Events, domain.WebhookEventType(event))
+	}
+
+	endpoint, err := h.webhookService.CreateEndpoint(r.Context(), userID, input)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrInvalidWebhookURL):
+			vm.Errors["url"] = strings.TrimPrefix(err.Error(), domain.ErrInvalidWebhookURL.Error()+": ")
+		case errors.Is(err, domain.ErrInvalidWebhookEvent):
+			vm.Errors["events"] = "select at least one known event"
+		default:
+			logger.Error("Failed to create webhook endpoint", zap.String("userID", userID.String()), zap.Error(err))
+			vm.Error = "Failed to add the webhook, please try again."
+		}
+		if loadErr := response.LoadAccountNewWebhookForm(w, r, logger, vm); loadErr != nil {
+			response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		}
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	created := viewmodel.AccountWebhookCreated{Webhook: accountWebhook(endpoint), Secret: endpoint.Secret}
+	if loadErr := response.LoadAccountCreatedWebhook(w, r, logger, created); loadErr != nil {
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+	}
+}
+
+func (h *AccountHandler) HandleWebhookActive(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	userID, err := auth.UserID(r.Context())
+	if err != nil {
+		logger.Error("Failed to get userID from context", zap.Error(err))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "webhook not found", http.StatusNotFound)
+		return
+	}
+
+	active := r.FormValue("active") == "true"
+	endpoint, err := h.webhookService.SetEndpointActive(r.Context(), userID, id, active)
+	if err != nil {
+		if errors.Is(err, domain.ErrRecordNotFound) {
+			http.Error(w, "webhook not found", http.StatusNotFound)
+			return
+		}
+		logger.Error("Failed to update webhook endpoint", zap.String("endpointID", id.String()), zap.Error(err))
+		http.Error(w, "failed to update webhook", http.StatusInternalServerError)
+		return
+	}
+
+	if loadErr := response.LoadAccountWebhookRow(w, r, logger, accountWebhook(endpoint)); loadErr != nil {
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+	}
+}
+
+func (h *AccountHandler) HandleWebhookDelete(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	userID, err := auth.UserID(r.Context())
+	if err != nil {
+		logger.Error("Failed to get userID from context", zap.Error(err))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "webhook not found", http.StatusNotFound)
+		return
+	}
+
+	if err := h.webhookService.DeleteEndpoint(r.Context(), userID, id); err != nil {
+		if errors.Is(err, domain.ErrRecordNotFound) {
+			http.Error(w, "webhook not found", http.StatusNotFound)
+			return
+		}
+		logger.Error("Failed to delete webhook endpoint", zap.String("endpointID", id.String()), zap.Error(err))
+		http.Error(w, "failed to delete webhook", http.StatusInternalServerError)
+		return
+	}
+
+	// The empty response removes the row
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *AccountHandler) HandleWebhookRedeliver(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	userID, err := auth.UserID(r.Context())
+	if err != nil {
+		logger.Error("Failed to get userID from context", zap.Error(err))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "delivery not found", http.StatusNotFound)
+		return
+	}
+
+	delivery, err := h.webhookService.Redeliver(r.Context(), userID, id)
+	if err != nil {
+		if errors.Is(err, domain.ErrRecordNotFound) {
+			http.Error(w, "delivery not found", http.StatusNotFound)
+			return
+		}
+		logger.Error("Failed to redeliver webhook", zap.String("deliveryID", id.String()), zap.Error(err))
+		http.Error(w, "failed to redeliver webhook", http.StatusInternalServerError)
+		return
+	}
+
+	if loadErr := response.LoadAccountWebhookDeliveryRow(w, r, logger, accountWebhookDelivery(delivery)); loadErr != nil {
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+	}
+}
+
+func newWebhookForm() viewmodel.AccountWebhookForm {
+	form := viewmodel.AccountWebhookForm{Errors: map[string]string{}}
+	for _, event := range domain.WebhookEventTypes {
+		form.EventOptions = append(form.EventOptions, viewmodel.AccountOption{Value: string(event), Label: webhookEventLabels[event]})
+	}
+	return form
+}
+
+func accountWebhook(endpoint *domain.WebhookEndpoint) viewmodel.AccountWebhook {
+	vm := viewmodel.AccountWebhook{
+		ID:      endpoint.ID.String(),
+		URL:     endpoint.URL,
+		Created: endpoint.CreatedAt.Format("2 Jan 2006"),
+		Active:  endpoint.Active,
+	}
+	for _, event := range endpoint.EventList() {
+		vm.Events = append(vm.Events, string(event))
+	}
+	return vm
+}
+
+func accountWebhookDelivery(delivery *domain.WebhookDelivery) viewmodel.AccountWebhookDelivery {
+	vm := viewmodel.AccountWebhookDelivery{
+		ID:             delivery.ID.String(),
+		EventID:        delivery.EventID.String(),
+		EventType:      string(delivery.EventType),
+		Created:        delivery.CreatedAt.Format("2 Jan 2006 15:04:05"),
+		Attempts:       fmt.Sprintf("%d/%d", delivery.Attempts, delivery.MaxAttempts),
+		ResponseStatus: delivery.ResponseStatus,
+		LastError:      delivery.LastError,
+		Payload:        delivery.Payload,
+	}
+
+	switch delivery.Status {
+	case domain.DeliverySucceeded:
+		vm.Status, vm.StatusClass = "Delivered", "badge-success"
+		vm.Redeliverable = true
+	case domain.DeliveryFailed:
+		vm.Status, vm.StatusClass = "Failed", "badge-error"
+		vm.Redeliverable = true
+	case domain.DeliverySending:
+		vm.Status, vm.StatusClass = "Sending", "badge-info"
+	default:
+		vm.Status, vm.StatusClass = "Pending", "badge-warning"
+		if delivery.Attempts > 0 {
+			vm.Status = "Retrying"
+			vm.NextAttempt = delivery.NextAttemptAt.Format(time.TimeOnly)
+		}
+	}
+
+	return vm
+}
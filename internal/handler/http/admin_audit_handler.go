@@ -0,0 +1,147 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/CP-Payne/wonderpicai/internal/context/auth"
+	"github.com/CP-Payne/wonderpicai/internal/context/requestlog"
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/CP-Payne/wonderpicai/internal/handler/http/response"
+	"github.com/CP-Payne/wonderpicai/internal/port"
+	"github.com/CP-Payne/wonderpicai/internal/service"
+	adminPages "github.com/CP-Payne/wonderpicai/web/template/pages/admin"
+	"github.com/CP-Payne/wonderpicai/web/template/viewmodel"
+)
+
+// adminDateTimeLayout is used for the timestamps of the admin area
+const adminDateTimeLayout = "2 Jan 2006 15:04:05"
+
+func (h *AdminHandler) ShowAuditPage(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	query := r.URL.Query()
+	filter := port.AuditFilter{
+		Action:     domain.AuditAction(strings.TrimSpace(query.Get("action"))),
+		ActorEmail: strings.TrimSpace(query.Get("actor")),
+		TargetType: strings.TrimSpace(query.Get("target_type")),
+		TargetID:   strings.TrimSpace(query.Get("target_id")),
+	}
+	page := adminPage(r)
+
+	events, total, err := h.auditService.List(r.Context(), filter, page)
+	if err != nil {
+		logger.Error("Failed to list audit events", zap.Error(err))
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		return
+	}
+
+	data := viewmodel.AdminAuditViewData{
+		Action:     string(filter.Action),
+		Actor:      filter.ActorEmail,
+		TargetType: filter.TargetType,
+		TargetID:   filter.TargetID,
+		Events:     auditEvents(events),
+		Pager:      adminPager(r, page, len(events), total),
+	}
+	for _, action := range domain.AuditActions {
+		data.Actions = append(data.Actions, string(action))
+	}
+
+	err = adminPages.AuditPage(data).Render(r.Context(), w)
+	if err != nil {
+		logger.Error("Failed to render admin audit page", zap.Error(err))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *AdminHandler) HandleAuditVerify(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	var vm viewmodel.AdminAuditVerification
+
+	result, err := h.auditService.Verify(r.Context())
+	if err != nil {
+		logger.Error("Failed to verify audit chain", zap.Error(err))
+		vm.Error = "Failed to read the audit log, please try again."
+	} else {
+		vm.Intact = result.Intact()
+		vm.Checked = result.Checked
+		vm.BrokenAt = result.BrokenAt
+		vm.Problem = result.Problem
+	}
+
+	if loadErr := response.LoadAdminAuditVerification(w, r, logger, vm); loadErr != nil {
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+	}
+}
+
+// auditActor identifies the signed in staff member for the audit log
+func auditActor(r *http.Request) (service.AuditActor, error) {
+	userID, err := auth.UserID(r.Context())
+	if err != nil {
+		return service.AuditActor{}, err
+	}
+	return service.AuditActor{UserID: userID}, nil
+}
+
+// adminPage reads the offset query parameter of admin listings
+func adminPage(r *http.Request) domain.Page {
+	page := domain.Page{Limit: domain.DefaultPageLimit}
+	if offset, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && offset > 0 {
+		page.Offset = offset
+	}
+	return page
+}
+
+// adminPager links to the neighbouring pages of the current URL
+func adminPager(r *http.Request, page domain.Page, count int, total int64) viewmodel.AdminPager {
+	if total == 0 {
+		return viewmodel.AdminPager{}
+	}
+
+	pager := viewmodel.AdminPager{
+		Summary: fmt.Sprintf("%d-%d of %d", page.Offset+1, page.Offset+count, total),
+	}
+
+	withOffset := func(offset int) string {
+		query := r.URL.Query()
+		query.Set("offset", strconv.Itoa(offset))
+		return (&url.URL{Path: r.URL.Path, RawQuery: query.Encode()}).String()
+	}
+	if page.Offset > 0 {
+		pager.PrevURL = withOffset(max(page.Offset-page.Limit, 0))
+	}
+	if int64(page.Offset+count) < total {
+		pager.NextURL = withOffset(page.Offset + page.Limit)
+	}
+
+	return pager
+}
+
+func auditEvents(events []domain.AuditEvent) []viewmodel.AdminAuditEvent {
+	vms := make([]viewmodel.AdminAuditEvent, len(events))
+	for i, event := range events {
+		vms[i] = viewmodel.AdminAuditEvent{
+			Sequence:   event.Sequence,
+			Date:       event.CreatedAt.Format(adminDateTimeLayout),
+			Actor:      event.ActorEmail,
+			Action:     string(event.Action),
+			TargetType: event.TargetType,
+			TargetID:   event.TargetID,
+			Metadata:   event.Metadata,
+			IP:         event.IP,
+			UserAgent:  event.UserAgent,
+		}
+		if event.TargetType == "user" && event.TargetID != "" {
+			vms[i].TargetURL = "/admin/users/" + event.TargetID
+		}
+	}
+	return vms
+}
@@ -0,0 +1,301 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/CP-Payne/wonderpicai/internal/context/requestlog"
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/CP-Payne/wonderpicai/internal/handler/http/response"
+	"github.com/CP-Payne/wonderpicai/internal/money"
+	"github.com/CP-Payne/wonderpicai/internal/service"
+	"github.com/CP-Payne/wonderpicai/internal/validation"
+	adminPages "github.com/CP-Payne/wonderpicai/web/template/pages/admin"
+	"github.com/CP-Payne/wonderpicai/web/template/viewmodel"
+)
+
+type AdminHandler struct {
+	logger         *zap.Logger
+	validate       *validator.Validate
+	packageService service.CreditPackageService
+	promoService   service.PromoService
+	adminService   service.AdminService
+	auditService   service.AuditService
+}
+
+type CreditPackageRequest struct {
+	Name       string `validate:"required,max=60"`
+	Credits    int    `validate:"required,gte=1"`
+	PriceMinor int64  `validate:"gte=0"`
+	Currency   string `validate:"required,len=3,alpha"`
+	SortOrder  int
+	Badge      string `validate:"max=30"`
+	Active     bool
+	Prices     map[string]int64
+}
+
+func NewAdminHandler(logger *zap.Logger, validate *validator.Validate, packageService service.CreditPackageService, promoService service.PromoService, adminService service.AdminService, auditService service.AuditService) *AdminHandler {
+	return &AdminHandler{
+		logger:         logger.With(zap.String("component", "AdminHandler")),
+		validate:       validate,
+		packageService: packageService,
+		promoService:   promoService,
+		adminService:   adminService,
+		auditService:   auditService,
+	}
+}
+
+func (h *AdminHandler) ShowPackagesPage(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	pkgs, err := h.packageService.ListAll(r.Context())
+	if err != nil {
+		logger.Error("Failed to list credit packages", zap.Error(err))
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		return
+	}
+
+	rows := make([]viewmodel.AdminPackageRow, len(pkgs))
+	for i, pkg := range pkgs {
+		rows[i] = packageRow(&pkg)
+	}
+
+	data := viewmodel.AdminPackagesViewData{
+		Packages: rows,
+		New: viewmodel.AdminPackageRow{
+			Currency: "usd",
+			Active:   true,
+		},
+	}
+
+	err = adminPages.PackagesPage(data).Render(r.Context(), w)
+	if err != nil {
+		logger.Error("Failed to render admin packages page", zap.Error(err))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *AdminHandler) HandlePackageCreate(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	req, vm, ok := h.parsePackageForm(r)
+	if !ok {
+		if loadErr := response.LoadAdminNewPackageForm(w, r, logger, vm); loadErr != nil {
+			response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		}
+		return
+	}
+
+	_, err := h.packageService.Create(r.Context(), req.input())
+	if err != nil {
+		logger.Error("Failed to create credit package", zap.Error(err))
+		vm.Error = "Failed to create the package, please try again."
+		if loadErr := response.LoadAdminNewPackageForm(w, r, logger, vm); loadErr != nil {
+			response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		}
+		return
+	}
+
+	response.HxRedirect(w, r, "/admin/packages")
+}
+
+func (h *AdminHandler) HandlePackageUpdate(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "invalid package id", http.StatusBadRequest)
+		return
+	}
+
+	req, vm, ok := h.parsePackageForm(r)
+	vm.ID = id.String()
+	if !ok {
+		if loadErr := response.LoadAdminPackageRow(w, r, logger, vm); loadErr != nil {
+			response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		}
+		return
+	}
+
+	pkg, err := h.packageService.Update(r.Context(), id, req.input())
+	if err != nil {
+		if errors.Is(err, domain.ErrRecordNotFound) {
+			http.Error(w, "package not found", http.StatusNotFound)
+			return
+		}
+		logger.Error("Failed to update credit package", zap.String("packageID", id.String()), zap.Error(err))
+		vm.Error = "Failed to save, please try again."
+		if loadErr := response.LoadAdminPackageRow(w, r, logger, vm); loadErr != nil {
+			response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		}
+		return
+	}
+
+	if loadErr := response.LoadAdminPackageRow(w, r, logger, packageRow(pkg)); loadErr != nil {
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		return
+	}
+
+	toastID, loadErr := response.LoadSuccessToast(w, r, logger, "Package saved")
+	if loadErr != nil {
+		logger.Error("failed loading SuccessToast", zap.String("toastID", toastID), zap.Error(loadErr))
+	}
+}
+
+func (h *AdminHandler) HandlePackageDelete(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "invalid package id", http.StatusBadRequest)
+		return
+	}
+
+	err = h.packageService.Delete(r.Context(), id)
+	if err != nil && !errors.Is(err, domain.ErrRecordNotFound) {
+		logger.Error("Failed to delete credit package", zap.String("packageID", id.String()), zap.Error(err))
+		// HTMX does not swap error responses, so the row stays in place
+		http.Error(w, "failed to delete package", http.StatusInternalServerError)
+		return
+	}
+
+	// Empty response removes the row
+	w.WriteHeader(http.StatusOK)
+}
+
+// parsePackageForm reads and validates the package form. vm holds the submitted values and
+// any errors so the form can be rendered again.
+func (h *AdminHandler) parsePackageForm(r *http.Request) (CreditPackageRequest, viewmodel.AdminPackageRow, bool) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	vm := viewmodel.AdminPackageRow{Errors: map[string]string{}}
+
+	if err := r.ParseForm(); err != nil {
+		logger.Error("Failed to parse form", zap.Error(err))
+		vm.Error = "Invalid form submission."
+		return CreditPackageRequest{}, vm, false
+	}
+
+	req := CreditPackageRequest{
+		Name:     r.FormValue("name"),
+		Currency: r.FormValue("currency"),
+		Badge:    r.FormValue("badge"),
+		Active:   r.FormValue("active") == "true",
+	}
+
+	vm.Name = req.Name
+	vm.Currency = req.Currency
+	vm.Badge = req.Badge
+	vm.Active = req.Active
+	vm.Prices = r.FormValue("prices")
+
+	var err error
+	if req.Credits, err = strconv.Atoi(r.FormValue("credits")); err != nil {
+		vm.Errors["credits"] = "credits must be a whole number"
+	}
+	if req.PriceMinor, err = strconv.ParseInt(r.FormValue("price_minor"), 10, 64); err != nil {
+		vm.Errors["priceMinor"] = "price must be a whole number of minor units"
+	}
+	if req.Prices, err = parsePrices(vm.Prices, req.Currency); err != nil {
+		vm.Errors["prices"] = err.Error()
+	}
+	if sortOrder := r.FormValue("sort_order"); sortOrder != "" {
+		if req.SortOrder, err = strconv.Atoi(sortOrder); err != nil {
+			vm.Errors["sortOrder"] = "sort order must be a whole number"
+		}
+	}
+
+	vm.Credits = req.Credits
+	vm.PriceMinor = req.PriceMinor
+	vm.SortOrder = req.SortOrder
+
+	if len(vm.Errors) > 0 {
+		return req, vm, false
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		vm.Errors, vm.Error = validation.TranslateValidationErrors(err)
+		return req, vm, false
+	}
+
+	return req, vm, true
+}
+
+func (req CreditPackageRequest) input() service.CreditPackageInput {
+	return service.CreditPackageInput{
+		Name:       req.Name,
+		Credits:    req.Credits,
+		PriceMinor: req.PriceMinor,
+		Currency:   req.Currency,
+		Active:     req.Active,
+		SortOrder:  req.SortOrder,
+		Badge:      req.Badge,
+		Prices:     req.Prices,
+	}
+}
+
+// parsePrices parses a comma separated list of currency:price pairs with prices in minor units
+func parsePrices(value, defaultCurrency string) (map[string]int64, error) {
+	prices := make(map[string]int64)
+
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		code, amount, ok := strings.Cut(entry, ":")
+		code = strings.ToLower(strings.TrimSpace(code))
+		if !ok || len(code) != 3 {
+			return nil, fmt.Errorf("%q must have the form currency:price, e.g. eur:499", entry)
+		}
+		if !money.IsSupported(code) {
+			return nil, fmt.Errorf("currency %s is not supported", strings.ToUpper(code))
+		}
+		if strings.EqualFold(code, defaultCurrency) {
+			return nil, fmt.Errorf("the %s price is set in the price field", strings.ToUpper(code))
+		}
+		if _, dup := prices[code]; dup {
+			return nil, fmt.Errorf("currency %s is listed twice", strings.ToUpper(code))
+		}
+
+		priceMinor, err := strconv.ParseInt(strings.TrimSpace(amount), 10, 64)
+		if err != nil || priceMinor < 0 {
+			return nil, fmt.Errorf("price for %s must be a whole number of minor units", strings.ToUpper(code))
+		}
+		prices[code] = priceMinor
+	}
+
+	return prices, nil
+}
+
+func formatPrices(prices []domain.CreditPackagePrice) string {
+	entries := make([]string, len(prices))
+	for i, price := range prices {
+		entries[i] = fmt.Sprintf("%s:%d", price.Currency, price.PriceMinor)
+	}
+	return strings.Join(entries, ", ")
+}
+
+func packageRow(pkg *domain.CreditPackage) viewmodel.AdminPackageRow {
+	return viewmodel.AdminPackageRow{
+		ID:         pkg.ID.String(),
+		Name:       pkg.Name,
+		Credits:    pkg.Credits,
+		PriceMinor: pkg.PriceMinor,
+		Currency:   pkg.Currency,
+		Prices:     formatPrices(pkg.Prices),
+		SortOrder:  pkg.SortOrder,
+		Badge:      pkg.Badge,
+		Active:     pkg.Active,
+	}
+}
@@ -0,0 +1,328 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/CP-Payne/wonderpicai/internal/context/requestlog"
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/CP-Payne/wonderpicai/internal/handler/http/response"
+	"github.com/CP-Payne/wonderpicai/internal/service"
+	"github.com/CP-Payne/wonderpicai/internal/validation"
+	adminPages "github.com/CP-Payne/wonderpicai/web/template/pages/admin"
+	"github.com/CP-Payne/wonderpicai/web/template/viewmodel"
+)
+
+// promoTimeLayout is the format of datetime-local inputs
+const promoTimeLayout = "2006-01-02T15:04"
+
+type PromoCodeRequest struct {
+	Code           string `validate:"required,min=3,max=32,alphanum"`
+	Kind           string `validate:"required,oneof=credits discount"`
+	Credits        int    `validate:"gte=0"`
+	PercentOff     int    `validate:"gte=0,lte=100"`
+	PackageID      *uuid.UUID
+	MaxRedemptions int `validate:"gte=0"`
+	PerUserLimit   int `validate:"gte=0"`
+	ValidFrom      *time.Time
+	ValidUntil     *time.Time
+	Active         bool
+}
+
+func (h *AdminHandler) ShowPromosPage(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	promos, err := h.promoService.ListAll(r.Context())
+	if err != nil {
+		logger.Error("Failed to list promo codes", zap.Error(err))
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		return
+	}
+
+	pkgs, err := h.packageService.ListAll(r.Context())
+	if err != nil {
+		logger.Error("Failed to list credit packages", zap.Error(err))
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		return
+	}
+
+	rows := make([]viewmodel.AdminPromoRow, len(promos))
+	for i, promo := range promos {
+		rows[i] = promoRow(&promo, pkgs)
+	}
+
+	data := viewmodel.AdminPromosViewData{
+		Promos: rows,
+		New: viewmodel.AdminPromoForm{
+			Kind:         string(domain.PromoCredits),
+			PerUserLimit: 1,
+			Active:       true,
+			Packages:     packageChoices(pkgs),
+		},
+	}
+
+	err = adminPages.PromosPage(data).Render(r.Context(), w)
+	if err != nil {
+		logger.Error("Failed to render admin promos page", zap.Error(err))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *AdminHandler) HandlePromoCreate(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	pkgs, err := h.packageService.ListAll(r.Context())
+	if err != nil {
+		logger.Error("Failed to list credit packages", zap.Error(err))
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		return
+	}
+
+	req, vm, ok := h.parsePromoForm(r)
+	vm.Packages = packageChoices(pkgs)
+	if !ok {
+		if loadErr := response.LoadAdminNewPromoForm(w, r, logger, vm); loadErr != nil {
+			response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		}
+		return
+	}
+
+	_, err = h.promoService.Create(r.Context(), req.input())
+	if err != nil {
+		if errors.Is(err, domain.ErrDuplicateEntry) {
+			vm.Errors["code"] = "A promo code with this code already exists."
+		} else {
+			logger.Error("Failed to create promo code", zap.Error(err))
+			vm.Error = "Failed to create the promo code, please try again."
+		}
+		if loadErr := response.LoadAdminNewPromoForm(w, r, logger, vm); loadErr != nil {
+			response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		}
+		return
+	}
+
+	response.HxRedirect(w, r, "/admin/promos")
+}
+
+func (h *AdminHandler) HandlePromoActive(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "invalid promo code id", http.StatusBadRequest)
+		return
+	}
+
+	promo, err := h.promoService.SetActive(r.Context(), id, r.FormValue("active") == "true")
+	if err != nil {
+		if errors.Is(err, domain.ErrRecordNotFound) {
+			http.Error(w, "promo code not found", http.StatusNotFound)
+			return
+		}
+		logger.Error("Failed to update promo code", zap.String("promoCodeID", id.String()), zap.Error(err))
+		http.Error(w, "failed to update promo code", http.StatusInternalServerError)
+		return
+	}
+
+	pkgs, err := h.packageService.ListAll(r.Context())
+	if err != nil {
+		logger.Error("Failed to list credit packages", zap.Error(err))
+		http.Error(w, "failed to list credit packages", http.StatusInternalServerError)
+		return
+	}
+
+	if loadErr := response.LoadAdminPromoRow(w, r, logger, promoRow(promo, pkgs)); loadErr != nil {
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+	}
+}
+
+// parsePromoForm reads and validates the promo code form. vm holds the submitted values and
+// any errors so the form can be rendered again.
+func (h *AdminHandler) parsePromoForm(r *http.Request) (PromoCodeRequest, viewmodel.AdminPromoForm, bool) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	vm := viewmodel.AdminPromoForm{Errors: map[string]string{}}
+
+	if err := r.ParseForm(); err != nil {
+		logger.Error("Failed to parse form", zap.Error(err))
+		vm.Error = "Invalid form submission."
+		return PromoCodeRequest{}, vm, false
+	}
+
+	req := PromoCodeRequest{
+		Code:   strings.ToUpper(strings.TrimSpace(r.FormValue("code"))),
+		Kind:   r.FormValue("kind"),
+		Active: r.FormValue("active") == "true",
+	}
+
+	vm.Code = req.Code
+	vm.Kind = req.Kind
+	vm.Active = req.Active
+	vm.PackageID = r.FormValue("package_id")
+	vm.ValidFrom = r.FormValue("valid_from")
+	vm.ValidUntil = r.FormValue("valid_until")
+
+	intFields := []struct {
+		form  string
+		key   string
+		value *int
+	}{
+		{"credits", "credits", &req.Credits},
+		{"percent_off", "percentOff", &req.PercentOff},
+		{"max_redemptions", "maxRedemptions", &req.MaxRedemptions},
+		{"per_user_limit", "perUserLimit", &req.PerUserLimit},
+	}
+	for _, field := range intFields {
+		value := r.FormValue(field.form)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			vm.Errors[field.key] = "must be a whole number"
+			continue
+		}
+		*field.value = n
+	}
+
+	vm.Credits = req.Credits
+	vm.PercentOff = req.PercentOff
+	vm.MaxRedemptions = req.MaxRedemptions
+	vm.PerUserLimit = req.PerUserLimit
+
+	if vm.PackageID != "" {
+		id, err := uuid.Parse(vm.PackageID)
+		if err != nil {
+			vm.Errors["packageID"] = "invalid package"
+		} else {
+			req.PackageID = &id
+		}
+	}
+
+	var err error
+	if req.ValidFrom, err = parsePromoTime(vm.ValidFrom); err != nil {
+		vm.Errors["validFrom"] = "invalid date"
+	}
+	if req.ValidUntil, err = parsePromoTime(vm.ValidUntil); err != nil {
+		vm.Errors["validUntil"] = "invalid date"
+	}
+
+	if len(vm.Errors) > 0 {
+		return req, vm, false
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		vm.Errors, vm.Error = validation.TranslateValidationErrors(err)
+		return req, vm, false
+	}
+
+	switch domain.PromoKind(req.Kind) {
+	case domain.PromoCredits:
+		if req.Credits < 1 {
+			vm.Errors["credits"] = "credit codes must grant at least 1 credit"
+		}
+	case domain.PromoDiscount:
+		if req.PercentOff < 1 {
+			vm.Errors["percentOff"] = "discount codes need a discount between 1 and 100 percent"
+		}
+	}
+	if req.ValidFrom != nil && req.ValidUntil != nil && !req.ValidUntil.After(*req.ValidFrom) {
+		vm.Errors["validUntil"] = "must be after the start of the validity window"
+	}
+
+	return req, vm, len(vm.Errors) == 0
+}
+
+func parsePromoTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.ParseInLocation(promoTimeLayout, value, time.UTC)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (req PromoCodeRequest) input() service.PromoCodeInput {
+	return service.PromoCodeInput{
+		Code:           req.Code,
+		Kind:           domain.PromoKind(req.Kind),
+		Credits:        req.Credits,
+		PercentOff:     req.PercentOff,
+		PackageID:      req.PackageID,
+		MaxRedemptions: req.MaxRedemptions,
+		PerUserLimit:   req.PerUserLimit,
+		ValidFrom:      req.ValidFrom,
+		ValidUntil:     req.ValidUntil,
+		Active:         req.Active,
+	}
+}
+
+func promoRow(promo *domain.PromoCode, pkgs []domain.CreditPackage) viewmodel.AdminPromoRow {
+	row := viewmodel.AdminPromoRow{
+		ID:             promo.ID.String(),
+		Code:           promo.Code,
+		Package:        "-",
+		Redeemed:       promo.Redeemed,
+		MaxRedemptions: limitLabel(promo.MaxRedemptions),
+		PerUserLimit:   limitLabel(promo.PerUserLimit),
+		Validity:       validityLabel(promo.ValidFrom, promo.ValidUntil),
+		Active:         promo.Active,
+	}
+
+	switch promo.Kind {
+	case domain.PromoCredits:
+		row.Value = fmt.Sprintf("%d credits", promo.Credits)
+	case domain.PromoDiscount:
+		row.Value = fmt.Sprintf("%d%% off", promo.PercentOff)
+		row.Package = "All packs"
+		if promo.PackageID != nil {
+			row.Package = "Deleted pack"
+			for _, pkg := range pkgs {
+				if pkg.ID == *promo.PackageID {
+					row.Package = pkg.Name
+				}
+			}
+		}
+	}
+
+	return row
+}
+
+func packageChoices(pkgs []domain.CreditPackage) []viewmodel.AdminPackageChoice {
+	choices := make([]viewmodel.AdminPackageChoice, len(pkgs))
+	for i, pkg := range pkgs {
+		choices[i] = viewmodel.AdminPackageChoice{ID: pkg.ID.String(), Name: pkg.Name}
+	}
+	return choices
+}
+
+func limitLabel(limit int) string {
+	if limit == 0 {
+		return "unlimited"
+	}
+	return strconv.Itoa(limit)
+}
+
+func validityLabel(from, until *time.Time) string {
+	const layout = "2006-01-02 15:04"
+	switch {
+	case from == nil && until == nil:
+		return "always"
+	case from == nil:
+		return "until " + until.UTC().Format(layout)
+	case until == nil:
+		return "from " + from.UTC().Format(layout)
+	}
+	return from.UTC().Format(layout) + " - " + until.UTC().Format(layout)
+}
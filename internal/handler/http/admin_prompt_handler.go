@@ -0,0 +1,99 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/CP-Payne/wonderpicai/internal/context/requestlog"
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/CP-Payne/wonderpicai/internal/handler/http/response"
+	adminPages "github.com/CP-Payne/wonderpicai/web/template/pages/admin"
+	"github.com/CP-Payne/wonderpicai/web/template/viewmodel"
+)
+
+func (h *AdminHandler) ShowStuckPromptsPage(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	jobs, err := h.adminService.StuckPrompts(r.Context())
+	if err != nil {
+		logger.Error("Failed to list stuck prompts", zap.Error(err))
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		return
+	}
+
+	rows := make([]viewmodel.AdminStuckPrompt, len(jobs))
+	for i := range jobs {
+		rows[i] = stuckPromptRow(&jobs[i])
+	}
+
+	data := viewmodel.AdminStuckPromptsViewData{
+		Threshold: h.adminService.StuckPromptAfter().String(),
+		Prompts:   rows,
+	}
+
+	err = adminPages.StuckPromptsPage(data).Render(r.Context(), w)
+	if err != nil {
+		logger.Error("Failed to render stuck prompts page", zap.Error(err))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *AdminHandler) HandlePromptRetry(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	actor, err := auditActor(r)
+	if err != nil {
+		logger.Error("Failed to get userID from context", zap.Error(err))
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		return
+	}
+
+	promptID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "invalid prompt id", http.StatusBadRequest)
+		return
+	}
+
+	err = h.adminService.RetryPrompt(r.Context(), actor, promptID)
+	if err != nil {
+		if !errors.Is(err, domain.ErrPromptNotRetryable) && !errors.Is(err, domain.ErrPromptNotFound) {
+			logger.Error("Failed to retry prompt", zap.String("promptID", promptID.String()), zap.Error(err))
+			// HTMX does not swap error responses, so the row stays in place
+			http.Error(w, "failed to retry prompt", http.StatusInternalServerError)
+			return
+		}
+
+		// The prompt finished or failed in the meantime, it is no longer stuck
+		toastID, loadErr := response.LoadErrorToast(w, r, logger, "The prompt is no longer pending")
+		if loadErr != nil {
+			logger.Error("failed loading ErrorToast", zap.String("toastID", toastID), zap.Error(loadErr))
+		}
+		return
+	}
+
+	// The row is replaced by the toast alone, which removes it from the list
+	toastID, loadErr := response.LoadSuccessToast(w, r, logger, "Prompt submitted again")
+	if loadErr != nil {
+		logger.Error("failed loading SuccessToast", zap.String("toastID", toastID), zap.Error(loadErr))
+	}
+}
+
+func stuckPromptRow(job *domain.GenerationJob) viewmodel.AdminStuckPrompt {
+	return viewmodel.AdminStuckPrompt{
+		ID:        job.PromptID.String(),
+		UserID:    job.UserID.String(),
+		Text:      job.Prompt.Text,
+		Images:    job.Prompt.ImageCount,
+		Created:   job.Prompt.CreatedAt.Format(adminDateTimeLayout),
+		Updated:   job.Prompt.UpdatedAt.Format(adminDateTimeLayout),
+		JobStatus: string(job.Status),
+		Attempts:  fmt.Sprintf("%d/%d", job.Attempts, job.MaxAttempts),
+		LastError: job.LastError,
+	}
+}
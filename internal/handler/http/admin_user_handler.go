@@ -0,0 +1,294 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/CP-Payne/wonderpicai/internal/context/auth"
+	"github.com/CP-Payne/wonderpicai/internal/context/requestlog"
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/CP-Payne/wonderpicai/internal/handler/http/response"
+	"github.com/CP-Payne/wonderpicai/internal/port"
+	"github.com/CP-Payne/wonderpicai/internal/service"
+	adminPages "github.com/CP-Payne/wonderpicai/web/template/pages/admin"
+	"github.com/CP-Payne/wonderpicai/web/template/viewmodel"
+)
+
+// adminUserAuditLimit caps the admin actions shown on a user's page
+const adminUserAuditLimit = 20
+
+func (h *AdminHandler) ShowUsersPage(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	page := adminPage(r)
+
+	users, total, err := h.adminService.SearchUsers(r.Context(), query, page)
+	if err != nil {
+		logger.Error("Failed to search users", zap.String("query", query), zap.Error(err))
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		return
+	}
+
+	rows := make([]viewmodel.AdminUserRow, len(users))
+	for i := range users {
+		rows[i] = userRow(&users[i])
+	}
+
+	data := viewmodel.AdminUsersViewData{
+		Query: query,
+		Users: rows,
+		Pager: adminPager(r, page, len(users), total),
+	}
+
+	err = adminPages.UsersPage(data).Render(r.Context(), w)
+	if err != nil {
+		logger.Error("Failed to render admin users page", zap.Error(err))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *AdminHandler) ShowUserPage(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	userID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "invalid user id", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.adminService.User(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			http.Error(w, "user not found", http.StatusNotFound)
+			return
+		}
+		logger.Error("Failed to load user", zap.String("userID", userID.String()), zap.Error(err))
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		return
+	}
+
+	page := adminPage(r)
+	transactions, total, err := h.adminService.CreditHistory(r.Context(), userID, page)
+	if err != nil {
+		logger.Error("Failed to load credit history", zap.String("userID", userID.String()), zap.Error(err))
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		return
+	}
+
+	events, _, err := h.auditService.List(r.Context(), port.AuditFilter{TargetType: "user", TargetID: userID.String()}, domain.Page{Limit: adminUserAuditLimit})
+	if err != nil {
+		logger.Error("Failed to load audit events of user", zap.String("userID", userID.String()), zap.Error(err))
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		return
+	}
+
+	data := viewmodel.AdminUserViewData{
+		User:         userRow(user),
+		CanManage:    auth.HasRole(r.Context(), domain.RoleAdmin),
+		Actions:      h.userActions(r, user),
+		Transactions: creditTransactions(transactions),
+		Pager:        adminPager(r, page, len(transactions), total),
+		Audit:        auditEvents(events),
+	}
+	if user.DisabledAt != nil {
+		data.DisabledAt = user.DisabledAt.Format(adminDateTimeLayout)
+		data.DisabledNote = user.DisabledReason
+	}
+
+	err = adminPages.UserPage(data).Render(r.Context(), w)
+	if err != nil {
+		logger.Error("Failed to render admin user page", zap.Error(err))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *AdminHandler) HandleUserCredits(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	user, actor, ok := h.userActionTarget(w, r)
+	if !ok {
+		return
+	}
+
+	vm := h.userActions(r, user)
+	vm.Reason = strings.TrimSpace(r.FormValue("reason"))
+	amount, err := strconv.Atoi(strings.TrimSpace(r.FormValue("amount")))
+	vm.Amount = amount
+	if err != nil || amount == 0 {
+		vm.Errors["amount"] = "enter a whole number of credits other than 0"
+		h.loadUserActions(w, r, vm)
+		return
+	}
+
+	err = h.adminService.AdjustCredits(r.Context(), actor, user.ID, amount, vm.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrReasonRequired):
+			vm.Errors["reason"] = "a reason is required"
+		case errors.Is(err, domain.ErrInsufficientFunds):
+			vm.Errors["amount"] = "the user does not have that many credits"
+		default:
+			logger.Error("Failed to adjust credits", zap.String("userID", user.ID.String()), zap.Error(err))
+			vm.Error = "Failed to change the credits, please try again."
+		}
+		h.loadUserActions(w, r, vm)
+		return
+	}
+
+	response.HxRedirect(w, r, "/admin/users/"+user.ID.String())
+}
+
+func (h *AdminHandler) HandleUserRole(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	user, actor, ok := h.userActionTarget(w, r)
+	if !ok {
+		return
+	}
+
+	vm := h.userActions(r, user)
+	role := domain.Role(r.FormValue("role"))
+
+	err := h.adminService.SetRole(r.Context(), actor, user.ID, role)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrInvalidRole):
+			vm.Errors["role"] = "unknown role"
+		case errors.Is(err, domain.ErrOwnAccount):
+			vm.Error = "You cannot change your own role."
+		default:
+			logger.Error("Failed to change role", zap.String("userID", user.ID.String()), zap.Error(err))
+			vm.Error = "Failed to change the role, please try again."
+		}
+		h.loadUserActions(w, r, vm)
+		return
+	}
+
+	response.HxRedirect(w, r, "/admin/users/"+user.ID.String())
+}
+
+func (h *AdminHandler) HandleUserDisabled(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	user, actor, ok := h.userActionTarget(w, r)
+	if !ok {
+		return
+	}
+
+	vm := h.userActions(r, user)
+	disabled := r.FormValue("disabled") == "true"
+	vm.DisableReason = strings.TrimSpace(r.FormValue("reason"))
+
+	err := h.adminService.SetDisabled(r.Context(), actor, user.ID, disabled, vm.DisableReason)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrReasonRequired):
+			vm.Errors["disableReason"] = "a reason is required"
+		case errors.Is(err, domain.ErrOwnAccount):
+			vm.Error = "You cannot disable your own account."
+		default:
+			logger.Error("Failed to change account state", zap.String("userID", user.ID.String()), zap.Error(err))
+			vm.Error = "Failed to change the account, please try again."
+		}
+		h.loadUserActions(w, r, vm)
+		return
+	}
+
+	response.HxRedirect(w, r, "/admin/users/"+user.ID.String())
+}
+
+// userActionTarget loads the user of an admin action and the admin taking it
+func (h *AdminHandler) userActionTarget(w http.ResponseWriter, r *http.Request) (*domain.User, service.AuditActor, bool) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	actor, err := auditActor(r)
+	if err != nil {
+		logger.Error("Failed to get userID from context", zap.Error(err))
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		return nil, service.AuditActor{}, false
+	}
+
+	userID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "invalid user id", http.StatusBadRequest)
+		return nil, service.AuditActor{}, false
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form submission", http.StatusBadRequest)
+		return nil, service.AuditActor{}, false
+	}
+
+	user, err := h.adminService.User(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			http.Error(w, "user not found", http.StatusNotFound)
+			return nil, service.AuditActor{}, false
+		}
+		logger.Error("Failed to load user", zap.String("userID", userID.String()), zap.Error(err))
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		return nil, service.AuditActor{}, false
+	}
+
+	return user, actor, true
+}
+
+func (h *AdminHandler) loadUserActions(w http.ResponseWriter, r *http.Request, vm viewmodel.AdminUserActions) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	if loadErr := response.LoadAdminUserActions(w, r, logger, vm); loadErr != nil {
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+	}
+}
+
+func (h *AdminHandler) userActions(r *http.Request, user *domain.User) viewmodel.AdminUserActions {
+	vm := viewmodel.AdminUserActions{
+		UserID:   user.ID.String(),
+		Role:     string(user.Role),
+		Disabled: user.Disabled(),
+		Errors:   map[string]string{},
+	}
+	for _, role := range domain.Roles {
+		vm.Roles = append(vm.Roles, string(role))
+	}
+	if actorID, err := auth.UserID(r.Context()); err == nil {
+		vm.Self = actorID == user.ID
+	}
+	return vm
+}
+
+func userRow(user *domain.User) viewmodel.AdminUserRow {
+	return viewmodel.AdminUserRow{
+		ID:       user.ID.String(),
+		Email:    user.Email,
+		Username: user.Username,
+		Role:     string(user.Role),
+		Credits:  user.Wallet.Credits,
+		Created:  user.CreatedAt.Format("2 Jan 2006"),
+		Disabled: user.Disabled(),
+	}
+}
+
+func creditTransactions(transactions []domain.CreditTransaction) []viewmodel.AdminCreditTransaction {
+	vms := make([]viewmodel.AdminCreditTransaction, len(transactions))
+	for i, transaction := range transactions {
+		vms[i] = viewmodel.AdminCreditTransaction{
+			Date:      transaction.CreatedAt.Format(adminDateTimeLayout),
+			Kind:      string(transaction.Kind),
+			Amount:    transaction.Amount,
+			Balance:   transaction.Balance,
+			Reference: transaction.Reference,
+			Reason:    transaction.Reason,
+		}
+	}
+	return vms
+}
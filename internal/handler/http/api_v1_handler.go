@@ -0,0 +1,442 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/CP-Payne/wonderpicai/internal/context/auth"
+	"github.com/CP-Payne/wonderpicai/internal/context/requestlog"
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/CP-Payne/wonderpicai/internal/handler/http/response"
+	"github.com/CP-Payne/wonderpicai/internal/openapi"
+	"github.com/CP-Payne/wonderpicai/internal/service"
+	"github.com/CP-Payne/wonderpicai/internal/validation"
+)
+
+const maxApiRequestBytes = 64 << 10
+
+// ApiV1Handler serves the versioned JSON API under /api/v1.
+// Errors are returned as RFC 7807 problem details.
+type ApiV1Handler struct {
+	logger        *zap.Logger
+	validate      *validator.Validate
+	genService    service.GenService
+	walletService service.WalletService
+}
+
+type GenerationCreateRequest struct {
+	Prompt     string `json:"prompt" validate:"required,min=3"`
+	ImageCount int    `json:"image_count" validate:"required,number,gte=1,lte=10"`
+	Size       string `json:"size" validate:"required,imagesize"`
+	Model      string `json:"model,omitempty"`
+	Steps      int    `json:"steps,omitempty" validate:"gte=0"`
+	Priority   bool   `json:"priority,omitempty"`
+}
+
+type PromptResource struct {
+	ID           uuid.UUID       `json:"id"`
+	Status       string          `json:"status"`
+	Prompt       string          `json:"prompt"`
+	Cost         int             `json:"cost"`
+	PriceVersion string          `json:"price_version,omitempty"`
+	ImageCount   int             `json:"image_count"`
+	Size         string          `json:"size"`
+	Width        int             `json:"width"`
+	Height       int             `json:"height"`
+	Images       []ImageResource `json:"images"`
+	CreatedAt    time.Time       `json:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+}
+
+type ImageResource struct {
+	ID         uuid.UUID `json:"id"`
+	PromptID   uuid.UUID `json:"prompt_id"`
+	Status     string    `json:"status"`
+	ContentURL string    `json:"content_url,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type WalletResource struct {
+	Credits uint `json:"credits"`
+}
+
+type Pagination struct {
+	Limit  int   `json:"limit"`
+	Offset int   `json:"offset"`
+	Total  int64 `json:"total"`
+}
+
+type ListResponse[T any] struct {
+	Data       []T        `json:"data"`
+	Pagination Pagination `json:"pagination"`
+}
+
+func NewApiV1Handler(logger *zap.Logger, validate *validator.Validate, genService service.GenService, walletService service.WalletService) *ApiV1Handler {
+	return &ApiV1Handler{
+		logger:        logger.With(zap.String("component", "ApiV1Handler")),
+		validate:      validate,
+		genService:    genService,
+		walletService: walletService,
+	}
+}
+
+func (h *ApiV1Handler) HandleGenerationCreate(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	userID, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	var req GenerationCreateRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxApiRequestBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		response.ProblemStatus(w, r, http.StatusBadRequest, fmt.Sprintf("invalid JSON body: %v", err))
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		fieldErrors, generalErr := validation.TranslateValidationErrors(err)
+		problem := response.NewProblem(r, http.StatusUnprocessableEntity, generalErr)
+		if problem.Detail == "" {
+			problem.Detail = "the generation request is invalid"
+		}
+		problem.Errors = fieldErrors
+		response.WriteProblem(w, problem)
+		return
+	}
+
+	prompt, err := h.genService.GenerateImage(r.Context(), userID, &service.PromptData{
+		Prompt:     req.Prompt,
+		ImageCount: req.ImageCount,
+		Size:       req.Size,
+		Model:      req.Model,
+		Steps:      req.Steps,
+		Priority:   req.Priority,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrInsufficientFunds):
+			response.ProblemStatus(w, r, http.StatusPaymentRequired, "insufficient credits")
+		case errors.Is(err, domain.ErrGenerationLimitReached):
+			response.ProblemStatus(w, r, http.StatusTooManyRequests, "too many generations in progress, wait for them to finish")
+		case errors.Is(err, domain.ErrInvalidGenerationInput):
+			response.ProblemStatus(w, r, http.StatusUnprocessableEntity, err.Error())
+		default:
+			logger.Error("Failed to create generation", zap.String("userID", userID.String()), zap.Error(err))
+			response.ProblemStatus(w, r, http.StatusInternalServerError, "")
+		}
+		return
+	}
+
+	w.Header().Set("Location", "/api/v1/prompts/"+prompt.ID.String())
+	response.JSON(w, http.StatusAccepted, promptResource(prompt))
+}
+
+func (h *ApiV1Handler) HandlePromptGet(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	userID, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	promptID, ok := h.pathID(w, r)
+	if !ok {
+		return
+	}
+
+	prompt, err := h.genService.GetPrompt(r.Context(), userID, promptID)
+	if err != nil {
+		if errors.Is(err, domain.ErrPromptNotFound) {
+			response.ProblemStatus(w, r, http.StatusNotFound, "prompt not found")
+			return
+		}
+		logger.Error("Failed to retrieve prompt", zap.String("userID", userID.String()), zap.String("promptID", promptID.String()), zap.Error(err))
+		response.ProblemStatus(w, r, http.StatusInternalServerError, "")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, promptResource(prompt))
+}
+
+func (h *ApiV1Handler) HandlePromptList(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	userID, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	page, ok := h.page(w, r)
+	if !ok {
+		return
+	}
+
+	prompts, total, err := h.genService.ListPrompts(r.Context(), userID, page)
+	if err != nil {
+		logger.Error("Failed to list prompts", zap.String("userID", userID.String()), zap.Error(err))
+		response.ProblemStatus(w, r, http.StatusInternalServerError, "")
+		return
+	}
+
+	data := make([]PromptResource, len(prompts))
+	for i := range prompts {
+		data[i] = promptResource(&prompts[i])
+	}
+
+	response.JSON(w, http.StatusOK, ListResponse[PromptResource]{
+		Data:       data,
+		Pagination: Pagination{Limit: page.Limit, Offset: page.Offset, Total: total},
+	})
+}
+
+func (h *ApiV1Handler) HandleImageList(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	userID, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	page, ok := h.page(w, r)
+	if !ok {
+		return
+	}
+
+	images, total, err := h.genService.ListImages(r.Context(), userID, page)
+	if err != nil {
+		logger.Error("Failed to list images", zap.String("userID", userID.String()), zap.Error(err))
+		response.ProblemStatus(w, r, http.StatusInternalServerError, "")
+		return
+	}
+
+	data := make([]ImageResource, len(images))
+	for i := range images {
+		data[i] = imageResource(&images[i])
+	}
+
+	response.JSON(w, http.StatusOK, ListResponse[ImageResource]{
+		Data:       data,
+		Pagination: Pagination{Limit: page.Limit, Offset: page.Offset, Total: total},
+	})
+}
+
+// HandleImageContent returns the raw image of a completed image
+func (h *ApiV1Handler) HandleImageContent(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	userID, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	imageID, ok := h.pathID(w, r)
+	if !ok {
+		return
+	}
+
+	image, err := h.genService.GetImageByID(r.Context(), userID, imageID)
+	if err != nil {
+		if errors.Is(err, domain.ErrImageNotFound) {
+			response.ProblemStatus(w, r, http.StatusNotFound, "image not found")
+			return
+		}
+		logger.Error("Failed to retrieve image", zap.String("userID", userID.String()), zap.String("imageID", imageID.String()), zap.Error(err))
+		response.ProblemStatus(w, r, http.StatusInternalServerError, "")
+		return
+	}
+
+	if image.Status != domain.Completed || len(image.ImageData) == 0 {
+		response.ProblemStatus(w, r, http.StatusConflict, fmt.Sprintf("image is %s", image.Status.APIName()))
+		return
+	}
+
+	contentType := http.DetectContentType(image.ImageData)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(image.ImageData)))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "image-"+image.ID.String()+imageExtension(contentType)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(image.ImageData)
+}
+
+func (h *ApiV1Handler) HandleImageDelete(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	userID, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	imageID, ok := h.pathID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.genService.DeleteImageByID(r.Context(), userID, imageID); err != nil {
+		logger.Error("Failed to delete image", zap.String("userID", userID.String()), zap.String("imageID", imageID.String()), zap.Error(err))
+		response.ProblemStatus(w, r, http.StatusInternalServerError, "")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ApiV1Handler) HandleFailedImagesDelete(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	userID, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.genService.DeleteFailedImages(r.Context(), userID); err != nil {
+		logger.Error("Failed to delete failed images", zap.String("userID", userID.String()), zap.Error(err))
+		response.ProblemStatus(w, r, http.StatusInternalServerError, "")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ApiV1Handler) HandleWalletGet(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	userID, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	wallet, err := h.walletService.GetWallet(r.Context(), userID)
+	if err != nil {
+		logger.Error("Failed to retrieve wallet", zap.String("userID", userID.String()), zap.Error(err))
+		response.ProblemStatus(w, r, http.StatusInternalServerError, "")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, WalletResource{Credits: wallet.Credits})
+}
+
+// HandleOpenAPISpec serves the OpenAPI document describing this API
+func (h *ApiV1Handler) HandleOpenAPISpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.Write(openapi.Spec)
+}
+
+// HandleNotFound returns a problem for unknown API routes instead of the HTML 404 page
+func (h *ApiV1Handler) HandleNotFound(w http.ResponseWriter, r *http.Request) {
+	response.ProblemStatus(w, r, http.StatusNotFound, "")
+}
+
+func (h *ApiV1Handler) HandleMethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	response.ProblemStatus(w, r, http.StatusMethodNotAllowed, "")
+}
+
+func (h *ApiV1Handler) userID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	userID, err := auth.UserID(r.Context())
+	if err != nil {
+		logger.Error("Failed to get UserID from context")
+		response.ProblemStatus(w, r, http.StatusInternalServerError, "")
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+func (h *ApiV1Handler) pathID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.ProblemStatus(w, r, http.StatusBadRequest, "id must be a UUID")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// page reads the limit and offset query parameters
+func (h *ApiV1Handler) page(w http.ResponseWriter, r *http.Request) (domain.Page, bool) {
+	page := domain.Page{Limit: domain.DefaultPageLimit}
+	query := r.URL.Query()
+
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > domain.MaxPageLimit {
+			response.ProblemStatus(w, r, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", domain.MaxPageLimit))
+			return domain.Page{}, false
+		}
+		page.Limit = limit
+	}
+
+	if raw := query.Get("offset"); raw != "" {
+		offset, err := strconv.Atoi(raw)
+		if err != nil || offset < 0 {
+			response.ProblemStatus(w, r, http.StatusBadRequest, "offset must be a non-negative integer")
+			return domain.Page{}, false
+		}
+		page.Offset = offset
+	}
+
+	return page, true
+}
+
+func promptResource(prompt *domain.Prompt) PromptResource {
+	images := make([]ImageResource, len(prompt.Images))
+	for i := range prompt.Images {
+		images[i] = imageResource(&prompt.Images[i])
+	}
+
+	return PromptResource{
+		ID:           prompt.ID,
+		Status:       prompt.Status.APIName(),
+		Prompt:       prompt.Text,
+		Cost:         prompt.Cost,
+		PriceVersion: prompt.PriceVersion,
+		ImageCount:   prompt.ImageCount,
+		Size:         prompt.Size,
+		Width:        prompt.Width,
+		Height:       prompt.Height,
+		Images:       images,
+		CreatedAt:    prompt.CreatedAt,
+		UpdatedAt:    prompt.UpdatedAt,
+	}
+}
+
+func imageResource(image *domain.Image) ImageResource {
+	resource := ImageResource{
+		ID:        image.ID,
+		PromptID:  image.PromptID,
+		Status:    image.Status.APIName(),
+		CreatedAt: image.CreatedAt,
+	}
+	if image.Status == domain.Completed {
+		resource.ContentURL = "/api/v1/images/" + image.ID.String() + "/content"
+	}
+	return resource
+}
+
+// imageExtension returns the file extension for a sniffed image content type
+func imageExtension(contentType string) string {
+	switch contentType {
+	case "image/png":
+		return ".png"
+	case "image/jpeg":
+		return ".jpg"
+	case "image/webp":
+		return ".webp"
+	case "image/gif":
+		return ".gif"
+	default:
+		return ".bin"
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/CP-Payne/wonderpicai/internal/config"
+	"github.com/CP-Payne/wonderpicai/internal/context/requestlog"
 	"github.com/CP-Payne/wonderpicai/internal/domain"
 	"github.com/CP-Payne/wonderpicai/internal/handler/http/response"
 	"github.com/CP-Payne/wonderpicai/internal/service"
@@ -13,7 +14,6 @@ import (
 	authPages "github.com/CP-Payne/wonderpicai/web/template/pages/auth"
 	"github.com/CP-Payne/wonderpicai/web/template/viewmodel"
 	"github.com/go-playground/validator/v10"
-	"github.com/rs/xid"
 	"go.uber.org/zap"
 )
 
@@ -40,6 +40,8 @@ type LoginRequest struct {
 }
 
 func (h *AuthHandler) ShowLoginPage(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
 	// Viewmodel empty on initial load
 	vm := viewmodel.LoginFormComponentData{
 		Form: viewmodel.LoginFormData{
@@ -50,13 +52,15 @@ func (h *AuthHandler) ShowLoginPage(w http.ResponseWriter, r *http.Request) {
 	}
 	err := authPages.AuthPage(authComponents.LoginForm(vm), config.Cfg.GoogleAuth.ClientSecret, viewmodel.LoginTitle).Render(r.Context(), w)
 	if err != nil {
-		h.logger.Error("Failed to render login page", zap.Error(err))
+		logger.Error("Failed to render login page", zap.Error(err))
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 }
 
 func (h *AuthHandler) ShowSignupPage(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
 	// Viewmodel empty on initial load
 	vm := viewmodel.SignupFormComponentData{
 		Form: viewmodel.SignupFormData{
@@ -69,16 +73,18 @@ func (h *AuthHandler) ShowSignupPage(w http.ResponseWriter, r *http.Request) {
 
 	err := authPages.AuthPage(authComponents.SignupForm(vm), config.Cfg.GoogleAuth.ClientSecret, viewmodel.SignUpTitle).Render(r.Context(), w)
 	if err != nil {
-		h.logger.Error("Failed to render login page", zap.Error(err))
+		logger.Error("Failed to render login page", zap.Error(err))
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 }
 
 func (h *AuthHandler) HandleSignup(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
 	if err := r.ParseForm(); err != nil {
-		h.logger.Error("Failed to parse form", zap.Error(err))
-		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, xid.New().String(), "")
+		logger.Error("Failed to parse form", zap.Error(err))
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
 		return
 	}
 
@@ -104,21 +110,21 @@ func (h *AuthHandler) HandleSignup(w http.ResponseWriter, r *http.Request) {
 		vm.Error = generalValError
 
 		if vm.Error != "" {
-			h.logger.Error("General signup validation error", zap.String("error", vm.Error), zap.Error(err))
+			logger.Error("General signup validation error", zap.String("error", vm.Error), zap.Error(err))
 
-			toastID, loadErr := response.LoadErrorToast(w, r, h.logger, vm.Error)
+			_, loadErr := response.LoadErrorToast(w, r, logger, vm.Error)
 			if loadErr != nil {
-				response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, toastID, "")
+				response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
 				return
 			}
 			// Load form with validation errors (if any) before ending request processing
 		}
 
-		h.logger.Warn("Signup validation errors", zap.Any("errors", vm.Errors))
+		logger.Warn("Signup validation errors", zap.Any("errors", vm.Errors))
 
-		loadErr := response.LoadSignupForm(w, r, h.logger, vm)
+		loadErr := response.LoadSignupForm(w, r, logger, vm)
 		if loadErr != nil {
-			response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "", "")
+			response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
 			return
 		}
 
@@ -132,23 +138,23 @@ func (h *AuthHandler) HandleSignup(w http.ResponseWriter, r *http.Request) {
 	vm.Errors = make(map[string]string)
 	vm.Error = ""
 
-	user, token, err := h.authService.Register(req.Username, req.Email, req.Password)
+	user, token, err := h.authService.Register(r.Context(), req.Username, req.Email, req.Password)
 	if err != nil {
 		if errors.Is(err, domain.ErrEmailAlreadyExists) {
 			vm.Errors["email"] = "This email address is already registered."
 		} else {
 			vm.Error = "An error occured while creating your account. Please try again"
-			h.logger.Error("Unexpected error from AuthService.Register", zap.Error(err))
+			logger.Error("Unexpected error from AuthService.Register", zap.Error(err))
 		}
-		loadErr := response.LoadSignupForm(w, r, h.logger, vm)
+		loadErr := response.LoadSignupForm(w, r, logger, vm)
 		if loadErr != nil {
-			response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "", "")
+			response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
 			return
 		}
 		return
 	}
 
-	h.logger.Info("User registered successfully", zap.String("userID", user.ID.String()), zap.String("email", user.Email))
+	logger.Info("User registered successfully", zap.String("userID", user.ID.String()), zap.String("email", user.Email))
 
 	response.SetAuthCookie(w, r, token)
 
@@ -156,9 +162,11 @@ func (h *AuthHandler) HandleSignup(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *AuthHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
 	if err := r.ParseForm(); err != nil {
-		h.logger.Error("Failed to parse form", zap.Error(err))
-		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, xid.New().String(), "")
+		logger.Error("Failed to parse form", zap.Error(err))
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
 		return
 	}
 
@@ -182,21 +190,21 @@ func (h *AuthHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
 		vm.Error = generalValError
 
 		if vm.Error != "" {
-			h.logger.Error("General login validation error", zap.String("error", vm.Error), zap.Error(err))
+			logger.Error("General login validation error", zap.String("error", vm.Error), zap.Error(err))
 
-			toastID, loadErr := response.LoadErrorToast(w, r, h.logger, vm.Error)
+			_, loadErr := response.LoadErrorToast(w, r, logger, vm.Error)
 			if loadErr != nil {
-				response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, toastID, "")
+				response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
 				return
 			}
 
 		}
 
-		h.logger.Warn("Login validation errors", zap.Any("errors", vm.Errors))
+		logger.Warn("Login validation errors", zap.Any("errors", vm.Errors))
 
-		loadErr := response.LoadLoginForm(w, r, h.logger, vm)
+		loadErr := response.LoadLoginForm(w, r, logger, vm)
 		if loadErr != nil {
-			response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "", "")
+			response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
 			return
 		}
 		// Toast and Form loaded and ready to be returned
@@ -209,14 +217,17 @@ func (h *AuthHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
 	vm.Errors = make(map[string]string)
 	vm.Error = ""
 
-	user, token, err := h.authService.Login(req.Email, req.Password)
+	user, token, err := h.authService.Login(r.Context(), req.Email, req.Password)
 	if err != nil {
-		if errors.Is(err, domain.ErrInvalidCredentials) {
+		if errors.Is(err, domain.ErrInvalidCredentials) || errors.Is(err, domain.ErrAccountDisabled) {
 			vm.Error = "Invalid Credentials"
+			if errors.Is(err, domain.ErrAccountDisabled) {
+				vm.Error = "This account has been disabled. Please contact support."
+			}
 
-			loadErr := response.LoadLoginForm(w, r, h.logger, vm)
+			loadErr := response.LoadLoginForm(w, r, logger, vm)
 			if loadErr != nil {
-				response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "", "")
+				response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
 				return
 			}
 
@@ -225,17 +236,17 @@ func (h *AuthHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
 
 		vm.Error = "Something went wrong. Please try again."
 
-		h.logger.Error("General login validation error", zap.Error(err))
+		logger.Error("General login validation error", zap.Error(err))
 
-		toastID, loadErr := response.LoadErrorToast(w, r, h.logger, vm.Error)
+		_, loadErr := response.LoadErrorToast(w, r, logger, vm.Error)
 		if loadErr != nil {
-			response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, toastID, "")
+			response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
 			return
 		}
 
-		loadErr = response.LoadLoginForm(w, r, h.logger, vm)
+		loadErr = response.LoadLoginForm(w, r, logger, vm)
 		if loadErr != nil {
-			response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "", "")
+			response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
 			return
 		}
 
@@ -243,7 +254,7 @@ func (h *AuthHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.logger.Info("User authenticated successfully", zap.String("userID", user.ID.String()))
+	logger.Info("User authenticated successfully", zap.String("userID", user.ID.String()))
 
 	response.SetAuthCookie(w, r, token)
 	response.HxRedirect(w, r, "/gen")
@@ -257,6 +268,7 @@ func (h *AuthHandler) HandleLogout(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *AuthHandler) HandleExternalAuth(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
 
 	vm := viewmodel.LoginFormComponentData{
 		Form:   viewmodel.LoginFormData{},
@@ -268,25 +280,28 @@ func (h *AuthHandler) HandleExternalAuth(w http.ResponseWriter, r *http.Request)
 	if err != nil {
 
 		vm.Error = "Something went wrong. Please try again."
+		if errors.Is(err, domain.ErrAccountDisabled) {
+			vm.Error = "This account has been disabled. Please contact support."
+		}
 
-		h.logger.Error("General login validation error", zap.Error(err))
+		logger.Error("General login validation error", zap.Error(err))
 
-		toastID, loadErr := response.LoadErrorToast(w, r, h.logger, vm.Error)
+		_, loadErr := response.LoadErrorToast(w, r, logger, vm.Error)
 		if loadErr != nil {
-			response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, toastID, "")
+			response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
 			return
 		}
 
-		loadErr = response.LoadLoginForm(w, r, h.logger, vm)
+		loadErr = response.LoadLoginForm(w, r, logger, vm)
 		if loadErr != nil {
-			response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "", "")
+			response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
 			return
 		}
 
 		return
 	}
 
-	h.logger.Info("User authenticated successfully", zap.String("userID", user.ID.String()))
+	logger.Info("User authenticated successfully", zap.String("userID", user.ID.String()))
 
 	response.SetAuthCookie(w, r, token)
 	response.HxRedirect(w, r, "/gen")
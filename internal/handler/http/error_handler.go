@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/CP-Payne/wonderpicai/internal/context/requestlog"
 	errorpages "github.com/CP-Payne/wonderpicai/web/template/pages/error"
 	"github.com/CP-Payne/wonderpicai/web/template/viewmodel"
 	"go.uber.org/zap"
@@ -18,6 +19,8 @@ func NewErrorHandler(logger *zap.Logger) *ErrorHandler {
 }
 
 func (h *ErrorHandler) ServeGenericErrorPage(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
 	statusCodeStr := r.URL.Query().Get("statusCode")
 	errorID := r.URL.Query().Get("errorID")
 	customMessage := r.URL.Query().Get("message")
@@ -52,7 +55,7 @@ func (h *ErrorHandler) ServeGenericErrorPage(w http.ResponseWriter, r *http.Requ
 	component := errorpages.ServerErrorPage(pageData)
 	err := component.Render(r.Context(), w)
 	if err != nil {
-		h.logger.Error("Failed to render error page template itself!", zap.Error(err))
+		logger.Error("Failed to render error page template itself!", zap.Error(err))
 		http.Error(w, "An error occured, and then another error occured while trying to display the first error.", http.StatusInternalServerError)
 	}
 
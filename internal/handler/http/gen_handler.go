@@ -1,8 +1,10 @@
 package http
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -10,14 +12,17 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
-	"github.com/rs/xid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 
 	"github.com/CP-Payne/wonderpicai/internal/context/auth"
 	"github.com/CP-Payne/wonderpicai/internal/context/credits"
+	"github.com/CP-Payne/wonderpicai/internal/context/requestlog"
 	"github.com/CP-Payne/wonderpicai/internal/domain"
 	"github.com/CP-Payne/wonderpicai/internal/handler/http/response"
 	"github.com/CP-Payne/wonderpicai/internal/service"
+	"github.com/CP-Payne/wonderpicai/internal/tracing"
 	"github.com/CP-Payne/wonderpicai/internal/validation"
 	genPages "github.com/CP-Payne/wonderpicai/web/template/pages/gen"
 	"github.com/CP-Payne/wonderpicai/web/template/viewmodel"
@@ -32,6 +37,7 @@ type GenHandler struct {
 type GenRequest struct {
 	Prompt     string `validate:"required,min=3"`
 	ImageCount int    `validate:"required,number,gte=1,lte=10"`
+	Size       string `validate:"required,imagesize"`
 }
 
 type ImageUpdateWebhookRequest struct {
@@ -50,29 +56,30 @@ func NewGenHandler(logger *zap.Logger, validate *validator.Validate, genService
 }
 
 func (h *GenHandler) ShowGenPage(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
 
 	var images []viewmodel.Image
 
 	userID, err := auth.UserID(r.Context())
 	if err != nil {
-		h.logger.Error("Failed to get UserID from context")
-		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "", "")
+		logger.Error("Failed to get UserID from context")
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
 		return
 	}
 
 	userCredits, err := credits.RemainingCredits(r.Context())
 	if err != nil {
-		h.logger.Error("failed to retrieve user credits from context", zap.Error(err))
-		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "", "")
+		logger.Error("failed to retrieve user credits from context", zap.Error(err))
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
 		return
 	}
 
 	userPrompts, err := h.genService.GetAllPrompts(r.Context(), userID)
 	if err != nil {
-		h.logger.Error("failed to retrieve images from genService", zap.Error(err), zap.String("userID", userID.String()))
-		toastID, loadErr := response.LoadErrorToast(w, r, h.logger, "failed loading images")
+		logger.Error("failed to retrieve images from genService", zap.Error(err), zap.String("userID", userID.String()))
+		_, loadErr := response.LoadErrorToast(w, r, logger, "failed loading images")
 		if loadErr != nil {
-			response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, toastID, "")
+			response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
 			return
 		}
 		images = []viewmodel.Image{}
@@ -94,22 +101,20 @@ func (h *GenHandler) ShowGenPage(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// Calculate minimum cost
-	minCost := h.genService.CalculateCost(r.Context(), &service.PromptData{
-		ImageCount: 1,
-	})
-
 	genPageData := viewmodel.GenPageData{
 		GalleryData: viewmodel.GalleryComponentData{
 			Images: images,
 		},
 		GenFormData: viewmodel.GenFormComponentData{
 			Form: viewmodel.GenFormData{
-				MinCost:         minCost,
+				CostPerImage:    h.genService.CalculateCost(r.Context(), &service.PromptData{ImageCount: 1, Size: domain.DefaultImageSize}),
 				Credits:         userCredits,
 				MaxImagesPerGen: 10,
 				HasFailedImages: containsFailedImages,
 				ImageCount:      1,
+				Size:            domain.DefaultImageSize,
+				Sizes:           h.sizeOptions(r.Context()),
+				QueuePosition:   h.queuePosition(r.Context(), userID),
 			},
 			Errors: map[string]string{},
 			Error:  "",
@@ -117,37 +122,39 @@ func (h *GenHandler) ShowGenPage(w http.ResponseWriter, r *http.Request) {
 	}
 	err = genPages.GenPage(genPageData).Render(r.Context(), w)
 	if err != nil {
-		h.logger.Error("Failed to render login page", zap.Error(err))
+		logger.Error("Failed to render login page", zap.Error(err))
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 }
 
 func (h *GenHandler) HandleGenerationCreate(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
 	if err := r.ParseForm(); err != nil {
-		h.logger.Error("Failed to parse form", zap.Error(err))
-		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, xid.New().String(), "")
+		logger.Error("Failed to parse form", zap.Error(err))
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
 		return
 	}
 
 	userID, err := auth.UserID(r.Context())
 	if err != nil {
-		h.logger.Error("Failed to get UserID from context")
-		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "", "")
+		logger.Error("Failed to get UserID from context")
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
 		return
 	}
 
 	// Get user credits
 	userCredits, err := credits.RemainingCredits(r.Context())
 	if err != nil {
-		h.logger.Error("failed to retrieve user credits from context", zap.Error(err))
-		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "", "")
+		logger.Error("failed to retrieve user credits from context", zap.Error(err))
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
 		return
 	}
 
 	containsFailedImages, err := h.genService.ContainsFailedImages(r.Context(), userID)
 	if err != nil {
-		h.logger.Error("failed to determine if user has failed images defaulting to false", zap.Error(err))
+		logger.Error("failed to determine if user has failed images defaulting to false", zap.Error(err))
 		containsFailedImages = false
 	}
 
@@ -155,9 +162,12 @@ func (h *GenHandler) HandleGenerationCreate(w http.ResponseWriter, r *http.Reque
 		Form: viewmodel.GenFormData{
 			Prompt:          r.FormValue("prompt"),
 			Credits:         userCredits,
-			MinCost:         h.genService.CalculateCost(r.Context(), &service.PromptData{ImageCount: 1}),
+			CostPerImage:    h.genService.CalculateCost(r.Context(), &service.PromptData{ImageCount: 1, Size: r.FormValue("size")}),
+			Size:            r.FormValue("size"),
+			Sizes:           h.sizeOptions(r.Context()),
 			MaxImagesPerGen: 10,
 			HasFailedImages: containsFailedImages,
+			QueuePosition:   h.queuePosition(r.Context(), userID),
 		},
 		Errors: map[string]string{},
 		Error:  "",
@@ -166,11 +176,11 @@ func (h *GenHandler) HandleGenerationCreate(w http.ResponseWriter, r *http.Reque
 	imageCountStr := r.FormValue("image_count")
 	imageCount, err := strconv.Atoi(imageCountStr)
 	if err != nil {
-		h.logger.Error("Failed to parse image count", zap.Error(err))
+		logger.Error("Failed to parse image count", zap.Error(err))
 		vm.Errors["imageCount"] = "invalid image count"
-		loadErr := response.LoadGenForm(w, r, h.logger, vm)
+		loadErr := response.LoadGenForm(w, r, logger, vm)
 		if loadErr != nil {
-			response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "", "")
+			response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
 			return
 		}
 		// End request processing here
@@ -180,6 +190,7 @@ func (h *GenHandler) HandleGenerationCreate(w http.ResponseWriter, r *http.Reque
 	req := GenRequest{
 		Prompt:     r.FormValue("prompt"),
 		ImageCount: imageCount,
+		Size:       r.FormValue("size"),
 	}
 	vm.Form.ImageCount = req.ImageCount
 
@@ -190,20 +201,20 @@ func (h *GenHandler) HandleGenerationCreate(w http.ResponseWriter, r *http.Reque
 		vm.Error = generalValError
 
 		if vm.Error != "" {
-			h.logger.Error("General generation validation error", zap.String("error", vm.Error), zap.Error(err))
+			logger.Error("General generation validation error", zap.String("error", vm.Error), zap.Error(err))
 
-			toastID, loadErr := response.LoadErrorToast(w, r, h.logger, vm.Error)
+			_, loadErr := response.LoadErrorToast(w, r, logger, vm.Error)
 			if loadErr != nil {
-				response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, toastID, "")
+				response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
 				return
 			}
 		}
 
-		h.logger.Warn("generation validation errors", zap.Any("errors", vm.Errors))
+		logger.Warn("generation validation errors", zap.Any("errors", vm.Errors))
 
-		loadErr := response.LoadGenForm(w, r, h.logger, vm)
+		loadErr := response.LoadGenForm(w, r, logger, vm)
 		if loadErr != nil {
-			response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "", "")
+			response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
 			return
 		}
 		return
@@ -219,13 +230,13 @@ func (h *GenHandler) HandleGenerationCreate(w http.ResponseWriter, r *http.Reque
 	// --- Note ---
 	// Avaible funds is also checked in the service and repository layer
 	// This check is to prevent unecessary calls to service and repository layer
-	totalCost := h.genService.CalculateCost(r.Context(), &service.PromptData{ImageCount: req.ImageCount})
+	totalCost := h.genService.CalculateCost(r.Context(), &service.PromptData{ImageCount: req.ImageCount, Size: req.Size})
 
 	if totalCost > userCredits {
 		vm.Errors["credits"] = fmt.Sprintf("insufficient credits - you require %d more", totalCost-userCredits)
-		loadErr := response.LoadGenForm(w, r, h.logger, vm)
+		loadErr := response.LoadGenForm(w, r, logger, vm)
 		if loadErr != nil {
-			response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "", "")
+			response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
 			return
 		}
 		return
@@ -236,30 +247,52 @@ func (h *GenHandler) HandleGenerationCreate(w http.ResponseWriter, r *http.Reque
 	prompt, err := h.genService.GenerateImage(r.Context(), userID, &service.PromptData{
 		Prompt:     req.Prompt,
 		ImageCount: req.ImageCount,
+		Size:       req.Size,
 	})
 
 	if err != nil {
-		h.logger.Error("failed to create prompt", zap.Error(err))
-		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "", "")
+		logger.Error("failed to create prompt", zap.Error(err))
+
+		message, ok := generationErrorMessage(err)
+		if !ok {
+			response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+			return
+		}
+
+		if errors.Is(err, domain.ErrInsufficientFunds) {
+			// Credits were spent elsewhere after the balance check above
+			vm.Errors["credits"] = message
+		} else {
+			_, loadErr := response.LoadErrorToast(w, r, logger, message)
+			if loadErr != nil {
+				response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+				return
+			}
+		}
+
+		loadErr := response.LoadGenForm(w, r, logger, vm)
+		if loadErr != nil {
+			response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		}
 		return
 	}
 
-	// Generation request successfully sent show new credit balance
+	// Prompt queued, show new credit balance. Credits are refunded if the job is dead-lettered.
 	vm.Form.Credits = vm.Form.Credits - totalCost
-	// TODO: if the image generation API fails generating images, refund credits (events)
+	vm.Form.QueuePosition = h.queuePosition(r.Context(), userID)
 
 	// Load new pending images
 	for _, image := range prompt.Images {
-		loadErr := response.LoadOOBPendingImage(w, r, h.logger, viewmodel.Image{
+		loadErr := response.LoadOOBPendingImage(w, r, logger, viewmodel.Image{
 			ID:     image.ID.String(),
 			Data:   string(image.ImageData),
 			Status: "Pending",
 		})
 		if loadErr != nil {
 
-			toastID, loadErr := response.LoadErrorToast(w, r, h.logger, "failed to load pending image")
+			_, loadErr := response.LoadErrorToast(w, r, logger, "failed to load pending image")
 			if loadErr != nil {
-				response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, toastID, "")
+				response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
 				return
 			}
 		}
@@ -267,53 +300,132 @@ func (h *GenHandler) HandleGenerationCreate(w http.ResponseWriter, r *http.Reque
 
 	// Images loaded
 
-	loadErr := response.LoadGenForm(w, r, h.logger, vm)
+	loadErr := response.LoadGenForm(w, r, logger, vm)
 	if loadErr != nil {
-		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "", "")
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		return
+	}
+
+}
+
+// HandleCostEstimate returns the total cost in credits for the size and image count in the
+// query as plain text. The gen form calls it so that its estimate always matches what
+// GenerateImage charges, including bulk discounts.
+func (h *GenHandler) HandleCostEstimate(w http.ResponseWriter, r *http.Request) {
+	imageCount, err := strconv.Atoi(r.URL.Query().Get("image_count"))
+	if err != nil {
+		http.Error(w, "invalid image count", http.StatusBadRequest)
 		return
 	}
 
+	total := h.genService.CalculateCost(r.Context(), &service.PromptData{
+		ImageCount: imageCount,
+		Size:       r.URL.Query().Get("size"),
+	})
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte(strconv.Itoa(total)))
+}
+
+// sizeOptions lists the selectable image sizes. Costs come from CalculateCost so the form
+// shows exactly what will be charged.
+func (h *GenHandler) sizeOptions(ctx context.Context) []viewmodel.GenSizeOption {
+	sizes := h.genService.ImageSizes(ctx)
+	options := make([]viewmodel.GenSizeOption, 0, len(sizes))
+	for _, size := range sizes {
+		options = append(options, viewmodel.GenSizeOption{
+			Key:          size.Key,
+			Label:        size.Label,
+			Description:  fmt.Sprintf("%s · %d×%d", size.AspectRatio, size.Width, size.Height),
+			CostPerImage: h.genService.CalculateCost(ctx, &service.PromptData{ImageCount: 1, Size: size.Key}),
+		})
+	}
+	return options
+}
+
+// queuePosition returns the user's position in the generation queue, 0 when nothing is queued
+// or the position could not be determined.
+func (h *GenHandler) queuePosition(ctx context.Context, userID uuid.UUID) int {
+	logger := requestlog.Logger(ctx, h.logger)
+
+	position, err := h.genService.QueuePosition(ctx, userID)
+	if err != nil {
+		logger.Error("failed to determine queue position, hiding it", zap.Error(err))
+		return 0
+	}
+	return position
+}
+
+// generationErrorMessage returns a user facing message for errors reported while queueing a prompt.
+// Backend failures happen later in the worker, which refunds the credits. ok is false for errors
+// that should be treated as internal failures.
+func generationErrorMessage(err error) (message string, ok bool) {
+	switch {
+	case errors.Is(err, domain.ErrInsufficientFunds):
+		return "insufficient credits", true
+	case errors.Is(err, domain.ErrGenerationLimitReached):
+		return "You have too many generations in progress. Please wait for them to finish.", true
+	case errors.Is(err, domain.ErrInvalidGenerationInput):
+		return "The image generator could not process these settings.", true
+	default:
+		return "", false
+	}
 }
 
 func (h *GenHandler) HandleImageCompletionWebhook(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
 
 	request := ImageUpdateWebhookRequest{}
 	err := json.NewDecoder(r.Body).Decode(&request)
 	if err != nil {
-		h.logger.Error("failed to decode webhook request body", zap.Error(err))
+		logger.Error("failed to decode webhook request body", zap.Error(err))
 		http.Error(w, "invalid request body", http.StatusBadRequest)
 		return
 	}
 
 	externalPromptID, err := uuid.Parse(request.PromptID)
 	if err != nil {
-		h.logger.Error("webhook received with invalid promptID format", zap.String("promptID", request.PromptID), zap.Error(err))
+		logger.Error("webhook received with invalid promptID format", zap.String("promptID", request.PromptID), zap.Error(err))
 		http.Error(w, "invalid prompt_id format", http.StatusBadRequest)
 		return
 	}
 
+	// Each ComfyLite node reports back with its name, results for prompts of another node, or
+	// without a name, are refused
+	backend := r.URL.Query().Get("backend")
+
 	if request.Status == "failure" {
-		h.logger.Warn("received failure webhook for prompt",
+		logger.Warn("received failure webhook for prompt",
 			zap.String("promptID", request.PromptID),
+			zap.String("backend", backend),
 			zap.String("error", request.Error),
 		)
 
-		_, err := h.genService.UpdatePlaceholderImages(r.Context(), externalPromptID, [][]byte{}, domain.Failed)
+		prompt, err := h.genService.UpdatePlaceholderImages(r.Context(), externalPromptID, backend, [][]byte{}, domain.Failed)
 		if err != nil {
-			h.logger.Error("failed to update prompt status to failed",
+			if errors.Is(err, domain.ErrPromptBackendMismatch) {
+				http.Error(w, "prompt belongs to another backend", http.StatusConflict)
+				return
+			}
+			if errors.Is(err, domain.ErrPromptAlreadyFinished) {
+				http.Error(w, "prompt already finished", http.StatusConflict)
+				return
+			}
+			logger.Error("failed to update prompt status to failed",
 				zap.String("promptID", request.PromptID),
 				zap.Error(err),
 			)
 			http.Error(w, "internal server error", http.StatusInternalServerError)
 			return
 		}
+		linkPromptTrace(r, prompt)
 
 		w.WriteHeader(http.StatusOK)
 		return
 	}
 
 	if len(request.Images) == 0 {
-		h.logger.Error("success webhook received with no images", zap.String("promptID", request.PromptID))
+		logger.Error("success webhook received with no images", zap.String("promptID", request.PromptID))
 		http.Error(w, "no images provided in success payload", http.StatusBadRequest)
 		return
 	}
@@ -323,7 +435,7 @@ func (h *GenHandler) HandleImageCompletionWebhook(w http.ResponseWriter, r *http
 	for i, imageData := range request.Images {
 		decoded, err := base64.StdEncoding.DecodeString(imageData)
 		if err != nil {
-			h.logger.Error("image base64 could not be decoded",
+			logger.Error("image base64 could not be decoded",
 				zap.String("promptID", request.PromptID),
 				zap.Int("imageIndex", i),
 				zap.Error(err),
@@ -334,39 +446,60 @@ func (h *GenHandler) HandleImageCompletionWebhook(w http.ResponseWriter, r *http
 		imagesDecoded = append(imagesDecoded, decoded)
 	}
 
-	_, err = h.genService.UpdatePlaceholderImages(r.Context(), externalPromptID, imagesDecoded, domain.Completed)
+	prompt, err := h.genService.UpdatePlaceholderImages(r.Context(), externalPromptID, backend, imagesDecoded, domain.Completed)
 	if err != nil {
-		h.logger.Error("failed to update placeholder images to status completed",
+		if errors.Is(err, domain.ErrPromptBackendMismatch) {
+			http.Error(w, "prompt belongs to another backend", http.StatusConflict)
+			return
+		}
+		if errors.Is(err, domain.ErrPromptAlreadyFinished) {
+			http.Error(w, "prompt already finished", http.StatusConflict)
+			return
+		}
+		logger.Error("failed to update placeholder images to status completed",
 			zap.String("promptID", request.PromptID),
 			zap.Error(err),
 		)
 		http.Error(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
+	linkPromptTrace(r, prompt)
 
-	h.logger.Info("successfully processed completion webhook", zap.String("promptID", request.PromptID))
+	logger.Info("successfully processed completion webhook", zap.String("promptID", request.PromptID))
 	w.WriteHeader(http.StatusOK)
 
 }
 
+// linkPromptTrace links the span of a completion webhook to the trace of the request that
+// created the prompt, which the webhook arrives outside of
+func linkPromptTrace(r *http.Request, prompt *domain.Prompt) {
+	span := trace.SpanFromContext(r.Context())
+	span.SetAttributes(attribute.String("prompt.id", prompt.ID.String()))
+	if link, ok := tracing.LinkTo(prompt.TraceParent); ok {
+		span.AddLink(link)
+	}
+}
+
 func (h *GenHandler) HandleImageStatus(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
 	idStr := chi.URLParam(r, "id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		h.logger.Warn("invalid image uuid provided", zap.Error(err), zap.String("id", idStr))
+		logger.Warn("invalid image uuid provided", zap.Error(err), zap.String("id", idStr))
 		return
 	}
 
 	userID, err := auth.UserID(r.Context())
 	if err != nil {
-		h.logger.Error("Failed to get UserID from context")
-		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "", "")
+		logger.Error("Failed to get UserID from context")
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
 		return
 	}
 
 	image, err := h.genService.GetImageByID(r.Context(), userID, id)
 	if err != nil {
-		h.logger.Error("failed to retrieve image by ID", zap.Error(err))
+		logger.Error("failed to retrieve image by ID", zap.Error(err))
 		return
 	}
 
@@ -377,9 +510,9 @@ func (h *GenHandler) HandleImageStatus(w http.ResponseWriter, r *http.Request) {
 			Status: "completed",
 		}
 
-		loadErr := response.LoadCompletedImage(w, r, h.logger, vm)
+		loadErr := response.LoadCompletedImage(w, r, logger, vm)
 		if loadErr != nil {
-			response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "", "")
+			response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
 			return
 		}
 		return
@@ -393,9 +526,9 @@ func (h *GenHandler) HandleImageStatus(w http.ResponseWriter, r *http.Request) {
 			Status: "failed",
 		}
 
-		loadErr := response.LoadFailedImage(w, r, h.logger, vm)
+		loadErr := response.LoadFailedImage(w, r, logger, vm)
 		if loadErr != nil {
-			response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "", "")
+			response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
 			return
 		}
 		return
@@ -407,58 +540,61 @@ func (h *GenHandler) HandleImageStatus(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *GenHandler) HandleImageDelete(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
 	idStr := chi.URLParam(r, "id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		h.logger.Warn("invalid image uuid provided", zap.Error(err), zap.String("id", idStr))
+		logger.Warn("invalid image uuid provided", zap.Error(err), zap.String("id", idStr))
 		return
 	}
 
 	userID, err := auth.UserID(r.Context())
 	if err != nil {
-		h.logger.Error("Failed to get UserID from context")
-		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "", "")
+		logger.Error("Failed to get UserID from context")
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
 		return
 	}
 
 	err = h.genService.DeleteImageByID(r.Context(), userID, id)
 	if err != nil {
-		h.logger.Error("failed to delete image", zap.Error(err))
+		logger.Error("failed to delete image", zap.Error(err))
 
-		toastID, loadErr := response.LoadErrorToast(w, r, h.logger, "deletion failed")
+		toastID, loadErr := response.LoadErrorToast(w, r, logger, "deletion failed")
 		if loadErr != nil {
-			h.logger.Error("failed loading ErrorToast", zap.String("toastID", toastID), zap.Error(err))
-			response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "", "")
+			logger.Error("failed loading ErrorToast", zap.String("toastID", toastID), zap.Error(err))
+			response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
 			return
 		}
 		return
 	}
 
-	toastID, loadErr := response.LoadSuccessToast(w, r, h.logger, "image deleted")
+	toastID, loadErr := response.LoadSuccessToast(w, r, logger, "image deleted")
 	if loadErr != nil {
-		h.logger.Error("failed loading SuccessToast", zap.String("toastID", toastID), zap.Error(err))
-		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "", "")
+		logger.Error("failed loading SuccessToast", zap.String("toastID", toastID), zap.Error(err))
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
 		return
 	}
 
 }
 
 func (h *GenHandler) HandleFailedImagesDelete(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
 
 	userID, err := auth.UserID(r.Context())
 	if err != nil {
-		h.logger.Error("Failed to get UserID from context")
-		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "", "")
+		logger.Error("Failed to get UserID from context")
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
 		return
 	}
 
 	err = h.genService.DeleteFailedImages(r.Context(), userID)
 	if err != nil {
 
-		toastID, loadErr := response.LoadErrorToast(w, r, h.logger, "deletion failed")
+		toastID, loadErr := response.LoadErrorToast(w, r, logger, "deletion failed")
 		if loadErr != nil {
-			h.logger.Error("failed loading ErrorToast", zap.String("toastID", toastID), zap.Error(err))
-			response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "", "")
+			logger.Error("failed loading ErrorToast", zap.String("toastID", toastID), zap.Error(err))
+			response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
 			return
 		}
 		return
@@ -12,9 +12,12 @@ type ApiHandlers struct {
 	ErrorHandler    *ErrorHandler
 	GenHandler      *GenHandler
 	PurchaseHandler *PurchaseHandler
+	AdminHandler    *AdminHandler
+	AccountHandler  *AccountHandler
+	ApiV1Handler    *ApiV1Handler
 }
 
-func NewApiHandlers(authService service.AuthService, genService service.GenService, purchaseService service.PurcaseService, logger *zap.Logger) *ApiHandlers {
+func NewApiHandlers(authService service.AuthService, genService service.GenService, purchaseService service.PurcaseService, packageService service.CreditPackageService, promoService service.PromoService, subscriptionService service.SubscriptionService, receiptService service.ReceiptService, walletService service.WalletService, apiKeyService service.APIKeyService, webhookService service.WebhookService, adminService service.AdminService, auditService service.AuditService, logger *zap.Logger) *ApiHandlers {
 
 	appValidator := validation.New()
 
@@ -23,6 +26,9 @@ func NewApiHandlers(authService service.AuthService, genService service.GenServi
 		LandingHandler:  NewLandingHandler(logger),
 		ErrorHandler:    NewErrorHandler(logger),
 		GenHandler:      NewGenHandler(logger, appValidator, genService),
-		PurchaseHandler: NewPurchaseHandler(logger, appValidator, purchaseService),
+		PurchaseHandler: NewPurchaseHandler(logger, appValidator, purchaseService, promoService, subscriptionService),
+		AdminHandler:    NewAdminHandler(logger, appValidator, packageService, promoService, adminService, auditService),
+		AccountHandler:  NewAccountHandler(logger, appValidator, receiptService, apiKeyService, webhookService),
+		ApiV1Handler:    NewApiV1Handler(logger, appValidator, genService, walletService),
 	}
 }
@@ -3,6 +3,7 @@ package http
 import (
 	"net/http"
 
+	"github.com/CP-Payne/wonderpicai/internal/context/requestlog"
 	"github.com/CP-Payne/wonderpicai/web/template/pages/landing"
 	"go.uber.org/zap"
 )
@@ -18,9 +19,11 @@ func NewLandingHandler(logger *zap.Logger) *LandingHandler {
 }
 
 func (h *LandingHandler) ShowLandingPage(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
 	err := landing.LandingPage().Render(r.Context(), w)
 	if err != nil {
-		h.logger.Error("Failed to render landing page", zap.Error(err))
+		logger.Error("Failed to render landing page", zap.Error(err))
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 	}
 }
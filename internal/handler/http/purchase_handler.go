@@ -2,16 +2,22 @@ package http
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 
 	"github.com/CP-Payne/wonderpicai/internal/context/auth"
+	"github.com/CP-Payne/wonderpicai/internal/context/requestlog"
 	"github.com/CP-Payne/wonderpicai/internal/domain"
 	"github.com/CP-Payne/wonderpicai/internal/handler/http/response"
+	"github.com/CP-Payne/wonderpicai/internal/money"
 	"github.com/CP-Payne/wonderpicai/internal/service"
 	creditPages "github.com/CP-Payne/wonderpicai/web/template/pages/credits"
 	"github.com/CP-Payne/wonderpicai/web/template/viewmodel"
@@ -21,93 +27,404 @@ type PurchaseHandler struct {
 	logger          *zap.Logger
 	validate        *validator.Validate
 	purchaseService service.PurcaseService
+	promoService    service.PromoService
+
+	subscriptionService service.SubscriptionService
 }
 
-func NewPurchaseHandler(logger *zap.Logger, validate *validator.Validate, purchaseService service.PurcaseService) *PurchaseHandler {
+func NewPurchaseHandler(logger *zap.Logger, validate *validator.Validate, purchaseService service.PurcaseService, promoService service.PromoService, subscriptionService service.SubscriptionService) *PurchaseHandler {
 	return &PurchaseHandler{
 		logger:          logger.With(zap.String("component", "PurchaseHandler")),
 		validate:        validate,
 		purchaseService: purchaseService,
+		promoService:    promoService,
+
+		subscriptionService: subscriptionService,
 	}
 }
 
 func (h *PurchaseHandler) ShowPurchasePage(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	purchasePageData, err := h.purchasePageData(r)
+	if err != nil {
+		logger.Error("Failed to load purchase options", zap.Error(err))
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		return
+	}
+
+	err = creditPages.PurchasePage(purchasePageData).Render(r.Context(), w)
+	if err != nil {
+		logger.Error("Failed to render Purchase page", zap.Error(err))
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		return
+	}
+}
 
-	// Building viewmodel options for display
+// purchasePageData builds the viewmodel options for display, with prices in the user's
+// currency formatted for their locale
+func (h *PurchaseHandler) purchasePageData(r *http.Request) (viewmodel.PurchaseViewData, error) {
+	userID, err := auth.UserID(r.Context())
+	if err != nil {
+		return viewmodel.PurchaseViewData{}, err
+	}
 
-	availableOptions := h.purchaseService.GetOptions(r.Context())
+	acceptLanguage := r.Header.Get("Accept-Language")
+	catalog, err := h.purchaseService.GetOptions(r.Context(), userID, acceptLanguage, r.URL.Query().Get("promo"))
+	if err != nil {
+		return viewmodel.PurchaseViewData{}, err
+	}
 
-	viewOptions := make([]viewmodel.PurchaseOption, len(availableOptions))
+	formatter := money.NewFormatter(acceptLanguage)
+	viewOptions := make([]viewmodel.PurchaseOption, len(catalog.Options))
 
-	for i, availOpt := range availableOptions {
+	for i, availOpt := range catalog.Options {
 		viewOptions[i] = viewmodel.PurchaseOption{
-			Credits:   availOpt.Credits,
-			Price:     availOpt.Price,
-			ActionURL: availOpt.ActionURL,
+			Name:           availOpt.Name,
+			Credits:        availOpt.Credits,
+			Price:          formatter.Format(availOpt.PriceMinor, availOpt.Currency),
+			PricePerCredit: formatter.FormatPerUnit(availOpt.PriceMinor, availOpt.Credits, availOpt.Currency),
+			Badge:          availOpt.Badge,
+			ActionURL:      availOpt.ActionURL,
+		}
+		if availOpt.OriginalPriceMinor > 0 {
+			viewOptions[i].OriginalPrice = formatter.Format(availOpt.OriginalPriceMinor, availOpt.Currency)
+		}
+	}
+
+	currencies := []viewmodel.CurrencyOption{{
+		Label:    "Automatic",
+		Selected: catalog.ProfileCurrency == "",
+	}}
+	if catalog.ProfileCurrency == "" {
+		currencies[0].Label = fmt.Sprintf("Automatic (%s)", strings.ToUpper(catalog.Currency))
+	}
+	for _, code := range money.Currencies() {
+		currencies = append(currencies, viewmodel.CurrencyOption{
+			Code:     code,
+			Label:    strings.ToUpper(code),
+			Selected: code == catalog.ProfileCurrency,
+		})
+	}
+
+	data := viewmodel.PurchaseViewData{
+		Options:    viewOptions,
+		Currencies: currencies,
+		PromoCode:  catalog.PromoCode,
+	}
+	if catalog.PromoCode != "" {
+		data.PromoMessage = fmt.Sprintf("%s: %d%% off applied", catalog.PromoCode, catalog.PercentOff)
+	}
+
+	if err := h.addSubscriptionData(r, userID, formatter, &data); err != nil {
+		return viewmodel.PurchaseViewData{}, err
+	}
+
+	return data, nil
+}
+
+func (h *PurchaseHandler) addSubscriptionData(r *http.Request, userID uuid.UUID, formatter money.Formatter, data *viewmodel.PurchaseViewData) error {
+	plans, err := h.subscriptionService.ListPlans(r.Context())
+	if err != nil {
+		return err
+	}
+
+	current, err := h.subscriptionService.Current(r.Context(), userID)
+	if err != nil && !errors.Is(err, domain.ErrNoSubscription) {
+		return err
+	}
+	if current != nil {
+		data.Subscription = &viewmodel.SubscriptionStatus{
+			PlanName:          current.Plan.Name,
+			Status:            subscriptionStatusLabel(current.Status),
+			CancelAtPeriodEnd: current.CancelAtPeriodEnd,
+		}
+		if current.CurrentPeriodEnd != nil {
+			data.Subscription.RenewsOn = current.CurrentPeriodEnd.Format("2 January 2006")
+		}
+	}
+
+	for _, plan := range plans {
+		data.Plans = append(data.Plans, viewmodel.SubscriptionPlanOption{
+			Name:      plan.Name,
+			Credits:   plan.CreditsPerPeriod,
+			Price:     formatter.Format(plan.PriceMinor, plan.Currency),
+			Interval:  plan.Interval,
+			Rollover:  rolloverLabel(plan.RolloverCap),
+			Current:   current != nil && current.PlanID == plan.ID,
+			ActionURL: "/purchase/subscribe/" + plan.ID.String(),
+		})
+	}
+
+	return nil
+}
+
+func rolloverLabel(rolloverCap *int) string {
+	switch {
+	case rolloverCap == nil:
+		return "Unused credits roll over"
+	case *rolloverCap == 0:
+		return "Unused credits expire at the end of each period"
+	}
+	return fmt.Sprintf("Up to %d unused credits roll over", *rolloverCap)
+}
+
+func subscriptionStatusLabel(status domain.SubscriptionStatus) string {
+	switch status {
+	case domain.SubscriptionActive:
+		return "Active"
+	case domain.SubscriptionPastDue:
+		return "Payment overdue"
+	case domain.SubscriptionIncomplete:
+		return "Awaiting payment"
+	}
+	return string(status)
+}
+
+func (h *PurchaseHandler) HandleSubscribe(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	userID, err := auth.UserID(r.Context())
+	if err != nil {
+		logger.Error("Failed to get userID from context", zap.Error(err))
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		return
+	}
+
+	checkoutURL, err := h.subscriptionService.CreateCheckout(r.Context(), userID, chi.URLParam(r, "plan"))
+	if err != nil {
+		var message string
+		switch {
+		case errors.Is(err, domain.ErrInvalidSubscription):
+			message = "This plan is not available."
+		case errors.Is(err, domain.ErrAlreadySubscribed):
+			message = "You already have a subscription. Change it with Manage subscription."
+		default:
+			logger.Error("Failed to create subscription checkout", zap.String("userID", userID.String()), zap.Error(err))
+			response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+			return
+		}
+		toastID, loadErr := response.LoadErrorToast(w, r, logger, message)
+		if loadErr != nil {
+			logger.Error("failed loading ErrorToast", zap.String("toastID", toastID), zap.Error(loadErr))
+		}
+		return
+	}
+
+	response.HxRedirect(w, r, checkoutURL)
+}
+
+func (h *PurchaseHandler) HandleSubscriptionPortal(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	userID, err := auth.UserID(r.Context())
+	if err != nil {
+		logger.Error("Failed to get userID from context", zap.Error(err))
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		return
+	}
+
+	portalURL, err := h.subscriptionService.PortalURL(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNoSubscription) {
+			toastID, loadErr := response.LoadErrorToast(w, r, logger, "You have no subscription to manage yet.")
+			if loadErr != nil {
+				logger.Error("failed loading ErrorToast", zap.String("toastID", toastID), zap.Error(loadErr))
+			}
+			return
 		}
+		logger.Error("Failed to create customer portal session", zap.String("userID", userID.String()), zap.Error(err))
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		return
 	}
 
-	purchasePageData := viewmodel.PurchaseViewData{
-		Options: viewOptions,
+	response.HxRedirect(w, r, portalURL)
+}
+
+func (h *PurchaseHandler) HandlePromoRedeem(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	userID, err := auth.UserID(r.Context())
+	if err != nil {
+		logger.Error("Failed to get userID from context", zap.Error(err))
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		return
 	}
 
-	err := creditPages.PurchasePage(purchasePageData).Render(r.Context(), w)
+	promo, err := h.promoService.Redeem(r.Context(), userID, r.FormValue("code"))
 	if err != nil {
-		h.logger.Error("Failed to render Purchase page", zap.Error(err))
-		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "", "")
+		message, ok := promoErrorMessage(err)
+		if !ok {
+			logger.Error("Failed to redeem promo code", zap.String("userID", userID.String()), zap.Error(err))
+			message = "Failed to redeem the code, please try again."
+		}
+		toastID, loadErr := response.LoadErrorToast(w, r, logger, message)
+		if loadErr != nil {
+			logger.Error("failed loading ErrorToast", zap.String("toastID", toastID), zap.Error(loadErr))
+		}
+		return
+	}
+
+	if promo.Kind == domain.PromoDiscount {
+		// Show the discounted prices, the code is redeemed at checkout
+		response.HxRedirect(w, r, "/purchase?promo="+url.QueryEscape(promo.Code))
+		return
+	}
+
+	toastID, loadErr := response.LoadSuccessToast(w, r, logger, fmt.Sprintf("%d credits added to your wallet", promo.Credits))
+	if loadErr != nil {
+		logger.Error("failed loading SuccessToast", zap.String("toastID", toastID), zap.Error(loadErr))
+	}
+}
+
+// promoErrorMessage returns the message shown to users for promo code errors
+func promoErrorMessage(err error) (string, bool) {
+	switch {
+	case errors.Is(err, domain.ErrPromoCodeInvalid):
+		return "This promo code is not valid.", true
+	case errors.Is(err, domain.ErrPromoCodeExpired):
+		return "This promo code has expired.", true
+	case errors.Is(err, domain.ErrPromoCodeExhausted):
+		return "This promo code has been fully redeemed.", true
+	case errors.Is(err, domain.ErrPromoCodeAlreadyUsed):
+		return "You have already used this promo code. Codes held by an unfinished checkout are released once it expires.", true
+	case errors.Is(err, domain.ErrPromoCodeNotApplicable):
+		return "This promo code cannot be used for this pack.", true
+	}
+	return "", false
+}
+
+func (h *PurchaseHandler) HandleCurrencyUpdate(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	userID, err := auth.UserID(r.Context())
+	if err != nil {
+		logger.Error("Failed to get userID from context", zap.Error(err))
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		return
+	}
+
+	err = h.purchaseService.SetCurrency(r.Context(), userID, r.FormValue("currency"))
+	if err != nil {
+		if errors.Is(err, domain.ErrUnsupportedCurrency) {
+			toastID, loadErr := response.LoadErrorToast(w, r, logger, "This currency is not supported")
+			if loadErr != nil {
+				logger.Error("failed loading ErrorToast", zap.String("toastID", toastID), zap.Error(loadErr))
+			}
+			return
+		}
+		logger.Error("Failed to update preferred currency", zap.String("userID", userID.String()), zap.Error(err))
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
 		return
 	}
+
+	response.HxRedirect(w, r, "/purchase")
 }
 
 func (h *PurchaseHandler) ShowSuccessPage(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	status, ok := h.checkoutStatus(w, r)
+	if !ok {
+		return
+	}
 
-	err := creditPages.SuccessPage().Render(r.Context(), w)
+	err := creditPages.SuccessPage(status).Render(r.Context(), w)
 	if err != nil {
-		h.logger.Error("Failed to render success page", zap.Error(err))
-		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "", "")
+		logger.Error("Failed to render success page", zap.Error(err))
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		return
+	}
+}
+
+// HandleCheckoutStatus is polled by the success page until the checkout completed
+func (h *PurchaseHandler) HandleCheckoutStatus(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	status, ok := h.checkoutStatus(w, r)
+	if !ok {
 		return
 	}
+
+	if loadErr := response.LoadCheckoutStatus(w, r, logger, status); loadErr != nil {
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+	}
+}
+
+// checkoutStatus loads the status of the session_id checkout. When ok is false the response
+// has been written.
+func (h *PurchaseHandler) checkoutStatus(w http.ResponseWriter, r *http.Request) (viewmodel.CheckoutStatus, bool) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
+	userID, err := auth.UserID(r.Context())
+	if err != nil {
+		logger.Error("Failed to get userID from context", zap.Error(err))
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		return viewmodel.CheckoutStatus{}, false
+	}
+
+	sessionID := r.URL.Query().Get("session_id")
+	if sessionID == "" {
+		response.HxRedirect(w, r, "/account/purchases")
+		return viewmodel.CheckoutStatus{}, false
+	}
+
+	status, err := h.purchaseService.CheckoutStatus(r.Context(), userID, sessionID)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrRecordNotFound):
+			response.HxRedirectErrorPage(w, r, http.StatusNotFound, "")
+		case errors.Is(err, domain.ErrInsufficientPermissions):
+			response.HxRedirectErrorPage(w, r, http.StatusForbidden, "")
+		default:
+			logger.Error("Failed to load checkout status", zap.String("sessionID", sessionID), zap.Error(err))
+			response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		}
+		return viewmodel.CheckoutStatus{}, false
+	}
+
+	return viewmodel.CheckoutStatus{
+		State:       string(status.State),
+		Description: status.Description,
+		Credits:     status.Credits,
+		Balance:     status.Balance,
+		StatusURL:   "/purchase/success/status?session_id=" + url.QueryEscape(sessionID),
+	}, true
 }
 
 func (h *PurchaseHandler) ShowCancelPage(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
 
 	err := creditPages.CancelPage().Render(r.Context(), w)
 	if err != nil {
-		h.logger.Error("Failed to render success page", zap.Error(err))
-		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "", "")
+		logger.Error("Failed to render success page", zap.Error(err))
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
 		return
 	}
 }
 
 func (h *PurchaseHandler) HandlePurchaseOption(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
+
 	option := chi.URLParam(r, "option")
 
 	exist := h.purchaseService.OptionExists(r.Context(), option)
 	if !exist {
-		toastID, loadErr := response.LoadErrorToast(w, r, h.logger, "invalid option")
+		toastID, loadErr := response.LoadErrorToast(w, r, logger, "invalid option")
 		if loadErr != nil {
-			h.logger.Error("failed loading ErrorToast", zap.String("toastID", toastID), zap.Error(loadErr))
-			response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "", "")
+			logger.Error("failed loading ErrorToast", zap.String("toastID", toastID), zap.Error(loadErr))
+			response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
 			return
 		}
 		// Rerender page with purchase options including the added toast
-		availableOptions := h.purchaseService.GetOptions(r.Context())
-
-		viewOptions := make([]viewmodel.PurchaseOption, len(availableOptions))
-
-		for i, availOpt := range availableOptions {
-			viewOptions[i] = viewmodel.PurchaseOption{
-				Credits:   availOpt.Credits,
-				Price:     availOpt.Price,
-				ActionURL: availOpt.ActionURL,
-			}
-		}
-
-		purchasePageData := viewmodel.PurchaseViewData{
-			Options: viewOptions,
+		purchasePageData, err := h.purchasePageData(r)
+		if err != nil {
+			logger.Error("Failed to load purchase options", zap.Error(err))
+			response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+			return
 		}
 
-		response.LoadPurchasePage(w, r, h.logger, purchasePageData)
+		response.LoadPurchasePage(w, r, logger, purchasePageData)
 		// End request processing
 		return
 
@@ -115,15 +432,22 @@ func (h *PurchaseHandler) HandlePurchaseOption(w http.ResponseWriter, r *http.Re
 
 	userID, err := auth.UserID(r.Context())
 	if err != nil {
-		h.logger.Error("Failed to get userID from context", zap.String("userID", userID.String()), zap.Error(err))
-		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "", "")
+		logger.Error("Failed to get userID from context", zap.String("userID", userID.String()), zap.Error(err))
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
 		return
 	}
 
-	checkoutURL, err := h.purchaseService.CreateCheckout(r.Context(), userID, option)
+	checkoutURL, err := h.purchaseService.CreateCheckout(r.Context(), userID, option, r.Header.Get("Accept-Language"), r.URL.Query().Get("promo"))
 	if err != nil {
-		h.logger.Error("Failed to get checkout URL", zap.String("option", option+" credits"), zap.String("userID", userID.String()), zap.Error(err))
-		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "", "")
+		if message, ok := promoErrorMessage(err); ok {
+			toastID, loadErr := response.LoadErrorToast(w, r, logger, message)
+			if loadErr != nil {
+				logger.Error("failed loading ErrorToast", zap.String("toastID", toastID), zap.Error(loadErr))
+			}
+			return
+		}
+		logger.Error("Failed to get checkout URL", zap.String("option", option), zap.String("userID", userID.String()), zap.Error(err))
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
 		return
 	}
 
@@ -131,12 +455,13 @@ func (h *PurchaseHandler) HandlePurchaseOption(w http.ResponseWriter, r *http.Re
 }
 
 func (h *PurchaseHandler) HandlePurchaseEvents(w http.ResponseWriter, r *http.Request) {
+	logger := requestlog.Logger(r.Context(), h.logger)
 
 	const MaxBodyBytes = int64(65536)
 	r.Body = http.MaxBytesReader(w, r.Body, MaxBodyBytes)
 	payload, err := io.ReadAll(r.Body)
 	if err != nil {
-		h.logger.Error("Error reading request body", zap.Error(err))
+		logger.Error("Error reading request body", zap.Error(err))
 		w.WriteHeader(http.StatusServiceUnavailable)
 		return
 	}
@@ -0,0 +1,73 @@
+package response
+
+import (
+	"fmt"
+	"net/http"
+
+	accountComponents "github.com/CP-Payne/wonderpicai/web/template/components/account"
+	"github.com/CP-Payne/wonderpicai/web/template/viewmodel"
+	"go.uber.org/zap"
+)
+
+func LoadAccountAPIKeyRow(w http.ResponseWriter, r *http.Request, logger *zap.Logger, vm viewmodel.AccountAPIKey) (renderErr error) {
+	err := accountComponents.APIKeyRow(vm).Render(r.Context(), w)
+	if err != nil {
+		logger.Error("Failed to render API key row", zap.Error(err))
+		return fmt.Errorf("failed to render API key row: %w", err)
+	}
+	return nil
+}
+
+func LoadAccountNewAPIKeyForm(w http.ResponseWriter, r *http.Request, logger *zap.Logger, vm viewmodel.AccountAPIKeyForm) (renderErr error) {
+	err := accountComponents.NewAPIKeyForm(vm).Render(r.Context(), w)
+	if err != nil {
+		logger.Error("Failed to render new API key form", zap.Error(err))
+		return fmt.Errorf("failed to render new API key form: %w", err)
+	}
+	return nil
+}
+
+func LoadAccountCreatedAPIKey(w http.ResponseWriter, r *http.Request, logger *zap.Logger, vm viewmodel.AccountAPIKeyCreated) (renderErr error) {
+	err := accountComponents.CreatedAPIKey(vm).Render(r.Context(), w)
+	if err != nil {
+		logger.Error("Failed to render created API key", zap.Error(err))
+		return fmt.Errorf("failed to render created API key: %w", err)
+	}
+	return nil
+}
+
+func LoadAccountWebhookRow(w http.ResponseWriter, r *http.Request, logger *zap.Logger, vm viewmodel.AccountWebhook) (renderErr error) {
+	err := accountComponents.WebhookRow(vm).Render(r.Context(), w)
+	if err != nil {
+		logger.Error("Failed to render webhook row", zap.Error(err))
+		return fmt.Errorf("failed to render webhook row: %w", err)
+	}
+	return nil
+}
+
+func LoadAccountNewWebhookForm(w http.ResponseWriter, r *http.Request, logger *zap.Logger, vm viewmodel.AccountWebhookForm) (renderErr error) {
+	err := accountComponents.NewWebhookForm(vm).Render(r.Context(), w)
+	if err != nil {
+		logger.Error("Failed to render new webhook form", zap.Error(err))
+		return fmt.Errorf("failed to render new webhook form: %w", err)
+	}
+	return nil
+}
+
+func LoadAccountCreatedWebhook(w http.ResponseWriter, r *http.Request, logger *zap.Logger, vm viewmodel.AccountWebhookCreated) (renderErr error) {
+	err := accountComponents.CreatedWebhook(vm).Render(r.Context(), w)
+	if err != nil {
+		logger.Error("Failed to render created webhook", zap.Error(err))
+		return fmt.Errorf("failed to render created webhook: %w", err)
+	}
+	return nil
+}
+
+func LoadAccountWebhookDeliveryRow(w http.ResponseWriter, r *http.Request, logger *zap.Logger, vm viewmodel.AccountWebhookDelivery) (renderErr error) {
+	err := accountComponents.DeliveryRow(vm).Render(r.Context(), w)
+	if err != nil {
+		logger.Error("Failed to render webhook delivery row", zap.Error(err))
+		return fmt.Errorf("failed to render webhook delivery row: %w", err)
+	}
+	return nil
+}
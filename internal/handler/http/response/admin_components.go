@@ -0,0 +1,73 @@
+package response
+
+import (
+	"fmt"
+	"net/http"
+
+	adminComponents "github.com/CP-Payne/wonderpicai/web/template/components/admin"
+	"github.com/CP-Payne/wonderpicai/web/template/viewmodel"
+	"go.uber.org/zap"
+)
+
+func LoadAdminPackageRow(w http.ResponseWriter, r *http.Request, logger *zap.Logger, vm viewmodel.AdminPackageRow) (renderErr error) {
+	err := adminComponents.PackageRow(vm).Render(r.Context(), w)
+	if err != nil {
+		logger.Error("Failed to render admin package row", zap.Error(err))
+		return fmt.Errorf("failed to render admin package row: %w", err)
+	}
+	return nil
+}
+
+func LoadAdminNewPackageForm(w http.ResponseWriter, r *http.Request, logger *zap.Logger, vm viewmodel.AdminPackageRow) (renderErr error) {
+	err := adminComponents.NewPackageForm(vm).Render(r.Context(), w)
+	if err != nil {
+		logger.Error("Failed to render new package form", zap.Error(err))
+		return fmt.Errorf("failed to render new package form: %w", err)
+	}
+	return nil
+}
+
+func LoadAdminPromoRow(w http.ResponseWriter, r *http.Request, logger *zap.Logger, vm viewmodel.AdminPromoRow) (renderErr error) {
+	err := adminComponents.PromoRow(vm).Render(r.Context(), w)
+	if err != nil {
+		logger.Error("Failed to render admin promo row", zap.Error(err))
+		return fmt.Errorf("failed to render admin promo row: %w", err)
+	}
+	return nil
+}
+
+func LoadAdminNewPromoForm(w http.ResponseWriter, r *http.Request, logger *zap.Logger, vm viewmodel.AdminPromoForm) (renderErr error) {
+	err := adminComponents.NewPromoForm(vm).Render(r.Context(), w)
+	if err != nil {
+		logger.Error("Failed to render new promo form", zap.Error(err))
+		return fmt.Errorf("failed to render new promo form: %w", err)
+	}
+	return nil
+}
+
+func LoadAdminUserActions(w http.ResponseWriter, r *http.Request, logger *zap.Logger, vm viewmodel.AdminUserActions) (renderErr error) {
+	err := adminComponents.UserActions(vm).Render(r.Context(), w)
+	if err != nil {
+		logger.Error("Failed to render admin user actions", zap.Error(err))
+		return fmt.Errorf("failed to render admin user actions: %w", err)
+	}
+	return nil
+}
+
+func LoadAdminStuckPromptRow(w http.ResponseWriter, r *http.Request, logger *zap.Logger, vm viewmodel.AdminStuckPrompt) (renderErr error) {
+	err := adminComponents.StuckPromptRow(vm).Render(r.Context(), w)
+	if err != nil {
+		logger.Error("Failed to render stuck prompt row", zap.Error(err))
+		return fmt.Errorf("failed to render stuck prompt row: %w", err)
+	}
+	return nil
+}
+
+func LoadAdminAuditVerification(w http.ResponseWriter, r *http.Request, logger *zap.Logger, vm viewmodel.AdminAuditVerification) (renderErr error) {
+	err := adminComponents.AuditVerification(vm).Render(r.Context(), w)
+	if err != nil {
+		logger.Error("Failed to render audit verification", zap.Error(err))
+		return fmt.Errorf("failed to render audit verification: %w", err)
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/CP-Payne/wonderpicai/internal/config"
+	"github.com/CP-Payne/wonderpicai/internal/context/forwarded"
 )
 
 func SetAuthCookie(w http.ResponseWriter, r *http.Request, accessToken string) {
@@ -13,7 +14,7 @@ func SetAuthCookie(w http.ResponseWriter, r *http.Request, accessToken string) {
 		Value:    accessToken,
 		Path:     "/",
 		HttpOnly: true,
-		Secure:   r.TLS != nil,
+		Secure:   forwarded.IsSecure(r),
 		MaxAge:   config.Cfg.JWT.ExpiryMinutes * 60,
 		Expires:  time.Now().Add(time.Duration(config.Cfg.JWT.ExpiryMinutes) * time.Minute),
 	})
@@ -25,7 +26,7 @@ func SetEmptyAuthCookie(w http.ResponseWriter, r *http.Request) {
 		Value:    "",
 		Path:     "/",
 		HttpOnly: true,
-		Secure:   r.TLS != nil,
+		Secure:   forwarded.IsSecure(r),
 		MaxAge:   -1,
 		Expires:  time.Unix(0, 0),
 	})
@@ -0,0 +1,49 @@
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details object returned by the JSON API.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// Errors holds field validation errors keyed by field name
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// NewProblem returns a problem without a specific type, its title is the status text.
+func NewProblem(r *http.Request, statusCode int, detail string) Problem {
+	return Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(statusCode),
+		Status:   statusCode,
+		Detail:   detail,
+		Instance: r.URL.Path,
+	}
+}
+
+// WriteProblem writes the problem as application/problem+json with the problem status.
+func WriteProblem(w http.ResponseWriter, problem Problem) {
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(problem.Status)
+	_ = json.NewEncoder(w).Encode(problem)
+}
+
+// ProblemStatus writes a problem for the status code with an optional detail message.
+func ProblemStatus(w http.ResponseWriter, r *http.Request, statusCode int, detail string) {
+	WriteProblem(w, NewProblem(r, statusCode, detail))
+}
+
+// JSON writes v as application/json with the given status code.
+func JSON(w http.ResponseWriter, statusCode int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(v)
+}
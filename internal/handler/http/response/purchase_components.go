@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/CP-Payne/wonderpicai/web/template/components/purchase"
 	creditPages "github.com/CP-Payne/wonderpicai/web/template/pages/credits"
 	"github.com/CP-Payne/wonderpicai/web/template/viewmodel"
 	"go.uber.org/zap"
@@ -17,3 +18,12 @@ func LoadPurchasePage(w http.ResponseWriter, r *http.Request, logger *zap.Logger
 	}
 	return nil
 }
+
+func LoadCheckoutStatus(w http.ResponseWriter, r *http.Request, logger *zap.Logger, vm viewmodel.CheckoutStatus) (renderErr error) {
+	err := purchase.CheckoutStatus(vm).Render(r.Context(), w)
+	if err != nil {
+		logger.Error("Failed to render checkout status", zap.Error(err))
+		return fmt.Errorf("failed to render checkout status: %w", err)
+	}
+	return nil
+}
@@ -3,6 +3,9 @@ package response
 import (
 	"fmt"
 	"net/http"
+	"net/url"
+
+	"github.com/CP-Payne/wonderpicai/internal/context/requestlog"
 )
 
 // HxRedirect adds the appropriate HTMX redirect headers
@@ -18,8 +21,10 @@ func HxRedirect(w http.ResponseWriter, r *http.Request, to string) {
 
 // HxRedirectErrorPage prepares the query parameters for the error page.
 // It calls HxRedirect with the `to` parameter set to the the error page with the prepared query.
-func HxRedirectErrorPage(w http.ResponseWriter, r *http.Request, statusCode int, errorID, message string) {
-	redirectString := fmt.Sprintf("/error?statusCode=%d&errorID=%s&message=%s", http.StatusInternalServerError, errorID, message)
+// The error ID shown on the page is the request ID, so a report can be matched with the logs.
+func HxRedirectErrorPage(w http.ResponseWriter, r *http.Request, statusCode int, message string) {
+	errorID := requestlog.ID(r.Context())
+	redirectString := fmt.Sprintf("/error?statusCode=%d&errorID=%s&message=%s", statusCode, url.QueryEscape(errorID), url.QueryEscape(message))
 
 	HxRedirect(w, r, redirectString)
 }
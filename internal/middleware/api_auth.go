@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/CP-Payne/wonderpicai/internal/context/auth"
+	"github.com/CP-Payne/wonderpicai/internal/context/requestlog"
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/CP-Payne/wonderpicai/internal/handler/http/response"
+	"github.com/CP-Payne/wonderpicai/internal/port"
+	"github.com/CP-Payne/wonderpicai/internal/service"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// WithAPIAuth authenticates JSON API requests with a personal API key or a session token sent
+// as a bearer token, falling back to the auth cookie.
+// Unauthenticated requests receive a 401 problem instead of a redirect to the login page.
+func WithAPIAuth(logger *zap.Logger, tokenService port.TokenService, apiKeyService service.APIKeyService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			token := bearerToken(r)
+
+			if strings.HasPrefix(token, domain.APIKeyPrefix) {
+				key, err := apiKeyService.Authenticate(r.Context(), token)
+				if err != nil {
+					if !errors.Is(err, domain.ErrInvalidAPIKey) {
+						logger.Error("Failed to authenticate API key", zap.Error(err))
+						response.ProblemStatus(w, r, http.StatusInternalServerError, "")
+						return
+					}
+					unauthorized(w, r, "invalid, expired or revoked API key")
+					return
+				}
+
+				ctx := auth.NewContextWithUserID(r.Context(), key.UserID)
+				requestlog.SetUserID(ctx, key.UserID.String())
+				ctx = auth.NewContextWithAPIKey(ctx, key)
+
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			if token == "" {
+				if cookie, err := r.Cookie("auth_token"); err == nil {
+					token = cookie.Value
+				}
+			}
+
+			if token == "" {
+				unauthorized(w, r, "missing bearer token")
+				return
+			}
+
+			userID, err := userIDFromToken(tokenService, token)
+			if err != nil {
+				logger.Warn("API token failed verification", zap.Error(err))
+				unauthorized(w, r, "invalid or expired token")
+				return
+			}
+
+			ctx := auth.NewContextWithUserID(r.Context(), userID)
+			requestlog.SetUserID(ctx, userID.String())
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// RequireScope rejects requests made with an API key that was not granted the scope.
+// Requests authenticated with a session act as the user and are not limited by scopes.
+func RequireScope(scope domain.APIScope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if key, ok := auth.APIKey(r.Context()); ok && !key.HasScope(scope) {
+				response.ProblemStatus(w, r, http.StatusForbidden, fmt.Sprintf("API key is missing the %q scope", scope))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" header, or an empty string
+func bearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+func userIDFromToken(tokenService port.TokenService, raw string) (uuid.UUID, error) {
+	token, err := tokenService.ValidateToken(raw)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return uuid.Nil, errors.New("invalid token claims type")
+	}
+
+	sub, ok := claims["sub"].(string)
+	if !ok {
+		return uuid.Nil, errors.New("token missing 'sub' claim")
+	}
+
+	userID, err := uuid.Parse(sub)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid userID in 'sub' claim: %w", err)
+	}
+	return userID, nil
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request, detail string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+	response.ProblemStatus(w, r, http.StatusUnauthorized, detail)
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/CP-Payne/wonderpicai/internal/context/auth"
+	"github.com/CP-Payne/wonderpicai/internal/context/requestlog"
 	"github.com/CP-Payne/wonderpicai/internal/handler/http/response"
 	"github.com/CP-Payne/wonderpicai/internal/port"
 	"github.com/golang-jwt/jwt/v5"
@@ -22,7 +23,7 @@ func WithAuth(logger *zap.Logger, tokenService port.TokenService) func(http.Hand
 					response.HxRedirect(w, r, "/auth/login")
 					return
 				} else {
-					response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "", "")
+					response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
 					return
 				}
 			}
@@ -39,25 +40,26 @@ func WithAuth(logger *zap.Logger, tokenService port.TokenService) func(http.Hand
 			if !ok {
 				// This can happen if the token is valid but the claims are not what you expect.
 				logger.Error("Invalid token claims type", zap.String("token", jwtCookie))
-				response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "", "")
+				response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
 				return
 			}
 
 			subClaim, ok := claims["sub"].(string)
 			if !ok {
 				logger.Warn("Token missing or has malformed 'sub' claim", zap.Any("claims", claims))
-				response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "", "")
+				response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
 				return
 			}
 
 			userID, err := uuid.Parse(subClaim)
 			if err != nil {
 				logger.Warn("Invalid userID UUID in claims", zap.String("sub", subClaim), zap.Error(err))
-				response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "", "")
+				response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
 				return
 			}
 
 			ctx := auth.NewContextWithUserID(r.Context(), userID)
+			requestlog.SetUserID(ctx, userID.String())
 
 			next.ServeHTTP(w, r.WithContext(ctx))
 		}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/CP-Payne/wonderpicai/internal/context/client"
+)
+
+// maxUserAgentLength keeps oversized User-Agent headers out of the audit log
+const maxUserAgentLength = 512
+
+// WithClientInfo records the client address and user agent for the audit log. It must run
+// after TrustedProxies so the address is the client's rather than the proxy's.
+func WithClientInfo(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		info := client.Info{UserAgent: r.UserAgent()}
+		if len(info.UserAgent) > maxUserAgentLength {
+			info.UserAgent = info.UserAgent[:maxUserAgentLength]
+		}
+		if addr, err := remoteAddr(r.RemoteAddr); err == nil {
+			info.IP = addr.Unmap().String()
+		}
+
+		next.ServeHTTP(w, r.WithContext(client.NewContext(r.Context(), info)))
+	}
+
+	return http.HandlerFunc(fn)
+}
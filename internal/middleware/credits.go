@@ -16,14 +16,14 @@ func WithCredits(logger *zap.Logger, walletService service.WalletService) func(h
 
 			userID, err := auth.UserID(r.Context())
 			if err != nil {
-				response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "", "")
+				response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
 				return
 			}
 
 			wallet, err := walletService.GetWallet(r.Context(), userID)
 			if err != nil {
 				logger.Error("Failed to retrieve user Wallet", zap.String("userID", userID.String()), zap.Error(err))
-				response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "", "")
+				response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
 				return
 
 			}
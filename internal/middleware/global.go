@@ -3,22 +3,23 @@ package middleware
 import (
 	"net/http"
 
+	"github.com/CP-Payne/wonderpicai/internal/context/requestlog"
 	"github.com/CP-Payne/wonderpicai/internal/handler/http/response"
-	"github.com/rs/xid"
 	"go.uber.org/zap"
 )
 
+// CustomRecoverer logs panics with the request ID and sends the user to the error page, which
+// shows the request ID as the error ID
 func CustomRecoverer(logger *zap.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		fn := func(w http.ResponseWriter, r *http.Request) {
 			defer func() {
 				if rvr := recover(); rvr != nil && rvr != http.ErrAbortHandler {
-					errorID := xid.New().String()
-					logger.Error("Panic recovered", zap.Any("panic", rvr), zap.String("errorID", errorID), zap.Stack("stack"))
+					requestlog.Logger(r.Context(), logger).Error("Panic recovered", zap.Any("panic", rvr), zap.Stack("stack"))
 
 					message := "An unexpected problem occured. We are looking into it."
 
-					response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, errorID, message)
+					response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, message)
 				}
 			}()
 
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/CP-Payne/wonderpicai/internal/context/forwarded"
+	"go.uber.org/zap"
+)
+
+// TrustedProxies applies the X-Forwarded-For, X-Forwarded-Proto and X-Forwarded-Host headers
+// of requests coming from a trusted proxy. The headers of other clients are ignored, since
+// anyone can set them.
+func TrustedProxies(logger *zap.Logger, trusted []netip.Prefix) func(http.Handler) http.Handler {
+	isTrusted := func(addr netip.Addr) bool {
+		addr = addr.Unmap()
+		for _, prefix := range trusted {
+			if prefix.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if len(trusted) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			remote, err := remoteAddr(r.RemoteAddr)
+			if err != nil || !isTrusted(remote) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// The client is the last address not added by one of our proxies
+			if forwardedFor := r.Header.Get("X-Forwarded-For"); forwardedFor != "" {
+				hops := strings.Split(forwardedFor, ",")
+				for i := len(hops) - 1; i >= 0; i-- {
+					hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+					if err != nil {
+						logger.Debug("Invalid X-Forwarded-For entry", zap.String("entry", hops[i]))
+						break
+					}
+					r.RemoteAddr = hop.String()
+					if !isTrusted(hop) {
+						break
+					}
+				}
+			}
+
+			if host := firstValue(r.Header.Get("X-Forwarded-Host")); host != "" {
+				r.Host = host
+			}
+
+			ctx := r.Context()
+			if proto := strings.ToLower(firstValue(r.Header.Get("X-Forwarded-Proto"))); proto == "http" || proto == "https" {
+				ctx = forwarded.NewContextWithScheme(ctx, proto)
+			}
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+func remoteAddr(addr string) (netip.Addr, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	return netip.ParseAddr(host)
+}
+
+// firstValue returns the first entry of a comma separated header set by chained proxies
+func firstValue(header string) string {
+	value, _, _ := strings.Cut(header, ",")
+	return strings.TrimSpace(value)
+}
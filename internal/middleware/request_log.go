@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/CP-Payne/wonderpicai/internal/context/client"
+	"github.com/CP-Payne/wonderpicai/internal/context/requestlog"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/xid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// RequestIDHeader carries the request ID in both directions, so that a request can be followed
+// through a proxy or a client that already assigned one
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds request IDs taken from clients
+const maxRequestIDLength = 64
+
+// LogRequests assigns every request an ID, honouring a well formed X-Request-ID sent by the
+// client, echoes it in the response and writes an access log line once the request is served.
+// It must run after WithClientInfo for the client address and before CustomRecoverer so that
+// recovered panics are logged as 500s.
+func LogRequests(logger *zap.Logger) func(http.Handler) http.Handler {
+	logger = logger.With(zap.String("component", "AccessLog"))
+
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			requestID := r.Header.Get(RequestIDHeader)
+			if !validRequestID(requestID) {
+				requestID = xid.New().String()
+			}
+			w.Header().Set(RequestIDHeader, requestID)
+			trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("http.request.id", requestID))
+
+			r = r.WithContext(requestlog.NewContext(r.Context(), requestID))
+			ww := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+			defer func() {
+				status := ww.Status()
+				if status == 0 {
+					// Nothing was written, net/http answers with 200
+					status = http.StatusOK
+				}
+
+				level := zapcore.InfoLevel
+				switch {
+				case status >= http.StatusInternalServerError:
+					level = zapcore.ErrorLevel
+				case r.URL.Path == "/health" || strings.HasPrefix(r.URL.Path, "/static/"):
+					level = zapcore.DebugLevel
+				}
+
+				info := client.FromContext(r.Context())
+				requestlog.Logger(r.Context(), logger).Log(level, "Request served",
+					zap.String("method", r.Method),
+					zap.String("path", r.URL.Path),
+					zap.Int("status", status),
+					zap.Int("bytes", ww.BytesWritten()),
+					zap.Duration("duration", time.Since(start)),
+					zap.String("ip", info.IP),
+					zap.String("userAgent", info.UserAgent),
+				)
+			}()
+
+			next.ServeHTTP(ww, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// validRequestID only accepts short IDs made of characters that are safe to log and echo
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidRequestID(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+		want bool
+	}{
+		{name: "uuid", id: "3f2504e0-4f89-41d3-9a0c-0305e82c3301", want: true},
+		{name: "letters digits and punctuation", id: "Req_42.retry-1", want: true},
+		{name: "longest", id: strings.Repeat("a", maxRequestIDLength), want: true},
+		{name: "empty", id: "", want: false},
+		{name: "too long", id: strings.Repeat("a", maxRequestIDLength+1), want: false},
+		{name: "space", id: "req 42", want: false},
+		{name: "newline", id: "req\n42", want: false},
+		{name: "quote", id: `req"42`, want: false},
+		{name: "html", id: "<script>", want: false},
+		{name: "non ascii", id: "réq", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validRequestID(tt.id); got != tt.want {
+				t.Errorf("validRequestID(%q) = %v, want %v", tt.id, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/CP-Payne/wonderpicai/internal/context/auth"
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/CP-Payne/wonderpicai/internal/handler/http/response"
+	"github.com/CP-Payne/wonderpicai/internal/port"
+	"go.uber.org/zap"
+)
+
+// RequireActiveUser signs out users whose account was disabled and records the user's role
+// for the navigation. It must run after WithAuth.
+func RequireActiveUser(logger *zap.Logger, userRepo port.UserRepository) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			user, ok := loadActiveUser(w, r, logger, userRepo)
+			if !ok {
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(auth.NewContextWithRole(r.Context(), user.Role)))
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// RequireActiveAPIUser rejects API requests of disabled users with a problem response.
+// It must run after WithAPIAuth.
+func RequireActiveAPIUser(logger *zap.Logger, userRepo port.UserRepository) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			userID, err := auth.UserID(r.Context())
+			if err != nil {
+				unauthorized(w, r, "authentication required")
+				return
+			}
+
+			user, err := userRepo.GetByID(userID)
+			if err != nil {
+				logger.Error("Failed to load user for API request", zap.String("userID", userID.String()), zap.Error(err))
+				response.ProblemStatus(w, r, http.StatusInternalServerError, "")
+				return
+			}
+
+			if user.Disabled() {
+				response.ProblemStatus(w, r, http.StatusForbidden, "this account has been disabled")
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(auth.NewContextWithRole(r.Context(), user.Role)))
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// RequireRole only lets users with at least the given role through. It must run after WithAuth,
+// a role loaded by an earlier middleware is reused.
+func RequireRole(logger *zap.Logger, userRepo port.UserRepository, required domain.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			role, ok := auth.Role(r.Context())
+			if !ok {
+				user, ok := loadActiveUser(w, r, logger, userRepo)
+				if !ok {
+					return
+				}
+				role = user.Role
+			}
+
+			if !role.Allows(required) {
+				userID, _ := auth.UserID(r.Context())
+				logger.Warn("User without the required role tried to access admin page",
+					zap.String("userID", userID.String()),
+					zap.String("role", string(role)),
+					zap.String("required", string(required)),
+					zap.String("path", r.URL.Path),
+				)
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(auth.NewContextWithRole(r.Context(), role)))
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// loadActiveUser loads the signed in user. Disabled users are signed out and sent to the login page.
+func loadActiveUser(w http.ResponseWriter, r *http.Request, logger *zap.Logger, userRepo port.UserRepository) (*domain.User, bool) {
+	userID, err := auth.UserID(r.Context())
+	if err != nil {
+		response.HxRedirect(w, r, "/auth/login")
+		return nil, false
+	}
+
+	user, err := userRepo.GetByID(userID)
+	if err != nil {
+		logger.Error("Failed to load signed in user", zap.String("userID", userID.String()), zap.Error(err))
+		response.HxRedirectErrorPage(w, r, http.StatusInternalServerError, "")
+		return nil, false
+	}
+
+	if user.Disabled() {
+		logger.Info("Signing out disabled user", zap.String("userID", userID.String()))
+		response.SetEmptyAuthCookie(w, r)
+		response.HxRedirect(w, r, "/auth/login")
+		return nil, false
+	}
+
+	return user, true
+}
@@ -0,0 +1,205 @@
+// Package money formats amounts stored in minor currency units for display.
+package money
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/currency"
+	"golang.org/x/text/language"
+)
+
+type currencyInfo struct {
+	symbol string
+	// exponent is the number of minor unit digits, e.g. 2 for cents
+	exponent int
+	// isCode is set for unknown currencies, which are shown with their code instead of a symbol
+	isCode bool
+}
+
+var currencies = map[string]currencyInfo{
+	"usd": {symbol: "$", exponent: 2},
+	"eur": {symbol: "€", exponent: 2},
+	"gbp": {symbol: "£", exponent: 2},
+	"zar": {symbol: "R", exponent: 2},
+	"jpy": {symbol: "¥", exponent: 0},
+}
+
+// IsSupported reports whether prices can be shown and charged in the currency
+func IsSupported(code string) bool {
+	_, ok := currencies[strings.ToLower(code)]
+	return ok
+}
+
+// Currencies lists the supported currency codes in alphabetical order
+func Currencies() []string {
+	codes := make([]string, 0, len(currencies))
+	for code := range currencies {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// CurrencyForLocale picks the currency of the first region in an Accept-Language header
+// that uses a supported currency, e.g. "de-DE,de;q=0.9" gives "eur".
+func CurrencyForLocale(acceptLanguage string) (string, bool) {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil {
+		return "", false
+	}
+	for _, tag := range tags {
+		region, confidence := tag.Region()
+		if confidence == language.No {
+			continue
+		}
+		unit, ok := currency.FromRegion(region)
+		if !ok {
+			continue
+		}
+		if code := strings.ToLower(unit.String()); IsSupported(code) {
+			return code, true
+		}
+	}
+	return "", false
+}
+
+// Formatter formats amounts following the number conventions of a locale
+type Formatter struct {
+	decimal string
+	group   string
+	// symbolAfter places the currency symbol after the amount, separated by a space
+	symbolAfter bool
+	// symbolSpace separates a leading symbol from the amount
+	symbolSpace bool
+}
+
+var english = Formatter{decimal: ".", group: ","}
+
+// formatters by base language, languages that are not listed use the English conventions
+var formatters = map[string]Formatter{
+	"en": english,
+	"ja": english,
+	"de": {decimal: ",", group: ".", symbolAfter: true},
+	"es": {decimal: ",", group: ".", symbolAfter: true},
+	"it": {decimal: ",", group: ".", symbolAfter: true},
+	"pt": {decimal: ",", group: ".", symbolAfter: true},
+	"fr": {decimal: ",", group: "\u202f", symbolAfter: true},
+	"nl": {decimal: ",", group: ".", symbolSpace: true},
+}
+
+// NewFormatter returns the formatter for the most preferred known language of an
+// Accept-Language header.
+func NewFormatter(acceptLanguage string) Formatter {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil {
+		return english
+	}
+	for _, tag := range tags {
+		base, _ := tag.Base()
+		if f, ok := formatters[base.String()]; ok {
+			return f
+		}
+	}
+	return english
+}
+
+// Format renders an amount in minor units in English notation, e.g. Format(499, "usd") is "$4.99".
+func Format(minor int64, code string) string {
+	return english.Format(minor, code)
+}
+
+// FormatPerUnit is FormatPerUnit of the English notation
+func FormatPerUnit(minor int64, count int, code string) string {
+	return english.FormatPerUnit(minor, count, code)
+}
+
+// Format renders an amount in minor units, e.g. 123456 "eur" is "€1,234.56" in English and
+// "1.234,56 €" in German. Unknown currencies are shown with their code and two decimals.
+func (f Formatter) Format(minor int64, code string) string {
+	cur := lookup(code)
+	return f.withSymbol(formatDecimal(minor, cur.exponent), cur)
+}
+
+// FormatPerUnit renders the price of a single unit when minor is the price of count units,
+// with extra precision since unit prices are often fractions of a cent.
+func (f Formatter) FormatPerUnit(minor int64, count int, code string) string {
+	if count <= 0 {
+		return f.Format(minor, code)
+	}
+	cur := lookup(code)
+
+	perUnit := float64(minor) / float64(count)
+	for i := 0; i < cur.exponent; i++ {
+		perUnit /= 10
+	}
+	return f.withSymbol(fmt.Sprintf("%.*f", cur.exponent+1, perUnit), cur)
+}
+
+func lookup(code string) currencyInfo {
+	code = strings.ToLower(code)
+	if cur, ok := currencies[code]; ok {
+		return cur
+	}
+	return currencyInfo{symbol: strings.ToUpper(code), exponent: 2, isCode: true}
+}
+
+// withSymbol localizes a plain decimal such as "-1234.56" and adds the currency symbol
+func (f Formatter) withSymbol(plain string, cur currencyInfo) string {
+	sign := ""
+	if strings.HasPrefix(plain, "-") {
+		sign = "-"
+		plain = plain[1:]
+	}
+
+	intPart, fracPart, hasFrac := strings.Cut(plain, ".")
+	number := f.groupDigits(intPart)
+	if hasFrac {
+		number += f.decimal + fracPart
+	}
+
+	// Non-breaking spaces keep amount and symbol on one line
+	if f.symbolAfter {
+		return sign + number + "\u00a0" + cur.symbol
+	}
+	if f.symbolSpace || cur.isCode {
+		return sign + cur.symbol + "\u00a0" + number
+	}
+	return sign + cur.symbol + number
+}
+
+func (f Formatter) groupDigits(digits string) string {
+	if len(digits) <= 3 {
+		return digits
+	}
+	var b strings.Builder
+	lead := len(digits) % 3
+	if lead > 0 {
+		b.WriteString(digits[:lead])
+	}
+	for i := lead; i < len(digits); i += 3 {
+		if b.Len() > 0 {
+			b.WriteString(f.group)
+		}
+		b.WriteString(digits[i : i+3])
+	}
+	return b.String()
+}
+
+func formatDecimal(minor int64, exponent int) string {
+	sign := ""
+	if minor < 0 {
+		sign = "-"
+		minor = -minor
+	}
+	if exponent == 0 {
+		return fmt.Sprintf("%s%d", sign, minor)
+	}
+
+	divisor := int64(1)
+	for i := 0; i < exponent; i++ {
+		divisor *= 10
+	}
+	return fmt.Sprintf("%s%d.%0*d", sign, minor/divisor, exponent, minor%divisor)
+}
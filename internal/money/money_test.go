@@ -0,0 +1,77 @@
+package money
+
+import "testing"
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name  string
+		minor int64
+		code  string
+		want  string
+	}{
+		{name: "cents", minor: 499, code: "usd", want: "$4.99"},
+		{name: "upper case code", minor: 499, code: "USD", want: "$4.99"},
+		{name: "grouping", minor: 123456, code: "eur", want: "€1,234.56"},
+		{name: "millions", minor: 123456789, code: "gbp", want: "£1,234,567.89"},
+		{name: "zero", minor: 0, code: "zar", want: "R0.00"},
+		{name: "leading zero cents", minor: 5, code: "usd", want: "$0.05"},
+		{name: "negative", minor: -1050, code: "usd", want: "-$10.50"},
+		{name: "no minor units", minor: 1500, code: "jpy", want: "¥1,500"},
+		{name: "unknown currency", minor: 1999, code: "chf", want: "CHF\u00a019.99"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Format(tt.minor, tt.code); got != tt.want {
+				t.Errorf("Format(%d, %q) = %q, want %q", tt.minor, tt.code, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatterFormat(t *testing.T) {
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		minor          int64
+		code           string
+		want           string
+	}{
+		{name: "english", acceptLanguage: "en-US", minor: 123456, code: "eur", want: "€1,234.56"},
+		{name: "german", acceptLanguage: "de-DE,de;q=0.9", minor: 123456, code: "eur", want: "1.234,56\u00a0€"},
+		{name: "french", acceptLanguage: "fr", minor: 123456, code: "eur", want: "1\u202f234,56\u00a0€"},
+		{name: "dutch", acceptLanguage: "nl-NL", minor: 123456, code: "eur", want: "€\u00a01.234,56"},
+		{name: "unknown language", acceptLanguage: "sv-SE", minor: 499, code: "usd", want: "$4.99"},
+		{name: "invalid header", acceptLanguage: ";;;", minor: 499, code: "usd", want: "$4.99"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewFormatter(tt.acceptLanguage).Format(tt.minor, tt.code); got != tt.want {
+				t.Errorf("Format(%d, %q) = %q, want %q", tt.minor, tt.code, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatPerUnit(t *testing.T) {
+	tests := []struct {
+		name  string
+		minor int64
+		count int
+		code  string
+		want  string
+	}{
+		{name: "fraction of a cent", minor: 499, count: 100, code: "usd", want: "$0.050"},
+		{name: "no minor units", minor: 1500, count: 100, code: "jpy", want: "¥15.0"},
+		{name: "no units", minor: 499, count: 0, code: "usd", want: "$4.99"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatPerUnit(tt.minor, tt.count, tt.code); got != tt.want {
+				t.Errorf("FormatPerUnit(%d, %d, %q) = %q, want %q", tt.minor, tt.count, tt.code, got, tt.want)
+			}
+		})
+	}
+}
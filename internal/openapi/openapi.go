@@ -0,0 +1,60 @@
+// Package openapi embeds the OpenAPI document of the JSON API served at /api/v1/openapi.json.
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+//go:embed openapi.json
+var Spec []byte
+
+// Operation is a method and path of the document, e.g. GET /api/v1/prompts/{id}
+type Operation struct {
+	Method string
+	Path   string
+}
+
+func (o Operation) String() string {
+	return o.Method + " " + o.Path
+}
+
+var operationMethods = map[string]string{
+	"get":    http.MethodGet,
+	"put":    http.MethodPut,
+	"post":   http.MethodPost,
+	"delete": http.MethodDelete,
+	"patch":  http.MethodPatch,
+	"head":   http.MethodHead,
+}
+
+// Operations returns the operations of the document sorted by path and method
+func Operations() ([]Operation, error) {
+	var doc struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(Spec, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI document: %w", err)
+	}
+
+	var ops []Operation
+	for path, item := range doc.Paths {
+		for key := range item {
+			if method, ok := operationMethods[strings.ToLower(key)]; ok {
+				ops = append(ops, Operation{Method: method, Path: path})
+			}
+		}
+	}
+
+	sort.Slice(ops, func(i, j int) bool {
+		if ops[i].Path != ops[j].Path {
+			return ops[i].Path < ops[j].Path
+		}
+		return ops[i].Method < ops[j].Method
+	})
+	return ops, nil
+}
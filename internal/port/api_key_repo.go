@@ -0,0 +1,19 @@
+package port
+
+import (
+	"context"
+	"time"
+
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/google/uuid"
+)
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *domain.APIKey) error
+	// ListByUser returns the user's keys, newest first, including revoked keys
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]domain.APIKey, error)
+	GetByPrefix(ctx context.Context, prefix string) (*domain.APIKey, error)
+	// Revoke marks the user's key as revoked and returns it
+	Revoke(ctx context.Context, userID uuid.UUID, id uuid.UUID, at time.Time) (*domain.APIKey, error)
+	TouchLastUsed(ctx context.Context, id uuid.UUID, at time.Time) error
+}
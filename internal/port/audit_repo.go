@@ -0,0 +1,26 @@
+package port
+
+import (
+	"context"
+
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+)
+
+// AuditFilter narrows an audit log listing, empty fields match every event
+type AuditFilter struct {
+	Action domain.AuditAction
+	// ActorEmail matches part of the actor's email
+	ActorEmail string
+	TargetType string
+	TargetID   string
+}
+
+type AuditRepository interface {
+	// Append adds the event to the end of the chain. It sets the sequence, creation time,
+	// previous hash and hash of the event.
+	Append(ctx context.Context, event *domain.AuditEvent) error
+	// List returns a page of matching events, newest first
+	List(ctx context.Context, filter AuditFilter, page domain.Page) ([]domain.AuditEvent, int64, error)
+	// ListAfter returns up to limit events following the given sequence number, in chain order
+	ListAfter(ctx context.Context, sequence int64, limit int) ([]domain.AuditEvent, error)
+}
@@ -0,0 +1,21 @@
+package port
+
+import (
+	"context"
+
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/google/uuid"
+)
+
+type CreditPackageRepository interface {
+	// ListActive returns the packages shown to customers, ordered by sort order
+	ListActive(ctx context.Context) ([]domain.CreditPackage, error)
+	ListAll(ctx context.Context) ([]domain.CreditPackage, error)
+	// GetByID also returns deleted packages, so purchases of since removed packages can be completed
+	GetByID(ctx context.Context, id uuid.UUID) (*domain.CreditPackage, error)
+	Create(ctx context.Context, pkg *domain.CreditPackage) error
+	Update(ctx context.Context, pkg *domain.CreditPackage) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	// CreateIfEmpty stores the packages only when no package exists yet
+	CreateIfEmpty(ctx context.Context, pkgs []domain.CreditPackage) (created bool, err error)
+}
@@ -0,0 +1,44 @@
+package port
+
+import (
+	"context"
+	"time"
+
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/google/uuid"
+)
+
+type GenerationJobRepository interface {
+	// EnqueuePrompt debits prompt.Cost from the user's wallet, creates the prompt with its
+	// placeholder images and queues a generation job, all in a single transaction.
+	// It returns domain.ErrGenerationLimitReached when the prompt would exceed the user's quota.
+	EnqueuePrompt(ctx context.Context, prompt *domain.Prompt, maxAttempts int, quota domain.GenerationQuota) (*domain.Prompt, error)
+	// ClaimNext locks up to limit runnable jobs for the given lease and returns them with their prompt.
+	// Jobs are handed out round-robin across users, so a single user cannot starve the others.
+	ClaimNext(ctx context.Context, limit int, lease time.Duration) ([]domain.GenerationJob, error)
+	// QueuePosition returns the 1-based position of the user's next queued job, or 0 when none is queued.
+	QueuePosition(ctx context.Context, userID uuid.UUID) (int, error)
+	// QueueDepth counts the jobs of pending prompts by job status
+	QueueDepth(ctx context.Context) (map[domain.JobStatus]int64, error)
+	// ExtendLease keeps a running job locked for another lease, for submissions that take longer
+	// than a single lease
+	ExtendLease(ctx context.Context, jobID uuid.UUID, lease time.Duration) error
+	MarkSubmitted(ctx context.Context, jobID uuid.UUID, externalPromptID uuid.UUID, backend string) error
+	Reschedule(ctx context.Context, jobID uuid.UUID, runAt time.Time, lastErr string) error
+	// StorePartialImages completes the first pending placeholders of the job's prompt with the
+	// images generated before a failed attempt and counts them in job.Generated.
+	// It returns domain.ErrPromptAlreadyFinished when the prompt is no longer pending.
+	StorePartialImages(ctx context.Context, jobID uuid.UUID, images [][]byte) error
+	// MarkDead fails the job and its prompt and refunds the prompt cost in a single transaction.
+	// It returns domain.ErrPromptAlreadyFinished when the prompt is no longer pending.
+	MarkDead(ctx context.Context, jobID uuid.UUID, lastErr string) error
+	// ListExpiredSubmissions returns the submitted jobs of pending prompts that were submitted
+	// before the given time, oldest first, with their prompt
+	ListExpiredSubmissions(ctx context.Context, submittedBefore time.Time, limit int) ([]domain.GenerationJob, error)
+	// ListStuck returns the jobs of pending prompts that have not changed since before,
+	// oldest first, with their prompt.
+	ListStuck(ctx context.Context, before time.Time, limit int) ([]domain.GenerationJob, error)
+	// Requeue submits the job of a pending prompt again right away. The result of the earlier
+	// submission is ignored. It returns domain.ErrPromptNotRetryable for finished prompts.
+	Requeue(ctx context.Context, promptID uuid.UUID, reason string) (*domain.GenerationJob, error)
+}
@@ -0,0 +1,14 @@
+package port
+
+import (
+	"context"
+
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/google/uuid"
+)
+
+// GenerationResultSink receives the images of synchronous generation backends, which the
+// generation worker hands over once they return instead of waiting for the completion webhook.
+type GenerationResultSink interface {
+	UpdatePlaceholderImages(ctx context.Context, externalPromptID uuid.UUID, backend string, images [][]byte, desiredStatus domain.Status) (*domain.Prompt, error)
+}
@@ -1,6 +1,10 @@
 package port
 
-import "github.com/google/uuid"
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
 
 type ImageGenerationInput struct {
 	Prompt     string
@@ -9,6 +13,30 @@ type ImageGenerationInput struct {
 	Height     int
 }
 
+type ImageGenerationResult struct {
+	PromptID uuid.UUID
+	// Backend identifies the generation node that accepted the prompt.
+	// Empty when the client talks to a single unnamed backend.
+	Backend string
+	// Images holds the generated images of synchronous backends, which return them right away
+	// instead of reporting back through the completion webhook
+	Images [][]byte
+}
+
 type ImageGeneration interface {
-	GenerateImage(*ImageGenerationInput) (promptID uuid.UUID, err error)
+	// GenerateImage submits the prompt to the backend. A synchronous backend that fails after
+	// generating some of the images returns them together with the error.
+	GenerateImage(ctx context.Context, input *ImageGenerationInput) (*ImageGenerationResult, error)
+}
+
+// ImageGenerationHealthChecker is implemented by generation clients that can report
+// whether their backend is reachable.
+type ImageGenerationHealthChecker interface {
+	CheckHealth(ctx context.Context) error
+}
+
+// ImageGenerationCompletionObserver is optionally implemented by generation clients that
+// need to know when a prompt accepted by one of their backends has finished.
+type ImageGenerationCompletionObserver interface {
+	PromptFinished(backend string)
 }
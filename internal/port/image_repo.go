@@ -9,6 +9,8 @@ import (
 
 type ImageRepository interface {
 	GetByID(ctx context.Context, userID uuid.UUID, imageID uuid.UUID) (*domain.Image, error)
+	// ListByUser returns a page of images without image data and the total number of images
+	ListByUser(ctx context.Context, userID uuid.UUID, page domain.Page) ([]domain.Image, int64, error)
 	Delete(ctx context.Context, userID uuid.UUID, imageID uuid.UUID) error
 	DeleteFailed(ctx context.Context, userID uuid.UUID) error
 	ContainsFailedImages(ctx context.Context, userID uuid.UUID) (bool, error)
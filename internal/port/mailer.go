@@ -0,0 +1,21 @@
+package port
+
+import "context"
+
+type MailMessage struct {
+	To      string
+	Subject string
+	// Body is plain text
+	Body        string
+	Attachments []MailAttachment
+}
+
+type MailAttachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg MailMessage) error
+}
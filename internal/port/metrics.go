@@ -0,0 +1,40 @@
+package port
+
+import (
+	"time"
+
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+)
+
+// Outcomes recorded by Metrics. Labels stay within this set so the number of series is bounded.
+const (
+	OutcomeSuccess           = "success"
+	OutcomeError             = "error"
+	OutcomeInsufficientFunds = "insufficient_funds"
+	OutcomeLimitReached      = "limit_reached"
+	OutcomeBusy              = "busy"
+	OutcomeUnavailable       = "unavailable"
+	OutcomeUnknown           = "unknown"
+	OutcomeRejected          = "rejected"
+	OutcomeCircuitOpen       = "circuit_open"
+	OutcomeIgnored           = "ignored"
+	OutcomeInvalid           = "invalid"
+)
+
+// Metrics records operational metrics. Implementations must be safe for concurrent use.
+type Metrics interface {
+	// ObserveHTTPRequest records a handled request. route is the matched route pattern rather
+	// than the path, so that IDs in the path do not create a series each.
+	ObserveHTTPRequest(method, route string, status int, duration time.Duration)
+	// GenerationSubmitted records the outcome of a user's generation request
+	GenerationSubmitted(outcome string)
+	// GenerationFinished records a prompt reaching a final status. took is the time since the
+	// prompt was created.
+	GenerationFinished(status domain.Status, took time.Duration)
+	// GenerationBackendRequest records a single request to a generation backend
+	GenerationBackendRequest(backend, outcome string, duration time.Duration)
+	// CreditsMoved records credits taken from or added to wallets, by ledger kind
+	CreditsMoved(kind domain.CreditTransactionKind, amount int)
+	// PaymentWebhook records the outcome of an event received from the payment provider
+	PaymentWebhook(eventType, outcome string)
+}
@@ -1,24 +1,132 @@
 package port
 
-import "net/http"
+import (
+	"net/http"
+	"time"
+)
 
 type UserData struct {
 	Email string
+	// CustomerID is the user's customer at the provider, if known
+	CustomerID string
 }
 
 type ProductData struct {
-	Name     string
-	Price    int
-	Quantity int
-	Option   string
+	Name string
+	// PriceMinor is the unit price in the currency's minor unit
+	PriceMinor int64
+	Currency   string
+	Quantity   int
+	Option     string
+	// Reference identifies our payment record and is returned with the completed session
+	Reference string
+	// DiscountPercent is taken off the price at checkout, DiscountName is shown to the customer
+	DiscountPercent int
+	DiscountName    string
+	// ExpiresAt ends the checkout session early, zero keeps the provider's default
+	ExpiresAt time.Time
+}
+
+type SubscriptionData struct {
+	Name       string
+	PriceMinor int64
+	Currency   string
+	// Interval is the billing period, "month" or "year"
+	Interval string
+	// Reference identifies our subscription record and is returned with all its events
+	Reference string
+}
+
+type CheckoutSession struct {
+	ID  string
+	URL string
+}
+
+type ProviderEventType string
+
+const (
+	// EventCheckoutCompleted is sent when the customer finished a checkout. Delayed payment
+	// methods are not paid at that point and follow up with one of the async events.
+	EventCheckoutCompleted      ProviderEventType = "checkout.completed"
+	EventCheckoutAsyncSucceeded ProviderEventType = "checkout.async_payment_succeeded"
+	EventCheckoutAsyncFailed    ProviderEventType = "checkout.async_payment_failed"
+	EventChargeRefunded         ProviderEventType = "charge.refunded"
+	EventChargeDisputed         ProviderEventType = "charge.dispute_created"
+	EventInvoicePaid            ProviderEventType = "invoice.paid"
+	EventSubscriptionUpdated    ProviderEventType = "subscription.updated"
+	EventSubscriptionCanceled   ProviderEventType = "subscription.canceled"
+)
+
+// ProviderEvent is a webhook event of the payment provider. Exactly one of the payloads is
+// set, depending on Type.
+type ProviderEvent struct {
+	ID           string
+	Type         ProviderEventType
+	Checkout     *SessionSuccess
+	Reversal     *PaymentReversal
+	Invoice      *InvoicePaid
+	Subscription *SubscriptionChange
 }
 
 type SessionSuccess struct {
+	SessionID string
+	Reference string
 	UserEmail string
 	Option    string
+	// AmountTotal and Currency are what the customer was charged
+	AmountTotal int64
+	Currency    string
+	// Paid is false for delayed payment methods until their funds arrived
+	Paid bool
+	// PaymentID is the provider's payment, referred to by refunds and disputes
+	PaymentID string
+	// SubscriptionID and CustomerID are set for subscription checkouts
+	SubscriptionID string
+	CustomerID     string
+}
+
+// PaymentReversal is a refund or dispute of a payment
+type PaymentReversal struct {
+	PaymentID string
+	ChargeID  string
+	// DisputeID is set for disputes
+	DisputeID string
+	// AmountMinor is the total refunded so far, or the disputed amount
+	AmountMinor int64
+	Currency    string
+	// Reason is the dispute reason given by the bank, empty for refunds
+	Reason string
+}
+
+type InvoicePaid struct {
+	InvoiceID      string
+	SubscriptionID string
+	CustomerID     string
+	// Reference is the Reference of the subscription's checkout
+	Reference string
+	// PaymentID is the provider payment that paid the invoice, refunds and disputes refer to it
+	PaymentID   string
+	AmountPaid  int64
+	Currency    string
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+}
+
+type SubscriptionChange struct {
+	SubscriptionID string
+	Reference      string
+	// Status is the provider's subscription status, e.g. "active" or "past_due"
+	Status            string
+	CurrentPeriodEnd  time.Time
+	CancelAtPeriodEnd bool
+	CanceledAt        time.Time
 }
 
 type PaymentProvider interface {
-	CreateCheckoutSession(UserData, ProductData) (string, error)
-	HandleEvent(r *http.Request, data []byte) (*SessionSuccess, error)
+	CreateCheckoutSession(UserData, ProductData) (*CheckoutSession, error)
+	CreateSubscriptionCheckout(UserData, SubscriptionData) (*CheckoutSession, error)
+	// CreatePortalSession returns the URL of the provider's page where customers manage
+	// their subscription and billing details
+	CreatePortalSession(customerID string, returnURL string) (string, error)
+	HandleEvent(r *http.Request, data []byte) (*ProviderEvent, error)
 }
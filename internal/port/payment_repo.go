@@ -0,0 +1,47 @@
+package port
+
+import (
+	"context"
+
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/google/uuid"
+)
+
+type PaymentRepository interface {
+	Create(ctx context.Context, payment *domain.Payment) error
+	GetByID(ctx context.Context, id uuid.UUID) (*domain.Payment, error)
+	GetBySessionID(ctx context.Context, sessionID string) (*domain.Payment, error)
+	// ListByUser returns the user's payments that were paid or are being paid, newest first
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]domain.Payment, error)
+	AttachSession(ctx context.Context, id uuid.UUID, sessionID string) error
+	MarkFailed(ctx context.Context, id uuid.UUID) error
+	// MarkProcessing records a checkout paid with a delayed method that is not settled yet
+	MarkProcessing(ctx context.Context, id uuid.UUID, providerPaymentID string) error
+	// Complete marks a pending payment as completed and adds its credits to the user's wallet.
+	// completed is false when the payment was already completed. A charged amount or currency
+	// that differs from the payment's is not completed but marked as domain.PaymentAmountMismatch,
+	// returning the payment with domain.ErrPaymentAmountMismatch.
+	Complete(ctx context.Context, id uuid.UUID, amountMinor int64, currency string, providerPaymentID string) (payment *domain.Payment, completed bool, err error)
+	// Reverse takes back the credits of a refunded or disputed payment. Credits the user has
+	// already spent are reported as shortfall and the user is flagged with flagReason.
+	Reverse(ctx context.Context, req PaymentReversalRequest) (*PaymentReversalResult, error)
+	// AssignInvoice gives a completed payment the next invoice number and records the tax
+	// rate included in its amount. Payments that already have a number are returned unchanged.
+	AssignInvoice(ctx context.Context, id uuid.UUID, taxRateBps int) (*domain.Payment, error)
+}
+
+type PaymentReversalRequest struct {
+	ProviderPaymentID string
+	// RefundedMinor is the total refunded so far, ignored for disputes
+	RefundedMinor int64
+	Disputed      bool
+	FlagReason    string
+}
+
+type PaymentReversalResult struct {
+	Payment *domain.Payment
+	// Reversed is the number of credits taken from the wallet, Shortfall the number that had
+	// already been spent
+	Reversed  int
+	Shortfall int
+}
@@ -0,0 +1,12 @@
+package port
+
+import (
+	"context"
+
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+)
+
+type PricingRulesSource interface {
+	// Load returns the current pricing rules. It is called periodically to pick up changes.
+	Load(ctx context.Context) (*domain.PricingRules, error)
+}
@@ -0,0 +1,33 @@
+package port
+
+import (
+	"context"
+	"time"
+
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/google/uuid"
+)
+
+type PromoRedemptionRequest struct {
+	Code   string
+	UserID uuid.UUID
+	// PackageID is the package bought with a discount code, nil for credit grants
+	PackageID *uuid.UUID
+	PaymentID *uuid.UUID
+	// ReservedUntil reserves the code for an open checkout, nil redeems it right away
+	ReservedUntil *time.Time
+}
+
+type PromoCodeRepository interface {
+	GetByCode(ctx context.Context, code string) (*domain.PromoCode, error)
+	// ListAll returns all codes, newest first, with their redemption counts
+	ListAll(ctx context.Context) ([]domain.PromoCode, error)
+	Create(ctx context.Context, promo *domain.PromoCode) error
+	SetActive(ctx context.Context, id uuid.UUID, active bool) (*domain.PromoCode, error)
+	// Redeem checks the code and its limits and records the redemption in one transaction,
+	// so concurrent redemptions cannot exceed the limits. Redeeming a credit code adds its
+	// credits to the user's wallet in the same transaction.
+	Redeem(ctx context.Context, req PromoRedemptionRequest) (*domain.PromoCode, *domain.PromoRedemption, error)
+	// Release removes a redemption whose checkout failed
+	Release(ctx context.Context, redemptionID uuid.UUID) error
+}
@@ -10,5 +10,12 @@ import (
 type PromptRepository interface {
 	Create(ctx context.Context, prompt *domain.Prompt) (*domain.Prompt, error)
 	FindAllByUser(ctx context.Context, userID uuid.UUID) ([]domain.Prompt, error)
-	UpdatePlaceholderImages(ctx context.Context, externalPromptID uuid.UUID, images [][]byte, desiredStatus domain.Status) (*domain.Prompt, error)
+	// FindByID returns the prompt and its images without image data
+	FindByID(ctx context.Context, userID uuid.UUID, promptID uuid.UUID) (*domain.Prompt, error)
+	// ListByUser returns a page of prompts without image data and the total number of prompts
+	ListByUser(ctx context.Context, userID uuid.UUID, page domain.Page) ([]domain.Prompt, int64, error)
+	// UpdatePlaceholderImages stores the result of the prompt the backend accepted under externalPromptID.
+	// It returns domain.ErrPromptBackendMismatch when the prompt was submitted to another or an
+	// unnamed backend and domain.ErrPromptAlreadyFinished when the prompt is no longer pending.
+	UpdatePlaceholderImages(ctx context.Context, externalPromptID uuid.UUID, backend string, images [][]byte, desiredStatus domain.Status) (*domain.Prompt, error)
 }
@@ -0,0 +1,8 @@
+package port
+
+import "github.com/CP-Payne/wonderpicai/internal/domain"
+
+// ReceiptRenderer renders receipts as PDF documents
+type ReceiptRenderer interface {
+	Render(receipt *domain.Receipt) ([]byte, error)
+}
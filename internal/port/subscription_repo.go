@@ -0,0 +1,29 @@
+package port
+
+import (
+	"context"
+
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/google/uuid"
+)
+
+type SubscriptionRepository interface {
+	ListActivePlans(ctx context.Context) ([]domain.SubscriptionPlan, error)
+	GetPlanByID(ctx context.Context, id uuid.UUID) (*domain.SubscriptionPlan, error)
+	// CreatePlansIfEmpty stores the plans only when no plan exists yet
+	CreatePlansIfEmpty(ctx context.Context, plans []domain.SubscriptionPlan) (created bool, err error)
+
+	Create(ctx context.Context, sub *domain.Subscription) error
+	// GetByID and GetCurrentByUserID preload the plan
+	GetByID(ctx context.Context, id uuid.UUID) (*domain.Subscription, error)
+	GetCurrentByUserID(ctx context.Context, userID uuid.UUID) (*domain.Subscription, error)
+	GetByProviderID(ctx context.Context, providerSubscriptionID string) (*domain.Subscription, error)
+	GetByCheckoutSessionID(ctx context.Context, sessionID string) (*domain.Subscription, error)
+	Update(ctx context.Context, sub *domain.Subscription) error
+	// GrantPeriod records the grant of a paid invoice and adjusts the wallet in one
+	// transaction: unused allowance above the plan's rollover cap expires and the new
+	// allowance is added. The invoice is recorded as the given completed payment, so it is
+	// listed with the purchases and gets a receipt. granted is false when the invoice was
+	// granted before.
+	GrantPeriod(ctx context.Context, grant *domain.SubscriptionGrant, payment *domain.Payment) (granted bool, err error)
+}
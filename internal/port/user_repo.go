@@ -1,6 +1,9 @@
 package port
 
 import (
+	"context"
+	"time"
+
 	"github.com/CP-Payne/wonderpicai/internal/domain"
 	"github.com/google/uuid"
 )
@@ -9,4 +12,13 @@ type UserRepository interface {
 	Create(user *domain.User) error
 	GetByEmail(email string) (*domain.User, error)
 	GetByID(userID uuid.UUID) (*domain.User, error)
+	UpdateCurrency(userID uuid.UUID, currency string) error
+	// Search matches query against email and username, an empty query lists all users.
+	// Users are returned newest first with their wallet.
+	Search(ctx context.Context, query string, page domain.Page) ([]domain.User, int64, error)
+	SetRole(ctx context.Context, userID uuid.UUID, role domain.Role) error
+	// SetDisabled disables the account at disabledAt, nil enables it again
+	SetDisabled(ctx context.Context, userID uuid.UUID, disabledAt *time.Time, reason string) error
+	// PromoteToAdmin gives the admin role to the users with the given IDs
+	PromoteToAdmin(ctx context.Context, userIDs []uuid.UUID) (int64, error)
 }
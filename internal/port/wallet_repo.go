@@ -7,9 +7,23 @@ import (
 	"github.com/google/uuid"
 )
 
+// CreditChange describes why a wallet balance changes, it is recorded in the credit ledger
+type CreditChange struct {
+	Kind domain.CreditTransactionKind
+	// Reference is the ID of what caused the change, e.g. the prompt or payment
+	Reference string
+	Reason    string
+	// ActorID is the admin who made a manual change
+	ActorID *uuid.UUID
+}
+
 type WalletRepository interface {
 	GetByUserID(ctx context.Context, userID uuid.UUID) (*domain.Wallet, error)
-	SubtractCredits(ctx context.Context, userID uuid.UUID, amount int) error
-	AddCredits(ctx context.Context, userID uuid.UUID, amount int) error
-	AddCreditsToEmail(ctx context.Context, email string, amount int) error
+	SubtractCredits(ctx context.Context, userID uuid.UUID, amount int, change CreditChange) error
+	AddCredits(ctx context.Context, userID uuid.UUID, amount int, change CreditChange) error
+	// AddCreditsToEmail adds credits once per change kind and reference. It reports false when
+	// the ledger already has an entry for them, e.g. for a redelivered provider event.
+	AddCreditsToEmail(ctx context.Context, email string, amount int, change CreditChange) (bool, error)
+	// ListTransactions returns a page of the user's credit ledger, newest first
+	ListTransactions(ctx context.Context, userID uuid.UUID, page domain.Page) ([]domain.CreditTransaction, int64, error)
 }
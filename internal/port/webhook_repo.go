@@ -0,0 +1,39 @@
+package port
+
+import (
+	"context"
+	"time"
+
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/google/uuid"
+)
+
+// WebhookAttempt is the outcome of sending a delivery
+type WebhookAttempt struct {
+	ResponseStatus int
+	ResponseBody   string
+	Error          string
+	// NextAttemptAt reschedules a failed attempt, nil marks the delivery as failed
+	NextAttemptAt *time.Time
+}
+
+type WebhookRepository interface {
+	CreateEndpoint(ctx context.Context, endpoint *domain.WebhookEndpoint) error
+	// ListEndpoints returns the user's endpoints, newest first
+	ListEndpoints(ctx context.Context, userID uuid.UUID) ([]domain.WebhookEndpoint, error)
+	GetEndpoint(ctx context.Context, userID uuid.UUID, id uuid.UUID) (*domain.WebhookEndpoint, error)
+	SetEndpointActive(ctx context.Context, userID uuid.UUID, id uuid.UUID, active bool) (*domain.WebhookEndpoint, error)
+	DeleteEndpoint(ctx context.Context, userID uuid.UUID, id uuid.UUID) error
+
+	// EnqueueEvent creates a pending delivery of the event for every active endpoint of the user
+	// subscribed to it and returns the number of deliveries
+	EnqueueEvent(ctx context.Context, userID uuid.UUID, event domain.WebhookEventType, eventID uuid.UUID, payload string, maxAttempts int) (int, error)
+	// ClaimDeliveries locks up to limit due deliveries for the given lease and returns them with their endpoint
+	ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]domain.WebhookDelivery, error)
+	MarkDelivered(ctx context.Context, id uuid.UUID, responseStatus int, responseBody string) error
+	MarkAttemptFailed(ctx context.Context, id uuid.UUID, attempt WebhookAttempt) error
+	// ListDeliveries returns the latest deliveries of the user's endpoint, newest first
+	ListDeliveries(ctx context.Context, userID uuid.UUID, endpointID uuid.UUID, limit int) ([]domain.WebhookDelivery, error)
+	// Redeliver queues a new delivery of the same event to the same endpoint
+	Redeliver(ctx context.Context, userID uuid.UUID, deliveryID uuid.UUID, maxAttempts int) (*domain.WebhookDelivery, error)
+}
@@ -0,0 +1,60 @@
+package routes
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	allHandlers "github.com/CP-Payne/wonderpicai/internal/handler/http"
+	"github.com/CP-Payne/wonderpicai/internal/openapi"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+const apiPrefix = "/api/v1/"
+
+// TestOpenAPIContract compares the routes registered under /api/v1 with the operations of the
+// embedded OpenAPI document. Routes are only registered here, so the handlers stay zero values.
+func TestOpenAPIContract(t *testing.T) {
+	handler := NewRouter(&allHandlers.ApiHandlers{}, zap.NewNop(), nil, nil, nil, nil, nil, nil, nil, "")
+	router, ok := handler.(chi.Routes)
+	if !ok {
+		t.Fatalf("router %T does not expose its routes", handler)
+	}
+
+	documented, err := openapi.Operations()
+	if err != nil {
+		t.Fatalf("failed to read the OpenAPI document: %v", err)
+	}
+	if len(documented) == 0 {
+		t.Fatal("OpenAPI document has no operations")
+	}
+
+	specOps := make(map[openapi.Operation]bool, len(documented))
+	for _, op := range documented {
+		specOps[op] = true
+	}
+
+	routeOps := make(map[openapi.Operation]bool)
+	err = chi.Walk(router, func(method string, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		route = strings.TrimSuffix(strings.ReplaceAll(route, "/*/", "/"), "/")
+		if strings.HasPrefix(route, apiPrefix) {
+			routeOps[openapi.Operation{Method: method, Path: route}] = true
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to walk routes: %v", err)
+	}
+
+	for op := range routeOps {
+		if !specOps[op] {
+			t.Errorf("route not documented: %s", op)
+		}
+	}
+	for _, op := range documented {
+		if !routeOps[op] {
+			t.Errorf("documented operation has no route: %s", op)
+		}
+	}
+}
@@ -2,7 +2,9 @@ package routes
 
 import (
 	"net/http"
+	"net/netip"
 
+	"github.com/CP-Payne/wonderpicai/internal/domain"
 	allHandlers "github.com/CP-Payne/wonderpicai/internal/handler/http"
 	"github.com/CP-Payne/wonderpicai/internal/middleware"
 	"github.com/CP-Payne/wonderpicai/internal/port"
@@ -12,10 +14,14 @@ import (
 	"go.uber.org/zap"
 )
 
-func NewRouter(handlers *allHandlers.ApiHandlers, logger *zap.Logger, tokenService port.TokenService, walletService service.WalletService) http.Handler {
+func NewRouter(handlers *allHandlers.ApiHandlers, logger *zap.Logger, tokenService port.TokenService, walletService service.WalletService, apiKeyService service.APIKeyService, userRepo port.UserRepository, trustedProxies []netip.Prefix, metrics port.Metrics, metricsHandler http.Handler, metricsToken string) http.Handler {
 	r := chi.NewRouter()
 
-	r.Use(chimiddleware.Logger)
+	r.Use(middleware.TrustedProxies(logger, trustedProxies))
+	r.Use(middleware.TraceRequests)
+	r.Use(middleware.WithClientInfo)
+	r.Use(middleware.LogRequests(logger))
+	r.Use(middleware.RecordMetrics(metrics))
 	r.Use(middleware.CustomRecoverer(logger))
 	// r.Use(middleware.Recoverer)
 	r.Use(chimiddleware.StripSlashes)
@@ -27,6 +33,11 @@ func NewRouter(handlers *allHandlers.ApiHandlers, logger *zap.Logger, tokenServi
 		w.Write([]byte("OK"))
 	})
 
+	// Metrics are only exposed on the main server to scrapers holding the token
+	if metricsToken != "" {
+		r.With(middleware.RequireBearerToken(metricsToken)).Handle("/metrics", metricsHandler)
+	}
+
 	r.With(middleware.RedirectIfAuthCookie("/gen")).Get("/", handlers.LandingHandler.ShowLandingPage)
 	r.Get("/error", handlers.ErrorHandler.ServeGenericErrorPage)
 
@@ -34,6 +45,7 @@ func NewRouter(handlers *allHandlers.ApiHandlers, logger *zap.Logger, tokenServi
 
 	r.Route("/gen", func(r chi.Router) {
 		r.Use(middleware.WithAuth(logger, tokenService))
+		r.Use(middleware.RequireActiveUser(logger, userRepo))
 
 		r.Group(func(r chi.Router) {
 			r.Use(middleware.WithCredits(logger, walletService))
@@ -41,6 +53,7 @@ func NewRouter(handlers *allHandlers.ApiHandlers, logger *zap.Logger, tokenServi
 			r.Post("/", handlers.GenHandler.HandleGenerationCreate)
 		})
 
+		r.Get("/estimate", handlers.GenHandler.HandleCostEstimate)
 		r.Get("/image/{id}/status", handlers.GenHandler.HandleImageStatus)
 		r.Delete("/image/{id}", handlers.GenHandler.HandleImageDelete)
 		r.Delete("/image/failed", handlers.GenHandler.HandleFailedImagesDelete)
@@ -48,14 +61,98 @@ func NewRouter(handlers *allHandlers.ApiHandlers, logger *zap.Logger, tokenServi
 
 	r.Route("/purchase", func(r chi.Router) {
 		r.Use(middleware.WithAuth(logger, tokenService))
+		r.Use(middleware.RequireActiveUser(logger, userRepo))
 		r.Get("/", handlers.PurchaseHandler.ShowPurchasePage)
+		r.Post("/currency", handlers.PurchaseHandler.HandleCurrencyUpdate)
+		r.Post("/promo", handlers.PurchaseHandler.HandlePromoRedeem)
+		r.Get("/success", handlers.PurchaseHandler.ShowSuccessPage)
+		r.Get("/success/status", handlers.PurchaseHandler.HandleCheckoutStatus)
+		r.Post("/subscribe/{plan}", handlers.PurchaseHandler.HandleSubscribe)
+		r.Post("/subscription/portal", handlers.PurchaseHandler.HandleSubscriptionPortal)
 		r.Post("/{option}", handlers.PurchaseHandler.HandlePurchaseOption)
 	})
 
-	r.Get("/purchase/success", handlers.PurchaseHandler.ShowSuccessPage)
+	r.Route("/account", func(r chi.Router) {
+		r.Use(middleware.WithAuth(logger, tokenService))
+		r.Use(middleware.RequireActiveUser(logger, userRepo))
+		r.Get("/purchases", handlers.AccountHandler.ShowPurchasesPage)
+		r.Get("/purchases/{id}/receipt", handlers.AccountHandler.HandleReceiptDownload)
+		r.Get("/api-keys", handlers.AccountHandler.ShowAPIKeysPage)
+		r.Post("/api-keys", handlers.AccountHandler.HandleAPIKeyCreate)
+		r.Delete("/api-keys/{id}", handlers.AccountHandler.HandleAPIKeyRevoke)
+		r.Get("/webhooks", handlers.AccountHandler.ShowWebhooksPage)
+		r.Post("/webhooks", handlers.AccountHandler.HandleWebhookCreate)
+		r.Get("/webhooks/{id}", handlers.AccountHandler.ShowWebhookPage)
+		r.Post("/webhooks/{id}/active", handlers.AccountHandler.HandleWebhookActive)
+		r.Delete("/webhooks/{id}", handlers.AccountHandler.HandleWebhookDelete)
+		r.Post("/webhooks/deliveries/{id}/redeliver", handlers.AccountHandler.HandleWebhookRedeliver)
+	})
+
 	r.Get("/purchase/cancel", handlers.PurchaseHandler.ShowCancelPage)
 	r.Post("/purchase/webhook", handlers.PurchaseHandler.HandlePurchaseEvents)
 
+	r.Route("/admin", func(r chi.Router) {
+		r.Use(middleware.WithAuth(logger, tokenService))
+		r.Use(middleware.RequireRole(logger, userRepo, domain.RoleSupport))
+
+		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
+		})
+		r.Get("/users", handlers.AdminHandler.ShowUsersPage)
+		r.Get("/users/{id}", handlers.AdminHandler.ShowUserPage)
+		r.Get("/prompts", handlers.AdminHandler.ShowStuckPromptsPage)
+		r.Post("/prompts/{id}/retry", handlers.AdminHandler.HandlePromptRetry)
+
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireRole(logger, userRepo, domain.RoleAdmin))
+
+			r.Post("/users/{id}/credits", handlers.AdminHandler.HandleUserCredits)
+			r.Post("/users/{id}/role", handlers.AdminHandler.HandleUserRole)
+			r.Post("/users/{id}/disabled", handlers.AdminHandler.HandleUserDisabled)
+
+			r.Get("/audit", handlers.AdminHandler.ShowAuditPage)
+			r.Post("/audit/verify", handlers.AdminHandler.HandleAuditVerify)
+
+			r.Get("/packages", handlers.AdminHandler.ShowPackagesPage)
+			r.Post("/packages", handlers.AdminHandler.HandlePackageCreate)
+			r.Post("/packages/{id}", handlers.AdminHandler.HandlePackageUpdate)
+			r.Delete("/packages/{id}", handlers.AdminHandler.HandlePackageDelete)
+
+			r.Get("/promos", handlers.AdminHandler.ShowPromosPage)
+			r.Post("/promos", handlers.AdminHandler.HandlePromoCreate)
+			r.Post("/promos/{id}/active", handlers.AdminHandler.HandlePromoActive)
+		})
+	})
+
+	r.Route("/api/v1", func(r chi.Router) {
+		r.NotFound(handlers.ApiV1Handler.HandleNotFound)
+		r.MethodNotAllowed(handlers.ApiV1Handler.HandleMethodNotAllowed)
+		r.Get("/openapi.json", handlers.ApiV1Handler.HandleOpenAPISpec)
+
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.WithAPIAuth(logger, tokenService, apiKeyService))
+			r.Use(middleware.RequireActiveAPIUser(logger, userRepo))
+
+			r.With(middleware.RequireScope(domain.ScopeGenerate)).Post("/generations", handlers.ApiV1Handler.HandleGenerationCreate)
+
+			r.Group(func(r chi.Router) {
+				r.Use(middleware.RequireScope(domain.ScopeGalleryRead))
+				r.Get("/prompts", handlers.ApiV1Handler.HandlePromptList)
+				r.Get("/prompts/{id}", handlers.ApiV1Handler.HandlePromptGet)
+				r.Get("/images", handlers.ApiV1Handler.HandleImageList)
+				r.Get("/images/{id}/content", handlers.ApiV1Handler.HandleImageContent)
+			})
+
+			r.Group(func(r chi.Router) {
+				r.Use(middleware.RequireScope(domain.ScopeGalleryWrite))
+				r.Delete("/images/failed", handlers.ApiV1Handler.HandleFailedImagesDelete)
+				r.Delete("/images/{id}", handlers.ApiV1Handler.HandleImageDelete)
+			})
+
+			r.With(middleware.RequireScope(domain.ScopeWalletRead)).Get("/wallet", handlers.ApiV1Handler.HandleWalletGet)
+		})
+	})
+
 	r.Post("/auth/login/google/callback", handlers.AuthHandler.HandleExternalAuth)
 
 	r.Route("/auth", func(r chi.Router) {
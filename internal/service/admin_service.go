@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/CP-Payne/wonderpicai/internal/context/requestlog"
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/CP-Payne/wonderpicai/internal/port"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// stuckPromptLimit caps the stuck prompts listed in the admin area
+const stuckPromptLimit = 100
+
+// AdminService backs the admin console. Every change is recorded in the audit log.
+type AdminService interface {
+	SearchUsers(ctx context.Context, query string, page domain.Page) ([]domain.User, int64, error)
+	// User returns the user with their wallet
+	User(ctx context.Context, userID uuid.UUID) (*domain.User, error)
+	CreditHistory(ctx context.Context, userID uuid.UUID, page domain.Page) ([]domain.CreditTransaction, int64, error)
+	SetRole(ctx context.Context, actor AuditActor, userID uuid.UUID, role domain.Role) error
+	// SetDisabled disables or enables an account. A reason is required to disable one.
+	SetDisabled(ctx context.Context, actor AuditActor, userID uuid.UUID, disabled bool, reason string) error
+	// AdjustCredits grants a positive amount or revokes a negative amount of credits
+	AdjustCredits(ctx context.Context, actor AuditActor, userID uuid.UUID, amount int, reason string) error
+	// StuckPrompts lists pending prompts that made no progress for the configured time
+	StuckPrompts(ctx context.Context) ([]domain.GenerationJob, error)
+	// StuckPromptAfter is how long a prompt must go without progress to count as stuck
+	StuckPromptAfter() time.Duration
+	RetryPrompt(ctx context.Context, actor AuditActor, promptID uuid.UUID) error
+	// BootstrapAdmins gives the admin role to the configured users
+	BootstrapAdmins(ctx context.Context, userIDs []uuid.UUID) error
+}
+
+type adminService struct {
+	logger           *zap.Logger
+	userRepo         port.UserRepository
+	walletRepo       port.WalletRepository
+	jobRepo          port.GenerationJobRepository
+	auditService     AuditService
+	stuckPromptAfter time.Duration
+}
+
+func NewAdminService(logger *zap.Logger, userRepo port.UserRepository, walletRepo port.WalletRepository, jobRepo port.GenerationJobRepository, auditService AuditService, stuckPromptAfter time.Duration) AdminService {
+	return &adminService{
+		logger:           logger.With(zap.String("component", "AdminService")),
+		userRepo:         userRepo,
+		walletRepo:       walletRepo,
+		jobRepo:          jobRepo,
+		auditService:     auditService,
+		stuckPromptAfter: stuckPromptAfter,
+	}
+}
+
+func (s *adminService) SearchUsers(ctx context.Context, query string, page domain.Page) ([]domain.User, int64, error) {
+	return s.userRepo.Search(ctx, query, page)
+}
+
+func (s *adminService) User(ctx context.Context, userID uuid.UUID) (*domain.User, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	wallet, err := s.walletRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load wallet: %w", err)
+	}
+	user.Wallet = *wallet
+
+	return user, nil
+}
+
+func (s *adminService) CreditHistory(ctx context.Context, userID uuid.UUID, page domain.Page) ([]domain.CreditTransaction, int64, error) {
+	return s.walletRepo.ListTransactions(ctx, userID, page)
+}
+
+func (s *adminService) SetRole(ctx context.Context, actor AuditActor, userID uuid.UUID, role domain.Role) error {
+	if !role.Valid() {
+		return domain.ErrInvalidRole
+	}
+	// Keeps admins from locking themselves out
+	if userID == actor.UserID {
+		return domain.ErrOwnAccount
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if user.Role == role {
+		return nil
+	}
+
+	if err := s.userRepo.SetRole(ctx, userID, role); err != nil {
+		return err
+	}
+
+	s.auditService.Record(ctx, actor, domain.AuditUserRoleChanged, "user", userID.String(), map[string]any{
+		"from": user.Role,
+		"to":   role,
+	})
+	return nil
+}
+
+func (s *adminService) SetDisabled(ctx context.Context, actor AuditActor, userID uuid.UUID, disabled bool, reason string) error {
+	if userID == actor.UserID {
+		return domain.ErrOwnAccount
+	}
+
+	reason = strings.TrimSpace(reason)
+	action := domain.AuditUserEnabled
+	var disabledAt *time.Time
+	if disabled {
+		if reason == "" {
+			return domain.ErrReasonRequired
+		}
+		now := time.Now()
+		disabledAt = &now
+		action = domain.AuditUserDisabled
+	} else {
+		reason = ""
+	}
+
+	if err := s.userRepo.SetDisabled(ctx, userID, disabledAt, reason); err != nil {
+		return err
+	}
+
+	details := map[string]any{}
+	if disabled {
+		details["reason"] = reason
+	}
+	s.auditService.Record(ctx, actor, action, "user", userID.String(), details)
+	return nil
+}
+
+func (s *adminService) AdjustCredits(ctx context.Context, actor AuditActor, userID uuid.UUID, amount int, reason string) error {
+	reason = strings.TrimSpace(reason)
+	if amount == 0 {
+		return domain.ErrInvalidCreditAmount
+	}
+	if reason == "" {
+		return domain.ErrReasonRequired
+	}
+
+	actorID := actor.UserID
+	change := port.CreditChange{
+		Kind:    domain.CreditAdminGrant,
+		Reason:  reason,
+		ActorID: &actorID,
+	}
+
+	action := domain.AuditCreditsGranted
+	var err error
+	if amount > 0 {
+		err = s.walletRepo.AddCredits(ctx, userID, amount, change)
+	} else {
+		change.Kind = domain.CreditAdminRevoke
+		action = domain.AuditCreditsRevoked
+		err = s.walletRepo.SubtractCredits(ctx, userID, -amount, change)
+	}
+	if err != nil {
+		return err
+	}
+
+	s.auditService.Record(ctx, actor, action, "user", userID.String(), map[string]any{
+		"amount": amount,
+		"reason": reason,
+	})
+	return nil
+}
+
+func (s *adminService) StuckPrompts(ctx context.Context) ([]domain.GenerationJob, error) {
+	return s.jobRepo.ListStuck(ctx, time.Now().Add(-s.stuckPromptAfter), stuckPromptLimit)
+}
+
+func (s *adminService) StuckPromptAfter() time.Duration {
+	return s.stuckPromptAfter
+}
+
+func (s *adminService) RetryPrompt(ctx context.Context, actor AuditActor, promptID uuid.UUID) error {
+	job, err := s.jobRepo.Requeue(ctx, promptID, "retried from the admin area")
+	if err != nil {
+		return err
+	}
+
+	s.auditService.Record(ctx, actor, domain.AuditPromptRetried, "prompt", promptID.String(), map[string]any{
+		"userID": job.UserID,
+	})
+	return nil
+}
+
+func (s *adminService) BootstrapAdmins(ctx context.Context, userIDs []uuid.UUID) error {
+	logger := requestlog.Logger(ctx, s.logger)
+
+	promoted, err := s.userRepo.PromoteToAdmin(ctx, userIDs)
+	if err != nil {
+		return err
+	}
+	if promoted > 0 {
+		logger.Info("Promoted configured admins", zap.Int64("count", promoted))
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/CP-Payne/wonderpicai/internal/context/requestlog"
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/CP-Payne/wonderpicai/internal/port"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// lastUsedResolution limits how often a key's last use is written while it is in use
+const lastUsedResolution = time.Minute
+
+// APIKeyService manages personal API keys and authenticates API requests made with them
+type APIKeyService interface {
+	// Create stores a new key and returns it with the full key, which cannot be recovered later
+	Create(ctx context.Context, userID uuid.UUID, input APIKeyInput) (*domain.APIKey, string, error)
+	List(ctx context.Context, userID uuid.UUID) ([]domain.APIKey, error)
+	Revoke(ctx context.Context, userID uuid.UUID, id uuid.UUID) (*domain.APIKey, error)
+	// Authenticate returns the key for a full key from an Authorization header.
+	// Unknown, revoked and expired keys return domain.ErrInvalidAPIKey.
+	Authenticate(ctx context.Context, fullKey string) (*domain.APIKey, error)
+}
+
+type APIKeyInput struct {
+	Name   string
+	Scopes []domain.APIScope
+	// ExpiresAt is nil for keys that do not expire
+	ExpiresAt *time.Time
+}
+
+type apiKeyService struct {
+	logger       *zap.Logger
+	apiKeyRepo   port.APIKeyRepository
+	auditService AuditService
+}
+
+func NewAPIKeyService(logger *zap.Logger, apiKeyRepo port.APIKeyRepository, auditService AuditService) APIKeyService {
+	return &apiKeyService{
+		logger:       logger.With(zap.String("component", "APIKeyService")),
+		apiKeyRepo:   apiKeyRepo,
+		auditService: auditService,
+	}
+}
+
+func (s *apiKeyService) Create(ctx context.Context, userID uuid.UUID, input APIKeyInput) (*domain.APIKey, string, error) {
+	logger := requestlog.Logger(ctx, s.logger)
+
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return nil, "", fmt.Errorf("API key name is required: %w", domain.ErrInvalidAPIKey)
+	}
+	if len(input.Scopes) == 0 {
+		return nil, "", fmt.Errorf("at least one scope is required: %w", domain.ErrInvalidAPIScope)
+	}
+	for _, scope := range input.Scopes {
+		if !scope.Valid() {
+			return nil, "", fmt.Errorf("unknown scope %q: %w", scope, domain.ErrInvalidAPIScope)
+		}
+	}
+
+	prefix, fullKey, err := generateAPIKey()
+	if err != nil {
+		logger.Error("Failed to generate API key", zap.Error(err))
+		return nil, "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+
+	now := time.Now()
+	key := &domain.APIKey{
+		BaseModel: domain.BaseModel{
+			ID:        uuid.New(),
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		UserID:     userID,
+		Name:       name,
+		Prefix:     prefix,
+		SecretHash: hashAPIKey(fullKey),
+		Scopes:     domain.JoinScopes(input.Scopes),
+		ExpiresAt:  input.ExpiresAt,
+	}
+
+	if err := s.apiKeyRepo.Create(ctx, key); err != nil {
+		return nil, "", fmt.Errorf("failed to store API key: %w", err)
+	}
+
+	logger.Info("API key created", zap.String("userID", userID.String()), zap.String("prefix", prefix), zap.String("scopes", key.Scopes))
+	s.auditService.Record(ctx, AuditActor{UserID: userID}, domain.AuditAPIKeyCreated, "api_key", key.ID.String(), map[string]any{
+		"name":   key.Name,
+		"prefix": key.Prefix,
+		"scopes": key.Scopes,
+	})
+
+	return key, fullKey, nil
+}
+
+func (s *apiKeyService) List(ctx context.Context, userID uuid.UUID) ([]domain.APIKey, error) {
+	keys, err := s.apiKeyRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	return keys, nil
+}
+
+func (s *apiKeyService) Revoke(ctx context.Context, userID uuid.UUID, id uuid.UUID) (*domain.APIKey, error) {
+	logger := requestlog.Logger(ctx, s.logger)
+
+	key, err := s.apiKeyRepo.Revoke(ctx, userID, id, time.Now())
+	if err != nil {
+		if errors.Is(err, domain.ErrRecordNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to revoke API key: %w", err)
+	}
+
+	logger.Info("API key revoked", zap.String("userID", userID.String()), zap.String("prefix", key.Prefix))
+	s.auditService.Record(ctx, AuditActor{UserID: userID}, domain.AuditAPIKeyRevoked, "api_key", key.ID.String(), map[string]any{
+		"name":   key.Name,
+		"prefix": key.Prefix,
+	})
+	return key, nil
+}
+
+func (s *apiKeyService) Authenticate(ctx context.Context, fullKey string) (*domain.APIKey, error) {
+	logger := requestlog.Logger(ctx, s.logger)
+
+	prefix, ok := apiKeyPrefix(fullKey)
+	if !ok {
+		return nil, domain.ErrInvalidAPIKey
+	}
+
+	key, err := s.apiKeyRepo.GetByPrefix(ctx, prefix)
+	if err != nil {
+		if errors.Is(err, domain.ErrRecordNotFound) {
+			return nil, domain.ErrInvalidAPIKey
+		}
+		return nil, fmt.Errorf("failed to look up API key: %w", err)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashAPIKey(fullKey)), []byte(key.SecretHash)) != 1 {
+		return nil, domain.ErrInvalidAPIKey
+	}
+
+	now := time.Now()
+	if !key.Usable(now) {
+		return nil, domain.ErrInvalidAPIKey
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= lastUsedResolution {
+		if err := s.apiKeyRepo.TouchLastUsed(ctx, key.ID, now); err != nil {
+			logger.Warn("Failed to record API key use", zap.String("prefix", key.Prefix), zap.Error(err))
+		} else {
+			key.LastUsedAt = &now
+		}
+	}
+
+	return key, nil
+}
+
+// generateAPIKey returns a key of the form wp_<12 hex>_<secret>, the part before the secret is its prefix
+func generateAPIKey() (prefix string, fullKey string, err error) {
+	id := make([]byte, 6)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", err
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+
+	prefix = domain.APIKeyPrefix + hex.EncodeToString(id)
+	return prefix, prefix + "_" + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+func apiKeyPrefix(fullKey string) (string, bool) {
+	rest, ok := strings.CutPrefix(fullKey, domain.APIKeyPrefix)
+	if !ok {
+		return "", false
+	}
+	id, secret, ok := strings.Cut(rest, "_")
+	if !ok || id == "" || secret == "" {
+		return "", false
+	}
+	return domain.APIKeyPrefix + id, true
+}
+
+func hashAPIKey(fullKey string) string {
+	sum := sha256.Sum256([]byte(fullKey))
+	return hex.EncodeToString(sum[:])
+}
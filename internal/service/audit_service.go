@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/CP-Payne/wonderpicai/internal/context/auth"
+	"github.com/CP-Payne/wonderpicai/internal/context/client"
+	"github.com/CP-Payne/wonderpicai/internal/context/requestlog"
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/CP-Payne/wonderpicai/internal/port"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// auditVerifyBatch is the number of events read at a time while verifying the chain
+const auditVerifyBatch = 500
+
+// AuditActor is who took an audited action. A nil UserID stands for an anonymous visitor
+// or the system.
+type AuditActor struct {
+	UserID uuid.UUID
+	// Email is looked up when it is empty
+	Email string
+}
+
+// AuditActorFromContext returns the signed in user of the current request as the actor
+func AuditActorFromContext(ctx context.Context) AuditActor {
+	userID, err := auth.UserID(ctx)
+	if err != nil {
+		return AuditActor{}
+	}
+	return AuditActor{UserID: userID}
+}
+
+// AuditVerification is the result of checking the audit chain
+type AuditVerification struct {
+	// Checked is the number of events that were checked
+	Checked int64
+	// BrokenAt is the sequence number of the first event that does not fit the chain, 0 if
+	// the chain is intact
+	BrokenAt int64
+	Problem  string
+}
+
+func (v AuditVerification) Intact() bool {
+	return v.BrokenAt == 0
+}
+
+// AuditService keeps the append-only audit log of security and money relevant actions
+type AuditService interface {
+	// Record appends an event to the audit log. The client address and user agent are taken
+	// from ctx. The audited change has already happened when Record is called, so failures
+	// are logged rather than returned.
+	Record(ctx context.Context, actor AuditActor, action domain.AuditAction, targetType, targetID string, metadata map[string]any)
+	List(ctx context.Context, filter port.AuditFilter, page domain.Page) ([]domain.AuditEvent, int64, error)
+	// Verify walks the whole chain and reports the first event that was changed, removed or
+	// inserted out of order
+	Verify(ctx context.Context) (AuditVerification, error)
+}
+
+type auditService struct {
+	logger    *zap.Logger
+	auditRepo port.AuditRepository
+	userRepo  port.UserRepository
+}
+
+func NewAuditService(logger *zap.Logger, auditRepo port.AuditRepository, userRepo port.UserRepository) AuditService {
+	return &auditService{
+		logger:    logger.With(zap.String("component", "AuditService")),
+		auditRepo: auditRepo,
+		userRepo:  userRepo,
+	}
+}
+
+func (s *auditService) Record(ctx context.Context, actor AuditActor, action domain.AuditAction, targetType, targetID string, metadata map[string]any) {
+	logger := requestlog.Logger(ctx, s.logger)
+
+	info := client.FromContext(ctx)
+	event := &domain.AuditEvent{
+		ActorEmail: actor.Email,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		IP:         info.IP,
+		UserAgent:  info.UserAgent,
+	}
+
+	if actor.UserID != uuid.Nil {
+		actorID := actor.UserID
+		event.ActorID = &actorID
+
+		// The email is copied so the log stays readable if the actor's account changes
+		if event.ActorEmail == "" {
+			if user, err := s.userRepo.GetByID(actor.UserID); err == nil {
+				event.ActorEmail = user.Email
+			} else {
+				logger.Warn("Failed to look up audit actor", zap.String("actorID", actor.UserID.String()), zap.Error(err))
+			}
+		}
+	}
+
+	if len(metadata) > 0 {
+		raw, err := json.Marshal(metadata)
+		if err != nil {
+			logger.Error("Failed to encode audit metadata", zap.String("action", string(action)), zap.Error(err))
+		} else {
+			event.Metadata = string(raw)
+		}
+	}
+
+	// The request may be cancelled once the response is written, the event must still be stored
+	if err := s.auditRepo.Append(context.WithoutCancel(ctx), event); err != nil {
+		logger.Error("Failed to record audit event",
+			zap.String("action", string(action)),
+			zap.String("targetType", targetType),
+			zap.String("targetID", targetID),
+			zap.Error(err))
+	}
+}
+
+func (s *auditService) List(ctx context.Context, filter port.AuditFilter, page domain.Page) ([]domain.AuditEvent, int64, error) {
+	return s.auditRepo.List(ctx, filter, page)
+}
+
+func (s *auditService) Verify(ctx context.Context) (AuditVerification, error) {
+	logger := requestlog.Logger(ctx, s.logger)
+
+	var result AuditVerification
+	var prev domain.AuditEvent
+
+	for {
+		events, err := s.auditRepo.ListAfter(ctx, prev.Sequence, auditVerifyBatch)
+		if err != nil {
+			return result, fmt.Errorf("failed to read audit chain: %w", err)
+		}
+
+		for i := range events {
+			event := &events[i]
+			switch {
+			case event.Sequence != prev.Sequence+1:
+				result.BrokenAt = prev.Sequence + 1
+				result.Problem = fmt.Sprintf("event %d is missing", prev.Sequence+1)
+			case event.PrevHash != prev.Hash:
+				result.BrokenAt = event.Sequence
+				result.Problem = "the previous hash does not match the event before it"
+			case event.Hash != event.ComputeHash():
+				result.BrokenAt = event.Sequence
+				result.Problem = "the content does not match its hash"
+			}
+			if !result.Intact() {
+				logger.Warn("Audit chain is broken",
+					zap.Int64("sequence", result.BrokenAt),
+					zap.String("problem", result.Problem))
+				return result, nil
+			}
+
+			result.Checked++
+			prev = *event
+		}
+
+		if len(events) < auditVerifyBatch {
+			return result, nil
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/CP-Payne/wonderpicai/internal/port"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// chainAuditRepo serves a fixed audit chain
+type chainAuditRepo struct {
+	port.AuditRepository
+	events []domain.AuditEvent
+}
+
+func (r *chainAuditRepo) ListAfter(ctx context.Context, sequence int64, limit int) ([]domain.AuditEvent, error) {
+	var events []domain.AuditEvent
+	for _, event := range r.events {
+		if event.Sequence > sequence && len(events) < limit {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// auditChain returns n correctly chained events
+func auditChain(n int) []domain.AuditEvent {
+	events := make([]domain.AuditEvent, n)
+	prevHash := ""
+	for i := range events {
+		events[i] = domain.AuditEvent{
+			ID:         uuid.New(),
+			Sequence:   int64(i + 1),
+			CreatedAt:  time.Date(2026, 1, 1, 0, i, 0, 0, time.UTC),
+			Action:     domain.AuditLogin,
+			TargetType: "user",
+			TargetID:   fmt.Sprint(i),
+			PrevHash:   prevHash,
+		}
+		events[i].Hash = events[i].ComputeHash()
+		prevHash = events[i].Hash
+	}
+	return events
+}
+
+func TestAuditServiceVerify(t *testing.T) {
+	tests := []struct {
+		name        string
+		size        int
+		tamper      func(events []domain.AuditEvent) []domain.AuditEvent
+		wantChecked int64
+		wantBroken  int64
+	}{
+		{name: "empty chain", size: 0},
+		{name: "intact chain", size: 5, wantChecked: 5},
+		{name: "intact chain across batches", size: auditVerifyBatch + 1, wantChecked: auditVerifyBatch + 1},
+		{
+			name: "changed content",
+			size: 5,
+			tamper: func(events []domain.AuditEvent) []domain.AuditEvent {
+				events[2].Metadata = `{"credits":1000}`
+				return events
+			},
+			wantChecked: 2,
+			wantBroken:  3,
+		},
+		{
+			name: "rehashed event",
+			size: 5,
+			tamper: func(events []domain.AuditEvent) []domain.AuditEvent {
+				events[2].Metadata = `{"credits":1000}`
+				events[2].Hash = events[2].ComputeHash()
+				return events
+			},
+			wantChecked: 3,
+			wantBroken:  4,
+		},
+		{
+			name: "removed event",
+			size: 5,
+			tamper: func(events []domain.AuditEvent) []domain.AuditEvent {
+				return append(events[:1], events[2:]...)
+			},
+			wantChecked: 1,
+			wantBroken:  2,
+		},
+		{
+			name: "first event not at the start of the chain",
+			size: 2,
+			tamper: func(events []domain.AuditEvent) []domain.AuditEvent {
+				events[0].PrevHash = events[1].Hash
+				events[0].Hash = events[0].ComputeHash()
+				return events
+			},
+			wantBroken: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := auditChain(tt.size)
+			if tt.tamper != nil {
+				events = tt.tamper(events)
+			}
+			svc := NewAuditService(zap.NewNop(), &chainAuditRepo{events: events}, nil)
+
+			result, err := svc.Verify(context.Background())
+			if err != nil {
+				t.Fatalf("Verify() unexpected error: %v", err)
+			}
+			if result.Checked != tt.wantChecked {
+				t.Errorf("Verify() checked %d events, want %d", result.Checked, tt.wantChecked)
+			}
+			if result.BrokenAt != tt.wantBroken {
+				t.Errorf("Verify() broken at %d (%s), want %d", result.BrokenAt, result.Problem, tt.wantBroken)
+			}
+			if result.Intact() != (tt.wantBroken == 0) {
+				t.Errorf("Intact() = %v, want %v", result.Intact(), tt.wantBroken == 0)
+			}
+		})
+	}
+}
@@ -1,12 +1,14 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/CP-Payne/wonderpicai/internal/config"
+	"github.com/CP-Payne/wonderpicai/internal/context/requestlog"
 	"github.com/CP-Payne/wonderpicai/internal/domain"
 	"github.com/CP-Payne/wonderpicai/internal/port"
 	"github.com/golang-jwt/jwt/v5"
@@ -15,8 +17,8 @@ import (
 )
 
 type AuthService interface {
-	Register(username, email, password string) (*domain.User, string, error)
-	Login(email, password string) (*domain.User, string, error)
+	Register(ctx context.Context, username, email, password string) (*domain.User, string, error)
+	Login(ctx context.Context, email, password string) (*domain.User, string, error)
 	HandleExternalAuthCallback(r *http.Request) (*domain.User, string, error)
 }
 
@@ -25,21 +27,25 @@ type authServiceImpl struct {
 	userRepo     port.UserRepository
 	tokenService port.TokenService
 	externalAuth port.ExternalAuthService
+	auditService AuditService
 }
 
-func NewAuthService(userRepo port.UserRepository, tokenService port.TokenService, logger *zap.Logger, externalAuth port.ExternalAuthService) AuthService {
+func NewAuthService(userRepo port.UserRepository, tokenService port.TokenService, logger *zap.Logger, externalAuth port.ExternalAuthService, auditService AuditService) AuthService {
 	return &authServiceImpl{
 		userRepo:     userRepo,
 		logger:       logger.With(zap.String("component", "AuthService")),
 		tokenService: tokenService,
 		externalAuth: externalAuth,
+		auditService: auditService,
 	}
 }
 
-func (s *authServiceImpl) Register(username, email, password string) (*domain.User, string, error) {
+func (s *authServiceImpl) Register(ctx context.Context, username, email, password string) (*domain.User, string, error) {
+	logger := requestlog.Logger(ctx, s.logger)
+
 	existingUser, err := s.userRepo.GetByEmail(email)
 	if err != nil && !errors.Is(err, domain.ErrUserNotFound) {
-		s.logger.Error("Error checking existing user by email", zap.Error(err))
+		logger.Error("Error checking existing user by email", zap.Error(err))
 		return nil, "", fmt.Errorf("registration process failed: %w", err)
 	}
 
@@ -49,7 +55,7 @@ func (s *authServiceImpl) Register(username, email, password string) (*domain.Us
 
 	hashedPassword, err := hashPassword(password)
 	if err != nil {
-		s.logger.Error("Password hashing failed", zap.Error(err))
+		logger.Error("Password hashing failed", zap.Error(err))
 		return nil, "", fmt.Errorf("user creation failed: %w", err)
 	}
 
@@ -66,10 +72,10 @@ func (s *authServiceImpl) Register(username, email, password string) (*domain.Us
 
 	if err := s.userRepo.Create(userToCreate); err != nil {
 		if errors.Is(err, domain.ErrEmailAlreadyExists) || errors.Is(err, domain.ErrDuplicateEntry) {
-			s.logger.Warn("User creation conflict", zap.Error(err))
+			logger.Warn("User creation conflict", zap.Error(err))
 			return nil, "", err
 		}
-		s.logger.Error("Failed to create user via repository", zap.Error(err))
+		logger.Error("Failed to create user via repository", zap.Error(err))
 
 		return nil, "", fmt.Errorf("failed to complete registration due to an internal issue: %w", err)
 	}
@@ -77,7 +83,10 @@ func (s *authServiceImpl) Register(username, email, password string) (*domain.Us
 	// Don't return password, even if it is hashed
 	userToCreate.Password = ""
 
-	s.logger.Info("User creation successfull", zap.String("email", userToCreate.Email), zap.String("UserID", userToCreate.ID.String()))
+	logger.Info("User creation successfull", zap.String("email", userToCreate.Email), zap.String("UserID", userToCreate.ID.String()))
+	s.auditService.Record(ctx, AuditActor{UserID: userToCreate.ID, Email: userToCreate.Email}, domain.AuditSignup, "user", userToCreate.ID.String(), map[string]any{
+		"method": "password",
+	})
 
 	claims := jwt.MapClaims{
 		"sub": userToCreate.ID,
@@ -91,7 +100,7 @@ func (s *authServiceImpl) Register(username, email, password string) (*domain.Us
 
 	token, err := s.tokenService.GenerateToken(claims)
 	if err != nil {
-		s.logger.Error("Failed to create JWT token after successfull user registration",
+		logger.Error("Failed to create JWT token after successfull user registration",
 			zap.String("email", userToCreate.Email),
 			zap.String("UserID", userToCreate.ID.String()),
 			zap.Error(err),
@@ -102,19 +111,29 @@ func (s *authServiceImpl) Register(username, email, password string) (*domain.Us
 	return userToCreate, token, nil
 }
 
-func (s *authServiceImpl) Login(email, password string) (*domain.User, string, error) {
+func (s *authServiceImpl) Login(ctx context.Context, email, password string) (*domain.User, string, error) {
+	logger := requestlog.Logger(ctx, s.logger)
+
 	user, err := s.userRepo.GetByEmail(email)
 	if err != nil {
 		if errors.Is(err, domain.ErrUserNotFound) {
+			s.recordLoginFailure(ctx, email, nil, "unknown email")
 			return nil, "", domain.ErrInvalidCredentials
 		}
 		return nil, "", fmt.Errorf("failed authenticating user: %w", err)
 	}
 
 	if ok := checkPasswordHash(password, user.Password); !ok {
+		s.recordLoginFailure(ctx, email, user, "wrong password")
 		return nil, "", domain.ErrInvalidCredentials
 	}
 
+	if user.Disabled() {
+		logger.Warn("Disabled user tried to log in", zap.String("userID", user.ID.String()))
+		s.recordLoginFailure(ctx, email, user, "account disabled")
+		return nil, "", domain.ErrAccountDisabled
+	}
+
 	claims := jwt.MapClaims{
 		"sub": user.ID,
 		"exp": time.Now().Add(time.Duration(config.Cfg.JWT.ExpiryMinutes) * time.Minute).Unix(),
@@ -127,7 +146,7 @@ func (s *authServiceImpl) Login(email, password string) (*domain.User, string, e
 
 	token, err := s.tokenService.GenerateToken(claims)
 	if err != nil {
-		s.logger.Error("Failed to create JWT token after successfull user authentication",
+		logger.Error("Failed to create JWT token after successfull user authentication",
 			zap.String("email", user.Email),
 			zap.String("UserID", user.ID.String()),
 			zap.Error(err),
@@ -135,9 +154,22 @@ func (s *authServiceImpl) Login(email, password string) (*domain.User, string, e
 		return user, "", fmt.Errorf("failed to generate jwt token via local token service: %w", err)
 	}
 
+	s.auditService.Record(ctx, AuditActor{UserID: user.ID, Email: user.Email}, domain.AuditLogin, "user", user.ID.String(), nil)
 	return user, token, nil
 }
 
+// recordLoginFailure audits a failed login. user is nil when no account has the email.
+func (s *authServiceImpl) recordLoginFailure(ctx context.Context, email string, user *domain.User, reason string) {
+	targetID := ""
+	if user != nil {
+		targetID = user.ID.String()
+	}
+	s.auditService.Record(ctx, AuditActor{}, domain.AuditLoginFailed, "user", targetID, map[string]any{
+		"email":  email,
+		"reason": reason,
+	})
+}
+
 // TODO: Implement Email verification
 
 // --- Developer Note ---
@@ -147,6 +179,8 @@ func (s *authServiceImpl) Login(email, password string) (*domain.User, string, e
 // As such, it is important that email verification is implemented to ensure that the user that signsup
 // owns the email.
 func (s *authServiceImpl) HandleExternalAuthCallback(r *http.Request) (*domain.User, string, error) {
+	logger := requestlog.Logger(r.Context(), s.logger)
+
 	externalUser, err := s.externalAuth.HandleCallback(r)
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to authenticate user through external provider: %w", err)
@@ -170,11 +204,18 @@ func (s *authServiceImpl) HandleExternalAuthCallback(r *http.Request) (*domain.U
 		}
 
 		if err := s.userRepo.Create(user); err != nil {
-			s.logger.Error("Failed to create user via repository", zap.Error(err))
+			logger.Error("Failed to create user via repository", zap.Error(err))
 			return nil, "", fmt.Errorf("failed to create user using repository: %w", err)
 		}
+		s.auditService.Record(r.Context(), AuditActor{UserID: user.ID, Email: user.Email}, domain.AuditSignup, "user", user.ID.String(), map[string]any{
+			"method": "google",
+		})
 	} else if err != nil {
 		return nil, "", fmt.Errorf("failed authenticating user: %w", err)
+	} else if user.Disabled() {
+		logger.Warn("Disabled user tried to log in", zap.String("userID", user.ID.String()))
+		s.recordLoginFailure(r.Context(), user.Email, user, "account disabled")
+		return nil, "", domain.ErrAccountDisabled
 	}
 
 	claims := jwt.MapClaims{
@@ -189,7 +230,7 @@ func (s *authServiceImpl) HandleExternalAuthCallback(r *http.Request) (*domain.U
 
 	token, err := s.tokenService.GenerateToken(claims)
 	if err != nil {
-		s.logger.Error("Failed to create JWT token after successfull user authentication",
+		logger.Error("Failed to create JWT token after successfull user authentication",
 			zap.String("email", user.Email),
 			zap.String("UserID", user.ID.String()),
 			zap.Error(err),
@@ -197,6 +238,7 @@ func (s *authServiceImpl) HandleExternalAuthCallback(r *http.Request) (*domain.U
 		return user, "", fmt.Errorf("failed to generate jwt token via local token service: %w", err)
 	}
 
+	s.auditService.Record(r.Context(), AuditActor{UserID: user.ID, Email: user.Email}, domain.AuditGoogleLogin, "user", user.ID.String(), nil)
 	return user, token, nil
 
 }
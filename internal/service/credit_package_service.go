@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/CP-Payne/wonderpicai/internal/context/requestlog"
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/CP-Payne/wonderpicai/internal/port"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// CreditPackageService manages the credit packages offered for sale
+type CreditPackageService interface {
+	ListAll(ctx context.Context) ([]domain.CreditPackage, error)
+	Create(ctx context.Context, input CreditPackageInput) (*domain.CreditPackage, error)
+	Update(ctx context.Context, id uuid.UUID, input CreditPackageInput) (*domain.CreditPackage, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+	// SeedDefaults creates the default packages on a fresh database
+	SeedDefaults(ctx context.Context) error
+}
+
+type CreditPackageInput struct {
+	Name       string
+	Credits    int
+	PriceMinor int64
+	Currency   string
+	Active     bool
+	SortOrder  int
+	Badge      string
+	// Prices in other currencies than Currency, by currency code
+	Prices map[string]int64
+}
+
+// defaultPackages are the packages that used to be hardcoded, priced in USD
+var defaultPackages = []CreditPackageInput{
+	{Name: "Starter", Credits: 100, PriceMinor: 500, Currency: "usd", Active: true, SortOrder: 10},
+	{Name: "Creator", Credits: 250, PriceMinor: 1000, Currency: "usd", Active: true, SortOrder: 20},
+	{Name: "Pro", Credits: 700, PriceMinor: 2500, Currency: "usd", Active: true, SortOrder: 30, Badge: "Popular"},
+	{Name: "Studio", Credits: 1500, PriceMinor: 5000, Currency: "usd", Active: true, SortOrder: 40, Badge: "Best value"},
+}
+
+type creditPackageService struct {
+	logger       *zap.Logger
+	packageRepo  port.CreditPackageRepository
+	auditService AuditService
+}
+
+func NewCreditPackageService(logger *zap.Logger, packageRepo port.CreditPackageRepository, auditService AuditService) CreditPackageService {
+	return &creditPackageService{
+		logger:       logger.With(zap.String("component", "CreditPackageService")),
+		packageRepo:  packageRepo,
+		auditService: auditService,
+	}
+}
+
+func (s *creditPackageService) ListAll(ctx context.Context) ([]domain.CreditPackage, error) {
+	pkgs, err := s.packageRepo.ListAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list credit packages: %w", err)
+	}
+	return pkgs, nil
+}
+
+func (s *creditPackageService) Create(ctx context.Context, input CreditPackageInput) (*domain.CreditPackage, error) {
+	logger := requestlog.Logger(ctx, s.logger)
+
+	pkg := &domain.CreditPackage{
+		BaseModel: domain.BaseModel{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		},
+	}
+	input.apply(pkg)
+
+	if err := s.packageRepo.Create(ctx, pkg); err != nil {
+		return nil, fmt.Errorf("failed to create credit package: %w", err)
+	}
+
+	logger.Info("Credit package created",
+		zap.String("packageID", pkg.ID.String()),
+		zap.String("name", pkg.Name),
+		zap.Int("credits", pkg.Credits),
+		zap.Int64("priceMinor", pkg.PriceMinor),
+		zap.String("currency", pkg.Currency),
+	)
+	s.auditService.Record(ctx, AuditActorFromContext(ctx), domain.AuditPackageCreated, "package", pkg.ID.String(), input.auditMetadata())
+	return pkg, nil
+}
+
+func (s *creditPackageService) Update(ctx context.Context, id uuid.UUID, input CreditPackageInput) (*domain.CreditPackage, error) {
+	logger := requestlog.Logger(ctx, s.logger)
+
+	pkg := &domain.CreditPackage{
+		BaseModel: domain.BaseModel{
+			ID:        id,
+			UpdatedAt: time.Now(),
+		},
+	}
+	input.apply(pkg)
+
+	if err := s.packageRepo.Update(ctx, pkg); err != nil {
+		if errors.Is(err, domain.ErrRecordNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to update credit package: %w", err)
+	}
+
+	logger.Info("Credit package updated",
+		zap.String("packageID", id.String()),
+		zap.Int("credits", pkg.Credits),
+		zap.Int64("priceMinor", pkg.PriceMinor),
+		zap.String("currency", pkg.Currency),
+		zap.Bool("active", pkg.Active),
+	)
+	s.auditService.Record(ctx, AuditActorFromContext(ctx), domain.AuditPackageUpdated, "package", id.String(), input.auditMetadata())
+	return s.packageRepo.GetByID(ctx, id)
+}
+
+func (s *creditPackageService) Delete(ctx context.Context, id uuid.UUID) error {
+	logger := requestlog.Logger(ctx, s.logger)
+
+	if err := s.packageRepo.Delete(ctx, id); err != nil {
+		if errors.Is(err, domain.ErrRecordNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to delete credit package: %w", err)
+	}
+
+	logger.Info("Credit package deleted", zap.String("packageID", id.String()))
+	s.auditService.Record(ctx, AuditActorFromContext(ctx), domain.AuditPackageDeleted, "package", id.String(), nil)
+	return nil
+}
+
+func (s *creditPackageService) SeedDefaults(ctx context.Context) error {
+	logger := requestlog.Logger(ctx, s.logger)
+
+	pkgs := make([]domain.CreditPackage, 0, len(defaultPackages))
+	for _, input := range defaultPackages {
+		pkg := domain.CreditPackage{
+			BaseModel: domain.BaseModel{
+				ID:        uuid.New(),
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+			},
+		}
+		input.apply(&pkg)
+		pkgs = append(pkgs, pkg)
+	}
+
+	created, err := s.packageRepo.CreateIfEmpty(ctx, pkgs)
+	if err != nil {
+		return fmt.Errorf("failed to seed default credit packages: %w", err)
+	}
+	if created {
+		logger.Info("Default credit packages created", zap.Int("count", len(pkgs)))
+	}
+	return nil
+}
+
+func (in CreditPackageInput) apply(pkg *domain.CreditPackage) {
+	pkg.Name = strings.TrimSpace(in.Name)
+	pkg.Credits = in.Credits
+	pkg.PriceMinor = in.PriceMinor
+	pkg.Currency = strings.ToLower(strings.TrimSpace(in.Currency))
+	pkg.Active = in.Active
+	pkg.SortOrder = in.SortOrder
+	pkg.Badge = strings.TrimSpace(in.Badge)
+
+	currencies := make([]string, 0, len(in.Prices))
+	for currency := range in.Prices {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	pkg.Prices = make([]domain.CreditPackagePrice, 0, len(currencies))
+	for _, currency := range currencies {
+		pkg.Prices = append(pkg.Prices, domain.CreditPackagePrice{
+			BaseModel: domain.BaseModel{
+				ID:        uuid.New(),
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+			},
+			PackageID:  pkg.ID,
+			Currency:   strings.ToLower(strings.TrimSpace(currency)),
+			PriceMinor: in.Prices[currency],
+		})
+	}
+}
+
+func (input CreditPackageInput) auditMetadata() map[string]any {
+	return map[string]any{
+		"name":       input.Name,
+		"credits":    input.Credits,
+		"priceMinor": input.PriceMinor,
+		"currency":   input.Currency,
+		"prices":     input.Prices,
+		"active":     input.Active,
+	}
+}
@@ -6,89 +6,100 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/CP-Payne/wonderpicai/internal/context/requestlog"
 	"github.com/CP-Payne/wonderpicai/internal/domain"
 	"github.com/CP-Payne/wonderpicai/internal/port"
+	"github.com/CP-Payne/wonderpicai/internal/tracing"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
-// TODO: Pass in context from handler
 type GenService interface {
 	GenerateImage(ctx context.Context, userID uuid.UUID, promptData *PromptData) (*domain.Prompt, error)
 	CalculateCost(ctx context.Context, promptData *PromptData) int
 	GetAllPrompts(ctx context.Context, userID uuid.UUID) ([]domain.Prompt, error)
-	UpdatePlaceholderImages(ctx context.Context, externalPromptID uuid.UUID, images [][]byte, desiredStatus domain.Status) (*domain.Prompt, error)
+	GetPrompt(ctx context.Context, userID uuid.UUID, promptID uuid.UUID) (*domain.Prompt, error)
+	ListPrompts(ctx context.Context, userID uuid.UUID, page domain.Page) ([]domain.Prompt, int64, error)
+	ListImages(ctx context.Context, userID uuid.UUID, page domain.Page) ([]domain.Image, int64, error)
+	UpdatePlaceholderImages(ctx context.Context, externalPromptID uuid.UUID, backend string, images [][]byte, desiredStatus domain.Status) (*domain.Prompt, error)
 	GetImageByID(ctx context.Context, userID uuid.UUID, imageID uuid.UUID) (image *domain.Image, err error)
 	DeleteImageByID(ctx context.Context, userID uuid.UUID, imageID uuid.UUID) error
 	DeleteFailedImages(ctx context.Context, userID uuid.UUID) error
 	ContainsFailedImages(ctx context.Context, userID uuid.UUID) (bool, error)
+	QueuePosition(ctx context.Context, userID uuid.UUID) (int, error)
+	ImageSizes(ctx context.Context) []domain.ImageSize
 }
 
 type PromptData struct {
 	Prompt     string
 	ImageCount int
+	// Size is a key of domain.ImageSizes
+	Size string
+	// Optional settings that affect the price
+	Model    string
+	Steps    int
+	Priority bool
 }
 
-const (
-	IMAGE_WIDTH     = 500
-	IMAGE_HEIGHT    = 500
-	GENERATION_COST = 2
-)
-
 type genService struct {
 	logger         *zap.Logger
 	imageGenClient port.ImageGeneration
 	walletService  WalletService
 	promptRepo     port.PromptRepository
 	imageRepo      port.ImageRepository
+	jobRepo        port.GenerationJobRepository
+	pricingService PricingService
+	jobMaxAttempts int
+	quota          domain.GenerationQuota
+	webhookService WebhookService
+	// lowCreditsThreshold emits credits.low when a generation takes the balance below it
+	lowCreditsThreshold int
+	auditService        AuditService
+	metrics             port.Metrics
 }
 
-func NewGenService(logger *zap.Logger, genClient port.ImageGeneration, promptRepo port.PromptRepository, imageRepo port.ImageRepository, walletService WalletService) GenService {
+func NewGenService(logger *zap.Logger, genClient port.ImageGeneration, promptRepo port.PromptRepository, imageRepo port.ImageRepository, jobRepo port.GenerationJobRepository, walletService WalletService, pricingService PricingService, jobMaxAttempts int, quota domain.GenerationQuota, webhookService WebhookService, lowCreditsThreshold int, auditService AuditService, metrics port.Metrics) GenService {
 	return &genService{
-		logger:         logger.With(zap.String("component", "GenService")),
-		imageGenClient: genClient,
-		promptRepo:     promptRepo,
-		imageRepo:      imageRepo,
-		walletService:  walletService,
+		logger:              logger.With(zap.String("component", "GenService")),
+		imageGenClient:      genClient,
+		promptRepo:          promptRepo,
+		imageRepo:           imageRepo,
+		jobRepo:             jobRepo,
+		walletService:       walletService,
+		pricingService:      pricingService,
+		jobMaxAttempts:      jobMaxAttempts,
+		quota:               quota,
+		webhookService:      webhookService,
+		lowCreditsThreshold: lowCreditsThreshold,
+		auditService:        auditService,
+		metrics:             metrics,
 	}
 }
 
-func (s *genService) GenerateImage(ctx context.Context, userID uuid.UUID, data *PromptData) (*domain.Prompt, error) {
-
-	clientReqData := port.ImageGenerationInput{
-		Prompt:     data.Prompt,
-		ImageCount: data.ImageCount,
-		Width:      IMAGE_WIDTH,
-		Height:     IMAGE_HEIGHT,
-	}
+func (s *genService) GenerateImage(ctx context.Context, userID uuid.UUID, data *PromptData) (created *domain.Prompt, err error) {
+	logger := requestlog.Logger(ctx, s.logger)
 
-	totalCost := s.CalculateCost(ctx, data)
+	ctx, span := tracer.Start(ctx, "GenService.GenerateImage", trace.WithAttributes(
+		attribute.String("user.id", userID.String()),
+		attribute.Int("prompt.image_count", data.ImageCount),
+		attribute.String("prompt.size", data.Size),
+	))
+	defer func() { endSpan(span, err) }()
 
-	err := s.walletService.DeductForImageGeneration(ctx, userID, totalCost)
-	if err != nil {
-		if errors.Is(err, domain.ErrInsufficientFunds) {
-			s.logger.Error("Failed to deduct credits for image generation", zap.String("userID", userID.String()), zap.Int("totalCost", totalCost), zap.Error(err))
-			return nil, err
-		}
-
-		s.logger.Error("Failed to deduct credits for image generation", zap.String("userID", userID.String()), zap.Int("totalCost", totalCost), zap.Error(err))
-		return nil, fmt.Errorf("failed to deduct credits for image generation: %w", err)
+	size, ok := domain.ImageSizeByKey(data.Size)
+	if !ok {
+		logger.Warn("Generation requested with unknown image size", zap.String("size", data.Size))
+		return nil, fmt.Errorf("unknown image size %q: %w", data.Size, domain.ErrInvalidGenerationInput)
 	}
 
-	externalPromptID, err := s.imageGenClient.GenerateImage(&clientReqData)
+	quote, err := s.pricingService.Quote(ctx, data.quoteInput())
 	if err != nil {
-		s.logger.Error("Failed to send image generation request", zap.Error(err))
-		s.logger.Info("Refunding credits", zap.Error(err))
-		refundErr := s.walletService.RefundCredits(ctx, userID, totalCost)
-		if refundErr != nil {
-			s.logger.Error("CRITICAL: Failed to refund credits", zap.String("userID", userID.String()), zap.Error(refundErr), zap.Int("totalCost", totalCost))
-			return nil, fmt.Errorf("failed refunding credits after failed generation request: %w", refundErr)
-		}
-
-		return nil, fmt.Errorf("failed to initiate image generation - credits refunded: %w", err)
+		logger.Warn("Failed to price generation request", zap.Error(err))
+		return nil, fmt.Errorf("failed to price generation request: %w", err)
 	}
-
-	s.logger.Debug("External Prompt Received", zap.String("ExternalPromptID", externalPromptID.String()))
+	totalCost := quote.Total
 
 	prompt := domain.Prompt{
 		BaseModel: domain.BaseModel{
@@ -96,62 +107,200 @@ func (s *genService) GenerateImage(ctx context.Context, userID uuid.UUID, data *
 			CreatedAt: time.Now(),
 			UpdatedAt: time.Now(),
 		},
-		UserID:           userID,
-		ExternalPromptID: externalPromptID,
-		Cost:             totalCost,
-		ImageCount:       data.ImageCount,
-		Width:            IMAGE_WIDTH,
-		Height:           IMAGE_HEIGHT,
-		Status:           domain.Pending,
-		LastChecked:      time.Now(),
+		UserID:       userID,
+		Text:         data.Prompt,
+		Cost:         totalCost,
+		PriceVersion: quote.Version,
+		ImageCount:   data.ImageCount,
+		Size:         size.Key,
+		Width:        size.Width,
+		Height:       size.Height,
+		Status:       domain.Pending,
+		LastChecked:  time.Now(),
+		// The generation worker continues this trace when it submits the prompt
+		TraceParent: tracing.TraceParent(ctx),
 	}
+	span.SetAttributes(attribute.String("prompt.id", prompt.ID.String()), attribute.Int("prompt.cost", totalCost))
 
-	promptCreated, err := s.promptRepo.Create(ctx, &prompt)
+	// The debit, prompt and job are stored together, a GenerationWorker submits the job to the backend
+	promptCreated, err := s.jobRepo.EnqueuePrompt(ctx, &prompt, s.jobMaxAttempts, s.quota)
 	if err != nil {
-		s.logger.Error("Prompt creation failed via repository", zap.Error(err))
+		if errors.Is(err, domain.ErrInsufficientFunds) {
+			s.metrics.GenerationSubmitted(port.OutcomeInsufficientFunds)
+			logger.Error("Failed to deduct credits for image generation", zap.String("userID", userID.String()), zap.Int("totalCost", totalCost), zap.Error(err))
+			return nil, err
+		}
+		if errors.Is(err, domain.ErrGenerationLimitReached) {
+			s.metrics.GenerationSubmitted(port.OutcomeLimitReached)
+			logger.Info("User reached generation limit", zap.String("userID", userID.String()), zap.Error(err))
+			return nil, err
+		}
+
+		s.metrics.GenerationSubmitted(port.OutcomeError)
+		logger.Error("Failed to enqueue prompt for image generation", zap.String("userID", userID.String()), zap.Int("totalCost", totalCost), zap.Error(err))
 		return nil, fmt.Errorf("failed to complete prompt image generation due to an internal issue: %w", err)
 	}
 
+	logger.Debug("Prompt queued for generation", zap.String("promptID", promptCreated.ID.String()))
+	s.metrics.GenerationSubmitted(port.OutcomeSuccess)
+	s.metrics.CreditsMoved(domain.CreditGeneration, totalCost)
+
+	s.emitLowCredits(ctx, userID, totalCost)
+
 	return promptCreated, nil
 
 }
 
 func (s *genService) CalculateCost(ctx context.Context, data *PromptData) int {
+	logger := requestlog.Logger(ctx, s.logger)
+
 	if data == nil {
 		return 0
 	}
 
-	return data.ImageCount * GENERATION_COST
+	quote, err := s.pricingService.Quote(ctx, data.quoteInput())
+	if err != nil {
+		logger.Debug("Failed to calculate cost", zap.Error(err))
+		return 0
+	}
+
+	return quote.Total
+}
+
+func (d *PromptData) quoteInput() domain.PriceQuoteInput {
+	return domain.PriceQuoteInput{
+		Size:       d.Size,
+		ImageCount: d.ImageCount,
+		Model:      d.Model,
+		Steps:      d.Steps,
+		Priority:   d.Priority,
+	}
+}
+
+func (s *genService) ImageSizes(ctx context.Context) []domain.ImageSize {
+	return domain.ImageSizes
 }
 
 func (s *genService) GetAllPrompts(ctx context.Context, userID uuid.UUID) ([]domain.Prompt, error) {
+	logger := requestlog.Logger(ctx, s.logger)
+
 	prompts, err := s.promptRepo.FindAllByUser(ctx, userID)
 	if err != nil {
-		s.logger.Error("Failed to retrieve user prompts from repository", zap.String("userID", userID.String()), zap.Error(err))
+		logger.Error("Failed to retrieve user prompts from repository", zap.String("userID", userID.String()), zap.Error(err))
 		return nil, fmt.Errorf("failed to retrieve user prompts from prompt repository: %w", err)
 	}
 
 	return prompts, nil
 }
 
-func (s *genService) UpdatePlaceholderImages(ctx context.Context, externalPromptID uuid.UUID, images [][]byte, desiredStatus domain.Status) (*domain.Prompt, error) {
+func (s *genService) GetPrompt(ctx context.Context, userID uuid.UUID, promptID uuid.UUID) (*domain.Prompt, error) {
+	logger := requestlog.Logger(ctx, s.logger)
 
-	prompt, err := s.promptRepo.UpdatePlaceholderImages(ctx, externalPromptID, images, desiredStatus)
+	prompt, err := s.promptRepo.FindByID(ctx, userID, promptID)
 	if err != nil {
-		s.logger.Error("Failed to update image placeholders", zap.String("ExternalPromptID", externalPromptID.String()), zap.Error(err))
+		if errors.Is(err, domain.ErrPromptNotFound) {
+			return nil, err
+		}
+		logger.Error("Failed to retrieve prompt from repository", zap.String("userID", userID.String()), zap.String("promptID", promptID.String()), zap.Error(err))
+		return nil, fmt.Errorf("failed to retrieve prompt: %w", err)
+	}
+
+	return prompt, nil
+}
+
+func (s *genService) ListPrompts(ctx context.Context, userID uuid.UUID, page domain.Page) ([]domain.Prompt, int64, error) {
+	logger := requestlog.Logger(ctx, s.logger)
+
+	prompts, total, err := s.promptRepo.ListByUser(ctx, userID, page)
+	if err != nil {
+		logger.Error("Failed to list user prompts from repository", zap.String("userID", userID.String()), zap.Error(err))
+		return nil, 0, fmt.Errorf("failed to list user prompts: %w", err)
+	}
+
+	return prompts, total, nil
+}
+
+func (s *genService) ListImages(ctx context.Context, userID uuid.UUID, page domain.Page) ([]domain.Image, int64, error) {
+	logger := requestlog.Logger(ctx, s.logger)
+
+	images, total, err := s.imageRepo.ListByUser(ctx, userID, page)
+	if err != nil {
+		logger.Error("Failed to list user images from repository", zap.String("userID", userID.String()), zap.Error(err))
+		return nil, 0, fmt.Errorf("failed to list user images: %w", err)
+	}
+
+	return images, total, nil
+}
+
+func (s *genService) UpdatePlaceholderImages(ctx context.Context, externalPromptID uuid.UUID, backend string, images [][]byte, desiredStatus domain.Status) (prompt *domain.Prompt, err error) {
+	logger := requestlog.Logger(ctx, s.logger)
+
+	ctx, span := tracer.Start(ctx, "GenService.UpdatePlaceholderImages", trace.WithAttributes(
+		attribute.String("prompt.external_id", externalPromptID.String()),
+		attribute.String("prompt.desired_status", desiredStatus.String()),
+	))
+	defer func() { endSpan(span, err) }()
+
+	prompt, err = s.promptRepo.UpdatePlaceholderImages(ctx, externalPromptID, backend, images, desiredStatus)
+	if err != nil {
+		logger.Error("Failed to update image placeholders", zap.String("ExternalPromptID", externalPromptID.String()), zap.Error(err))
 		return nil, fmt.Errorf("failed to update image placeholders using prompt repository: %w", err)
 	}
 
+	if observer, ok := s.imageGenClient.(port.ImageGenerationCompletionObserver); ok {
+		observer.PromptFinished(prompt.Backend)
+	}
+	s.metrics.GenerationFinished(prompt.Status, time.Since(prompt.CreatedAt))
+	span.SetAttributes(attribute.String("prompt.id", prompt.ID.String()), attribute.String("prompt.status", prompt.Status.String()))
+
+	event := domain.EventPromptCompleted
+	if prompt.Status == domain.Failed {
+		event = domain.EventPromptFailed
+	}
+	if err := s.webhookService.Emit(ctx, prompt.UserID, event, NewPromptEventData(prompt, false)); err != nil {
+		logger.Error("Failed to emit prompt webhook event", zap.String("promptID", prompt.ID.String()), zap.Error(err))
+	}
+
 	return prompt, nil
 }
 
+// emitLowCredits emits credits.low when spending cost took the balance below the threshold
+func (s *genService) emitLowCredits(ctx context.Context, userID uuid.UUID, cost int) {
+	logger := requestlog.Logger(ctx, s.logger)
+
+	if s.lowCreditsThreshold <= 0 {
+		return
+	}
+
+	wallet, err := s.walletService.GetWallet(ctx, userID)
+	if err != nil {
+		logger.Warn("Failed to check balance for low credits event", zap.String("userID", userID.String()), zap.Error(err))
+		return
+	}
+
+	balance := int(wallet.Credits)
+	if balance >= s.lowCreditsThreshold || balance+cost < s.lowCreditsThreshold {
+		return
+	}
+
+	err = s.webhookService.Emit(ctx, userID, domain.EventCreditsLow, CreditsLowEventData{
+		Balance:   wallet.Credits,
+		Threshold: s.lowCreditsThreshold,
+	})
+	if err != nil {
+		logger.Error("Failed to emit low credits webhook event", zap.String("userID", userID.String()), zap.Error(err))
+	}
+}
+
 func (s *genService) GetImageByID(ctx context.Context, userID uuid.UUID, imageID uuid.UUID) (image *domain.Image, err error) {
+	logger := requestlog.Logger(ctx, s.logger)
+
 	image, err = s.imageRepo.GetByID(ctx, userID, imageID)
 	if err != nil {
 		if errors.Is(err, domain.ErrImageNotFound) {
 			return nil, err
 		}
-		s.logger.Error("Repository failed to get image by ID",
+		logger.Error("Repository failed to get image by ID",
 			zap.String("imageID", imageID.String()),
 			zap.Error(err),
 		)
@@ -162,7 +311,9 @@ func (s *genService) GetImageByID(ctx context.Context, userID uuid.UUID, imageID
 }
 
 func (s *genService) DeleteImageByID(ctx context.Context, userID uuid.UUID, imageID uuid.UUID) error {
-	s.logger.Info("Attempting to delete image",
+	logger := requestlog.Logger(ctx, s.logger)
+
+	logger.Info("Attempting to delete image",
 		zap.String("userID", userID.String()),
 		zap.String("imageID", imageID.String()),
 	)
@@ -170,14 +321,14 @@ func (s *genService) DeleteImageByID(ctx context.Context, userID uuid.UUID, imag
 	err := s.imageRepo.Delete(ctx, userID, imageID)
 	if err != nil {
 		if errors.Is(err, domain.ErrRecordNotFound) {
-			s.logger.Warn("Delete request for a non-existent or already deleted image",
+			logger.Warn("Delete request for a non-existent or already deleted image",
 				zap.String("userID", userID.String()),
 				zap.String("imageID", imageID.String()),
 			)
 			return nil
 		}
 
-		s.logger.Error("Repository failed to delete image",
+		logger.Error("Repository failed to delete image",
 			zap.String("userID", userID.String()),
 			zap.String("imageID", imageID.String()),
 			zap.Error(err),
@@ -185,36 +336,42 @@ func (s *genService) DeleteImageByID(ctx context.Context, userID uuid.UUID, imag
 		return fmt.Errorf("failed to delete image: %w", err)
 	}
 
-	s.logger.Info("Successfully deleted image",
+	logger.Info("Successfully deleted image",
 		zap.String("userID", userID.String()),
 		zap.String("imageID", imageID.String()),
 	)
+	s.auditService.Record(ctx, AuditActor{UserID: userID}, domain.AuditImageDeleted, "image", imageID.String(), nil)
 	return nil
 }
 
 func (s *genService) DeleteFailedImages(ctx context.Context, userID uuid.UUID) error {
-	s.logger.Info("Attempting to delete all failed images for user",
+	logger := requestlog.Logger(ctx, s.logger)
+
+	logger.Info("Attempting to delete all failed images for user",
 		zap.String("userID", userID.String()),
 	)
 
 	if err := s.imageRepo.DeleteFailed(ctx, userID); err != nil {
-		s.logger.Error("Repository failed to delete failed images",
+		logger.Error("Repository failed to delete failed images",
 			zap.String("userID", userID.String()),
 			zap.Error(err),
 		)
 		return fmt.Errorf("failed to delete failed images: %w", err)
 	}
 
-	s.logger.Info("Successfully processed request to delete failed images for user",
+	logger.Info("Successfully processed request to delete failed images for user",
 		zap.String("userID", userID.String()),
 	)
+	s.auditService.Record(ctx, AuditActor{UserID: userID}, domain.AuditFailedImagesDeleted, "user", userID.String(), nil)
 	return nil
 }
 
 func (s *genService) ContainsFailedImages(ctx context.Context, userID uuid.UUID) (bool, error) {
+	logger := requestlog.Logger(ctx, s.logger)
+
 	contains, err := s.imageRepo.ContainsFailedImages(ctx, userID)
 	if err != nil {
-		s.logger.Error("Repository failed to determine if failed images exist",
+		logger.Error("Repository failed to determine if failed images exist",
 			zap.String("userID", userID.String()),
 			zap.Error(err),
 		)
@@ -223,3 +380,18 @@ func (s *genService) ContainsFailedImages(ctx context.Context, userID uuid.UUID)
 
 	return contains, nil
 }
+
+func (s *genService) QueuePosition(ctx context.Context, userID uuid.UUID) (int, error) {
+	logger := requestlog.Logger(ctx, s.logger)
+
+	position, err := s.jobRepo.QueuePosition(ctx, userID)
+	if err != nil {
+		logger.Error("Repository failed to determine queue position",
+			zap.String("userID", userID.String()),
+			zap.Error(err),
+		)
+		return 0, fmt.Errorf("failed to determine queue position: %w", err)
+	}
+
+	return position, nil
+}
@@ -0,0 +1,319 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/CP-Payne/wonderpicai/internal/port"
+	"github.com/CP-Payne/wonderpicai/internal/tracing"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+const (
+	jobBaseBackoff = 5 * time.Second
+	jobMaxBackoff  = 5 * time.Minute
+	// resultAttempts bounds how often the images of a synchronous backend are handed to the sink
+	resultAttempts = 3
+	// expirySweepInterval is how often submitted prompts are checked for a missing result
+	expirySweepInterval = time.Minute
+	expirySweepLimit    = 100
+)
+
+// GenerationWorker submits queued generation jobs to the image generation backend.
+// Jobs that keep failing are retried with backoff and dead-lettered (and refunded)
+// once they run out of attempts. Synchronous backends return the images right away,
+// which are handed to the result sink. Prompts whose result does not arrive within the
+// result timeout, e.g. because the completion webhook got lost, are failed and refunded.
+type GenerationWorker struct {
+	logger         *zap.Logger
+	jobRepo        port.GenerationJobRepository
+	imageGenClient port.ImageGeneration
+	resultSink     port.GenerationResultSink
+	webhookService WebhookService
+	metrics        port.Metrics
+	workers        int
+	pollInterval   time.Duration
+	lease          time.Duration
+	resultTimeout  time.Duration
+}
+
+func NewGenerationWorker(logger *zap.Logger, jobRepo port.GenerationJobRepository, genClient port.ImageGeneration, resultSink port.GenerationResultSink, webhookService WebhookService, metrics port.Metrics, workers int, pollInterval, lease, resultTimeout time.Duration) *GenerationWorker {
+	if workers < 1 {
+		workers = 1
+	}
+	if pollInterval <= 0 {
+		pollInterval = time.Second
+	}
+	if lease <= 0 {
+		lease = 2 * time.Minute
+	}
+
+	return &GenerationWorker{
+		logger:         logger.With(zap.String("component", "GenerationWorker")),
+		jobRepo:        jobRepo,
+		imageGenClient: genClient,
+		resultSink:     resultSink,
+		webhookService: webhookService,
+		metrics:        metrics,
+		workers:        workers,
+		pollInterval:   pollInterval,
+		lease:          lease,
+		resultTimeout:  resultTimeout,
+	}
+}
+
+// Start runs the worker pool until ctx is cancelled.
+func (w *GenerationWorker) Start(ctx context.Context) {
+	for i := 0; i < w.workers; i++ {
+		go w.run(ctx)
+	}
+	if w.resultTimeout > 0 {
+		go w.expireSubmissions(ctx)
+	}
+	<-ctx.Done()
+}
+
+func (w *GenerationWorker) run(ctx context.Context) {
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Keep draining while there is work, only wait for the ticker when the queue is empty
+		for w.processNext(ctx) {
+			if ctx.Err() != nil {
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// processNext claims and submits a single job. It reports whether a job was found.
+func (w *GenerationWorker) processNext(ctx context.Context) bool {
+	jobs, err := w.jobRepo.ClaimNext(ctx, 1, w.lease)
+	if err != nil {
+		if ctx.Err() == nil {
+			w.logger.Error("Failed to claim generation job", zap.Error(err))
+		}
+		return false
+	}
+	if len(jobs) == 0 {
+		return false
+	}
+
+	for _, job := range jobs {
+		w.submit(ctx, &job)
+	}
+	return true
+}
+
+func (w *GenerationWorker) submit(ctx context.Context, job *domain.GenerationJob) {
+	// The submission continues the trace of the request that created the prompt
+	ctx, span := tracer.Start(tracing.ContextWithTraceParent(ctx, job.Prompt.TraceParent), "GenerationWorker.submit", trace.WithAttributes(
+		attribute.String("job.id", job.ID.String()),
+		attribute.String("prompt.id", job.PromptID.String()),
+		attribute.Int("job.attempt", job.Attempts),
+	))
+	defer span.End()
+
+	logger := w.logger.With(
+		zap.String("jobID", job.ID.String()),
+		zap.String("promptID", job.PromptID.String()),
+		zap.Int("attempt", job.Attempts),
+	)
+
+	stopRenewing := w.renewLease(ctx, logger, job.ID)
+	result, err := w.imageGenClient.GenerateImage(ctx, &port.ImageGenerationInput{
+		Prompt:     job.Prompt.Text,
+		ImageCount: job.RemainingImages(),
+		Width:      job.Prompt.Width,
+		Height:     job.Prompt.Height,
+	})
+	stopRenewing()
+	if err == nil {
+		if err := w.jobRepo.MarkSubmitted(ctx, job.ID, result.PromptID, result.Backend); err != nil {
+			// The lease expires and the job is submitted again
+			logger.Error("Failed to mark generation job as submitted", zap.Error(err))
+			return
+		}
+		logger.Info("Generation job submitted",
+			zap.String("externalPromptID", result.PromptID.String()),
+			zap.String("backend", result.Backend),
+		)
+		if result.Images != nil {
+			w.deliverImages(ctx, logger, result)
+		}
+		return
+	}
+
+	setSpanError(span, err)
+	if ctx.Err() != nil {
+		// Shutting down, the lease expires and another worker picks the job up
+		return
+	}
+
+	// A submission with an unknown outcome is not sent again, as the backend may already be
+	// generating the images and a second request would have them generated twice
+	permanent := errors.Is(err, domain.ErrGenerationRejected) ||
+		errors.Is(err, domain.ErrInvalidGenerationInput) ||
+		errors.Is(err, domain.ErrGenerationOutcomeUnknown)
+	if permanent || job.Attempts >= job.MaxAttempts {
+		logger.Warn("Generation job failed permanently, refunding credits", zap.Bool("permanent", permanent), zap.Error(err))
+		w.fail(ctx, logger, job, err.Error())
+		return
+	}
+
+	if result != nil && len(result.Images) > 0 {
+		w.storePartialImages(ctx, logger, job, result.Images)
+	}
+
+	wait := jobBackoff(job.Attempts)
+	logger.Warn("Generation job failed, rescheduling", zap.Duration("backoff", wait), zap.Error(err))
+	if err := w.jobRepo.Reschedule(ctx, job.ID, time.Now().Add(wait), err.Error()); err != nil {
+		logger.Error("Failed to reschedule generation job", zap.Error(err))
+	}
+}
+
+// storePartialImages keeps the images generated before the attempt failed, so that the retry
+// only generates the rest. When they cannot be stored the retry generates all of them again.
+func (w *GenerationWorker) storePartialImages(ctx context.Context, logger *zap.Logger, job *domain.GenerationJob, images [][]byte) {
+	if err := w.jobRepo.StorePartialImages(ctx, job.ID, images); err != nil {
+		logger.Error("Failed to store partial generation result", zap.Int("images", len(images)), zap.Error(err))
+		return
+	}
+	logger.Info("Partial generation result stored",
+		zap.Int("images", len(images)),
+		zap.Int("remaining", job.RemainingImages()-len(images)),
+	)
+}
+
+// fail dead-letters the job, which fails its prompt and refunds the credits
+func (w *GenerationWorker) fail(ctx context.Context, logger *zap.Logger, job *domain.GenerationJob, reason string) {
+	if err := w.jobRepo.MarkDead(ctx, job.ID, reason); err != nil {
+		if errors.Is(err, domain.ErrPromptAlreadyFinished) {
+			logger.Info("Prompt finished before its generation job was dead-lettered")
+			return
+		}
+		logger.Error("Failed to dead-letter generation job", zap.Error(err))
+		return
+	}
+	w.metrics.GenerationFinished(domain.Failed, time.Since(job.Prompt.CreatedAt))
+	w.metrics.CreditsMoved(domain.CreditRefund, job.Prompt.Cost)
+	err := w.webhookService.Emit(ctx, job.UserID, domain.EventPromptFailed, PromptEventData{
+		PromptID: job.PromptID,
+		Status:   domain.Failed.APIName(),
+		ImageIDs: []uuid.UUID{},
+		Cost:     job.Prompt.Cost,
+		Refunded: true,
+	})
+	if err != nil {
+		logger.Error("Failed to emit prompt failed webhook event", zap.Error(err))
+	}
+}
+
+// expireSubmissions periodically fails the prompts that were submitted longer than the result
+// timeout ago and are still pending. Without it a lost completion webhook would keep the prompt
+// counting against the user's generation limits forever.
+func (w *GenerationWorker) expireSubmissions(ctx context.Context) {
+	ticker := time.NewTicker(expirySweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.expireOnce(ctx)
+		}
+	}
+}
+
+func (w *GenerationWorker) expireOnce(ctx context.Context) {
+	jobs, err := w.jobRepo.ListExpiredSubmissions(ctx, time.Now().Add(-w.resultTimeout), expirySweepLimit)
+	if err != nil {
+		if ctx.Err() == nil {
+			w.logger.Error("Failed to list expired generation submissions", zap.Error(err))
+		}
+		return
+	}
+
+	for _, job := range jobs {
+		logger := w.logger.With(
+			zap.String("jobID", job.ID.String()),
+			zap.String("promptID", job.PromptID.String()),
+		)
+		logger.Warn("No generation result within the result timeout, refunding credits", zap.Duration("timeout", w.resultTimeout))
+		w.fail(ctx, logger, &job, "no result from the generation backend within "+w.resultTimeout.String())
+	}
+}
+
+// renewLease keeps the job locked while a submission runs longer than half a lease, as
+// synchronous backends do, so that no other worker takes it over. The returned function stops it.
+func (w *GenerationWorker) renewLease(ctx context.Context, logger *zap.Logger, jobID uuid.UUID) func() {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(w.lease / 2)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := w.jobRepo.ExtendLease(ctx, jobID, w.lease); err != nil && ctx.Err() == nil {
+					logger.Warn("Failed to extend generation job lease", zap.Error(err))
+				}
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// deliverImages hands the images of a synchronous backend to the result sink. A prompt whose
+// images cannot be stored stays pending until it runs into the result timeout and is refunded.
+func (w *GenerationWorker) deliverImages(ctx context.Context, logger *zap.Logger, result *port.ImageGenerationResult) {
+	var err error
+	for attempt := 1; attempt <= resultAttempts; attempt++ {
+		if _, err = w.resultSink.UpdatePlaceholderImages(ctx, result.PromptID, result.Backend, result.Images, domain.Completed); err == nil {
+			logger.Info("Generated images stored", zap.Int("images", len(result.Images)))
+			return
+		}
+		if attempt == resultAttempts {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(jobBackoff(attempt)):
+		}
+	}
+	logger.Error("Failed to store generated images", zap.Error(err))
+}
+
+// jobBackoff returns an exponentially growing wait with jitter
+func jobBackoff(attempt int) time.Duration {
+	ceiling := jobBaseBackoff << (attempt - 1)
+	if ceiling > jobMaxBackoff || ceiling <= 0 {
+		ceiling = jobMaxBackoff
+	}
+	return ceiling/2 + time.Duration(rand.Int64N(int64(ceiling/2)+1))
+}
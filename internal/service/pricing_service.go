@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/CP-Payne/wonderpicai/internal/context/requestlog"
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/CP-Payne/wonderpicai/internal/port"
+	"go.uber.org/zap"
+)
+
+type PricingService interface {
+	Quote(ctx context.Context, input domain.PriceQuoteInput) (domain.PriceQuote, error)
+	// Start reloads the rules from the source periodically until ctx is cancelled
+	Start(ctx context.Context)
+}
+
+type pricingService struct {
+	logger         *zap.Logger
+	source         port.PricingRulesSource
+	reloadInterval time.Duration
+	rules          atomic.Pointer[domain.PricingRules]
+}
+
+// NewPricingService loads the initial rules from source. Without a source the
+// built-in domain.DefaultPricingRules are used and never reloaded.
+func NewPricingService(ctx context.Context, logger *zap.Logger, source port.PricingRulesSource, reloadInterval time.Duration) (PricingService, error) {
+	s := &pricingService{
+		logger:         logger.With(zap.String("component", "PricingService")),
+		source:         source,
+		reloadInterval: reloadInterval,
+	}
+
+	if source == nil {
+		s.rules.Store(domain.DefaultPricingRules())
+		s.logger.Info("Using built-in pricing rules", zap.String("version", s.rules.Load().Version))
+		return s, nil
+	}
+
+	rules, err := source.Load(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pricing rules: %w", err)
+	}
+	s.rules.Store(rules)
+	s.logger.Info("Pricing rules loaded", zap.String("version", rules.Version))
+
+	return s, nil
+}
+
+func (s *pricingService) Quote(ctx context.Context, input domain.PriceQuoteInput) (domain.PriceQuote, error) {
+	return s.rules.Load().Quote(input)
+}
+
+func (s *pricingService) Start(ctx context.Context) {
+	if s.source == nil || s.reloadInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.reloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.reload(ctx)
+		}
+	}
+}
+
+// reload swaps in new rules. Rules that fail to load are ignored and the current rules stay active.
+func (s *pricingService) reload(ctx context.Context) {
+	logger := requestlog.Logger(ctx, s.logger)
+
+	rules, err := s.source.Load(ctx)
+	if err != nil {
+		logger.Error("Failed to reload pricing rules, keeping current rules",
+			zap.String("version", s.rules.Load().Version),
+			zap.Error(err),
+		)
+		return
+	}
+
+	previous := s.rules.Swap(rules)
+	if previous.Version != rules.Version {
+		logger.Info("Pricing rules reloaded",
+			zap.String("previousVersion", previous.Version),
+			zap.String("version", rules.Version),
+		)
+	} else if previous != rules {
+		logger.Warn("Pricing rules changed without a new version", zap.String("version", rules.Version))
+	}
+}
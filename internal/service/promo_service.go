@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/CP-Payne/wonderpicai/internal/context/requestlog"
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/CP-Payne/wonderpicai/internal/port"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// PromoService manages promo codes and redeems credit codes
+type PromoService interface {
+	// Redeem applies a code entered on the purchase page. Credit codes are redeemed right away,
+	// discount codes are only checked here and redeemed with the checkout they are used for.
+	Redeem(ctx context.Context, userID uuid.UUID, code string) (*domain.PromoCode, error)
+	ListAll(ctx context.Context) ([]domain.PromoCode, error)
+	Create(ctx context.Context, input PromoCodeInput) (*domain.PromoCode, error)
+	SetActive(ctx context.Context, id uuid.UUID, active bool) (*domain.PromoCode, error)
+}
+
+type PromoCodeInput struct {
+	Code           string
+	Kind           domain.PromoKind
+	Credits        int
+	PercentOff     int
+	PackageID      *uuid.UUID
+	MaxRedemptions int
+	PerUserLimit   int
+	ValidFrom      *time.Time
+	ValidUntil     *time.Time
+	Active         bool
+}
+
+type promoService struct {
+	logger       *zap.Logger
+	promoRepo    port.PromoCodeRepository
+	userRepo     port.UserRepository
+	auditService AuditService
+}
+
+func NewPromoService(logger *zap.Logger, promoRepo port.PromoCodeRepository, userRepo port.UserRepository, auditService AuditService) PromoService {
+	return &promoService{
+		logger:       logger.With(zap.String("component", "PromoService")),
+		promoRepo:    promoRepo,
+		userRepo:     userRepo,
+		auditService: auditService,
+	}
+}
+
+func (s *promoService) Redeem(ctx context.Context, userID uuid.UUID, code string) (*domain.PromoCode, error) {
+	logger := requestlog.Logger(ctx, s.logger)
+
+	code = normalizePromoCode(code)
+	if code == "" {
+		return nil, domain.ErrPromoCodeInvalid
+	}
+
+	promo, err := s.promoRepo.GetByCode(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if err := promo.CheckValid(time.Now()); err != nil {
+		return nil, err
+	}
+
+	if promo.Kind == domain.PromoDiscount {
+		return promo, nil
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Records the redemption and adds the credits in one transaction
+	promo, redemption, err := s.promoRepo.Redeem(ctx, port.PromoRedemptionRequest{
+		Code:   code,
+		UserID: userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Promo code redeemed",
+		zap.String("userID", userID.String()),
+		zap.String("code", code),
+		zap.Int("credits", promo.Credits),
+	)
+	s.auditService.Record(ctx, AuditActor{UserID: userID, Email: user.Email}, domain.AuditPromoRedeemed, "promo", promo.ID.String(), map[string]any{
+		"code":         promo.Code,
+		"credits":      promo.Credits,
+		"redemptionID": redemption.ID,
+	})
+	return promo, nil
+}
+
+func (s *promoService) ListAll(ctx context.Context) ([]domain.PromoCode, error) {
+	promos, err := s.promoRepo.ListAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list promo codes: %w", err)
+	}
+	return promos, nil
+}
+
+func (s *promoService) Create(ctx context.Context, input PromoCodeInput) (*domain.PromoCode, error) {
+	logger := requestlog.Logger(ctx, s.logger)
+
+	promo := &domain.PromoCode{
+		BaseModel: domain.BaseModel{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		},
+		Code:           normalizePromoCode(input.Code),
+		Kind:           input.Kind,
+		MaxRedemptions: input.MaxRedemptions,
+		PerUserLimit:   input.PerUserLimit,
+		ValidFrom:      input.ValidFrom,
+		ValidUntil:     input.ValidUntil,
+		Active:         input.Active,
+	}
+
+	// Only the value of the code's kind is stored
+	switch input.Kind {
+	case domain.PromoCredits:
+		promo.Credits = input.Credits
+	case domain.PromoDiscount:
+		promo.PercentOff = input.PercentOff
+		promo.PackageID = input.PackageID
+	}
+
+	if err := s.promoRepo.Create(ctx, promo); err != nil {
+		if errors.Is(err, domain.ErrDuplicateEntry) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to create promo code: %w", err)
+	}
+
+	logger.Info("Promo code created",
+		zap.String("promoCodeID", promo.ID.String()),
+		zap.String("code", promo.Code),
+		zap.String("kind", string(promo.Kind)),
+	)
+	s.auditService.Record(ctx, AuditActorFromContext(ctx), domain.AuditPromoCreated, "promo", promo.ID.String(), map[string]any{
+		"code":           promo.Code,
+		"kind":           promo.Kind,
+		"credits":        promo.Credits,
+		"percentOff":     promo.PercentOff,
+		"maxRedemptions": promo.MaxRedemptions,
+		"perUserLimit":   promo.PerUserLimit,
+		"active":         promo.Active,
+	})
+	return promo, nil
+}
+
+func (s *promoService) SetActive(ctx context.Context, id uuid.UUID, active bool) (*domain.PromoCode, error) {
+	logger := requestlog.Logger(ctx, s.logger)
+
+	promo, err := s.promoRepo.SetActive(ctx, id, active)
+	if err != nil {
+		if errors.Is(err, domain.ErrRecordNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to update promo code: %w", err)
+	}
+
+	logger.Info("Promo code updated", zap.String("promoCodeID", id.String()), zap.Bool("active", active))
+	s.auditService.Record(ctx, AuditActorFromContext(ctx), domain.AuditPromoUpdated, "promo", id.String(), map[string]any{
+		"code":   promo.Code,
+		"active": active,
+	})
+	return promo, nil
+}
+
+func normalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
@@ -5,52 +5,93 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"sort"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/CP-Payne/wonderpicai/internal/context/requestlog"
 	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/CP-Payne/wonderpicai/internal/money"
 	"github.com/CP-Payne/wonderpicai/internal/port"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
+// defaultCurrency is used when neither the profile nor the browser locale decide a currency
+const defaultCurrency = "usd"
+
+const (
+	// checkoutLifetime is how long a checkout session can be paid, the minimum Stripe allows
+	checkoutLifetime = 30 * time.Minute
+	// A discount stays reserved a little longer than the checkout, for webhooks arriving late
+	promoReservationMargin = 10 * time.Minute
+	// receiptSendTimeout bounds mailing the receipt of a completed payment
+	receiptSendTimeout = time.Minute
+	// unknownProviderEvent labels the metrics of events that could not be read
+	unknownProviderEvent = "unknown"
+)
+
 type PurcaseService interface {
-	GetOptions(ctx context.Context) []PurchaseOption
+	// GetOptions returns the packages for sale priced for the user. acceptLanguage is the
+	// user's Accept-Language header, used when no currency is set in the profile. An optional
+	// discount promo code is applied to the packages it is valid for.
+	GetOptions(ctx context.Context, userID uuid.UUID, acceptLanguage string, promoCode string) (*PurchaseCatalog, error)
 	OptionExists(ctx context.Context, option string) bool
-	CreateCheckout(ctx context.Context, userID uuid.UUID, option string) (checkoutURL string, err error)
+	CreateCheckout(ctx context.Context, userID uuid.UUID, option string, acceptLanguage string, promoCode string) (checkoutURL string, err error)
+	// SetCurrency stores the preferred currency in the user's profile, an empty currency
+	// picks it from the locale again
+	SetCurrency(ctx context.Context, userID uuid.UUID, currency string) error
+	// CheckoutStatus reports whether the credits of a checkout session have been added yet.
+	// Sessions of other users are reported as domain.ErrInsufficientPermissions.
+	CheckoutStatus(ctx context.Context, userID uuid.UUID, sessionID string) (*CheckoutStatus, error)
 	HandleProviderEvents(r *http.Request, data []byte) error
 }
 
+type CheckoutState string
+
+const (
+	// CheckoutPending checkouts are waiting for the provider's confirmation
+	CheckoutPending CheckoutState = "pending"
+	// CheckoutProcessing checkouts were paid with a delayed method that has not settled yet
+	CheckoutProcessing CheckoutState = "processing"
+	CheckoutCompleted  CheckoutState = "completed"
+	CheckoutFailed     CheckoutState = "failed"
+)
+
+type CheckoutStatus struct {
+	State       CheckoutState
+	Description string
+	// Credits are the credits bought, or granted per period for subscriptions
+	Credits int
+	// Balance is the wallet balance, set once the checkout completed
+	Balance int
+}
+
+type PurchaseCatalog struct {
+	// Currency is the currency prices are shown in. Packages without a price in it are
+	// offered in their default currency.
+	Currency string
+	// ProfileCurrency is the currency set in the user's profile, empty when it is automatic
+	ProfileCurrency string
+	// PromoCode and PercentOff describe the discount applied to the options, if any
+	PromoCode  string
+	PercentOff int
+	Options    []PurchaseOption
+}
+
 type PurchaseOption struct {
-	Credits   int
-	Price     int
-	ActionURL string
-}
-
-// Current purchase options
-var options map[string]PurchaseOption = map[string]PurchaseOption{
-	"100": {
-		Credits:   100,
-		Price:     5,
-		ActionURL: "/purchase/100",
-	},
-
-	"250": {
-		Credits:   250,
-		Price:     10,
-		ActionURL: "/purchase/250",
-	},
-
-	"700": {
-		Credits:   700,
-		Price:     25,
-		ActionURL: "/purchase/700",
-	},
-
-	"1500": {
-		Credits:   1500,
-		Price:     50,
-		ActionURL: "/purchase/1500",
-	},
+	ID         uuid.UUID
+	Name       string
+	Credits    int
+	PriceMinor int64
+	// OriginalPriceMinor is the price before a promo discount, 0 when not discounted
+	OriginalPriceMinor int64
+	Currency           string
+	Badge              string
+	ActionURL          string
 }
 
 type purchaseService struct {
@@ -58,44 +99,143 @@ type purchaseService struct {
 	walletService WalletService
 	provider      port.PaymentProvider
 	userRepo      port.UserRepository
+	packageRepo   port.CreditPackageRepository
+	paymentRepo   port.PaymentRepository
+	promoRepo     port.PromoCodeRepository
+	// subscriptionService handles the provider events of subscriptions
+	subscriptionService SubscriptionService
+	receiptService      ReceiptService
+	webhookService      WebhookService
+	auditService        AuditService
+	metrics             port.Metrics
 }
 
-func NewPurchaseService(logger *zap.Logger, walletService WalletService, provider port.PaymentProvider, userRepo port.UserRepository) PurcaseService {
+func NewPurchaseService(logger *zap.Logger, walletService WalletService, provider port.PaymentProvider, userRepo port.UserRepository, packageRepo port.CreditPackageRepository, paymentRepo port.PaymentRepository, promoRepo port.PromoCodeRepository, subscriptionService SubscriptionService, receiptService ReceiptService, webhookService WebhookService, auditService AuditService, metrics port.Metrics) PurcaseService {
 	return &purchaseService{
 		logger:        logger.With(zap.String("component", "PurchaseService")),
 		walletService: walletService,
 		provider:      provider,
 		userRepo:      userRepo,
+		packageRepo:   packageRepo,
+		paymentRepo:   paymentRepo,
+		promoRepo:     promoRepo,
+
+		subscriptionService: subscriptionService,
+		receiptService:      receiptService,
+		webhookService:      webhookService,
+		auditService:        auditService,
+		metrics:             metrics,
 	}
 }
 
-func (s *purchaseService) GetOptions(ctx context.Context) []PurchaseOption {
-	var purchaseOptions []PurchaseOption
+func (s *purchaseService) GetOptions(ctx context.Context, userID uuid.UUID, acceptLanguage string, promoCode string) (*PurchaseCatalog, error) {
+	logger := requestlog.Logger(ctx, s.logger)
 
-	for _, option := range options {
-		purchaseOptions = append(purchaseOptions, option)
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
 	}
 
-	sort.Slice(purchaseOptions, func(i, j int) bool {
-		return purchaseOptions[i].Price < purchaseOptions[j].Price
-	})
+	pkgs, err := s.packageRepo.ListActive(ctx)
+	if err != nil {
+		logger.Error("Failed to load credit packages", zap.Error(err))
+		return nil, fmt.Errorf("failed to load credit packages: %w", err)
+	}
+
+	currency := preferredCurrency(user, acceptLanguage)
+	catalog := &PurchaseCatalog{
+		Currency:        currency,
+		ProfileCurrency: user.Currency,
+	}
+
+	// Limits are only checked at checkout, an invalid code is shown without discount
+	var promo *domain.PromoCode
+	if promoCode != "" {
+		promo, err = s.promoRepo.GetByCode(ctx, promoCode)
+		if err == nil && promo.Kind == domain.PromoDiscount && promo.CheckValid(time.Now()) == nil {
+			catalog.PromoCode = promo.Code
+			catalog.PercentOff = promo.PercentOff
+		} else {
+			promo = nil
+		}
+	}
+
+	catalog.Options = make([]PurchaseOption, 0, len(pkgs))
+	for _, pkg := range pkgs {
+		priceMinor, priceCurrency := pkg.PriceIn(currency)
+		option := PurchaseOption{
+			ID:         pkg.ID,
+			Name:       pkg.Name,
+			Credits:    pkg.Credits,
+			PriceMinor: priceMinor,
+			Currency:   priceCurrency,
+			Badge:      pkg.Badge,
+			ActionURL:  "/purchase/" + pkg.ID.String(),
+		}
+		if promo != nil && promo.AppliesTo(pkg.ID) {
+			option.OriginalPriceMinor = priceMinor
+			option.PriceMinor = promo.Discounted(priceMinor)
+			option.ActionURL += "?promo=" + url.QueryEscape(promo.Code)
+		}
+		catalog.Options = append(catalog.Options, option)
+	}
+
+	return catalog, nil
+}
 
-	return purchaseOptions
+// preferredCurrency picks the currency from the user's profile, then from the browser locale
+func preferredCurrency(user *domain.User, acceptLanguage string) string {
+	if user.Currency != "" && money.IsSupported(user.Currency) {
+		return user.Currency
+	}
+	if currency, ok := money.CurrencyForLocale(acceptLanguage); ok {
+		return currency
+	}
+	return defaultCurrency
 }
 
 func (s *purchaseService) OptionExists(ctx context.Context, option string) bool {
-	_, exists := options[option]
-	return exists
+	_, err := s.activePackage(ctx, option)
+	return err == nil
 }
 
-func (s *purchaseService) CreateCheckout(ctx context.Context, userID uuid.UUID, option string) (checkoutURL string, err error) {
+// activePackage resolves a purchase option to a package that is currently for sale
+func (s *purchaseService) activePackage(ctx context.Context, option string) (*domain.CreditPackage, error) {
+	id, err := uuid.Parse(option)
+	if err != nil {
+		return nil, domain.ErrInvalidPurchaseOption
+	}
 
-	opt, ok := options[option]
-	if !ok {
-		return "", domain.ErrInvalidPurchaseOption
+	pkg, err := s.packageRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, domain.ErrRecordNotFound) {
+			return nil, domain.ErrInvalidPurchaseOption
+		}
+		return nil, err
 	}
 
-	productName := fmt.Sprintf("%d credits", opt.Credits)
+	if !pkg.Active || pkg.DeletedAt.Valid {
+		return nil, domain.ErrInvalidPurchaseOption
+	}
+
+	return pkg, nil
+}
+
+func (s *purchaseService) CreateCheckout(ctx context.Context, userID uuid.UUID, option string, acceptLanguage string, promoCode string) (checkoutURL string, err error) {
+	logger := requestlog.Logger(ctx, s.logger)
+
+	ctx, span := tracer.Start(ctx, "PurchaseService.CreateCheckout", trace.WithAttributes(
+		attribute.String("user.id", userID.String()),
+		attribute.String("purchase.option", option),
+	))
+	defer func() { endSpan(span, err) }()
+
+	pkg, err := s.activePackage(ctx, option)
+	if err != nil {
+		return "", err
+	}
+
+	productName := fmt.Sprintf("%s - %d credits", pkg.Name, pkg.Credits)
 
 	user, err := s.userRepo.GetByID(userID)
 	if err != nil {
@@ -105,41 +245,474 @@ func (s *purchaseService) CreateCheckout(ctx context.Context, userID uuid.UUID,
 		Email: user.Email,
 	}
 
+	priceMinor, currency := pkg.PriceIn(preferredCurrency(user, acceptLanguage))
+	expiresAt := time.Now().Add(checkoutLifetime)
+
+	payment := &domain.Payment{
+		BaseModel: domain.BaseModel{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		},
+		UserID:      userID,
+		PackageID:   pkg.ID,
+		Description: pkg.Name,
+		Credits:     pkg.Credits,
+		AmountMinor: priceMinor,
+		Currency:    currency,
+		Status:      domain.PaymentPending,
+	}
+
 	productData := port.ProductData{
-		Name:     productName,
-		Price:    opt.Price,
-		Quantity: 1,
-		Option:   option,
+		Name:       productName,
+		PriceMinor: priceMinor,
+		Currency:   currency,
+		Quantity:   1,
+		Option:     pkg.ID.String(),
+		Reference:  payment.ID.String(),
+		ExpiresAt:  expiresAt,
 	}
-	checkoutURL, err = s.provider.CreateCheckoutSession(userData, productData)
+
+	// The discount is reserved for this checkout and redeemed once it is paid
+	var redemption *domain.PromoRedemption
+	if promoCode != "" {
+		reservedUntil := expiresAt.Add(promoReservationMargin)
+		var promo *domain.PromoCode
+		promo, redemption, err = s.promoRepo.Redeem(ctx, port.PromoRedemptionRequest{
+			Code:          promoCode,
+			UserID:        userID,
+			PackageID:     &pkg.ID,
+			PaymentID:     &payment.ID,
+			ReservedUntil: &reservedUntil,
+		})
+		if err != nil {
+			return "", err
+		}
+
+		payment.PromoCodeID = &promo.ID
+		payment.AmountMinor = promo.Discounted(priceMinor)
+		productData.DiscountPercent = promo.PercentOff
+		productData.DiscountName = promo.Code
+	}
+
+	// releasePromo gives the reserved discount back when the checkout cannot be started
+	releasePromo := func() {
+		if redemption == nil {
+			return
+		}
+		if releaseErr := s.promoRepo.Release(ctx, redemption.ID); releaseErr != nil {
+			logger.Warn("Failed to release promo redemption", zap.String("redemptionID", redemption.ID.String()), zap.Error(releaseErr))
+		}
+	}
+
+	if err := s.paymentRepo.Create(ctx, payment); err != nil {
+		releasePromo()
+		return "", fmt.Errorf("failed to record payment: %w", err)
+	}
+
+	// abandon fails the payment of a checkout that cannot be started
+	abandon := func() {
+		if markErr := s.paymentRepo.MarkFailed(ctx, payment.ID); markErr != nil {
+			logger.Warn("Failed to mark payment as failed", zap.String("paymentID", payment.ID.String()), zap.Error(markErr))
+		}
+		releasePromo()
+	}
+
+	checkoutSession, err := s.provider.CreateCheckoutSession(userData, productData)
 	if err != nil {
+		abandon()
 		return "", fmt.Errorf("failed to create checkout session: %w", err)
 	}
 
-	return checkoutURL, nil
+	// The success page finds the payment by its session, a checkout it cannot find is not
+	// started. The session expires unpaid.
+	if err := s.paymentRepo.AttachSession(ctx, payment.ID, checkoutSession.ID); err != nil {
+		abandon()
+		return "", fmt.Errorf("failed to store checkout session on payment: %w", err)
+	}
+
+	return checkoutSession.URL, nil
+}
+
+func (s *purchaseService) SetCurrency(ctx context.Context, userID uuid.UUID, currency string) error {
+	currency = strings.ToLower(strings.TrimSpace(currency))
+	if currency != "" && !money.IsSupported(currency) {
+		return domain.ErrUnsupportedCurrency
+	}
+
+	if err := s.userRepo.UpdateCurrency(userID, currency); err != nil {
+		return fmt.Errorf("failed to update preferred currency: %w", err)
+	}
+	return nil
+}
+
+func (s *purchaseService) CheckoutStatus(ctx context.Context, userID uuid.UUID, sessionID string) (*CheckoutStatus, error) {
+	logger := requestlog.Logger(ctx, s.logger)
+
+	status, ownerID, err := s.checkoutStatus(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if ownerID != userID {
+		logger.Warn("Checkout status requested by another user", zap.String("sessionID", sessionID), zap.String("userID", userID.String()))
+		return nil, domain.ErrInsufficientPermissions
+	}
+
+	if status.State == CheckoutCompleted {
+		wallet, err := s.walletService.GetWallet(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		status.Balance = int(wallet.Credits)
+	}
+	return status, nil
+}
+
+// checkoutStatus looks the session up in the payments, then in the subscriptions
+func (s *purchaseService) checkoutStatus(ctx context.Context, sessionID string) (*CheckoutStatus, uuid.UUID, error) {
+	payment, err := s.paymentRepo.GetBySessionID(ctx, sessionID)
+	if err == nil {
+		status := &CheckoutStatus{
+			State:       CheckoutPending,
+			Description: payment.Description,
+			Credits:     payment.Credits,
+		}
+		switch payment.Status {
+		case domain.PaymentProcessing, domain.PaymentAmountMismatch:
+			status.State = CheckoutProcessing
+		case domain.PaymentFailed:
+			status.State = CheckoutFailed
+		case domain.PaymentCompleted, domain.PaymentRefunded, domain.PaymentDisputed:
+			// Refunded and disputed payments were completed before
+			status.State = CheckoutCompleted
+		}
+		return status, payment.UserID, nil
+	}
+	if !errors.Is(err, domain.ErrRecordNotFound) {
+		return nil, uuid.Nil, err
+	}
+
+	sub, err := s.subscriptionService.ByCheckoutSession(ctx, sessionID)
+	if err != nil {
+		return nil, uuid.Nil, err
+	}
+	status := &CheckoutStatus{
+		State:       CheckoutPending,
+		Description: sub.Plan.Name,
+		Credits:     sub.Plan.CreditsPerPeriod,
+	}
+	switch {
+	case sub.CurrentPeriodEnd != nil:
+		// The period is set when the first invoice's credits were granted
+		status.State = CheckoutCompleted
+	case sub.Status == domain.SubscriptionCanceled:
+		status.State = CheckoutFailed
+	}
+	return status, sub.UserID, nil
 }
 
 func (s *purchaseService) HandleProviderEvents(r *http.Request, data []byte) error {
+	logger := requestlog.Logger(r.Context(), s.logger)
+
+	ctx, span := tracer.Start(r.Context(), "PurchaseService.HandleProviderEvents")
+	defer span.End()
 
-	sessionData, err := s.provider.HandleEvent(r, data)
+	event, err := s.provider.HandleEvent(r, data)
 	if err != nil {
+		// Events the provider could not verify or parse have no type to record
 		if errors.Is(err, domain.ErrUnhandledEvent) {
-			s.logger.Warn("skipping unhandled event from provided")
+			logger.Warn("skipping unhandled event from provided")
+			s.metrics.PaymentWebhook(unknownProviderEvent, port.OutcomeIgnored)
+		} else {
+			s.metrics.PaymentWebhook(unknownProviderEvent, port.OutcomeInvalid)
+			setSpanError(span, err)
 		}
 		return err
 	}
+	span.SetAttributes(attribute.String("payment.event_id", event.ID), attribute.String("payment.event_type", string(event.Type)))
 
-	purchasedOption, ok := options[sessionData.Option]
-	if !ok {
-		return domain.ErrInvalidPurchaseOption
+	err = s.dispatchProviderEvent(ctx, event)
+	switch {
+	case err == nil:
+		s.metrics.PaymentWebhook(string(event.Type), port.OutcomeSuccess)
+	case errors.Is(err, domain.ErrUnhandledEvent):
+		s.metrics.PaymentWebhook(string(event.Type), port.OutcomeIgnored)
+	default:
+		s.metrics.PaymentWebhook(string(event.Type), port.OutcomeError)
+		setSpanError(span, err)
 	}
+	return err
+}
 
-	err = s.walletService.AddCredits(r.Context(), sessionData.UserEmail, purchasedOption.Credits)
+func (s *purchaseService) dispatchProviderEvent(ctx context.Context, event *port.ProviderEvent) error {
+	logger := requestlog.Logger(ctx, s.logger)
+
+	switch event.Type {
+	case port.EventCheckoutCompleted:
+		if event.Checkout.SubscriptionID != "" {
+			return s.subscriptionService.HandleCheckoutCompleted(ctx, event.Checkout)
+		}
+		if !event.Checkout.Paid {
+			return s.awaitCheckoutPayment(ctx, event.Checkout)
+		}
+		return s.completeCheckout(ctx, event.Checkout)
+	case port.EventCheckoutAsyncSucceeded:
+		return s.completeCheckout(ctx, event.Checkout)
+	case port.EventCheckoutAsyncFailed:
+		return s.failCheckout(ctx, event.Checkout)
+	case port.EventChargeRefunded, port.EventChargeDisputed:
+		return s.reversePayment(ctx, event.Reversal, event.Type == port.EventChargeDisputed)
+	case port.EventInvoicePaid:
+		return s.subscriptionService.HandleInvoicePaid(ctx, event.Invoice)
+	case port.EventSubscriptionUpdated, port.EventSubscriptionCanceled:
+		return s.subscriptionService.HandleSubscriptionChange(ctx, event.Subscription, event.Type == port.EventSubscriptionCanceled)
+	}
+
+	logger.Warn("skipping unknown provider event", zap.String("type", string(event.Type)))
+	return domain.ErrUnhandledEvent
+}
+
+func (s *purchaseService) completeCheckout(ctx context.Context, sessionData *port.SessionSuccess) error {
+	logger := requestlog.Logger(ctx, s.logger)
+
+	if sessionData.Reference == "" {
+		// Checkouts started before payments were recorded
+		return s.creditLegacyCheckout(ctx, sessionData)
+	}
+
+	paymentID, err := s.paymentReference(sessionData)
+	if err != nil {
+		return err
+	}
+
+	payment, completed, err := s.paymentRepo.Complete(ctx, paymentID, sessionData.AmountTotal, strings.ToLower(sessionData.Currency), sessionData.PaymentID)
+	if errors.Is(err, domain.ErrPaymentAmountMismatch) {
+		// Not retried by the provider, the payment is left for review
+		logger.Error("CRITICAL - Paid amount does not match payment, credits held back",
+			zap.String("paymentID", paymentID.String()),
+			zap.String("sessionID", sessionData.SessionID),
+			zap.Int64("amountMinor", payment.AmountMinor),
+			zap.String("currency", payment.Currency),
+			zap.Int64("paidMinor", sessionData.AmountTotal),
+			zap.String("paidCurrency", sessionData.Currency),
+		)
+		s.auditService.Record(ctx, AuditActor{}, domain.AuditPaymentMismatch, "payment", payment.ID.String(), map[string]any{
+			"userID":       payment.UserID,
+			"amountMinor":  payment.AmountMinor,
+			"currency":     payment.Currency,
+			"paidMinor":    sessionData.AmountTotal,
+			"paidCurrency": strings.ToLower(sessionData.Currency),
+		})
+		return nil
+	}
+	if err != nil {
+		logger.Error("CRITICAL - Failed completing payment", zap.String("paymentID", paymentID.String()), zap.String("sessionID", sessionData.SessionID), zap.Error(err))
+		return err
+	}
+
+	if !completed {
+		logger.Info("Payment already completed, ignoring repeated event", zap.String("paymentID", paymentID.String()))
+		return nil
+	}
+
+	logger.Info("Payment completed",
+		zap.String("paymentID", payment.ID.String()),
+		zap.String("userID", payment.UserID.String()),
+		zap.Int("credits", payment.Credits),
+		zap.Int64("amountMinor", payment.AmountMinor),
+		zap.String("currency", payment.Currency),
+	)
+
+	s.metrics.CreditsMoved(domain.CreditPurchase, payment.Credits)
+	// Payments are completed by provider events, so the system is the actor
+	s.auditService.Record(ctx, AuditActor{}, domain.AuditCreditsPurchased, "payment", payment.ID.String(), map[string]any{
+		"userID":      payment.UserID,
+		"credits":     payment.Credits,
+		"amountMinor": payment.AmountMinor,
+		"currency":    payment.Currency,
+	})
+	s.emitCreditsPurchased(ctx, payment)
+
+	// Mailing the receipt must not hold up or fail the webhook, the receipt can also be
+	// downloaded from the purchases page
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), receiptSendTimeout)
+		defer cancel()
+		if err := s.receiptService.SendReceipt(ctx, payment.ID); err != nil {
+			logger.Error("Failed to send receipt", zap.String("paymentID", payment.ID.String()), zap.Error(err))
+		}
+	}()
+	return nil
+}
+
+func (s *purchaseService) emitCreditsPurchased(ctx context.Context, payment *domain.Payment) {
+	logger := requestlog.Logger(ctx, s.logger)
+
+	data := CreditsPurchasedEventData{
+		PaymentID:   payment.ID,
+		Description: payment.Description,
+		Credits:     payment.Credits,
+		AmountMinor: payment.AmountMinor,
+		Currency:    payment.Currency,
+	}
+	if wallet, err := s.walletService.GetWallet(ctx, payment.UserID); err == nil {
+		data.Balance = wallet.Credits
+	} else {
+		logger.Warn("Failed to get balance for credits purchased event", zap.String("paymentID", payment.ID.String()), zap.Error(err))
+	}
+
+	if err := s.webhookService.Emit(ctx, payment.UserID, domain.EventCreditsPurchased, data); err != nil {
+		logger.Error("Failed to emit credits purchased webhook event", zap.String("paymentID", payment.ID.String()), zap.Error(err))
+	}
+}
+
+// awaitCheckoutPayment records a checkout paid with a delayed method, its credits are added
+// once the payment succeeded
+func (s *purchaseService) awaitCheckoutPayment(ctx context.Context, sessionData *port.SessionSuccess) error {
+	logger := requestlog.Logger(ctx, s.logger)
+
+	paymentID, err := s.paymentReference(sessionData)
 	if err != nil {
-		s.logger.Error("CRITICAL - Failed adding credits to user account", zap.String("email", sessionData.UserEmail), zap.Int("amount", purchasedOption.Credits), zap.Error(err))
 		return err
 	}
 
+	if err := s.paymentRepo.MarkProcessing(ctx, paymentID, sessionData.PaymentID); err != nil {
+		return err
+	}
+
+	logger.Info("Checkout completed, awaiting delayed payment", zap.String("paymentID", paymentID.String()))
 	return nil
+}
+
+func (s *purchaseService) failCheckout(ctx context.Context, sessionData *port.SessionSuccess) error {
+	logger := requestlog.Logger(ctx, s.logger)
+
+	paymentID, err := s.paymentReference(sessionData)
+	if err != nil {
+		return err
+	}
+
+	// The promo code reservation has expired by now and is no longer counted
+	if err := s.paymentRepo.MarkFailed(ctx, paymentID); err != nil {
+		return err
+	}
 
+	logger.Warn("Delayed payment failed", zap.String("paymentID", paymentID.String()), zap.String("email", sessionData.UserEmail))
+	return nil
+}
+
+// reversePayment takes back the credits of a refunded or disputed payment. Credits that have
+// already been spent cannot be taken back, the user is flagged for review instead.
+func (s *purchaseService) reversePayment(ctx context.Context, reversal *port.PaymentReversal, disputed bool) error {
+	logger := requestlog.Logger(ctx, s.logger)
+
+	flagReason := fmt.Sprintf("payment %s refunded after its credits were spent", reversal.PaymentID)
+	if disputed {
+		flagReason = fmt.Sprintf("payment %s disputed (%s) after its credits were spent", reversal.PaymentID, reversal.Reason)
+	}
+
+	result, err := s.paymentRepo.Reverse(ctx, port.PaymentReversalRequest{
+		ProviderPaymentID: reversal.PaymentID,
+		RefundedMinor:     reversal.AmountMinor,
+		Disputed:          disputed,
+		FlagReason:        flagReason,
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrRecordNotFound) {
+			// Subscription invoices and checkouts started before payments were recorded
+			logger.Warn("No payment recorded for reversed charge", zap.String("providerPaymentID", reversal.PaymentID), zap.String("chargeID", reversal.ChargeID))
+			return domain.ErrUnhandledEvent
+		}
+		return err
+	}
+
+	logFields := []zap.Field{
+		zap.String("paymentID", result.Payment.ID.String()),
+		zap.String("userID", result.Payment.UserID.String()),
+		zap.String("status", string(result.Payment.Status)),
+		zap.Int("reversedCredits", result.Reversed),
+		zap.Int("shortfall", result.Shortfall),
+		zap.String("disputeID", reversal.DisputeID),
+	}
+	s.metrics.CreditsMoved(domain.CreditPurchaseReversal, result.Reversed)
+	s.auditService.Record(ctx, AuditActor{}, domain.AuditPaymentReversed, "payment", result.Payment.ID.String(), map[string]any{
+		"userID":          result.Payment.UserID,
+		"disputed":        disputed,
+		"refundedMinor":   reversal.AmountMinor,
+		"reversedCredits": result.Reversed,
+		"shortfall":       result.Shortfall,
+	})
+	if result.Shortfall > 0 {
+		logger.Warn("Payment reversed after its credits were spent, user flagged", logFields...)
+		return nil
+	}
+	logger.Info("Payment reversed", logFields...)
+	return nil
+}
+
+func (s *purchaseService) paymentReference(sessionData *port.SessionSuccess) (uuid.UUID, error) {
+	paymentID, err := uuid.Parse(sessionData.Reference)
+	if err != nil {
+		s.logger.Error("CRITICAL - Checkout has an invalid payment reference", zap.String("reference", sessionData.Reference), zap.String("email", sessionData.UserEmail))
+		return uuid.Nil, domain.ErrInvalidPurchaseOption
+	}
+	return paymentID, nil
+}
+
+func (s *purchaseService) creditLegacyCheckout(ctx context.Context, sessionData *port.SessionSuccess) error {
+	logger := requestlog.Logger(ctx, s.logger)
+
+	purchasedPackage, err := s.legacyCheckoutPackage(ctx, sessionData.Option)
+	if err != nil {
+		logger.Error("CRITICAL - Completed checkout references an invalid package", zap.String("option", sessionData.Option), zap.String("email", sessionData.UserEmail), zap.Error(err))
+		return err
+	}
+
+	// The session ID is the ledger reference, so a redelivered event does not add the credits twice
+	added, err := s.walletService.AddCredits(ctx, sessionData.UserEmail, purchasedPackage.Credits, port.CreditChange{
+		Kind:      domain.CreditPurchase,
+		Reference: sessionData.SessionID,
+		Reason:    purchasedPackage.Name,
+	})
+	if err != nil {
+		logger.Error("CRITICAL - Failed adding credits to user account", zap.String("email", sessionData.UserEmail), zap.Int("amount", purchasedPackage.Credits), zap.Error(err))
+		return err
+	}
+	if !added {
+		logger.Info("Checkout already credited, ignoring repeated event", zap.String("sessionID", sessionData.SessionID))
+		return nil
+	}
+
+	s.metrics.CreditsMoved(domain.CreditPurchase, purchasedPackage.Credits)
+	s.auditService.Record(ctx, AuditActor{}, domain.AuditCreditsPurchased, "checkout", sessionData.SessionID, map[string]any{
+		"email":   sessionData.UserEmail,
+		"credits": purchasedPackage.Credits,
+		"package": purchasedPackage.Name,
+	})
+	return nil
+}
+
+// legacyCheckoutPackage returns the package a checkout without a payment record was for.
+// Checkouts from before packages were stored carry the credit amount as option, e.g. "250",
+// which is the default package seeded with that many credits. Later ones carry the package ID.
+func (s *purchaseService) legacyCheckoutPackage(ctx context.Context, option string) (*domain.CreditPackage, error) {
+	for _, input := range defaultPackages {
+		if option == strconv.Itoa(input.Credits) {
+			return &domain.CreditPackage{Name: input.Name, Credits: input.Credits}, nil
+		}
+	}
+
+	// The package may have been deactivated or deleted since checkout, the purchase is still honoured
+	packageID, err := uuid.Parse(option)
+	if err != nil {
+		return nil, domain.ErrInvalidPurchaseOption
+	}
+	purchasedPackage, err := s.packageRepo.GetByID(ctx, packageID)
+	if err != nil {
+		if errors.Is(err, domain.ErrRecordNotFound) {
+			return nil, domain.ErrInvalidPurchaseOption
+		}
+		return nil, fmt.Errorf("failed to load purchased package: %w", err)
+	}
+	return purchasedPackage, nil
 }
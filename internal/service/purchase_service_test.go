@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/CP-Payne/wonderpicai/internal/port"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// packageRepoStub finds the packages it holds by ID
+type packageRepoStub struct {
+	port.CreditPackageRepository
+	packages map[uuid.UUID]domain.CreditPackage
+}
+
+func (r *packageRepoStub) GetByID(ctx context.Context, id uuid.UUID) (*domain.CreditPackage, error) {
+	pkg, ok := r.packages[id]
+	if !ok {
+		return nil, domain.ErrRecordNotFound
+	}
+	return &pkg, nil
+}
+
+func TestLegacyCheckoutPackage(t *testing.T) {
+	storedID := uuid.New()
+	s := &purchaseService{packageRepo: &packageRepoStub{packages: map[uuid.UUID]domain.CreditPackage{
+		storedID: {Name: "Custom", Credits: 400},
+	}}}
+
+	tests := []struct {
+		name        string
+		option      string
+		wantName    string
+		wantCredits int
+		wantErr     error
+	}{
+		{name: "starter", option: "100", wantName: "Starter", wantCredits: 100},
+		{name: "creator", option: "250", wantName: "Creator", wantCredits: 250},
+		{name: "pro", option: "700", wantName: "Pro", wantCredits: 700},
+		{name: "studio", option: "1500", wantName: "Studio", wantCredits: 1500},
+		{name: "stored package", option: storedID.String(), wantName: "Custom", wantCredits: 400},
+		{name: "unknown credit amount", option: "500", wantErr: domain.ErrInvalidPurchaseOption},
+		{name: "unknown package", option: uuid.NewString(), wantErr: domain.ErrInvalidPurchaseOption},
+		{name: "empty", option: "", wantErr: domain.ErrInvalidPurchaseOption},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pkg, err := s.legacyCheckoutPackage(context.Background(), tt.option)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("legacyCheckoutPackage(%q) error = %v, want %v", tt.option, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("legacyCheckoutPackage(%q) unexpected error: %v", tt.option, err)
+			}
+			if pkg.Name != tt.wantName || pkg.Credits != tt.wantCredits {
+				t.Errorf("legacyCheckoutPackage(%q) = %s with %d credits, want %s with %d credits", tt.option, pkg.Name, pkg.Credits, tt.wantName, tt.wantCredits)
+			}
+		})
+	}
+}
+
+type userRepoStub struct {
+	port.UserRepository
+	user domain.User
+}
+
+func (r *userRepoStub) GetByID(userID uuid.UUID) (*domain.User, error) {
+	return &r.user, nil
+}
+
+// checkoutPaymentRepoStub records the payment of a checkout and fails attaching its session
+type checkoutPaymentRepoStub struct {
+	port.PaymentRepository
+	attachErr error
+	created   *domain.Payment
+	failed    bool
+}
+
+func (r *checkoutPaymentRepoStub) Create(ctx context.Context, payment *domain.Payment) error {
+	r.created = payment
+	return nil
+}
+
+func (r *checkoutPaymentRepoStub) AttachSession(ctx context.Context, id uuid.UUID, sessionID string) error {
+	return r.attachErr
+}
+
+func (r *checkoutPaymentRepoStub) MarkFailed(ctx context.Context, id uuid.UUID) error {
+	r.failed = true
+	return nil
+}
+
+type checkoutProviderStub struct {
+	port.PaymentProvider
+}
+
+func (p *checkoutProviderStub) CreateCheckoutSession(user port.UserData, product port.ProductData) (*port.CheckoutSession, error) {
+	return &port.CheckoutSession{ID: "cs_test", URL: "https://checkout.example.com/cs_test"}, nil
+}
+
+func TestCreateCheckoutSessionNotStored(t *testing.T) {
+	pkgID := uuid.New()
+
+	tests := []struct {
+		name       string
+		attachErr  error
+		wantURL    string
+		wantFailed bool
+	}{
+		{name: "session stored", wantURL: "https://checkout.example.com/cs_test"},
+		{name: "session not stored", attachErr: errors.New("connection reset"), wantFailed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payments := &checkoutPaymentRepoStub{attachErr: tt.attachErr}
+			s := &purchaseService{
+				logger:   zap.NewNop(),
+				provider: &checkoutProviderStub{},
+				userRepo: &userRepoStub{user: domain.User{Email: "user@example.com", Currency: "usd"}},
+				packageRepo: &packageRepoStub{packages: map[uuid.UUID]domain.CreditPackage{
+					pkgID: {BaseModel: domain.BaseModel{ID: pkgID}, Name: "Starter", Credits: 100, PriceMinor: 500, Currency: "usd", Active: true},
+				}},
+				paymentRepo: payments,
+			}
+
+			url, err := s.CreateCheckout(context.Background(), uuid.New(), pkgID.String(), "en-US", "")
+			if (err != nil) != tt.wantFailed {
+				t.Fatalf("CreateCheckout() error = %v, want error %v", err, tt.wantFailed)
+			}
+			if url != tt.wantURL {
+				t.Errorf("CreateCheckout() = %q, want %q", url, tt.wantURL)
+			}
+			if payments.failed != tt.wantFailed {
+				t.Errorf("payment marked failed = %v, want %v", payments.failed, tt.wantFailed)
+			}
+		})
+	}
+}
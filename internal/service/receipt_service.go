@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/CP-Payne/wonderpicai/internal/context/requestlog"
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/CP-Payne/wonderpicai/internal/money"
+	"github.com/CP-Payne/wonderpicai/internal/port"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// BusinessDetails are the seller details and tax settings printed on receipts
+type BusinessDetails struct {
+	Name    string
+	Address []string
+	TaxID   string
+	Email   string
+	// TaxLabel and TaxRateBps describe the tax included in prices
+	TaxLabel      string
+	TaxRateBps    int
+	InvoicePrefix string
+}
+
+type ReceiptService interface {
+	// ListPurchases returns the user's paid and processing payments, newest first
+	ListPurchases(ctx context.Context, userID uuid.UUID) ([]domain.Payment, error)
+	// ReceiptPDF returns the receipt of one of the user's payments. Payments of other users
+	// are reported as domain.ErrRecordNotFound.
+	ReceiptPDF(ctx context.Context, userID uuid.UUID, paymentID uuid.UUID) (filename string, pdf []byte, err error)
+	// SendReceipt issues the receipt of a completed payment and mails it to the customer
+	SendReceipt(ctx context.Context, paymentID uuid.UUID) error
+}
+
+type receiptService struct {
+	logger      *zap.Logger
+	paymentRepo port.PaymentRepository
+	userRepo    port.UserRepository
+	renderer    port.ReceiptRenderer
+	mailer      port.Mailer
+	business    BusinessDetails
+}
+
+func NewReceiptService(logger *zap.Logger, paymentRepo port.PaymentRepository, userRepo port.UserRepository, renderer port.ReceiptRenderer, mailer port.Mailer, business BusinessDetails) ReceiptService {
+	return &receiptService{
+		logger:      logger.With(zap.String("component", "ReceiptService")),
+		paymentRepo: paymentRepo,
+		userRepo:    userRepo,
+		renderer:    renderer,
+		mailer:      mailer,
+		business:    business,
+	}
+}
+
+func (s *receiptService) ListPurchases(ctx context.Context, userID uuid.UUID) ([]domain.Payment, error) {
+	payments, err := s.paymentRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list purchases: %w", err)
+	}
+	return payments, nil
+}
+
+func (s *receiptService) ReceiptPDF(ctx context.Context, userID uuid.UUID, paymentID uuid.UUID) (string, []byte, error) {
+	payment, err := s.paymentRepo.GetByID(ctx, paymentID)
+	if err != nil {
+		return "", nil, err
+	}
+	if payment.UserID != userID {
+		return "", nil, domain.ErrRecordNotFound
+	}
+
+	receipt, err := s.issue(ctx, payment)
+	if err != nil {
+		return "", nil, err
+	}
+
+	pdf, err := s.renderer.Render(receipt)
+	if err != nil {
+		return "", nil, err
+	}
+	return receiptFilename(receipt), pdf, nil
+}
+
+func (s *receiptService) SendReceipt(ctx context.Context, paymentID uuid.UUID) error {
+	logger := requestlog.Logger(ctx, s.logger)
+
+	payment, err := s.paymentRepo.GetByID(ctx, paymentID)
+	if err != nil {
+		return err
+	}
+
+	receipt, err := s.issue(ctx, payment)
+	if err != nil {
+		return err
+	}
+
+	pdf, err := s.renderer.Render(receipt)
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("Hi %s,\n\nThank you for your purchase of %s (%s).\nYour receipt %s is attached.\n\n%s\n",
+		receipt.Customer.Name,
+		receipt.Lines[0].Description,
+		money.Format(receipt.TotalMinor, receipt.Currency),
+		receipt.InvoiceNumber,
+		s.business.Name,
+	)
+
+	err = s.mailer.Send(ctx, port.MailMessage{
+		To:      receipt.Customer.Email,
+		Subject: fmt.Sprintf("Your %s receipt %s", s.business.Name, receipt.InvoiceNumber),
+		Body:    body,
+		Attachments: []port.MailAttachment{{
+			Filename:    receiptFilename(receipt),
+			ContentType: "application/pdf",
+			Data:        pdf,
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to mail receipt: %w", err)
+	}
+
+	logger.Info("Receipt sent", zap.String("paymentID", payment.ID.String()), zap.String("invoiceNumber", receipt.InvoiceNumber))
+	return nil
+}
+
+// issue builds the receipt of a payment, assigning its invoice number on first use
+func (s *receiptService) issue(ctx context.Context, payment *domain.Payment) (*domain.Receipt, error) {
+	logger := requestlog.Logger(ctx, s.logger)
+
+	if payment.CompletedAt == nil {
+		return nil, domain.ErrReceiptUnavailable
+	}
+
+	if payment.InvoiceNumber == nil {
+		invoiced, err := s.paymentRepo.AssignInvoice(ctx, payment.ID, s.business.TaxRateBps)
+		if err != nil {
+			if !errors.Is(err, domain.ErrReceiptUnavailable) {
+				logger.Error("Failed to assign invoice number", zap.String("paymentID", payment.ID.String()), zap.Error(err))
+			}
+			return nil, err
+		}
+		payment = invoiced
+	}
+
+	user, err := s.userRepo.GetByID(payment.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	description := payment.Description
+	if description == "" {
+		description = "Credit pack"
+	}
+	description = fmt.Sprintf("%s - %d credits", description, payment.Credits)
+
+	tax := domain.InclusiveTax(payment.AmountMinor, payment.TaxRateBps)
+	receipt := &domain.Receipt{
+		InvoiceNumber: fmt.Sprintf("%s%06d", s.business.InvoicePrefix, *payment.InvoiceNumber),
+		IssuedAt:      *payment.InvoicedAt,
+		PaidAt:        *payment.CompletedAt,
+		Seller: domain.ReceiptParty{
+			Name:    s.business.Name,
+			Address: s.business.Address,
+			TaxID:   s.business.TaxID,
+			Email:   s.business.Email,
+		},
+		Customer: domain.ReceiptParty{
+			Name:  user.Username,
+			Email: user.Email,
+		},
+		Lines: []domain.ReceiptLine{{
+			Description: description,
+			Quantity:    1,
+			UnitMinor:   payment.AmountMinor,
+			TotalMinor:  payment.AmountMinor,
+		}},
+		Currency:      payment.Currency,
+		SubtotalMinor: payment.AmountMinor - tax,
+		TaxLabel:      s.business.TaxLabel,
+		TaxRateBps:    payment.TaxRateBps,
+		TaxMinor:      tax,
+		TotalMinor:    payment.AmountMinor,
+	}
+
+	if payment.PromoCodeID != nil {
+		receipt.Notes = append(receipt.Notes, "A promo code discount was applied to this purchase.")
+	}
+	switch payment.Status {
+	case domain.PaymentRefunded:
+		receipt.Notes = append(receipt.Notes, "This payment was refunded in full.")
+	case domain.PaymentDisputed:
+		receipt.Notes = append(receipt.Notes, "This payment is disputed.")
+	default:
+		if payment.RefundedMinor > 0 {
+			receipt.Notes = append(receipt.Notes, fmt.Sprintf("%s of this payment was refunded.", money.Format(payment.RefundedMinor, payment.Currency)))
+		}
+	}
+
+	return receipt, nil
+}
+
+func receiptFilename(receipt *domain.Receipt) string {
+	return fmt.Sprintf("receipt-%s.pdf", strings.ToLower(receipt.InvoiceNumber))
+}
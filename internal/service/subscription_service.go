@@ -0,0 +1,365 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/CP-Payne/wonderpicai/internal/context/requestlog"
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/CP-Payne/wonderpicai/internal/port"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// SubscriptionService sells subscription plans and grants their credits every billing period
+type SubscriptionService interface {
+	ListPlans(ctx context.Context) ([]domain.SubscriptionPlan, error)
+	// Current returns the user's running subscription or domain.ErrNoSubscription
+	Current(ctx context.Context, userID uuid.UUID) (*domain.Subscription, error)
+	CreateCheckout(ctx context.Context, userID uuid.UUID, planID string) (checkoutURL string, err error)
+	// ByCheckoutSession returns the subscription started with a provider checkout session
+	ByCheckoutSession(ctx context.Context, sessionID string) (*domain.Subscription, error)
+	// PortalURL returns the provider page where the user manages or cancels the subscription
+	PortalURL(ctx context.Context, userID uuid.UUID) (string, error)
+	// SeedDefaultPlans creates the default plans on a fresh database
+	SeedDefaultPlans(ctx context.Context) error
+
+	HandleCheckoutCompleted(ctx context.Context, session *port.SessionSuccess) error
+	HandleInvoicePaid(ctx context.Context, invoice *port.InvoicePaid) error
+	HandleSubscriptionChange(ctx context.Context, change *port.SubscriptionChange, canceled bool) error
+}
+
+func intPtr(v int) *int { return &v }
+
+var defaultPlans = []domain.SubscriptionPlan{
+	{Name: "Hobby", CreditsPerPeriod: 300, PriceMinor: 900, Currency: "usd", Interval: "month", RolloverCap: intPtr(150), Active: true, SortOrder: 10},
+	{Name: "Studio", CreditsPerPeriod: 1000, PriceMinor: 2500, Currency: "usd", Interval: "month", RolloverCap: intPtr(500), Active: true, SortOrder: 20},
+}
+
+type subscriptionService struct {
+	logger           *zap.Logger
+	provider         port.PaymentProvider
+	userRepo         port.UserRepository
+	subscriptionRepo port.SubscriptionRepository
+	receiptService   ReceiptService
+	portalReturnURL  string
+}
+
+func NewSubscriptionService(logger *zap.Logger, provider port.PaymentProvider, userRepo port.UserRepository, subscriptionRepo port.SubscriptionRepository, receiptService ReceiptService, portalReturnURL string) SubscriptionService {
+	return &subscriptionService{
+		logger:           logger.With(zap.String("component", "SubscriptionService")),
+		provider:         provider,
+		userRepo:         userRepo,
+		subscriptionRepo: subscriptionRepo,
+		receiptService:   receiptService,
+		portalReturnURL:  portalReturnURL,
+	}
+}
+
+func (s *subscriptionService) ListPlans(ctx context.Context) ([]domain.SubscriptionPlan, error) {
+	plans, err := s.subscriptionRepo.ListActivePlans(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subscription plans: %w", err)
+	}
+	return plans, nil
+}
+
+func (s *subscriptionService) Current(ctx context.Context, userID uuid.UUID) (*domain.Subscription, error) {
+	return s.subscriptionRepo.GetCurrentByUserID(ctx, userID)
+}
+
+func (s *subscriptionService) CreateCheckout(ctx context.Context, userID uuid.UUID, planID string) (string, error) {
+	logger := requestlog.Logger(ctx, s.logger)
+
+	id, err := uuid.Parse(planID)
+	if err != nil {
+		return "", domain.ErrInvalidSubscription
+	}
+
+	plan, err := s.subscriptionRepo.GetPlanByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, domain.ErrRecordNotFound) {
+			return "", domain.ErrInvalidSubscription
+		}
+		return "", err
+	}
+	if !plan.Active || plan.DeletedAt.Valid {
+		return "", domain.ErrInvalidSubscription
+	}
+
+	if _, err := s.subscriptionRepo.GetCurrentByUserID(ctx, userID); err == nil {
+		return "", domain.ErrAlreadySubscribed
+	} else if !errors.Is(err, domain.ErrNoSubscription) {
+		return "", err
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return "", err
+	}
+
+	sub := &domain.Subscription{
+		BaseModel: domain.BaseModel{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		},
+		UserID: userID,
+		PlanID: plan.ID,
+		Status: domain.SubscriptionIncomplete,
+	}
+	if err := s.subscriptionRepo.Create(ctx, sub); err != nil {
+		return "", fmt.Errorf("failed to record subscription: %w", err)
+	}
+
+	checkoutSession, err := s.provider.CreateSubscriptionCheckout(port.UserData{Email: user.Email}, port.SubscriptionData{
+		Name:       fmt.Sprintf("%s - %d credits per %s", plan.Name, plan.CreditsPerPeriod, plan.Interval),
+		PriceMinor: plan.PriceMinor,
+		Currency:   plan.Currency,
+		Interval:   plan.Interval,
+		Reference:  sub.ID.String(),
+	})
+	if err != nil {
+		// A subscription whose checkout never started must not linger as incomplete
+		sub.Status = domain.SubscriptionCanceled
+		if markErr := s.subscriptionRepo.Update(ctx, sub); markErr != nil {
+			logger.Warn("Failed to mark subscription as canceled", zap.String("subscriptionID", sub.ID.String()), zap.Error(markErr))
+		}
+		return "", fmt.Errorf("failed to create subscription checkout: %w", err)
+	}
+
+	sub.CheckoutSessionID = checkoutSession.ID
+	if err := s.subscriptionRepo.Update(ctx, sub); err != nil {
+		// The webhook finds the subscription through the reference, the session ID is informational
+		logger.Warn("Failed to store checkout session on subscription", zap.String("subscriptionID", sub.ID.String()), zap.Error(err))
+	}
+
+	return checkoutSession.URL, nil
+}
+
+func (s *subscriptionService) ByCheckoutSession(ctx context.Context, sessionID string) (*domain.Subscription, error) {
+	return s.subscriptionRepo.GetByCheckoutSessionID(ctx, sessionID)
+}
+
+func (s *subscriptionService) PortalURL(ctx context.Context, userID uuid.UUID) (string, error) {
+	sub, err := s.subscriptionRepo.GetCurrentByUserID(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if sub.ProviderCustomerID == "" {
+		return "", domain.ErrNoSubscription
+	}
+
+	portalURL, err := s.provider.CreatePortalSession(sub.ProviderCustomerID, s.portalReturnURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to create customer portal session: %w", err)
+	}
+	return portalURL, nil
+}
+
+func (s *subscriptionService) SeedDefaultPlans(ctx context.Context) error {
+	logger := requestlog.Logger(ctx, s.logger)
+
+	plans := make([]domain.SubscriptionPlan, len(defaultPlans))
+	for i, plan := range defaultPlans {
+		plan.BaseModel = domain.BaseModel{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		}
+		plans[i] = plan
+	}
+
+	created, err := s.subscriptionRepo.CreatePlansIfEmpty(ctx, plans)
+	if err != nil {
+		return fmt.Errorf("failed to seed default subscription plans: %w", err)
+	}
+	if created {
+		logger.Info("Default subscription plans created", zap.Int("count", len(plans)))
+	}
+	return nil
+}
+
+func (s *subscriptionService) HandleCheckoutCompleted(ctx context.Context, session *port.SessionSuccess) error {
+	logger := requestlog.Logger(ctx, s.logger)
+
+	sub, err := s.findSubscription(ctx, session.Reference, session.SubscriptionID)
+	if err != nil {
+		return err
+	}
+
+	linkProvider(sub, session.SubscriptionID, session.CustomerID)
+	if sub.Status == domain.SubscriptionIncomplete {
+		sub.Status = domain.SubscriptionActive
+	}
+
+	if err := s.subscriptionRepo.Update(ctx, sub); err != nil {
+		return fmt.Errorf("failed to activate subscription: %w", err)
+	}
+
+	logger.Info("Subscription checkout completed",
+		zap.String("subscriptionID", sub.ID.String()),
+		zap.String("userID", sub.UserID.String()),
+		zap.String("plan", sub.Plan.Name),
+	)
+	return nil
+}
+
+func (s *subscriptionService) HandleInvoicePaid(ctx context.Context, invoice *port.InvoicePaid) error {
+	logger := requestlog.Logger(ctx, s.logger)
+
+	sub, err := s.findSubscription(ctx, invoice.Reference, invoice.SubscriptionID)
+	if err != nil {
+		return err
+	}
+
+	// The invoice of a new subscription can arrive before its completed checkout
+	if sub.ProviderSubscriptionID == nil {
+		linkProvider(sub, invoice.SubscriptionID, invoice.CustomerID)
+		if err := s.subscriptionRepo.Update(ctx, sub); err != nil {
+			return fmt.Errorf("failed to link subscription: %w", err)
+		}
+	}
+
+	now := time.Now()
+	grant := &domain.SubscriptionGrant{
+		BaseModel: domain.BaseModel{
+			ID:        uuid.New(),
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		SubscriptionID:    sub.ID,
+		ProviderInvoiceID: invoice.InvoiceID,
+		Credits:           sub.Plan.CreditsPerPeriod,
+		PeriodStart:       invoice.PeriodStart,
+		PeriodEnd:         invoice.PeriodEnd,
+	}
+	// Every invoice is listed with the purchases and receipted like a credit pack
+	payment := &domain.Payment{
+		BaseModel: domain.BaseModel{
+			ID:        uuid.New(),
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		UserID:         sub.UserID,
+		SubscriptionID: &sub.ID,
+		Description:    sub.Plan.Name + " subscription",
+		Credits:        sub.Plan.CreditsPerPeriod,
+		AmountMinor:    invoice.AmountPaid,
+		Currency:       strings.ToLower(invoice.Currency),
+		Status:         domain.PaymentCompleted,
+		CompletedAt:    &now,
+		// Refunds and disputes of the invoice find the payment by it
+		ProviderPaymentID: invoice.PaymentID,
+	}
+
+	granted, err := s.subscriptionRepo.GrantPeriod(ctx, grant, payment)
+	if err != nil {
+		logger.Error("CRITICAL - Failed granting subscription credits", zap.String("subscriptionID", sub.ID.String()), zap.String("invoiceID", invoice.InvoiceID), zap.Error(err))
+		return err
+	}
+	if !granted {
+		logger.Info("Invoice already granted, ignoring repeated event", zap.String("invoiceID", invoice.InvoiceID))
+		return nil
+	}
+
+	logger.Info("Subscription credits granted",
+		zap.String("subscriptionID", sub.ID.String()),
+		zap.String("userID", sub.UserID.String()),
+		zap.Int("credits", grant.Credits),
+		zap.Int("expiredCredits", grant.ExpiredCredits),
+		zap.Int("rolloverCredits", grant.RolloverCredits),
+		zap.Time("periodEnd", grant.PeriodEnd),
+	)
+
+	// Mailing the receipt must not hold up or fail the webhook
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), receiptSendTimeout)
+		defer cancel()
+		if err := s.receiptService.SendReceipt(ctx, payment.ID); err != nil {
+			logger.Error("Failed to send receipt", zap.String("paymentID", payment.ID.String()), zap.Error(err))
+		}
+	}()
+	return nil
+}
+
+func (s *subscriptionService) HandleSubscriptionChange(ctx context.Context, change *port.SubscriptionChange, canceled bool) error {
+	logger := requestlog.Logger(ctx, s.logger)
+
+	sub, err := s.findSubscription(ctx, change.Reference, change.SubscriptionID)
+	if err != nil {
+		return err
+	}
+
+	if status, ok := subscriptionStatus(change.Status); ok {
+		sub.Status = status
+	}
+	if canceled {
+		sub.Status = domain.SubscriptionCanceled
+	}
+	sub.CancelAtPeriodEnd = change.CancelAtPeriodEnd
+	if !change.CurrentPeriodEnd.IsZero() {
+		sub.CurrentPeriodEnd = &change.CurrentPeriodEnd
+	}
+	if !change.CanceledAt.IsZero() {
+		sub.CanceledAt = &change.CanceledAt
+	}
+
+	if err := s.subscriptionRepo.Update(ctx, sub); err != nil {
+		return fmt.Errorf("failed to update subscription: %w", err)
+	}
+
+	logger.Info("Subscription updated",
+		zap.String("subscriptionID", sub.ID.String()),
+		zap.String("status", string(sub.Status)),
+		zap.Bool("cancelAtPeriodEnd", sub.CancelAtPeriodEnd),
+	)
+	return nil
+}
+
+// findSubscription looks a subscription up by our reference, falling back to the provider's ID
+func (s *subscriptionService) findSubscription(ctx context.Context, reference string, providerID string) (*domain.Subscription, error) {
+	logger := requestlog.Logger(ctx, s.logger)
+
+	if id, err := uuid.Parse(reference); err == nil {
+		sub, err := s.subscriptionRepo.GetByID(ctx, id)
+		if err == nil || !errors.Is(err, domain.ErrRecordNotFound) {
+			return sub, err
+		}
+	}
+
+	if providerID != "" {
+		sub, err := s.subscriptionRepo.GetByProviderID(ctx, providerID)
+		if err == nil || !errors.Is(err, domain.ErrRecordNotFound) {
+			return sub, err
+		}
+	}
+
+	logger.Error("CRITICAL - Event for unknown subscription", zap.String("reference", reference), zap.String("providerSubscriptionID", providerID))
+	return nil, domain.ErrRecordNotFound
+}
+
+func linkProvider(sub *domain.Subscription, providerSubscriptionID, customerID string) {
+	if providerSubscriptionID != "" {
+		sub.ProviderSubscriptionID = &providerSubscriptionID
+	}
+	if customerID != "" {
+		sub.ProviderCustomerID = customerID
+	}
+}
+
+// subscriptionStatus maps a provider status to ours
+func subscriptionStatus(status string) (domain.SubscriptionStatus, bool) {
+	switch status {
+	case "active", "trialing":
+		return domain.SubscriptionActive, true
+	case "past_due", "unpaid":
+		return domain.SubscriptionPastDue, true
+	case "canceled", "incomplete_expired":
+		return domain.SubscriptionCanceled, true
+	}
+	return "", false
+}
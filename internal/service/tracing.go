@@ -0,0 +1,25 @@
+package service
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer starts the spans of the services. It uses the global tracer provider, which is a
+// no-op unless tracing is configured.
+var tracer = otel.Tracer("github.com/CP-Payne/wonderpicai/internal/service")
+
+// endSpan marks the span as failed when err is set and ends it
+func endSpan(span trace.Span, err error) {
+	setSpanError(span, err)
+	span.End()
+}
+
+// setSpanError marks the span as failed when err is set
+func setSpanError(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/CP-Payne/wonderpicai/internal/context/requestlog"
 	"github.com/CP-Payne/wonderpicai/internal/domain"
 	"github.com/CP-Payne/wonderpicai/internal/port"
 	"github.com/google/uuid"
@@ -15,7 +16,9 @@ type WalletService interface {
 	DeductForImageGeneration(ctx context.Context, userID uuid.UUID, amount int) error
 	GetWallet(ctx context.Context, userID uuid.UUID) (*domain.Wallet, error)
 	RefundCredits(ctx context.Context, userID uuid.UUID, amount int) error
-	AddCredits(ctx context.Context, email string, amount int) error
+	// AddCredits adds credits once per change kind and reference, it reports false when they
+	// were added before
+	AddCredits(ctx context.Context, email string, amount int, change port.CreditChange) (bool, error)
 }
 
 type walletService struct {
@@ -32,7 +35,7 @@ func NewWalletService(logger *zap.Logger, walletRepo port.WalletRepository) Wall
 
 func (s *walletService) DeductForImageGeneration(ctx context.Context, userID uuid.UUID, amount int) error {
 
-	err := s.walletRepo.SubtractCredits(ctx, userID, amount)
+	err := s.walletRepo.SubtractCredits(ctx, userID, amount, port.CreditChange{Kind: domain.CreditGeneration})
 	if err != nil {
 		if errors.Is(err, domain.ErrInsufficientFunds) {
 			return err
@@ -44,10 +47,11 @@ func (s *walletService) DeductForImageGeneration(ctx context.Context, userID uui
 }
 
 func (s *walletService) GetWallet(ctx context.Context, userID uuid.UUID) (wallet *domain.Wallet, err error) {
+	logger := requestlog.Logger(ctx, s.logger)
 
 	wallet, err = s.walletRepo.GetByUserID(ctx, userID)
 	if err != nil {
-		s.logger.Error("Repository failed to get wallet by user ID", zap.String("userID", userID.String()), zap.Error(err))
+		logger.Error("Repository failed to get wallet by user ID", zap.String("userID", userID.String()), zap.Error(err))
 		return nil, fmt.Errorf("failed to retrieve wallet: %w", err)
 	}
 
@@ -55,25 +59,28 @@ func (s *walletService) GetWallet(ctx context.Context, userID uuid.UUID) (wallet
 }
 
 func (s *walletService) RefundCredits(ctx context.Context, userID uuid.UUID, amount int) error {
+	logger := requestlog.Logger(ctx, s.logger)
 
-	err := s.walletRepo.AddCredits(ctx, userID, amount)
+	err := s.walletRepo.AddCredits(ctx, userID, amount, port.CreditChange{Kind: domain.CreditRefund})
 	if err != nil {
-		s.logger.Error("Failed adding credits to wallet using wallet repository", zap.String("userID", userID.String()), zap.Int("amount", amount))
+		logger.Error("Failed adding credits to wallet using wallet repository", zap.String("userID", userID.String()), zap.Int("amount", amount))
 		return fmt.Errorf("repostiory failed to add credits to wallet: %w", err)
 	}
 
 	return nil
 }
 
-func (s *walletService) AddCredits(ctx context.Context, email string, amount int) error {
-	err := s.walletRepo.AddCreditsToEmail(ctx, email, amount)
+func (s *walletService) AddCredits(ctx context.Context, email string, amount int, change port.CreditChange) (bool, error) {
+	logger := requestlog.Logger(ctx, s.logger)
+
+	added, err := s.walletRepo.AddCreditsToEmail(ctx, email, amount, change)
 	if err != nil {
 		if errors.Is(err, domain.ErrRecordNotFound) {
-			s.logger.Error("Failed adding credits - user does not exist", zap.String("email", email), zap.Error(err))
+			logger.Error("Failed adding credits - user does not exist", zap.String("email", email), zap.Error(err))
 		}
-		s.logger.Error("Failed adding credits to wallet using repository", zap.String("email", email), zap.Error(err))
-		return fmt.Errorf("failed adding credits to wallet using repository: %w", err)
+		logger.Error("Failed adding credits to wallet using repository", zap.String("email", email), zap.Error(err))
+		return false, fmt.Errorf("failed adding credits to wallet using repository: %w", err)
 	}
 
-	return nil
+	return added, nil
 }
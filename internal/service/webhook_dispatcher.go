@@ -0,0 +1,238 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/CP-Payne/wonderpicai/internal/port"
+	"go.uber.org/zap"
+)
+
+const (
+	webhookBaseBackoff = 30 * time.Second
+	webhookMaxBackoff  = time.Hour
+	// webhookResponseLimit is how much of a response body is kept in the delivery log
+	webhookResponseLimit = 2 << 10
+
+	WebhookSignatureHeader = "WonderPic-Signature"
+	WebhookEventHeader     = "WonderPic-Event"
+	WebhookDeliveryHeader  = "WonderPic-Delivery"
+)
+
+var errPrivateTarget = errors.New("webhook target resolves to a private address")
+
+// WebhookDispatcher sends queued webhook deliveries. Every request is signed with the
+// endpoint secret, failed deliveries are retried with exponential backoff until they run
+// out of attempts.
+type WebhookDispatcher struct {
+	logger       *zap.Logger
+	webhookRepo  port.WebhookRepository
+	client       *http.Client
+	workers      int
+	pollInterval time.Duration
+	lease        time.Duration
+}
+
+func NewWebhookDispatcher(logger *zap.Logger, webhookRepo port.WebhookRepository, workers int, pollInterval, timeout time.Duration, allowPrivateTargets bool) *WebhookDispatcher {
+	if workers < 1 {
+		workers = 1
+	}
+	if pollInterval <= 0 {
+		pollInterval = time.Second
+	}
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivateTargets {
+		// Checked on the resolved address, so DNS names cannot point deliveries at internal services
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil || !publicAddr(addrPort.Addr()) {
+				return errPrivateTarget
+			}
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &WebhookDispatcher{
+		logger:      logger.With(zap.String("component", "WebhookDispatcher")),
+		webhookRepo: webhookRepo,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   timeout,
+			// Redirects are reported as failed deliveries instead of being followed
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		workers:      workers,
+		pollInterval: pollInterval,
+		lease:        2*timeout + 30*time.Second,
+	}
+}
+
+// Start runs the dispatcher pool until ctx is cancelled.
+func (d *WebhookDispatcher) Start(ctx context.Context) {
+	for i := 0; i < d.workers; i++ {
+		go d.run(ctx)
+	}
+	<-ctx.Done()
+}
+
+func (d *WebhookDispatcher) run(ctx context.Context) {
+	ticker := time.NewTicker(d.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		for d.processNext(ctx) {
+			if ctx.Err() != nil {
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// processNext claims and sends a single delivery. It reports whether a delivery was found.
+func (d *WebhookDispatcher) processNext(ctx context.Context) bool {
+	deliveries, err := d.webhookRepo.ClaimDeliveries(ctx, 1, d.lease)
+	if err != nil {
+		if ctx.Err() == nil {
+			d.logger.Error("Failed to claim webhook delivery", zap.Error(err))
+		}
+		return false
+	}
+	if len(deliveries) == 0 {
+		return false
+	}
+
+	for _, delivery := range deliveries {
+		d.deliver(ctx, &delivery)
+	}
+	return true
+}
+
+func (d *WebhookDispatcher) deliver(ctx context.Context, delivery *domain.WebhookDelivery) {
+	logger := d.logger.With(
+		zap.String("deliveryID", delivery.ID.String()),
+		zap.String("endpointID", delivery.EndpointID.String()),
+		zap.String("event", string(delivery.EventType)),
+		zap.Int("attempt", delivery.Attempts),
+	)
+
+	endpoint := &delivery.Endpoint
+	if endpoint.DeletedAt.Valid || !endpoint.Active {
+		if err := d.webhookRepo.MarkAttemptFailed(ctx, delivery.ID, port.WebhookAttempt{Error: "endpoint disabled"}); err != nil {
+			logger.Error("Failed to fail delivery of disabled endpoint", zap.Error(err))
+		}
+		return
+	}
+
+	status, body, err := d.send(ctx, endpoint, delivery)
+	if err == nil && status >= 200 && status < 300 {
+		if err := d.webhookRepo.MarkDelivered(ctx, delivery.ID, status, body); err != nil {
+			// The lease expires and the delivery is sent again, receivers deduplicate by event ID
+			logger.Error("Failed to mark webhook delivery as delivered", zap.Error(err))
+			return
+		}
+		logger.Debug("Webhook delivered", zap.Int("status", status))
+		return
+	}
+
+	if ctx.Err() != nil {
+		// Shutting down, the lease expires and another dispatcher picks the delivery up
+		return
+	}
+
+	attempt := port.WebhookAttempt{ResponseStatus: status, ResponseBody: body}
+	if err != nil {
+		attempt.Error = err.Error()
+	} else {
+		attempt.Error = fmt.Sprintf("endpoint responded with status %d", status)
+	}
+
+	if delivery.Attempts < delivery.MaxAttempts {
+		next := time.Now().Add(webhookBackoff(delivery.Attempts))
+		attempt.NextAttemptAt = &next
+		logger.Info("Webhook delivery failed, rescheduling", zap.Time("nextAttemptAt", next), zap.String("error", attempt.Error))
+	} else {
+		logger.Warn("Webhook delivery failed permanently", zap.String("error", attempt.Error))
+	}
+
+	if err := d.webhookRepo.MarkAttemptFailed(ctx, delivery.ID, attempt); err != nil {
+		logger.Error("Failed to record failed webhook attempt", zap.Error(err))
+	}
+}
+
+// send posts the payload and returns the response status and the start of the response body
+func (d *WebhookDispatcher) send(ctx context.Context, endpoint *domain.WebhookEndpoint, delivery *domain.WebhookDelivery) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader([]byte(delivery.Payload)))
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid webhook request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "WonderPicAI-Webhooks/1.0")
+	req.Header.Set(WebhookEventHeader, string(delivery.EventType))
+	req.Header.Set(WebhookDeliveryHeader, delivery.ID.String())
+	req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(endpoint.Secret, time.Now(), []byte(delivery.Payload)))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseLimit))
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	return resp.StatusCode, string(body), nil
+}
+
+// SignWebhookPayload returns the signature header value "t=<unix>,v1=<hex>" where v1 is the
+// HMAC-SHA256 of "<unix>.<payload>" keyed with the endpoint secret. Receivers recompute it and
+// reject old timestamps to prevent replays.
+func SignWebhookPayload(secret string, at time.Time, payload []byte) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookBackoff returns an exponentially growing wait with jitter
+func webhookBackoff(attempt int) time.Duration {
+	ceiling := webhookBaseBackoff << (attempt - 1)
+	if ceiling > webhookMaxBackoff || ceiling <= 0 {
+		ceiling = webhookMaxBackoff
+	}
+	return ceiling/2 + time.Duration(rand.Int64N(int64(ceiling/2)+1))
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSignWebhookPayload(t *testing.T) {
+	at := time.Unix(1700000000, 0)
+	payload := []byte(`{"event":"test"}`)
+
+	tests := []struct {
+		name    string
+		secret  string
+		at      time.Time
+		payload []byte
+		want    string
+	}{
+		{
+			name:    "known signature",
+			secret:  "whsec_test",
+			at:      at,
+			payload: payload,
+			want:    "t=1700000000,v1=21d2d3606ebbdbf9307ee15e83085df2b83c83dd87cc2e6d2ea6b1cb61afdc3c",
+		},
+		{name: "other secret", secret: "whsec_other", at: at, payload: payload},
+		{name: "other time", secret: "whsec_test", at: at.Add(time.Second), payload: payload},
+		{name: "other payload", secret: "whsec_test", at: at, payload: []byte(`{"event":"other"}`)},
+	}
+
+	known := SignWebhookPayload("whsec_test", at, payload)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SignWebhookPayload(tt.secret, tt.at, tt.payload)
+			if tt.want != "" {
+				if got != tt.want {
+					t.Errorf("SignWebhookPayload() = %q, want %q", got, tt.want)
+				}
+				return
+			}
+			if got == known {
+				t.Errorf("SignWebhookPayload() = %q, want a different signature", got)
+			}
+		})
+	}
+}
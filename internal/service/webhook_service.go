@@ -0,0 +1,298 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/CP-Payne/wonderpicai/internal/context/requestlog"
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/CP-Payne/wonderpicai/internal/port"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// deliveryLogLimit is the number of deliveries shown in an endpoint's delivery log
+const deliveryLogLimit = 50
+
+// WebhookService manages user webhook endpoints and queues events for delivery.
+// Queued deliveries are sent by the WebhookDispatcher.
+type WebhookService interface {
+	ListEndpoints(ctx context.Context, userID uuid.UUID) ([]domain.WebhookEndpoint, error)
+	Endpoint(ctx context.Context, userID uuid.UUID, id uuid.UUID) (*domain.WebhookEndpoint, error)
+	CreateEndpoint(ctx context.Context, userID uuid.UUID, input WebhookEndpointInput) (*domain.WebhookEndpoint, error)
+	SetEndpointActive(ctx context.Context, userID uuid.UUID, id uuid.UUID, active bool) (*domain.WebhookEndpoint, error)
+	DeleteEndpoint(ctx context.Context, userID uuid.UUID, id uuid.UUID) error
+	ListDeliveries(ctx context.Context, userID uuid.UUID, endpointID uuid.UUID) ([]domain.WebhookDelivery, error)
+	Redeliver(ctx context.Context, userID uuid.UUID, deliveryID uuid.UUID) (*domain.WebhookDelivery, error)
+	// Emit queues the event for every active endpoint of the user subscribed to it.
+	// Callers log the error, a failed emit must not fail the flow that caused the event.
+	Emit(ctx context.Context, userID uuid.UUID, event domain.WebhookEventType, data any) error
+}
+
+type WebhookEndpointInput struct {
+	URL    string
+	Events []domain.WebhookEventType
+}
+
+// PromptEventData is the data of prompt.completed and prompt.failed events
+type PromptEventData struct {
+	PromptID uuid.UUID   `json:"prompt_id"`
+	Status   string      `json:"status"`
+	ImageIDs []uuid.UUID `json:"image_ids"`
+	Cost     int         `json:"cost"`
+	// Refunded is set when a failed prompt's credits were refunded
+	Refunded bool `json:"refunded,omitempty"`
+}
+
+// NewPromptEventData builds the event data of a prompt, listing the images that were generated
+func NewPromptEventData(prompt *domain.Prompt, refunded bool) PromptEventData {
+	data := PromptEventData{
+		PromptID: prompt.ID,
+		Status:   prompt.Status.APIName(),
+		ImageIDs: make([]uuid.UUID, 0, len(prompt.Images)),
+		Cost:     prompt.Cost,
+		Refunded: refunded,
+	}
+	for _, image := range prompt.Images {
+		if image.Status != domain.Failed {
+			data.ImageIDs = append(data.ImageIDs, image.ID)
+		}
+	}
+	return data
+}
+
+// CreditsPurchasedEventData is the data of credits.purchased events
+type CreditsPurchasedEventData struct {
+	PaymentID   uuid.UUID `json:"payment_id"`
+	Description string    `json:"description,omitempty"`
+	Credits     int       `json:"credits"`
+	AmountMinor int64     `json:"amount_minor"`
+	Currency    string    `json:"currency"`
+	Balance     uint      `json:"balance"`
+}
+
+// CreditsLowEventData is the data of credits.low events
+type CreditsLowEventData struct {
+	Balance   uint `json:"balance"`
+	Threshold int  `json:"threshold"`
+}
+
+type webhookService struct {
+	logger              *zap.Logger
+	webhookRepo         port.WebhookRepository
+	maxAttempts         int
+	allowPrivateTargets bool
+	auditService        AuditService
+}
+
+func NewWebhookService(logger *zap.Logger, webhookRepo port.WebhookRepository, maxAttempts int, allowPrivateTargets bool, auditService AuditService) WebhookService {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	return &webhookService{
+		logger:              logger.With(zap.String("component", "WebhookService")),
+		webhookRepo:         webhookRepo,
+		maxAttempts:         maxAttempts,
+		allowPrivateTargets: allowPrivateTargets,
+		auditService:        auditService,
+	}
+}
+
+func (s *webhookService) ListEndpoints(ctx context.Context, userID uuid.UUID) ([]domain.WebhookEndpoint, error) {
+	endpoints, err := s.webhookRepo.ListEndpoints(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook endpoints: %w", err)
+	}
+	return endpoints, nil
+}
+
+func (s *webhookService) Endpoint(ctx context.Context, userID uuid.UUID, id uuid.UUID) (*domain.WebhookEndpoint, error) {
+	endpoint, err := s.webhookRepo.GetEndpoint(ctx, userID, id)
+	if err != nil {
+		if errors.Is(err, domain.ErrRecordNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to get webhook endpoint: %w", err)
+	}
+	return endpoint, nil
+}
+
+func (s *webhookService) CreateEndpoint(ctx context.Context, userID uuid.UUID, input WebhookEndpointInput) (*domain.WebhookEndpoint, error) {
+	logger := requestlog.Logger(ctx, s.logger)
+
+	target, err := s.validateURL(input.URL)
+	if err != nil {
+		return nil, err
+	}
+	if len(input.Events) == 0 {
+		return nil, fmt.Errorf("at least one event is required: %w", domain.ErrInvalidWebhookEvent)
+	}
+	for _, event := range input.Events {
+		if !event.Valid() {
+			return nil, fmt.Errorf("unknown event %q: %w", event, domain.ErrInvalidWebhookEvent)
+		}
+	}
+
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		logger.Error("Failed to generate webhook secret", zap.Error(err))
+		return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+
+	now := time.Now()
+	endpoint := &domain.WebhookEndpoint{
+		BaseModel: domain.BaseModel{
+			ID:        uuid.New(),
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		UserID: userID,
+		URL:    target,
+		Secret: secret,
+		Events: domain.JoinEvents(input.Events),
+		Active: true,
+	}
+
+	if err := s.webhookRepo.CreateEndpoint(ctx, endpoint); err != nil {
+		return nil, fmt.Errorf("failed to store webhook endpoint: %w", err)
+	}
+
+	logger.Info("Webhook endpoint created", zap.String("userID", userID.String()), zap.String("endpointID", endpoint.ID.String()), zap.String("events", endpoint.Events))
+	s.auditService.Record(ctx, AuditActor{UserID: userID}, domain.AuditWebhookCreated, "webhook", endpoint.ID.String(), map[string]any{
+		"url":    endpoint.URL,
+		"events": endpoint.Events,
+	})
+	return endpoint, nil
+}
+
+func (s *webhookService) SetEndpointActive(ctx context.Context, userID uuid.UUID, id uuid.UUID, active bool) (*domain.WebhookEndpoint, error) {
+	endpoint, err := s.webhookRepo.SetEndpointActive(ctx, userID, id, active)
+	if err != nil {
+		if errors.Is(err, domain.ErrRecordNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to update webhook endpoint: %w", err)
+	}
+	return endpoint, nil
+}
+
+func (s *webhookService) DeleteEndpoint(ctx context.Context, userID uuid.UUID, id uuid.UUID) error {
+	logger := requestlog.Logger(ctx, s.logger)
+
+	if err := s.webhookRepo.DeleteEndpoint(ctx, userID, id); err != nil {
+		if errors.Is(err, domain.ErrRecordNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to delete webhook endpoint: %w", err)
+	}
+
+	logger.Info("Webhook endpoint deleted", zap.String("userID", userID.String()), zap.String("endpointID", id.String()))
+	s.auditService.Record(ctx, AuditActor{UserID: userID}, domain.AuditWebhookDeleted, "webhook", id.String(), nil)
+	return nil
+}
+
+func (s *webhookService) ListDeliveries(ctx context.Context, userID uuid.UUID, endpointID uuid.UUID) ([]domain.WebhookDelivery, error) {
+	deliveries, err := s.webhookRepo.ListDeliveries(ctx, userID, endpointID, deliveryLogLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+func (s *webhookService) Redeliver(ctx context.Context, userID uuid.UUID, deliveryID uuid.UUID) (*domain.WebhookDelivery, error) {
+	logger := requestlog.Logger(ctx, s.logger)
+
+	delivery, err := s.webhookRepo.Redeliver(ctx, userID, deliveryID, s.maxAttempts)
+	if err != nil {
+		if errors.Is(err, domain.ErrRecordNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to redeliver webhook: %w", err)
+	}
+
+	logger.Info("Webhook redelivery queued", zap.String("userID", userID.String()), zap.String("deliveryID", deliveryID.String()), zap.String("redeliveryID", delivery.ID.String()))
+	return delivery, nil
+}
+
+func (s *webhookService) Emit(ctx context.Context, userID uuid.UUID, event domain.WebhookEventType, data any) error {
+	logger := requestlog.Logger(ctx, s.logger)
+
+	eventID := uuid.New()
+	payload, err := json.Marshal(domain.WebhookEvent{
+		ID:        eventID,
+		Type:      event,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", event, err)
+	}
+
+	queued, err := s.webhookRepo.EnqueueEvent(ctx, userID, event, eventID, string(payload), s.maxAttempts)
+	if err != nil {
+		return fmt.Errorf("failed to queue %s event: %w", event, err)
+	}
+
+	if queued > 0 {
+		logger.Debug("Webhook event queued", zap.String("event", string(event)), zap.String("eventID", eventID.String()), zap.Int("deliveries", queued))
+	}
+	return nil
+}
+
+// validateURL accepts absolute https URLs. Plain http and private addresses are only
+// accepted when private targets are allowed, the dispatcher checks resolved addresses again.
+func (s *webhookService) validateURL(raw string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("%w: %s is not an absolute URL", domain.ErrInvalidWebhookURL, raw)
+	}
+	if u.User != nil {
+		return "", fmt.Errorf("%w: credentials are not allowed in the URL", domain.ErrInvalidWebhookURL)
+	}
+
+	switch u.Scheme {
+	case "https":
+	case "http":
+		if !s.allowPrivateTargets {
+			return "", fmt.Errorf("%w: the URL must use https", domain.ErrInvalidWebhookURL)
+		}
+	default:
+		return "", fmt.Errorf("%w: the URL must use https", domain.ErrInvalidWebhookURL)
+	}
+
+	if !s.allowPrivateTargets {
+		host := u.Hostname()
+		if strings.EqualFold(host, "localhost") || strings.HasSuffix(strings.ToLower(host), ".localhost") {
+			return "", fmt.Errorf("%w: private addresses are not allowed", domain.ErrInvalidWebhookURL)
+		}
+		if addr, err := netip.ParseAddr(host); err == nil && !publicAddr(addr) {
+			return "", fmt.Errorf("%w: private addresses are not allowed", domain.ErrInvalidWebhookURL)
+		}
+	}
+
+	u.Fragment = ""
+	return u.String(), nil
+}
+
+// publicAddr reports whether addr is routable on the internet
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate()
+}
+
+func generateWebhookSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return "whsec_" + base64.RawURLEncoding.EncodeToString(secret), nil
+}
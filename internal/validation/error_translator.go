@@ -38,6 +38,8 @@ func TranslateValidationErrors(err error) (map[string]string, string) {
 				errorMessage = fmt.Sprintf("%s must be greater than or equal to %s", fieldName, fieldError.Param())
 			case "lte":
 				errorMessage = fmt.Sprintf("%s must be less than or equal to %s", fieldName, fieldError.Param())
+			case "imagesize":
+				errorMessage = "Please select one of the available image sizes."
 			case "passwordcomplexity":
 				errorMessage = "Password must be at least 8 characters long and include an uppercase letter, lowercase letter, number, and special character."
 			default:
@@ -0,0 +1,11 @@
+package validation
+
+import (
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/go-playground/validator/v10"
+)
+
+func validateImageSize(fl validator.FieldLevel) bool {
+	_, ok := domain.ImageSizeByKey(fl.Field().String())
+	return ok
+}
@@ -10,5 +10,10 @@ func New() *validator.Validate {
 		panic("Failed to register passwordcomplexity validator: " + err.Error())
 	}
 
+	err = validate.RegisterValidation("imagesize", validateImageSize)
+	if err != nil {
+		panic("Failed to register imagesize validator: " + err.Error())
+	}
+
 	return validate
 }
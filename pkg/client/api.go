@@ -0,0 +1,130 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// CreateGeneration debits the cost of the generation and queues the prompt.
+// The returned prompt is pending, use WaitForPrompt to wait for its images.
+func (c *Client) CreateGeneration(ctx context.Context, req GenerationRequest) (*Prompt, error) {
+	var prompt Prompt
+	err := c.doJSON(ctx, request{method: http.MethodPost, path: "/api/v1/generations", body: req}, &prompt)
+	if err != nil {
+		return nil, err
+	}
+	return &prompt, nil
+}
+
+func (c *Client) GetPrompt(ctx context.Context, id string) (*Prompt, error) {
+	var prompt Prompt
+	err := c.doJSON(ctx, request{method: http.MethodGet, path: "/api/v1/prompts/" + url.PathEscape(id)}, &prompt)
+	if err != nil {
+		return nil, err
+	}
+	return &prompt, nil
+}
+
+// WaitForPrompt polls the prompt every interval until it is no longer pending
+func (c *Client) WaitForPrompt(ctx context.Context, id string, interval time.Duration) (*Prompt, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		prompt, err := c.GetPrompt(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if prompt.Status.Done() {
+			return prompt, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return prompt, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// ListPrompts returns a page of prompts, newest first
+func (c *Client) ListPrompts(ctx context.Context, opts ListOptions) (*PromptList, error) {
+	var list PromptList
+	err := c.doJSON(ctx, request{method: http.MethodGet, path: "/api/v1/prompts", query: opts.query()}, &list)
+	if err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// ListImages returns a page of images, newest first
+func (c *Client) ListImages(ctx context.Context, opts ListOptions) (*ImageList, error) {
+	var list ImageList
+	err := c.doJSON(ctx, request{method: http.MethodGet, path: "/api/v1/images", query: opts.query()}, &list)
+	if err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// OpenImage starts the download of a completed image. The caller must close the returned body.
+func (c *Client) OpenImage(ctx context.Context, id string) (io.ReadCloser, error) {
+	resp, err := c.do(ctx, request{
+		method: http.MethodGet,
+		path:   "/api/v1/images/" + url.PathEscape(id) + "/content",
+		accept: "image/*",
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// DownloadImage streams a completed image to w and returns the number of bytes written
+func (c *Client) DownloadImage(ctx context.Context, id string, w io.Writer) (int64, error) {
+	body, err := c.OpenImage(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+
+	n, err := io.Copy(w, body)
+	if err != nil {
+		return n, fmt.Errorf("failed to download image %s: %w", id, err)
+	}
+	return n, nil
+}
+
+// DeleteImage deletes an image, deleting an image that does not exist succeeds
+func (c *Client) DeleteImage(ctx context.Context, id string) error {
+	return c.doJSON(ctx, request{method: http.MethodDelete, path: "/api/v1/images/" + url.PathEscape(id)}, nil)
+}
+
+func (c *Client) DeleteFailedImages(ctx context.Context) error {
+	return c.doJSON(ctx, request{method: http.MethodDelete, path: "/api/v1/images/failed"}, nil)
+}
+
+func (c *Client) GetWallet(ctx context.Context) (*Wallet, error) {
+	var wallet Wallet
+	err := c.doJSON(ctx, request{method: http.MethodGet, path: "/api/v1/wallet"}, &wallet)
+	if err != nil {
+		return nil, err
+	}
+	return &wallet, nil
+}
+
+func (o ListOptions) query() url.Values {
+	query := url.Values{}
+	if o.Limit > 0 {
+		query.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if o.Offset > 0 {
+		query.Set("offset", strconv.Itoa(o.Offset))
+	}
+	return query
+}
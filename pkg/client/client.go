@@ -0,0 +1,262 @@
+// Package client is a Go client for the WonderPicAI JSON API (/api/v1).
+//
+// Requests are authenticated with a personal API key created on the account page:
+//
+//	c, err := client.New("https://wonderpic.example.com", os.Getenv("WONDERPIC_API_KEY"))
+//	prompt, err := c.CreateGeneration(ctx, client.GenerationRequest{Prompt: "a red fox", ImageCount: 1, Size: "square"})
+//	prompt, err = c.WaitForPrompt(ctx, prompt.ID, 2*time.Second)
+//
+// Failed requests return an *Error holding the problem details sent by the server.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTimeout    = 60 * time.Second
+	defaultMaxRetries = 3
+	defaultMinBackoff = 500 * time.Millisecond
+	defaultMaxBackoff = 10 * time.Second
+	// maxRetryAfter caps the wait requested by a Retry-After header
+	maxRetryAfter = time.Minute
+)
+
+type Client struct {
+	baseURL    *url.URL
+	apiKey     string
+	httpClient *http.Client
+	userAgent  string
+	maxRetries int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client used for requests. Image downloads are streamed,
+// so a client timeout also limits how long a download may take.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithRetries sets how often a request is retried after a network error, a 429 or a 502-504 response.
+// Generations are only retried after a 429, as other failures may have queued the prompt.
+func WithRetries(maxRetries int) Option {
+	return func(c *Client) {
+		c.maxRetries = max(maxRetries, 0)
+	}
+}
+
+// WithBackoff sets the bounds of the exponential backoff between retries
+func WithBackoff(minBackoff, maxBackoff time.Duration) Option {
+	return func(c *Client) {
+		c.minBackoff = minBackoff
+		c.maxBackoff = max(maxBackoff, minBackoff)
+	}
+}
+
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// New returns a client for the server at baseURL, e.g. "https://wonderpic.example.com"
+func New(baseURL, apiKey string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(strings.TrimRight(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid base URL %q: scheme must be http or https", baseURL)
+	}
+	if apiKey == "" {
+		return nil, errors.New("API key is required")
+	}
+
+	c := &Client{
+		baseURL:    u,
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: defaultTimeout},
+		userAgent:  "wonderpic-go",
+		maxRetries: defaultMaxRetries,
+		minBackoff: defaultMinBackoff,
+		maxBackoff: defaultMaxBackoff,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// request describes an API call. Body is encoded once so it can be sent again on retries.
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	body   any
+	accept string
+}
+
+// do sends the request, retrying transient failures, and returns the successful response.
+// Responses with an error status are returned as *Error.
+func (c *Client) do(ctx context.Context, req request) (*http.Response, error) {
+	var payload []byte
+	if req.body != nil {
+		var err error
+		if payload, err = json.Marshal(req.body); err != nil {
+			return nil, fmt.Errorf("failed to encode request: %w", err)
+		}
+	}
+
+	target := *c.baseURL
+	target.Path += req.path
+	target.RawQuery = req.query.Encode()
+
+	for attempt := 0; ; attempt++ {
+		httpReq, err := http.NewRequestWithContext(ctx, req.method, target.String(), bytes.NewReader(payload))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+		httpReq.Header.Set("User-Agent", c.userAgent)
+		httpReq.Header.Set("Accept", req.accept)
+		if payload != nil {
+			httpReq.Header.Set("Content-Type", "application/json")
+		}
+
+		resp, err := c.httpClient.Do(httpReq)
+		retriesLeft := attempt < c.maxRetries
+
+		if err != nil {
+			if ctx.Err() != nil || !retriesLeft || !idempotent(req.method) {
+				return nil, fmt.Errorf("%s %s: %w", req.method, req.path, err)
+			}
+			if err := c.wait(ctx, attempt, 0); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if resp.StatusCode < 300 {
+			return resp, nil
+		}
+
+		if retriesLeft && retryable(req.method, resp.StatusCode) {
+			retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"))
+			drain(resp)
+			if err := c.wait(ctx, attempt, retryAfter); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		defer drain(resp)
+		return nil, decodeError(resp)
+	}
+}
+
+func (c *Client) doJSON(ctx context.Context, req request, out any) error {
+	req.accept = "application/json"
+	resp, err := c.do(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer drain(resp)
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s %s response: %w", req.method, req.path, err)
+	}
+	return nil
+}
+
+// wait sleeps before the next attempt, for at least retryAfter
+func (c *Client) wait(ctx context.Context, attempt int, retryAfter time.Duration) error {
+	// Full jitter spreads out clients retrying at the same time
+	delay := time.Duration(rand.Int64N(int64(c.backoff(attempt)) + 1))
+	if retryAfter > delay {
+		delay = retryAfter
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// backoff returns the longest wait before the attempt after the given one
+func (c *Client) backoff(attempt int) time.Duration {
+	backoff := c.minBackoff << attempt
+	if backoff <= 0 || backoff > c.maxBackoff {
+		backoff = c.maxBackoff
+	}
+	return backoff
+}
+
+func idempotent(method string) bool {
+	return method != http.MethodPost
+}
+
+func retryable(method string, statusCode int) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return idempotent(method)
+	default:
+		return false
+	}
+}
+
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	var d time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		d = time.Duration(seconds) * time.Second
+	} else if at, err := http.ParseTime(value); err == nil {
+		d = time.Until(at)
+	}
+	return min(max(d, 0), maxRetryAfter)
+}
+
+func decodeError(resp *http.Response) error {
+	apiErr := &Error{StatusCode: resp.StatusCode}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err := json.Unmarshal(body, apiErr); err != nil || apiErr.Title == "" {
+		apiErr.Title = http.StatusText(resp.StatusCode)
+		if apiErr.Detail == "" {
+			apiErr.Detail = strings.TrimSpace(string(body))
+		}
+	}
+	apiErr.StatusCode = resp.StatusCode
+	return apiErr
+}
+
+// drain reads the rest of the body so the connection can be reused
+func drain(resp *http.Response) {
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+}
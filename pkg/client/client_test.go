@@ -0,0 +1,219 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		method     string
+		statusCode int
+		want       bool
+	}{
+		{method: http.MethodGet, statusCode: http.StatusTooManyRequests, want: true},
+		{method: http.MethodPost, statusCode: http.StatusTooManyRequests, want: true},
+		{method: http.MethodGet, statusCode: http.StatusBadGateway, want: true},
+		{method: http.MethodGet, statusCode: http.StatusServiceUnavailable, want: true},
+		{method: http.MethodDelete, statusCode: http.StatusGatewayTimeout, want: true},
+		{method: http.MethodPost, statusCode: http.StatusServiceUnavailable, want: false},
+		{method: http.MethodGet, statusCode: http.StatusInternalServerError, want: false},
+		{method: http.MethodGet, statusCode: http.StatusNotFound, want: false},
+		{method: http.MethodPost, statusCode: http.StatusPaymentRequired, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+http.StatusText(tt.statusCode), func(t *testing.T) {
+			if got := retryable(tt.method, tt.statusCode); got != tt.want {
+				t.Errorf("retryable(%s, %d) = %v, want %v", tt.method, tt.statusCode, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{name: "empty", value: "", want: 0},
+		{name: "seconds", value: "3", want: 3 * time.Second},
+		{name: "negative", value: "-5", want: 0},
+		{name: "capped", value: "3600", want: maxRetryAfter},
+		{name: "past date", value: "Mon, 02 Jan 2006 15:04:05 GMT", want: 0},
+		{name: "invalid", value: "soon", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRetryAfter(tt.value); got != tt.want {
+				t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("future date", func(t *testing.T) {
+		value := time.Now().Add(30 * time.Second).UTC().Format(http.TimeFormat)
+		if got := parseRetryAfter(value); got <= 25*time.Second || got > 30*time.Second {
+			t.Errorf("parseRetryAfter(%q) = %v, want about 30s", value, got)
+		}
+	})
+}
+
+func TestBackoff(t *testing.T) {
+	c := &Client{minBackoff: 500 * time.Millisecond, maxBackoff: 10 * time.Second}
+
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 0, want: 500 * time.Millisecond},
+		{attempt: 1, want: time.Second},
+		{attempt: 4, want: 8 * time.Second},
+		{attempt: 5, want: 10 * time.Second},
+		// Shifting past the width of a Duration must not wrap around to no wait
+		{attempt: 64, want: 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := c.backoff(tt.attempt); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		post       bool
+		statuses   []int
+		maxRetries int
+		wantCalls  int
+		wantStatus int
+	}{
+		{name: "get succeeds after unavailable", statuses: []int{503, 502, 200}, maxRetries: 3, wantCalls: 3},
+		{name: "get gives up", statuses: []int{503, 503, 503, 503}, maxRetries: 2, wantCalls: 3, wantStatus: 503},
+		{name: "get is not retried on client errors", statuses: []int{404}, maxRetries: 3, wantCalls: 1, wantStatus: 404},
+		{name: "get is not retried on server errors", statuses: []int{500}, maxRetries: 3, wantCalls: 1, wantStatus: 500},
+		{name: "post is retried when rate limited", post: true, statuses: []int{429, 200}, maxRetries: 3, wantCalls: 2},
+		{name: "post is not retried when unavailable", post: true, statuses: []int{503}, maxRetries: 3, wantCalls: 1, wantStatus: 503},
+		{name: "retries disabled", statuses: []int{503}, maxRetries: 0, wantCalls: 1, wantStatus: 503},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := int(calls.Add(1))
+				if n > len(tt.statuses) {
+					t.Errorf("unexpected request %d", n)
+					w.WriteHeader(http.StatusTeapot)
+					return
+				}
+				if status := tt.statuses[n-1]; status != http.StatusOK {
+					w.Header().Set("Content-Type", "application/problem+json")
+					w.WriteHeader(status)
+					fmt.Fprintf(w, `{"title":%q,"status":%d}`, http.StatusText(status), status)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"id":"p1","status":"pending","credits":5}`))
+			}))
+			defer server.Close()
+
+			c, err := New(server.URL, "key", WithRetries(tt.maxRetries), WithBackoff(time.Millisecond, time.Millisecond))
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			if tt.post {
+				_, err = c.CreateGeneration(context.Background(), GenerationRequest{Prompt: "a red fox", ImageCount: 1, Size: "square"})
+			} else {
+				_, err = c.GetWallet(context.Background())
+			}
+
+			if got := int(calls.Load()); got != tt.wantCalls {
+				t.Errorf("server got %d requests, want %d", got, tt.wantCalls)
+			}
+			if tt.wantStatus == 0 {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !IsStatus(err, tt.wantStatus) {
+				t.Errorf("error = %v, want API error with status %d", err, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestRetriesAfterNetworkErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		post      bool
+		wantCalls int
+		wantErr   bool
+	}{
+		{name: "get is sent again", wantCalls: 2},
+		// The server may have queued the prompt before the connection broke
+		{name: "post is not sent again", post: true, wantCalls: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if calls.Add(1) == 1 {
+					// Drop the connection without a response
+					conn, _, err := w.(http.Hijacker).Hijack()
+					if err != nil {
+						t.Errorf("failed to hijack connection: %v", err)
+						return
+					}
+					conn.Close()
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"id":"p1","status":"pending","credits":5}`))
+			}))
+			defer server.Close()
+
+			c, err := New(server.URL, "key", WithBackoff(time.Millisecond, time.Millisecond))
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			if tt.post {
+				_, err = c.CreateGeneration(context.Background(), GenerationRequest{Prompt: "a red fox", ImageCount: 1, Size: "square"})
+			} else {
+				_, err = c.GetWallet(context.Background())
+			}
+
+			if got := int(calls.Load()); got != tt.wantCalls {
+				t.Errorf("server got %d requests, want %d", got, tt.wantCalls)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWaitStopsWhenCancelled(t *testing.T) {
+	c := &Client{minBackoff: time.Hour, maxBackoff: time.Hour}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := c.wait(ctx, 0, time.Hour); !errors.Is(err, context.Canceled) {
+		t.Errorf("wait() error = %v, want context.Canceled", err)
+	}
+}
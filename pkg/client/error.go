@@ -0,0 +1,39 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Error is an RFC 7807 problem returned by the API
+type Error struct {
+	Type       string            `json:"type"`
+	Title      string            `json:"title"`
+	StatusCode int               `json:"status"`
+	Detail     string            `json:"detail,omitempty"`
+	Instance   string            `json:"instance,omitempty"`
+	Errors     map[string]string `json:"errors,omitempty"`
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("wonderpic: %d %s", e.StatusCode, e.Title)
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+	for field, fieldErr := range e.Errors {
+		msg += fmt.Sprintf("; %s: %s", field, fieldErr)
+	}
+	return msg
+}
+
+// IsStatus reports whether err is an API error with the status code
+func IsStatus(err error, statusCode int) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == statusCode
+}
+
+// IsNotFound reports whether err is a 404 API error
+func IsNotFound(err error) bool {
+	return IsStatus(err, http.StatusNotFound)
+}
@@ -0,0 +1,101 @@
+package client
+
+import "time"
+
+// Status of a prompt or image
+type Status string
+
+const (
+	StatusPending            Status = "pending"
+	StatusPartiallyCompleted Status = "partially_completed"
+	StatusCompleted          Status = "completed"
+	StatusFailed             Status = "failed"
+)
+
+// Done reports whether the generation has finished, successfully or not
+func (s Status) Done() bool {
+	return s != StatusPending
+}
+
+// Size is an image size accepted by the API
+type Size struct {
+	Name   string
+	Width  int
+	Height int
+}
+
+// DefaultSize is the size of Sizes that the web app preselects
+const DefaultSize = "square"
+
+// Sizes lists the sizes of the API's GenerationRequest.size enum, from smallest to largest
+var Sizes = []Size{
+	{Name: "square", Width: 512, Height: 512},
+	{Name: "portrait", Width: 512, Height: 768},
+	{Name: "landscape", Width: 768, Height: 512},
+	{Name: "hd", Width: 1024, Height: 1024},
+}
+
+type GenerationRequest struct {
+	Prompt     string `json:"prompt"`
+	ImageCount int    `json:"image_count"`
+	// Size is the name of one of Sizes
+	Size     string `json:"size"`
+	Model    string `json:"model,omitempty"`
+	Steps    int    `json:"steps,omitempty"`
+	Priority bool   `json:"priority,omitempty"`
+}
+
+type Prompt struct {
+	ID           string    `json:"id"`
+	Status       Status    `json:"status"`
+	Prompt       string    `json:"prompt"`
+	Cost         int       `json:"cost"`
+	PriceVersion string    `json:"price_version,omitempty"`
+	ImageCount   int       `json:"image_count"`
+	Size         string    `json:"size"`
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+	Images       []Image   `json:"images"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+type Image struct {
+	ID       string `json:"id"`
+	PromptID string `json:"prompt_id"`
+	Status   Status `json:"status"`
+	// ContentURL is set once the image is completed
+	ContentURL string    `json:"content_url,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type Wallet struct {
+	Credits uint `json:"credits"`
+}
+
+type Pagination struct {
+	Limit  int   `json:"limit"`
+	Offset int   `json:"offset"`
+	Total  int64 `json:"total"`
+}
+
+// More reports whether there are items after this page
+func (p Pagination) More() bool {
+	return int64(p.Offset+p.Limit) < p.Total
+}
+
+type PromptList struct {
+	Data       []Prompt   `json:"data"`
+	Pagination Pagination `json:"pagination"`
+}
+
+type ImageList struct {
+	Data       []Image    `json:"data"`
+	Pagination Pagination `json:"pagination"`
+}
+
+// ListOptions selects a page of a listing, zero values use the server defaults
+type ListOptions struct {
+	Limit  int
+	Offset int
+}
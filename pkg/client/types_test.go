@@ -0,0 +1,48 @@
+package client
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/CP-Payne/wonderpicai/internal/domain"
+	"github.com/CP-Payne/wonderpicai/internal/openapi"
+)
+
+// TestSizesMatchAPI keeps Sizes in line with the OpenAPI document and the server's catalog
+func TestSizesMatchAPI(t *testing.T) {
+	var doc struct {
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]struct {
+					Enum []string `json:"enum"`
+				} `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(openapi.Spec, &doc); err != nil {
+		t.Fatalf("failed to parse OpenAPI document: %v", err)
+	}
+	enum := doc.Components.Schemas["GenerationRequest"].Properties["size"].Enum
+
+	if len(enum) != len(Sizes) {
+		t.Fatalf("OpenAPI document has sizes %v, client has %d sizes", enum, len(Sizes))
+	}
+	for i, size := range Sizes {
+		if enum[i] != size.Name {
+			t.Errorf("size %d is %q, OpenAPI document has %q", i, size.Name, enum[i])
+		}
+
+		catalog, ok := domain.ImageSizeByKey(size.Name)
+		if !ok {
+			t.Errorf("size %q is not in the server catalog", size.Name)
+			continue
+		}
+		if catalog.Width != size.Width || catalog.Height != size.Height {
+			t.Errorf("size %q is %dx%d, server generates %dx%d", size.Name, size.Width, size.Height, catalog.Width, catalog.Height)
+		}
+	}
+
+	if DefaultSize != domain.DefaultImageSize {
+		t.Errorf("DefaultSize is %q, server default is %q", DefaultSize, domain.DefaultImageSize)
+	}
+}
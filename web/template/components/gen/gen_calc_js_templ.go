@@ -43,7 +43,7 @@ func GenCalculationJS() templ.Component {
 				}()
 			}
 			ctx = templ.InitializeContext(ctx)
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<script>\n    function bindGenFormEvents() {\n        const incrementBtn = document.getElementById(\"increment-images\");\n        const decrementBtn = document.getElementById(\"decrement-images\");\n        const imageCountInput = document.getElementById(\"image-count-input\");\n        const estimatedCostDisplay = document.getElementById(\"estimated-cost\");\n\n        if (!incrementBtn || !decrementBtn || !imageCountInput || !estimatedCostDisplay) return;\n\n        const costPerImage = parseInt(document.getElementById(\"gen-form\").dataset.costPerImage || \"0\");\n\n        function updateCost() {\n            const count = parseInt(imageCountInput.value || \"1\");\n            estimatedCostDisplay.textContent = count * costPerImage;\n        }\n\n        incrementBtn.addEventListener(\"click\", function () {\n            let current = parseInt(imageCountInput.value || \"1\");\n            if (current < 10) {\n                imageCountInput.value = current + 1;\n                updateCost();\n            }\n        });\n\n        decrementBtn.addEventListener(\"click\", function () {\n            let current = parseInt(imageCountInput.value || \"1\");\n            if (current > 1) {\n                imageCountInput.value = current - 1;\n                updateCost();\n            }\n        });\n\n        imageCountInput.addEventListener(\"input\", updateCost);\n        updateCost();\n    }\n\n    document.addEventListener(\"DOMContentLoaded\", bindGenFormEvents);\n\n    // Rebind after HTMX swaps\n    document.body.addEventListener(\"htmx:afterSwap\", function (evt) {\n        if (evt.target.id === \"gen-form\" || evt.target.querySelector(\"#gen-form\")) {\n            bindGenFormEvents();\n        }\n    });\n</script>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<script>\n    function bindGenFormEvents() {\n        const incrementBtn = document.getElementById(\"increment-images\");\n        const decrementBtn = document.getElementById(\"decrement-images\");\n        const imageCountInput = document.getElementById(\"image-count-input\");\n        const estimatedCostDisplay = document.getElementById(\"estimated-cost\");\n        const sizeSelect = document.getElementById(\"image-size-select\");\n        const costPerImageDisplay = document.getElementById(\"cost-per-image\");\n\n        if (!incrementBtn || !decrementBtn || !imageCountInput || !estimatedCostDisplay) return;\n\n        function costPerImage() {\n            const selected = sizeSelect && sizeSelect.selectedOptions[0];\n            if (selected && selected.dataset.cost) {\n                return parseInt(selected.dataset.cost);\n            }\n            return parseInt(document.getElementById(\"gen-form\").dataset.costPerImage || \"0\");\n        }\n\n        // The total is priced by the server so that discounts match what is charged\n        let estimateRequest = 0;\n        function updateCost() {\n            const count = parseInt(imageCountInput.value || \"1\");\n            const perImage = costPerImage();\n            if (costPerImageDisplay) {\n                costPerImageDisplay.textContent = perImage;\n            }\n\n            const params = new URLSearchParams({\n                image_count: count,\n                size: sizeSelect ? sizeSelect.value : \"\",\n            });\n            const requestID = ++estimateRequest;\n            fetch(\"/gen/estimate?\" + params.toString())\n                .then(function (resp) { return resp.ok ? resp.text() : Promise.reject(resp.status); })\n                .then(function (total) {\n                    // Ignore responses that arrive after a newer request\n                    if (requestID === estimateRequest) {\n                        estimatedCostDisplay.textContent = total;\n                    }\n                })\n                .catch(function () {\n                    if (requestID === estimateRequest) {\n                        estimatedCostDisplay.textContent = count * perImage;\n                    }\n                });\n        }\n\n        incrementBtn.addEventListener(\"click\", function () {\n            let current = parseInt(imageCountInput.value || \"1\");\n            if (current < 10) {\n                imageCountInput.value = current + 1;\n                updateCost();\n            }\n        });\n\n        decrementBtn.addEventListener(\"click\", function () {\n            let current = parseInt(imageCountInput.value || \"1\");\n            if (current > 1) {\n                imageCountInput.value = current - 1;\n                updateCost();\n            }\n        });\n\n        imageCountInput.addEventListener(\"input\", updateCost);\n        if (sizeSelect) {\n            sizeSelect.addEventListener(\"change\", updateCost);\n        }\n        updateCost();\n    }\n\n    document.addEventListener(\"DOMContentLoaded\", bindGenFormEvents);\n\n    // Rebind after HTMX swaps\n    document.body.addEventListener(\"htmx:afterSwap\", function (evt) {\n        if (evt.target.id === \"gen-form\" || evt.target.querySelector(\"#gen-form\")) {\n            bindGenFormEvents();\n        }\n    });\n</script>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
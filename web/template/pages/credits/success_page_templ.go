@@ -9,8 +9,10 @@ import "github.com/a-h/templ"
 import templruntime "github.com/a-h/templ/runtime"
 
 import "github.com/CP-Payne/wonderpicai/web/template"
+import "github.com/CP-Payne/wonderpicai/web/template/components/purchase"
+import "github.com/CP-Payne/wonderpicai/web/template/viewmodel"
 
-func SuccessPage() templ.Component {
+func SuccessPage(status viewmodel.CheckoutStatus) templ.Component {
 	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
 		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
 		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
@@ -43,7 +45,15 @@ func SuccessPage() templ.Component {
 				}()
 			}
 			ctx = templ.InitializeContext(ctx)
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<div class=\"h-[100vh] bg-base-200 py-12 sm:py-16 lg:py-20 flex items-center justify-center px-4\"><div class=\"card w-full max-w-lg bg-base-100 shadow-xl text-center p-8 sm:p-12\"><div class=\"mb-6\"><svg xmlns=\"http://www.w3.org/2000/svg\" class=\"h-20 w-20 text-success mx-auto\" fill=\"none\" viewBox=\"0 0 24 24\" stroke=\"currentColor\" stroke-width=\"2\"><path stroke-linecap=\"round\" stroke-linejoin=\"round\" d=\"M9 12l2 2 4-4m6 2a9 9 0 11-18 0 9 9 0 0118 0z\"></path></svg></div><h1 class=\"text-3xl sm:text-4xl font-bold text-success mb-4\">Payment Submitted Successfully!</h1><p class=\"text-lg text-base-content/80 mb-3\">Thank you for your purchase!</p><p class=\"text-base-content/70 mb-8\">Your credits will reflect in your account shortly, once the payment is fully confirmed by Stripe. This usually happens within a few minutes.</p><div class=\"mt-8 space-y-4\"><a href=\"")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<div class=\"h-[100vh] bg-base-200 py-12 sm:py-16 lg:py-20 flex items-center justify-center px-4\"><div class=\"card w-full max-w-lg bg-base-100 shadow-xl text-center p-8 sm:p-12\">")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = purchase.CheckoutStatus(status).Render(ctx, templ_7745c5c3_Buffer)
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "<div class=\"mt-8 space-y-4\"><a href=\"")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
@@ -52,7 +62,16 @@ func SuccessPage() templ.Component {
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 2, "\" class=\"btn btn-primary btn-wide\">Go to Image Generation</a><p class=\"text-xs text-base-content/60\">If your credits don't appear after 15 minutes, please email us at <a href=\"mailto:wonderpicai@example.com\" class=\"link\">wonderpicai@example.com</a>.</p></div></div></div>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "\" class=\"btn btn-primary btn-wide\">Go to Image Generation</a><p><a href=\"")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var4 templ.SafeURL = templ.URL("/account/purchases")
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(string(templ_7745c5c3_Var4)))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "\" class=\"link link-primary\">View your purchases and receipts</a></p><p class=\"text-xs text-base-content/60\">If your credits don't appear after 15 minutes, please email us at <a href=\"mailto:wonderpicai@example.com\" class=\"link\">wonderpicai@example.com</a>.</p></div></div></div>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
@@ -0,0 +1,126 @@
+package viewmodel
+
+type AccountPurchase struct {
+	Date        string
+	Description string
+	Credits     int
+	Amount      string // formatted for the user's locale
+	Status      string
+	// StatusClass is the badge style of the status, e.g. "badge-success"
+	StatusClass string
+	// ReceiptURL is empty while the payment has not been paid
+	ReceiptURL string
+}
+
+type AccountPurchasesViewData struct {
+	Purchases []AccountPurchase
+}
+
+type AccountAPIKey struct {
+	ID       string
+	Name     string
+	Prefix   string
+	Scopes   []string
+	Created  string
+	LastUsed string
+	Expires  string
+	Status   string
+	// StatusClass is the badge style of the status, e.g. "badge-success"
+	StatusClass string
+	Revocable   bool
+}
+
+type AccountOption struct {
+	Value string
+	Label string
+}
+
+type AccountAPIKeyForm struct {
+	Name          string
+	Scopes        []string
+	Expiry        string
+	ScopeOptions  []AccountOption
+	ExpiryOptions []AccountOption
+	Errors        map[string]string
+	Error         string
+}
+
+func (f AccountAPIKeyForm) HasScope(scope string) bool {
+	for _, s := range f.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// AccountAPIKeyCreated shows a new key, Secret is the full key and only shown once
+type AccountAPIKeyCreated struct {
+	Key    AccountAPIKey
+	Secret string
+}
+
+type AccountAPIKeysViewData struct {
+	Keys []AccountAPIKey
+	Form AccountAPIKeyForm
+}
+
+type AccountWebhook struct {
+	ID      string
+	URL     string
+	Events  []string
+	Created string
+	Active  bool
+}
+
+type AccountWebhookForm struct {
+	URL          string
+	Events       []string
+	EventOptions []AccountOption
+	Errors       map[string]string
+	Error        string
+}
+
+func (f AccountWebhookForm) HasEvent(event string) bool {
+	for _, e := range f.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// AccountWebhookCreated shows a new endpoint together with its signing secret
+type AccountWebhookCreated struct {
+	Webhook AccountWebhook
+	Secret  string
+}
+
+type AccountWebhooksViewData struct {
+	Webhooks []AccountWebhook
+	Form     AccountWebhookForm
+}
+
+type AccountWebhookDelivery struct {
+	ID        string
+	EventID   string
+	EventType string
+	Created   string
+	Status    string
+	// StatusClass is the badge style of the status, e.g. "badge-success"
+	StatusClass string
+	// Attempts is shown as "attempts/max"
+	Attempts       string
+	ResponseStatus int
+	LastError      string
+	// NextAttempt is set while the delivery is waiting for a retry
+	NextAttempt   string
+	Payload       string
+	Redeliverable bool
+}
+
+type AccountWebhookViewData struct {
+	Webhook    AccountWebhook
+	Secret     string
+	Deliveries []AccountWebhookDelivery
+}
@@ -0,0 +1,169 @@
+package viewmodel
+
+type AdminPackageRow struct {
+	ID         string
+	Name       string
+	Credits    int
+	PriceMinor int64
+	Currency   string
+	// Prices in other currencies, e.g. "eur:499, gbp:429"
+	Prices    string
+	SortOrder int
+	Badge     string
+	Active    bool
+	Errors    map[string]string
+	Error     string
+}
+
+type AdminPackagesViewData struct {
+	Packages []AdminPackageRow
+	New      AdminPackageRow
+}
+
+type AdminPromoRow struct {
+	ID             string
+	Code           string
+	Value          string // e.g. "150 credits" or "20% off"
+	Package        string
+	Redeemed       int
+	MaxRedemptions string
+	PerUserLimit   string
+	Validity       string
+	Active         bool
+}
+
+type AdminPackageChoice struct {
+	ID   string
+	Name string
+}
+
+type AdminPromoForm struct {
+	Code           string
+	Kind           string
+	Credits        int
+	PercentOff     int
+	PackageID      string
+	MaxRedemptions int
+	PerUserLimit   int
+	// ValidFrom and ValidUntil use the datetime-local input format, in UTC
+	ValidFrom  string
+	ValidUntil string
+	Active     bool
+	Packages   []AdminPackageChoice
+	Errors     map[string]string
+	Error      string
+}
+
+type AdminPromosViewData struct {
+	Promos []AdminPromoRow
+	New    AdminPromoForm
+}
+
+// AdminPager links to the neighbouring pages of a listing, empty URLs hide the link
+type AdminPager struct {
+	Summary string // e.g. "21-40 of 73"
+	PrevURL string
+	NextURL string
+}
+
+type AdminUserRow struct {
+	ID       string
+	Email    string
+	Username string
+	Role     string
+	Credits  uint
+	Created  string
+	Disabled bool
+}
+
+type AdminUsersViewData struct {
+	Query string
+	Users []AdminUserRow
+	Pager AdminPager
+}
+
+type AdminCreditTransaction struct {
+	Date      string
+	Kind      string
+	Amount    int
+	Balance   uint
+	Reference string
+	Reason    string
+}
+
+type AdminAuditEvent struct {
+	Sequence   int64
+	Date       string
+	Actor      string
+	Action     string
+	TargetType string
+	TargetID   string
+	// TargetURL links to the admin page of the target, if there is one
+	TargetURL string
+	Metadata  string
+	IP        string
+	UserAgent string
+}
+
+// AdminUserActions holds the forms that change a user. They are only shown to admins.
+type AdminUserActions struct {
+	UserID   string
+	Role     string
+	Roles    []string
+	Disabled bool
+	// Self hides the role and account forms on the admin's own page
+	Self   bool
+	Amount int
+	Reason string
+	// DisableReason is the reason entered to disable the account
+	DisableReason string
+	Errors        map[string]string
+	Error         string
+}
+
+type AdminUserViewData struct {
+	User         AdminUserRow
+	DisabledAt   string
+	DisabledNote string
+	CanManage    bool
+	Actions      AdminUserActions
+	Transactions []AdminCreditTransaction
+	Pager        AdminPager
+	Audit        []AdminAuditEvent
+}
+
+type AdminStuckPrompt struct {
+	ID        string
+	UserID    string
+	Text      string
+	Images    int
+	Created   string
+	Updated   string
+	JobStatus string
+	Attempts  string
+	LastError string
+}
+
+type AdminStuckPromptsViewData struct {
+	Threshold string
+	Prompts   []AdminStuckPrompt
+}
+
+type AdminAuditViewData struct {
+	Action     string
+	Actor      string
+	TargetType string
+	TargetID   string
+	Actions    []string
+	Events     []AdminAuditEvent
+	Pager      AdminPager
+}
+
+// AdminAuditVerification is the result of checking the audit log's hash chain
+type AdminAuditVerification struct {
+	Intact   bool
+	Checked  int64
+	BrokenAt int64
+	Problem  string
+	Error    string
+}
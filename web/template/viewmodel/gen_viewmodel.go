@@ -3,10 +3,21 @@ package viewmodel
 type GenFormData struct {
 	Prompt          string
 	Credits         int
-	MinCost         int
+	CostPerImage    int
 	MaxImagesPerGen int
 	ImageCount      int
+	Size            string
+	Sizes           []GenSizeOption
 	HasFailedImages bool
+	// QueuePosition of the user's next queued prompt, 0 when nothing is queued
+	QueuePosition int
+}
+
+type GenSizeOption struct {
+	Key          string
+	Label        string
+	Description  string
+	CostPerImage int
 }
 
 type GenFormComponentData struct {
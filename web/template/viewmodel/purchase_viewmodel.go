@@ -1,11 +1,58 @@
 package viewmodel
 
 type PurchaseOption struct {
-	Credits   int    // e.g 100, 200, 1000
-	Price     int    // e.g., "$5", "€10"
-	ActionURL string // For HTMX or link later
+	Name           string
+	Credits        int    // e.g 100, 200, 1000
+	Price          string // formatted for the user's locale, e.g. "$5.00", "10,00 €"
+	PricePerCredit string
+	OriginalPrice  string // price before a promo discount, empty when not discounted
+	Badge          string
+	ActionURL      string // For HTMX or link later
+}
+
+type CurrencyOption struct {
+	// Code is empty for the automatic choice based on the browser locale
+	Code     string
+	Label    string
+	Selected bool
 }
 
 type PurchaseViewData struct {
-	Options []PurchaseOption
+	Options    []PurchaseOption
+	Currencies []CurrencyOption
+	// PromoCode is the discount code applied to the options
+	PromoCode    string
+	PromoMessage string
+	Plans        []SubscriptionPlanOption
+	// Subscription is the user's current subscription, nil when not subscribed
+	Subscription *SubscriptionStatus
+}
+
+type SubscriptionPlanOption struct {
+	Name     string
+	Credits  int    // credits granted every period
+	Price    string // formatted for the user's locale
+	Interval string // e.g. "month"
+	Rollover string // how unused credits carry over, e.g. "Up to 150 unused credits roll over"
+	// Current is set on the plan the user is subscribed to
+	Current   bool
+	ActionURL string
+}
+
+type SubscriptionStatus struct {
+	PlanName string
+	Status   string
+	// RenewsOn is the end of the current period, formatted for display
+	RenewsOn          string
+	CancelAtPeriodEnd bool
+}
+
+type CheckoutStatus struct {
+	// State is "pending", "processing", "completed" or "failed"
+	State       string
+	Description string
+	Credits     int
+	Balance     int
+	// StatusURL is polled while the checkout is not completed or failed
+	StatusURL string
 }